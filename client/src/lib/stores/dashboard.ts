@@ -1,3 +1,8 @@
-import { dashboardRegistry, streamStatusMessage } from '$lib/websocket/registry';
+import {
+	dashboardRegistry,
+	processorStatusMessage,
+	streamStatusMessage
+} from '$lib/websocket/registry';
 
 export const streamStatus = dashboardRegistry.On(streamStatusMessage);
+export const processorStatuses = dashboardRegistry.On(processorStatusMessage);
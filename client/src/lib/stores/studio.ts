@@ -36,7 +36,7 @@ async function startAudioCapture(): Promise<MediaStream> {
 
 const INGEST_API = "http://localhost:8089/api/ingress/whip"
 
-async function startStreamConn() {
+async function startStreamConn(stream: string, streamKey: string) {
 	try {
 		const screenStream = await startScreenCapture()
 		const audioStream = await startAudioCapture()
@@ -64,11 +64,12 @@ async function startStreamConn() {
 			peerConnection.setLocalDescription(offer);
 			console.log(offer)
 
-			fetch(INGEST_API, {
+			fetch(`${INGEST_API}/${stream}`, {
 				method: 'POST',
 				body: offer.sdp,
 				headers: {
-					'Content-Type': 'application/sdp'
+					'Content-Type': 'application/sdp',
+					Authorization: `Bearer ${streamKey}`
 				}
 			}).then(r => r.text()).then(answer => {
 				peerConnection.setRemoteDescription({
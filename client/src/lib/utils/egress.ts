@@ -14,3 +14,5 @@ export const STREAM_EGRESS_TYPE = StreamEgressType
 export const HLS_EGRESS: string = STREAM_EGRESS[STREAM_EGRESS_TYPE.STREAM_TYPE_HLS] as any;
 
 export const WEBRTC_EGRESS: string = STREAM_EGRESS[STREAM_EGRESS_TYPE.STREAM_TYPE_WEBRTC] as any;
+
+export const DASH_EGRESS: string = STREAM_EGRESS[STREAM_EGRESS_TYPE.STREAM_TYPE_DASH] as any;
@@ -1,4 +1,5 @@
 import * as channelpb from '$gen/streaming/v1alpha/channel';
+import * as ingestpb from '$gen/subject/v1alpha/ingest';
 import { writable, type Readable, type Writable } from 'svelte/store';
 
 interface WebsocketMessage {
@@ -39,6 +40,27 @@ export class StreamStatus
 	}
 }
 
+type ProcessorStatusesBaseType = Record<string, ingestpb.IngestProcessorStatus>;
+
+// Latest status of each media processor of the ingest
+export class IngestProcessorStatus
+	extends WebsocketMessageBase<ProcessorStatusesBaseType>
+	implements WebsocketMessage
+{
+	private statuses: ProcessorStatusesBaseType = {};
+
+	RegistryName(): string {
+		return ingestpb.IngestProcessorStatus.typeName;
+	}
+
+	Process(data: string): void {
+		const processorStatus = ingestpb.IngestProcessorStatus.fromJsonString(data);
+
+		this.statuses = { ...this.statuses, [processorStatus.processor]: processorStatus };
+		this.writable.set(this.statuses);
+	}
+}
+
 class WebsocketMesssageRegistry {
 	private readonly registry = new Map<string, WebsocketStatefulMessage<any>>();
 
@@ -79,6 +101,8 @@ export async function HandleDashboardWebsocket(event: MessageEvent<any>) {
 }
 
 export const streamStatusMessage = new StreamStatus();
+export const processorStatusMessage = new IngestProcessorStatus();
 
 export const dashboardRegistry = new WebsocketMesssageRegistry();
 dashboardRegistry.Register(streamStatusMessage);
+dashboardRegistry.Register(processorStatusMessage);
@@ -14,6 +14,7 @@ type Egress = {
 type Stream = {
 	active_stream_id: string;
 	username: string;
+	thumbnail?: string;
 	egresses: Array<Egress>;
 }
 
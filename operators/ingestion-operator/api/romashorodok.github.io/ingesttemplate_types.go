@@ -5,9 +5,47 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// HLS rendition of the ingest output. Bitrates are in kbit/s.
+// Rendition without video bitrate is audio only. Without height it keeps the source resolution
+type IngestRendition struct {
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_-]+$`
+	Name         string `json:"name"`
+	Width        int32  `json:"width,omitempty"`
+	Height       int32  `json:"height,omitempty"`
+	VideoBitrate int32  `json:"videoBitrate,omitempty"`
+	AudioBitrate int32  `json:"audioBitrate,omitempty"`
+}
+
+// Recording of the broadcasts. Recording is uploaded into S3-compatible bucket when endpoint is set
+type IngestRecording struct {
+	// +kubebuilder:validation:Enum=mkv;mp4
+	Format     string `json:"format,omitempty"`
+	S3Endpoint string `json:"s3Endpoint,omitempty"`
+	S3Region   string `json:"s3Region,omitempty"`
+	S3Bucket   string `json:"s3Bucket,omitempty"`
+	// Secret with accessKey and secretKey of the bucket
+	S3CredentialsSecret string `json:"s3CredentialsSecret,omitempty"`
+	// PersistentVolumeClaim of the recordings. Without it recordings live on the node only while the pod lives
+	ClaimName string `json:"claimName,omitempty"`
+}
+
 type IngestTemplateSpec struct {
 	Image string                 `json:"image,omitempty"`
 	Ports []corev1.ContainerPort `json:"ports,omitempty"`
+	// HLS ladder of the ingest. Ingest default is used when empty
+	Renditions []IngestRendition `json:"renditions,omitempty"`
+	// Serve LL-HLS with parts and blocking playlist reload
+	LowLatencyHLS bool `json:"lowLatencyHLS,omitempty"`
+	// Pack H264 of the publisher into HLS without transcoding. Renditions are ignored for H264 publishers
+	PassthroughHLS bool `json:"passthroughHLS,omitempty"`
+	// Time-shift window of the HLS playlist, e.g. 2h. Viewers may rewind the live stream within it
+	DVRWindow string `json:"dvrWindow,omitempty"`
+	// Serve MPEG-DASH with CMAF segments of the renditions alongside HLS
+	DASH bool `json:"dash,omitempty"`
+	// Record each broadcast
+	Recording *IngestRecording `json:"recording,omitempty"`
+	// Produce live preview thumbnails of the broadcast
+	Thumbnail bool `json:"thumbnail,omitempty"`
 }
 
 type IngestTemplateStatus struct {
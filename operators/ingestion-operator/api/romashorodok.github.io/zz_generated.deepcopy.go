@@ -26,6 +26,36 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngestRecording) DeepCopyInto(out *IngestRecording) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngestRecording.
+func (in *IngestRecording) DeepCopy() *IngestRecording {
+	if in == nil {
+		return nil
+	}
+	out := new(IngestRecording)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngestRendition) DeepCopyInto(out *IngestRendition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngestRendition.
+func (in *IngestRendition) DeepCopy() *IngestRendition {
+	if in == nil {
+		return nil
+	}
+	out := new(IngestRendition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngestTemplate) DeepCopyInto(out *IngestTemplate) {
 	*out = *in
@@ -93,6 +123,16 @@ func (in *IngestTemplateSpec) DeepCopyInto(out *IngestTemplateSpec) {
 		*out = make([]v1.ContainerPort, len(*in))
 		copy(*out, *in)
 	}
+	if in.Renditions != nil {
+		in, out := &in.Renditions, &out.Renditions
+		*out = make([]IngestRendition, len(*in))
+		copy(*out, *in)
+	}
+	if in.Recording != nil {
+		in, out := &in.Recording, &out.Recording
+		*out = new(IngestRecording)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngestTemplateSpec.
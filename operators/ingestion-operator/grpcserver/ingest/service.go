@@ -63,6 +63,7 @@ func (s *IngestControllerService) StartServer(context context.Context, req *inge
 		Template:      ingestTemplate,
 		Username:      req.Meta.Username,
 		BroadcasterID: req.Meta.BroadcasterId,
+		Restream:      restreamTargets(req.RestreamTargets),
 	}); err != nil {
 		s.logger.Error(err, "unable stop ingest system")
 		return nil, status.Error(codes.Internal, err.Error())
@@ -74,6 +75,18 @@ func (s *IngestControllerService) StartServer(context context.Context, req *inge
 	}, nil
 }
 
+func restreamTargets(targets []*ingestioncontrollerpb.RestreamTarget) []ingestresource.RestreamTarget {
+	result := make([]ingestresource.RestreamTarget, 0, len(targets))
+	for _, target := range targets {
+		result = append(result, ingestresource.RestreamTarget{
+			ID:  target.Id,
+			URL: target.Url,
+			Key: target.Key,
+		})
+	}
+	return result
+}
+
 func (s *IngestControllerService) StopServer(context context.Context, req *ingestioncontrollerpb.StopServerRequest) (*ingestioncontrollerpb.StopServerResponse, error) {
 
 	if err := s.ingestSystem.StopIngestSystem(context, req.Deployment, req.Namespace); err != nil {
@@ -2,8 +2,12 @@ package ingestresource
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	v1alpha1 "github.com/romashorodok/stream-platform/operators/ingestion-operator/api/romashorodok.github.io"
 	"github.com/romashorodok/stream-platform/pkg/variables"
@@ -26,8 +30,77 @@ const (
 	BROADCASTER_ID      = "romashorodok.github.io/ingest.broadcaster-id"
 	ISTIO_SIDECAR_LABEL = "sidecar.istio.io/inject"
 	ISTIO_SIDECAR_VALUE = "true"
+
+	RESTREAM_TARGETS_SECRET_KEY = "targets"
+
+	RECORDING_VOLUME_NAME = "recordings"
+	// Pod is killed this long after its processors are given up by the ingest
+	stopGraceMargin = 30 * time.Second
 )
 
+// Pod termination waits the ingest which waits its processors, e.g. recording finalize and upload
+func terminationGracePeriodSeconds() *int64 {
+	stopTimeout, _ := time.ParseDuration(variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT_DEFAULT)
+	seconds := int64((stopTimeout + stopGraceMargin) / time.Second)
+	return &seconds
+}
+
+// Recordings are kept by the claim, otherwise by the node while the pod lives
+func recordingVolume(recording *v1alpha1.IngestRecording) corev1.Volume {
+	if recording.ClaimName != "" {
+		return corev1.Volume{
+			Name: RECORDING_VOLUME_NAME,
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: recording.ClaimName,
+			}},
+		}
+	}
+	return corev1.Volume{
+		Name:         RECORDING_VOLUME_NAME,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+}
+
+// Credentials of the bucket are read from the secret of the ingest namespace
+func recordingEnv(recording *v1alpha1.IngestRecording) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if recording.Format != "" {
+		env = append(env, corev1.EnvVar{Name: variables.INGEST_RECORDING_FORMAT, Value: recording.Format})
+	}
+	if recording.S3Endpoint == "" {
+		return env
+	}
+
+	env = append(env, corev1.EnvVar{Name: variables.INGEST_RECORDING_S3_ENDPOINT, Value: recording.S3Endpoint})
+	if recording.S3Region != "" {
+		env = append(env, corev1.EnvVar{Name: variables.INGEST_RECORDING_S3_REGION, Value: recording.S3Region})
+	}
+	if recording.S3Bucket != "" {
+		env = append(env, corev1.EnvVar{Name: variables.INGEST_RECORDING_S3_BUCKET, Value: recording.S3Bucket})
+	}
+	if recording.S3CredentialsSecret != "" {
+		secretKey := func(key string) *corev1.EnvVarSource {
+			return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: recording.S3CredentialsSecret},
+				Key:                  key,
+			}}
+		}
+		env = append(env,
+			corev1.EnvVar{Name: variables.INGEST_RECORDING_S3_ACCESS_KEY, ValueFrom: secretKey("accessKey")},
+			corev1.EnvVar{Name: variables.INGEST_RECORDING_S3_SECRET_KEY, ValueFrom: secretKey("secretKey")},
+		)
+	}
+
+	return env
+}
+
+// Outbound RTMP destination of the broadcaster. Marshaled as the ingest expects
+type RestreamTarget struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	Key string `json:"key"`
+}
+
 type IngestDeploymentByTemplateParams struct {
 	Template   *v1alpha1.IngestTemplate
 	AppName    string
@@ -38,6 +111,8 @@ type IngestDeploymentByTemplateParams struct {
 
 	BroadcasterID string
 	Username      string
+	// Secret of the restream targets. Ingest doesn't restream without it
+	RestreamSecret string
 }
 
 func (mgr *IngestResourceManager) IngestDeploymentByTemplate(params IngestDeploymentByTemplateParams) *appsv1.Deployment {
@@ -69,8 +144,11 @@ func (mgr *IngestResourceManager) IngestDeploymentByTemplate(params IngestDeploy
 		Image: params.Template.Spec.Image,
 		Ports: ports,
 		Env: []corev1.EnvVar{
+			{Name: variables.INGEST_DEDICATED, Value: "true"},
 			{Name: variables.INGEST_BROADCASTER_ID, Value: params.BroadcasterID},
 			{Name: variables.INGEST_USERNAME, Value: params.Username},
+			{Name: variables.INGEST_IDENTITY_URL, Value: variables.INGEST_IDENTITY_URL_DEFAULT},
+			{Name: variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT, Value: variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT_DEFAULT},
 
 			{Name: variables.INGEST_UDP_PORT, Value: fmt.Sprint(params.WebrtcPort)},
 			{Name: variables.INGEST_TCP_PORT, Value: fmt.Sprint(params.WebrtcPort)},
@@ -87,6 +165,58 @@ func (mgr *IngestResourceManager) IngestDeploymentByTemplate(params IngestDeploy
 		},
 	}
 
+	// Ingest keeps own default ladder when template has no renditions
+	if len(params.Template.Spec.Renditions) > 0 {
+		ladder, err := json.Marshal(params.Template.Spec.Renditions)
+		if err != nil {
+			log.Printf("[IngestResourceManager] unable marshal %s renditions. Err: %s", params.Template.Name, err)
+		} else {
+			ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{Name: variables.INGEST_HLS_LADDER, Value: string(ladder)})
+		}
+	}
+
+	if params.Template.Spec.LowLatencyHLS {
+		ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{Name: variables.INGEST_HLS_LOW_LATENCY, Value: "true"})
+	}
+
+	if params.Template.Spec.PassthroughHLS {
+		ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{Name: variables.INGEST_HLS_PASSTHROUGH, Value: "true"})
+	}
+
+	if params.Template.Spec.DVRWindow != "" {
+		ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{Name: variables.INGEST_HLS_DVR_WINDOW, Value: params.Template.Spec.DVRWindow})
+	}
+
+	processors := []string{variables.INGEST_MEDIA_PROCESSOR_HLS}
+	if params.Template.Spec.DASH {
+		processors = append(processors, variables.INGEST_MEDIA_PROCESSOR_DASH)
+	}
+	if params.Template.Spec.Thumbnail {
+		processors = append(processors, variables.INGEST_MEDIA_PROCESSOR_THUMBNAIL)
+	}
+	var volumes []corev1.Volume
+	if recording := params.Template.Spec.Recording; recording != nil {
+		processors = append(processors, variables.INGEST_MEDIA_PROCESSOR_RECORDING)
+		ingestContainer.Env = append(ingestContainer.Env, recordingEnv(recording)...)
+		ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{Name: variables.INGEST_RECORDING_DIRECTORY, Value: variables.INGEST_RECORDING_DIRECTORY_DEFAULT})
+		ingestContainer.VolumeMounts = append(ingestContainer.VolumeMounts, corev1.VolumeMount{
+			Name:      RECORDING_VOLUME_NAME,
+			MountPath: variables.INGEST_RECORDING_DIRECTORY_DEFAULT,
+		})
+		volumes = append(volumes, recordingVolume(recording))
+	}
+	if params.RestreamSecret != "" {
+		processors = append(processors, variables.INGEST_MEDIA_PROCESSOR_RESTREAM)
+		ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{
+			Name: variables.INGEST_RESTREAM_TARGETS,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: params.RestreamSecret},
+				Key:                  RESTREAM_TARGETS_SECRET_KEY,
+			}},
+		})
+	}
+	ingestContainer.Env = append(ingestContainer.Env, corev1.EnvVar{Name: variables.INGEST_MEDIA_PROCESSORS, Value: strings.Join(processors, ",")})
+
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      params.AppName,
@@ -111,13 +241,44 @@ func (mgr *IngestResourceManager) IngestDeploymentByTemplate(params IngestDeploy
 							},
 						},
 					},
-					Containers: []corev1.Container{ingestContainer},
+					Containers:                    []corev1.Container{ingestContainer},
+					Volumes:                       volumes,
+					TerminationGracePeriodSeconds: terminationGracePeriodSeconds(),
 				},
 			},
 		},
 	}
 }
 
+type IngestRestreamSecretParams struct {
+	Template   *v1alpha1.IngestTemplate
+	SecretName string
+	Namespace  string
+	Owner      string
+	Restream   []RestreamTarget
+}
+
+// Restream targets keep the stream keys of the destinations, so they are passed to the ingest by the secret
+func (mgr *IngestResourceManager) IngestRestreamSecret(params IngestRestreamSecretParams) (*corev1.Secret, error) {
+	targets, err := json.Marshal(params.Restream)
+	if err != nil {
+		return nil, fmt.Errorf("unable marshal restream targets. Err: %s", err)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      params.SecretName,
+			Namespace: params.Namespace,
+			Labels: labels.Set{
+				CREATED_BY: params.Template.Name,
+				OWNED_BY:   params.Owner,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{RESTREAM_TARGETS_SECRET_KEY: targets},
+	}, nil
+}
+
 type IngestHeadlessServiceParams struct {
 	Template          *v1alpha1.IngestTemplate
 	IngestServiceName string
@@ -267,6 +428,33 @@ func (mgr *IngestResourceManager) GetIngestServiceByAppName(params GetIngestServ
 	return &service, nil
 }
 
+type GetIngestSecretByAppNameParams struct {
+	Context    context.Context
+	Namespace  string
+	SecretName string
+	Owner      string
+}
+
+func (mgr *IngestResourceManager) GetIngestSecretByAppName(params GetIngestSecretByAppNameParams) (*corev1.Secret, error) {
+	var secret corev1.Secret
+	namespacedName := types.NamespacedName{Namespace: params.Namespace, Name: params.SecretName}
+
+	if err := mgr.k8s.Get(params.Context, namespacedName, &secret); err != nil {
+		return nil, err
+	}
+
+	owner, ok := secret.Labels[OWNED_BY]
+	if !ok {
+		return nil, errors.New("trying to get non-owner resource access")
+	}
+
+	if owner != params.Owner {
+		return nil, errors.New("trying to get non-owner resource access")
+	}
+
+	return &secret, nil
+}
+
 type GetIngestNamespaceByAppName struct {
 	Context context.Context
 	AppName string
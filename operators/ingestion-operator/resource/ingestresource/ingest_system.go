@@ -18,6 +18,7 @@ import (
 	"golang.org/x/sync/errgroup"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -49,6 +50,7 @@ type StartIngestSystemParams struct {
 	Template      *v1alpha1.IngestTemplate
 	Username      string
 	BroadcasterID string
+	Restream      []RestreamTarget
 }
 
 func (s *IngestSystem) StartIngestSystem(params StartIngestSystemParams) error {
@@ -66,6 +68,28 @@ func (s *IngestSystem) StartIngestSystem(params StartIngestSystemParams) error {
 	}
 	webrtcPort := uint16(webrtcNodePort.Port())
 
+	// Ingest deployment references the secret, so it's created first
+	var restreamSecretName string
+	if len(params.Restream) > 0 {
+		restreamSecretName = fmt.Sprintf("%s-restream", params.AppName)
+
+		secret, err := s.ingestResourceManager.IngestRestreamSecret(IngestRestreamSecretParams{
+			Template:   params.Template,
+			SecretName: restreamSecretName,
+			Namespace:  params.Namespace,
+			Owner:      owner,
+			Restream:   params.Restream,
+		})
+		if err == nil {
+			err = s.k8s.Create(params.Context, secret)
+		}
+		if err != nil {
+			_ = s.nodePortRange.PortBack(webrtcNodePort.Port())
+
+			return fmt.Errorf("unable deploy ingest restream secret. Error: %s", err)
+		}
+	}
+
 	// TODO: How to deal with namespace
 	// NOTE: When I delete a namespace, the namespace changes its state to 'terminating,' and I can't create it again until the termination is complete.
 	// Create it with prefix like `username-uuid.new()`
@@ -84,14 +108,15 @@ func (s *IngestSystem) StartIngestSystem(params StartIngestSystemParams) error {
 
 	g.Go(func() error {
 		ingest := s.ingestResourceManager.IngestDeploymentByTemplate(IngestDeploymentByTemplateParams{
-			Namespace:     params.Namespace,
-			AppName:       params.AppName,
-			Template:      params.Template,
-			Replicas:      1,
-			Owner:         owner,
-			BroadcasterID: params.BroadcasterID,
-			Username:      params.Username,
-			WebrtcPort:    webrtcPort,
+			Namespace:      params.Namespace,
+			AppName:        params.AppName,
+			Template:       params.Template,
+			Replicas:       1,
+			Owner:          owner,
+			BroadcasterID:  params.BroadcasterID,
+			Username:       params.Username,
+			WebrtcPort:     webrtcPort,
+			RestreamSecret: restreamSecretName,
 		})
 
 		return s.k8s.Create(params.Context, ingest)
@@ -248,6 +273,7 @@ func (s *IngestSystem) StopIngestSystem(context context.Context, appName string,
 	ingestServiceName := appName
 	ingestWebrtcUDPGatewayServiceName := fmt.Sprintf("%s-webrtc-udp-gateway", appName)
 	ingestWebrtcTCPGatewayServiceName := fmt.Sprintf("%s-webrtc-tcp-gateway", appName)
+	restreamSecretName := fmt.Sprintf("%s-restream", appName)
 	owner := appName
 
 	// NOTE: If delete by namespace i need wait until namespace will terminated
@@ -345,6 +371,24 @@ func (s *IngestSystem) StopIngestSystem(context context.Context, appName string,
 		return s.k8s.Delete(context, virtualService)
 	})
 
+	g.Go(func() error {
+		secret, err := s.ingestResourceManager.GetIngestSecretByAppName(GetIngestSecretByAppNameParams{
+			Context:    context,
+			Namespace:  namespace,
+			SecretName: restreamSecretName,
+			Owner:      owner,
+		})
+		// Ingest without restream targets has no secret
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable find ingest restream secret for %s/%s. Error: %s", namespace, restreamSecretName, err)
+		}
+
+		return s.k8s.Delete(context, secret)
+	})
+
 	if err := g.Wait(); err != nil {
 		if result := findFirstWebrtcPortInDeploymentOrService(ingestDeployment, ingestWebrtcGatewayService); result != nil {
 			if err := s.nodePortRange.PortBack(portrange.Port(*result)); err != nil {
@@ -87,6 +87,10 @@ func authenticator(ctx context.Context, input *openapi3filter.AuthenticationInpu
 		return fmt.Errorf("Invalid token. Error: %s", err)
 	}
 
+	if err = ValidateTokenUse(verifiedPayload, ACCESS_TOKEN_USE); err != nil {
+		return fmt.Errorf("Invalid token. Error: %s", err)
+	}
+
 	authContext := context.WithValue(
 		request.Context(),
 		TOKEN_CONTEXT_VALUE,
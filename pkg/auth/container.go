@@ -4,12 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
 )
 
 const TOKEN_CONTEXT_VALUE = "__token_payload"
 
+const (
+	ACCESS_TOKEN_USE  = "access_token"
+	REFRESH_TOKEN_USE = "refresh_token"
+)
+
+var InvalidTokenUseError = errors.New("unexpected token use")
+
 type TokenPayload struct {
 	Aud      []string  `json:"aud"`
 	Exp      int       `json:"exp"`
@@ -34,6 +42,20 @@ func WithTokenPayload(ctx context.Context) (*TokenPayload, error) {
 	return &payload, nil
 }
 
+// Token issued for other use, like the stream key, isn't accepted in place of this one
+func ValidateTokenUse(tokenPayload []byte, use string) error {
+	payload, err := WithRawTokenPayload(tokenPayload)
+	if err != nil {
+		return err
+	}
+
+	if payload.TokenUse != use {
+		return fmt.Errorf("%w %s", InvalidTokenUseError, payload.TokenUse)
+	}
+
+	return nil
+}
+
 func WithRawTokenPayload(tokenPayload []byte) (*TokenPayload, error) {
 	var payload TokenPayload
 
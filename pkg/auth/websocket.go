@@ -24,6 +24,10 @@ func (s *RefreshTokenAuthenticator) Validate(context context.Context, plainToken
 		return nil, fmt.Errorf("Invalid token. Error: %s", err)
 	}
 
+	if err = ValidateTokenUse(verifiedPayload, REFRESH_TOKEN_USE); err != nil {
+		return nil, fmt.Errorf("Invalid token. Error: %s", err)
+	}
+
 	return verifiedPayload, nil
 }
 
@@ -24,3 +24,35 @@ type IngestDestroyed = subjectpb.IngestDestroyed
 func NewIngestDestroyed(broadcasterID string) string {
 	return strings.Replace(IngestAnyUserDestroyed, "*", broadcasterID, 1)
 }
+
+const IngestAnyUserRunning = "ingest.*.running"
+
+type IngestRunning = subjectpb.IngestRunning
+
+func NewIngestRunning(broadcasterID string) string {
+	return strings.Replace(IngestAnyUserRunning, "*", broadcasterID, 1)
+}
+
+const IngestAnyUserStopped = "ingest.*.stopped"
+
+type IngestStopped = subjectpb.IngestStoped
+
+func NewIngestStopped(broadcasterID string) string {
+	return strings.Replace(IngestAnyUserStopped, "*", broadcasterID, 1)
+}
+
+const IngestAnyUserHeartbeat = "ingest.*.heartbeat"
+
+type IngestHeartbeat = subjectpb.IngestHeartbeat
+
+func NewIngestHeartbeat(broadcasterID string) string {
+	return strings.Replace(IngestAnyUserHeartbeat, "*", broadcasterID, 1)
+}
+
+const IngestAnyUserProcessorStatus = "ingest.*.processor"
+
+type IngestProcessorStatus = subjectpb.IngestProcessorStatus
+
+func NewIngestProcessorStatus(broadcasterID string) string {
+	return strings.Replace(IngestAnyUserProcessorStatus, "*", broadcasterID, 1)
+}
@@ -7,3 +7,9 @@ const StreamDestroyedNotification = "private.stream.destroyed.notification.*.emp
 func NewStreamDestroyedNotification(broadcasterID string) string {
 	return strings.Replace(StreamDestroyedNotification, "*", broadcasterID, 1)
 }
+
+const StreamStatusNotification = "private.stream.status.notification.*.protobuf"
+
+func NewStreamStatusNotification(broadcasterID string) string {
+	return strings.Replace(StreamStatusNotification, "*", broadcasterID, 1)
+}
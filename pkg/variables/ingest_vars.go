@@ -3,6 +3,7 @@ package variables
 import "github.com/google/uuid"
 
 const (
+	INGEST_DEDICATED      = "INGEST_DEDICATED"
 	INGEST_BROADCASTER_ID = "INGEST_BROADCASTER_ID"
 	INGEST_USERNAME       = "INGEST_USERNAME"
 	INGEST_NAT_PUBLIC_IP  = "INGEST_NAT_PUBLIC_IP"
@@ -13,9 +14,48 @@ const (
 
 	INGEST_FAIL_FAST = "INGEST_FAIL_FAST"
 
+	INGEST_IDENTITY_URL = "INGEST_IDENTITY_URL"
+
+	INGEST_HEARTBEAT_INTERVAL = "INGEST_HEARTBEAT_INTERVAL"
+	INGEST_RECONNECT_WINDOW   = "INGEST_RECONNECT_WINDOW"
+	INGEST_FAILOVER_TIMEOUT   = "INGEST_FAILOVER_TIMEOUT"
+
+	INGEST_JITTER_BUFFER_LATENCY = "INGEST_JITTER_BUFFER_LATENCY"
+
+	INGEST_HLS_LADDER      = "INGEST_HLS_LADDER"
+	INGEST_HLS_LOW_LATENCY = "INGEST_HLS_LOW_LATENCY"
+	INGEST_HLS_PASSTHROUGH = "INGEST_HLS_PASSTHROUGH"
+	INGEST_HLS_DVR_WINDOW  = "INGEST_HLS_DVR_WINDOW"
+
+	INGEST_MEDIA_PROCESSORS             = "INGEST_MEDIA_PROCESSORS"
+	INGEST_MEDIA_PROCESSOR_MAX_RESTARTS = "INGEST_MEDIA_PROCESSOR_MAX_RESTARTS"
+	INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT = "INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT"
+
+	INGEST_RECORDING_FORMAT        = "INGEST_RECORDING_FORMAT"
+	INGEST_RECORDING_DIRECTORY     = "INGEST_RECORDING_DIRECTORY"
+	INGEST_RECORDING_S3_ENDPOINT   = "INGEST_RECORDING_S3_ENDPOINT"
+	INGEST_RECORDING_S3_REGION     = "INGEST_RECORDING_S3_REGION"
+	INGEST_RECORDING_S3_BUCKET     = "INGEST_RECORDING_S3_BUCKET"
+	INGEST_RECORDING_S3_ACCESS_KEY = "INGEST_RECORDING_S3_ACCESS_KEY"
+	INGEST_RECORDING_S3_SECRET_KEY = "INGEST_RECORDING_S3_SECRET_KEY"
+
+	INGEST_RESTREAM_TARGETS = "INGEST_RESTREAM_TARGETS"
+
+	INGEST_THUMBNAIL_INTERVAL = "INGEST_THUMBNAIL_INTERVAL"
+	INGEST_THUMBNAIL_WIDTH    = "INGEST_THUMBNAIL_WIDTH"
+	INGEST_THUMBNAIL_FORMAT   = "INGEST_THUMBNAIL_FORMAT"
+	INGEST_THUMBNAIL_SPRITE   = "INGEST_THUMBNAIL_SPRITE"
+
 	INGEST_HTTP_HOST = "INGEST_HTTP_HOST"
 	INGEST_HTTP_PORT = "INGEST_HTTP_PORT"
 
+	INGEST_RTMP_HOST = "INGEST_RTMP_HOST"
+	INGEST_RTMP_PORT = "INGEST_RTMP_PORT"
+
+	INGEST_SRT_HOST    = "INGEST_SRT_HOST"
+	INGEST_SRT_PORT    = "INGEST_SRT_PORT"
+	INGEST_SRT_LATENCY = "INGEST_SRT_LATENCY"
+
 	NATS_HOST = "NATS_HOST"
 	NATS_PORT = "NATS_PORT"
 
@@ -26,6 +66,9 @@ const (
 )
 
 const (
+	// Shared ingest accepts stream keys of any broadcaster
+	INGEST_DEDICATED_DEFAULT = "false"
+
 	INGEST_USERNAME_DEFAULT      = "admin"
 	// INGEST_NAT_PUBLIC_IP_DEFAULT = "127.0.0.1"
 	INGEST_NAT_PUBLIC_IP_DEFAULT = ""
@@ -36,9 +79,56 @@ const (
 
 	INGEST_FAIL_FAST_DEFAULT = "false"
 
+	INGEST_IDENTITY_URL_DEFAULT = "http://stream-platform-identity.default.svc.cluster.local:8083"
+
+	INGEST_HEARTBEAT_INTERVAL_DEFAULT = "10s"
+	// Outputs of the disconnected publisher wait its republish. Zero releases the stream at once
+	INGEST_RECONNECT_WINDOW_DEFAULT = "10s"
+	// Backup publisher takes over when the primary doesn't deliver media for this time
+	INGEST_FAILOVER_TIMEOUT_DEFAULT = "2s"
+	// WebRTC packet is waited for this time while it's retransmitted. Longer latency recovers more loss of the slow uplink
+	INGEST_JITTER_BUFFER_LATENCY_DEFAULT = "200ms"
+
+	// Single rendition of the source resolution
+	INGEST_HLS_LADDER_DEFAULT      = `[{"name":"source","videoBitrate":2000,"audioBitrate":128}]`
+	INGEST_HLS_LOW_LATENCY_DEFAULT = "false"
+	INGEST_HLS_PASSTHROUGH_DEFAULT = "false"
+	// Time-shift window of the playlist. Zero keeps only the live edge
+	INGEST_HLS_DVR_WINDOW_DEFAULT = "0s"
+
+	// Comma separated processors of each stream
+	INGEST_MEDIA_PROCESSORS_DEFAULT = INGEST_MEDIA_PROCESSOR_HLS
+	// Crashed processor is restarted this many times in a row before it's failed
+	INGEST_MEDIA_PROCESSOR_MAX_RESTARTS_DEFAULT = "5"
+	// Stopped stream waits its processors this long, e.g. recording finalize and upload
+	INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT_DEFAULT = "5m"
+
+	INGEST_RECORDING_FORMAT_DEFAULT    = "mkv"
+	INGEST_RECORDING_DIRECTORY_DEFAULT = "/var/lib/ingest/recordings"
+	// Upload is disabled without endpoint
+	INGEST_RECORDING_S3_ENDPOINT_DEFAULT = ""
+	INGEST_RECORDING_S3_REGION_DEFAULT   = "us-east-1"
+	INGEST_RECORDING_S3_BUCKET_DEFAULT   = "recordings"
+
+	// JSON array of {"id","url","key"}
+	INGEST_RESTREAM_TARGETS_DEFAULT = "[]"
+
+	INGEST_THUMBNAIL_INTERVAL_DEFAULT = "10s"
+	INGEST_THUMBNAIL_WIDTH_DEFAULT    = "320"
+	// jpg or webp
+	INGEST_THUMBNAIL_FORMAT_DEFAULT = "jpg"
+	INGEST_THUMBNAIL_SPRITE_DEFAULT = "false"
+
 	INGEST_HTTP_HOST_DEFAULT = "0.0.0.0"
 	INGEST_HTTP_PORT_DEFAULT = "8089"
 
+	INGEST_RTMP_HOST_DEFAULT = "0.0.0.0"
+	INGEST_RTMP_PORT_DEFAULT = "1935"
+
+	INGEST_SRT_HOST_DEFAULT    = "0.0.0.0"
+	INGEST_SRT_PORT_DEFAULT    = "9000"
+	INGEST_SRT_LATENCY_DEFAULT = "120ms"
+
 	NATS_HOST_DEFAULT  = "0.0.0.0"
 	NATS_HOST_HEADLESS = "nats-release-headless.nats-system.svc.cluster.local"
 	NATS_PORT_DEFAULT  = "4222"
@@ -49,4 +139,14 @@ const (
 	TURN_PASSWORD_DEFAULT = "pass-1"
 )
 
+// Media processors of INGEST_MEDIA_PROCESSORS
+const (
+	INGEST_MEDIA_PROCESSOR_HLS  = "hls"
+	INGEST_MEDIA_PROCESSOR_DASH = "dash"
+	// Recording has no egress
+	INGEST_MEDIA_PROCESSOR_RECORDING = "recording"
+	INGEST_MEDIA_PROCESSOR_RESTREAM  = "restream"
+	INGEST_MEDIA_PROCESSOR_THUMBNAIL = "thumbnail"
+)
+
 var INGEST_BROADCASTER_ID_DEFAULT = uuid.NullUUID{}.UUID.String()
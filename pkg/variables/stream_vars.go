@@ -4,14 +4,18 @@ var (
 	STREAM_HTTP_HOST = "STREAM_HTTP_HOST"
 	STREAM_HTTP_PORT = "STREAM_HTTP_PORT"
 
-	STREAM_INGEST_TEMPLATE = "STREAM_INGEST_TEMPLATE"
-
-	STREAM_STANDALONE                      = "STREAN_STANDALONE"
-	STREAM_STANDALONE_INGEST_URI           = "STREAM_STANDALONE_INGEST_URI"
-	STREAM_STANDALONE_INGEST_DEPLOYMENT    = "STREAM_STANDALONE_INGEST_DEPLOYMENT"
-	STREAM_STANDALONE_INGEST_NAMESPACE     = "STREAM_STANDALONE_INGEST_NAMESPACE"
-	STREAM_STANDALONE_INGEST_EGRESS_WEBRTC = "STREAM_STANDALONE_INGEST_EGRESS_WEBRTC"
-	STREAM_STANDALONE_INGEST_EGRESS_HLS    = "STREAM_STANDALONE_INGEST_EGRESS_HLS"
+	STREAM_INGEST_TEMPLATE     = "STREAM_INGEST_TEMPLATE"
+	STREAM_INGEST_IDLE_TIMEOUT = "STREAM_INGEST_IDLE_TIMEOUT"
+
+	STREAM_STANDALONE                         = "STREAN_STANDALONE"
+	STREAM_STANDALONE_INGEST_URI              = "STREAM_STANDALONE_INGEST_URI"
+	STREAM_STANDALONE_INGEST_DEPLOYMENT       = "STREAM_STANDALONE_INGEST_DEPLOYMENT"
+	STREAM_STANDALONE_INGEST_NAMESPACE        = "STREAM_STANDALONE_INGEST_NAMESPACE"
+	STREAM_STANDALONE_INGEST_EGRESS_WEBRTC    = "STREAM_STANDALONE_INGEST_EGRESS_WEBRTC"
+	STREAM_STANDALONE_INGEST_EGRESS_HLS       = "STREAM_STANDALONE_INGEST_EGRESS_HLS"
+	STREAM_STANDALONE_INGEST_EGRESS_DASH      = "STREAM_STANDALONE_INGEST_EGRESS_DASH"
+	STREAM_STANDALONE_INGEST_EGRESS_THUMBNAIL = "STREAM_STANDALONE_INGEST_EGRESS_THUMBNAIL"
+	STREAM_STANDALONE_INGEST_PROCESSORS       = "STREAM_STANDALONE_INGEST_PROCESSORS"
 
 	STREAM_IDENTITY_GRPC_PUBLIC_KEY_PORT = "STREAM_IDENTITY_GRPC_PUBLIC_KEY_PORT"
 	STREAM_IDENTITY_GRPC_PUBLIC_KEY_HOST = "STREAM_IDENTITY_GRPC_PUBLIC_KEY_HOST"
@@ -22,13 +26,18 @@ var (
 	STREAM_HTTP_PORT_DEFAULT = "8082"
 
 	STREAM_INGEST_TEMPLATE_DEFAULT = "golang-ingest-template"
-
-	STREAM_STANDALONE_DEFAULT                      = "true"
-	STREAM_STANDALONE_INGEST_URI_DEFAULT           = "http://localhost:8089"
-	STREAM_STANDALONE_INGEST_DEPLOYMENT_DEFAULT    = "admin"
-	STREAM_STANDALONE_INGEST_NAMESPACE_DEFAULT     = "default"
-	STREAM_STANDALONE_INGEST_EGRESS_WEBRTC_DEFAULT = "/api/egress/whep"
-	STREAM_STANDALONE_INGEST_EGRESS_HLS_DEFAULT    = "/api/egress/hls"
+	// Deployed ingest without publisher is stopped after the timeout. Zero disables the reaper
+	STREAM_INGEST_IDLE_TIMEOUT_DEFAULT = "10m"
+
+	STREAM_STANDALONE_DEFAULT                         = "true"
+	STREAM_STANDALONE_INGEST_URI_DEFAULT              = "http://localhost:8089"
+	STREAM_STANDALONE_INGEST_DEPLOYMENT_DEFAULT       = "admin"
+	STREAM_STANDALONE_INGEST_NAMESPACE_DEFAULT        = "default"
+	STREAM_STANDALONE_INGEST_EGRESS_WEBRTC_DEFAULT    = "/api/egress/whep"
+	STREAM_STANDALONE_INGEST_EGRESS_HLS_DEFAULT       = "/api/egress/hls"
+	STREAM_STANDALONE_INGEST_EGRESS_DASH_DEFAULT      = "/api/egress/dash"
+	STREAM_STANDALONE_INGEST_EGRESS_THUMBNAIL_DEFAULT = "/api/egress/thumbnail"
+	STREAM_STANDALONE_INGEST_PROCESSORS_DEFAULT       = INGEST_MEDIA_PROCESSORS_DEFAULT
 
 	STREAM_IDENTITY_GRPC_PUBLIC_KEY_HOST_DEFAULT = "0.0.0.0"
 	STREAM_IDENTITY_GRPC_PUBLIC_KEY_PORT_DEFAULT = "9093"
@@ -165,6 +165,37 @@ func (h IdentityHandler) TokenRevocationServiceVerifyTokenRevocation(w http.Resp
 	_, _ = w.Write(verified)
 }
 
+func (h *IdentityHandler) StreamKeyServiceCreateStreamKey(w http.ResponseWriter, r *http.Request) {
+	plainToken := r.Header.Get("Authorization")
+
+	if plainToken == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionFailed)
+
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Message: fmt.Sprintf(
+				"Empty token in authorization header",
+			),
+		})
+		return
+	}
+
+	streamKey, err := h.userService.UserCreateStreamKey(r.Context(), plainToken)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Message: fmt.Sprintf("Unable create stream key. Error: %s", err),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateStreamKeyResponse{StreamKey: &streamKey})
+}
+
 const REFRESH_TOKEN_COOKIE_NAME = "_refresh_token"
 
 func deleteRefreshTokenCookie(w http.ResponseWriter) {
@@ -23,6 +23,11 @@ const (
 	BearerAuthScopes = "BearerAuth.Scopes"
 )
 
+// CreateStreamKeyResponse defines model for CreateStreamKeyResponse.
+type CreateStreamKeyResponse struct {
+	StreamKey *string `json:"streamKey,omitempty"`
+}
+
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
 	Message string `json:"message"`
@@ -91,6 +96,9 @@ type ServerInterface interface {
 	// (POST /sign-up)
 	IdentityServiceSignUp(w http.ResponseWriter, r *http.Request)
 
+	// (POST /stream-key)
+	StreamKeyServiceCreateStreamKey(w http.ResponseWriter, r *http.Request)
+
 	// (POST /token-revocation:verify)
 	TokenRevocationServiceVerifyTokenRevocation(w http.ResponseWriter, r *http.Request)
 }
@@ -124,6 +132,11 @@ func (_ Unimplemented) IdentityServiceSignUp(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// (POST /stream-key)
+func (_ Unimplemented) StreamKeyServiceCreateStreamKey(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // (POST /token-revocation:verify)
 func (_ Unimplemented) TokenRevocationServiceVerifyTokenRevocation(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
@@ -217,6 +230,23 @@ func (siw *ServerInterfaceWrapper) IdentityServiceSignUp(w http.ResponseWriter,
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// StreamKeyServiceCreateStreamKey operation middleware
+func (siw *ServerInterfaceWrapper) StreamKeyServiceCreateStreamKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.StreamKeyServiceCreateStreamKey(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // TokenRevocationServiceVerifyTokenRevocation operation middleware
 func (siw *ServerInterfaceWrapper) TokenRevocationServiceVerifyTokenRevocation(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -362,6 +392,9 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/sign-up", wrapper.IdentityServiceSignUp)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/stream-key", wrapper.StreamKeyServiceCreateStreamKey)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/token-revocation:verify", wrapper.TokenRevocationServiceVerifyTokenRevocation)
 	})
@@ -372,19 +405,20 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/9xWTU/jPBD+K9W87zFtusstN5A4VCCB+NgL6sE408SQ2sYed4lQ/vvKTpo2aVp2Japd",
-	"uCX2fD7PzHjegKulVhIlWUjewPIclyx8nhujzA1araRFf6CN0mhIYLheorUsCxdUaoQELBkhM6iqCAy+",
-	"OGEwheShFZxHa0H1+IScoIrg/JXnTGZ4p55R7vfFOEdrg9Cwvx3D1+6xEPwCy0thab/hDCUaRpiekv9d",
-	"KLNkBAmkjHBMYokQ9b1F8IxlUBaESzsQT6vCjGHlcHy3IpMzeYMvDi3txqWZtT+VSQPQ7PUSZUY5JCfT",
-	"gXCcRSPZEt+X7RHTKkYbf/MDse4DEfuF8r/BBSTwX7yprbgprLhbVXuxuXId1gZl7vXnwc/HeiCdH2jE",
-	"omy6YKU4I6EOIG5wpZ4x3TL1qFSBTA4BWkVgkTsjqLz1JNQmzpAZNKfOp9q0fbATjjdVnxNpqLwNIRfK",
-	"i3IliXGPeRVBipYboX20kMBdjiOLZiU4jnIm0wJHHqMRc5SjJFGnBREUgmOTWI08hK4RVPjvWeqFqRzd",
-	"NrZOr2cQwQqNrf1MJ9PJN6+hNEqmBSRwMplOTgIRlIf84npmjGk9NLQLdeJhDGHMUh+xv23cdGYReKpr",
-	"+IO579PpOnmUwRDTumgyip+skpvp+W4LDA69AHIXz6sLCGcL5gr6OPe9DtxxGwRGZrtH1wUEyUO3dB7m",
-	"lS95llnfEdtwwtwrxuthmeEA/O2UbnQ6U/uYFAw/D/80BWuM+5g1OFuRybGoK13ZAazXXdWo1UMd6omG",
-	"ls5UWn5Yft3XreoOTjIOqyNy23uuPgWpPXK2OVX13PptUq/cUTun/zh/hbF1AH2n/wj8e33EjtrsO3+h",
-	"o7YWmE/cUWEbGJt2xUpWYfHaz3FvJWusDa5rx+y6w/vhl1kddoD2tAUbxm9/wYQzRbOX2iSOC8VZkXvq",
-	"toy1W2W/CKqovdp5Rrfu9sTSl2jP59WvAAAA//83GNrkRw8AAA==",
+	"H4sIAAAAAAAC/9xXT0/jOhD/KtW8d0ybvsctN1jtoQIJRGEvqAeTTBPTxDb2pEuE8t1Xdv40TZOySFS7",
+	"cEvs+effb2Y8foVQZkoKFGQgeAUTJpgx9/lNIyNckkaWXWJxi0ZJYdBuKS0VauJY6TQi9ocKhRDYNS5i",
+	"KEuvWZGPTxgSlB5811rqcXMZGsNiHDam8TnnGiMIHlrB1ZCPlzBhIsY7uUEx7ouFIRrjhH4z+Jv8MeXh",
+	"JRZX3NC44RgFakYYnZP9XUudMYIAIkY4JZ4heH1vHmywcMqcMDMD8bQqTGtWDMe35LFYiFt8ztHQYVyK",
+	"GfNT6sgBzV6uUMSUQHA2HwgnN6gFy/Bt2R4xraK387c6EusYiNhPlH81riGAf/xd0vp1xvr7WTWKzXW+",
+	"x9qgzL36PPjdq6PH+YGar4u6CrYyZMTlEcQ1buUGo46pRylTZGII0NIDg2GuORVLS0Jl4gKZRn2eU9L2",
+	"E2fHLe+yPiFSUFobXKylFQ2lIBZazEsPIjSh5spGCwHcJTgxqLc8xEnCRJTixGI0YTklKIhXxwIPUh5i",
+	"fbAKeXBVwym134vIClMxWda2zm8W4MEWtan8zGfz2X9WQyoUTHEI4Gw2n505Iihx5/OrnjGlpmmo3OWJ",
+	"hdGFsYhsxHa3drPXi8BSXcHvzP0/nzeHR+EMMaXS+kT+k5Fi15bfLIHBpleWB3heX4JbW7M8pY9z36vA",
+	"A7dOYKK7NdokEAQP+6nzsCptyrPY2Irowgkrq+g3zTLGAfjbLl3r7HXtU1IwfD381RQ0GPcxq3E2PBZT",
+	"XmW6NANYN1VVq1VNHaqOhoYuZFR82Pn2b7dyv3GSzrE8Ibe96+pTkNojp8upzOldpF7nJ62c/uX8FdrW",
+	"EfRz9S7w79UJK2o37/yBiuoMMJ+5otwbaLrBYpzW9ilVq/YeWKcsrrG33Fcosj6sNSNuPpvqdugNtm4U",
+	"HqenNyTX1gYH6FNSdXxi/zLD3AHQljZnQ9t53JnIdVq/FEzg+6kMWZpY6jrG2jm/X5al124dDDadvYPk",
+	"6eyNxNmXaNdX5a8BAFqG6UZOEQAA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -94,6 +94,42 @@ func (s *SecurityServiceImpl) CreateRefreshToken(params CreateRefreshTokenParams
 	return string(signed), &expiresAt, nil
 }
 
+type CreateStreamKeyParams struct {
+	PrivateKeyJwsMessage string
+	Kid                  string
+	Username             string
+	UserID               uuid.UUID
+}
+
+// Stream key is the bearer of the ingest publishing. Ingest verify it by identity public keys and `user:id` of the broadcaster.
+// It has no scope claims, so it's not accepted by the apis in place of the access token
+func (s *SecurityServiceImpl) CreateStreamKey(params CreateStreamKeyParams) (string, error) {
+	expiresAt := time.Now().AddDate(1, 0, 0)
+
+	token, err := s.CreateToken(params.Username, nil, expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("Unable create stream key. Error: %s", err)
+	}
+
+	if err = token.Set("user:id", params.UserID); err != nil {
+		return "", fmt.Errorf("Unable set `user:id` claim. Error: %s", err)
+	}
+
+	if err = token.Set("token:use", "stream_key"); err != nil {
+		return "", fmt.Errorf("unable set `token:use` claim. Error: %s", err)
+	}
+
+	headers := jws.NewHeaders()
+	headers.Set(jws.KeyIDKey, params.Kid)
+
+	signed, err := s.signToken(params.PrivateKeyJwsMessage, headers, token)
+	if err != nil {
+		return "", fmt.Errorf("Unable sign stream key. Error: %s", err)
+	}
+
+	return string(signed), nil
+}
+
 func (s *SecurityServiceImpl) CreateToken(username string, claims []string, expiresAt time.Time) (jwt.Token, error) {
 	return jwt.NewBuilder().
 		Issuer("0.0.0.0").
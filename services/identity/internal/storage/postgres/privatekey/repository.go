@@ -44,6 +44,50 @@ func (r *PrivateKeyRepository) InsertPrivateKey(q qrm.Queryable, ctx context.Con
 	}, nil
 }
 
+// Private key of the stream key. It's not a session key, so it's kept on sign out
+func (r *PrivateKeyRepository) InsertStreamKeyPrivateKey(q qrm.Queryable, ctx context.Context, jwsMessage string) (*createPrivateKeyResult, error) {
+	var model models.PrivateKeys
+
+	if q == nil {
+		q = r.db
+	}
+
+	err := PrivateKeys.INSERT(PrivateKeys.JwsMessage, PrivateKeys.StreamKey).
+		VALUES(jwsMessage, true).
+		RETURNING(PrivateKeys.ID, PrivateKeys.JwsMessage).
+		QueryContext(ctx, q, &model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &createPrivateKeyResult{
+		JwsMessage: model.JwsMessage,
+		ID:         model.ID,
+	}, nil
+}
+
+// Revoke stream keys of the user. Stream key signed by the deleted private key is not verified anymore
+func (r *PrivateKeyRepository) DeleteUserStreamKeyPrivateKeys(q qrm.Executable, ctx context.Context, userID uuid.UUID) error {
+	if q == nil {
+		q = r.db
+	}
+
+	_, err := PrivateKeys.DELETE().
+		WHERE(
+			PrivateKeys.StreamKey.IS_TRUE().AND(
+				PrivateKeys.ID.IN(
+					SELECT(UserPrivateKeys.PrivateKeyID).
+						FROM(UserPrivateKeys).
+						WHERE(UserPrivateKeys.UserID.EQ(UUID(userID))),
+				),
+			),
+		).
+		ExecContext(ctx, q)
+
+	return err
+}
+
 type getUserPrivateKeyResult struct {
 	JwsMessage string
 }
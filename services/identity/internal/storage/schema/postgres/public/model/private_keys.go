@@ -14,4 +14,5 @@ import (
 type PrivateKeys struct {
 	ID         uuid.UUID `sql:"primary_key"`
 	JwsMessage string
+	StreamKey  bool
 }
@@ -19,6 +19,7 @@ type privateKeysTable struct {
 	// Columns
 	ID         postgres.ColumnString
 	JwsMessage postgres.ColumnString
+	StreamKey  postgres.ColumnBool
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -61,8 +62,9 @@ func newPrivateKeysTableImpl(schemaName, tableName, alias string) privateKeysTab
 	var (
 		IDColumn         = postgres.StringColumn("id")
 		JwsMessageColumn = postgres.StringColumn("jws_message")
-		allColumns       = postgres.ColumnList{IDColumn, JwsMessageColumn}
-		mutableColumns   = postgres.ColumnList{JwsMessageColumn}
+		StreamKeyColumn  = postgres.BoolColumn("stream_key")
+		allColumns       = postgres.ColumnList{IDColumn, JwsMessageColumn, StreamKeyColumn}
+		mutableColumns   = postgres.ColumnList{JwsMessageColumn, StreamKeyColumn}
 	)
 
 	return privateKeysTable{
@@ -71,6 +73,7 @@ func newPrivateKeysTableImpl(schemaName, tableName, alias string) privateKeysTab
 		//Columns
 		ID:         IDColumn,
 		JwsMessage: JwsMessageColumn,
+		StreamKey:  StreamKeyColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
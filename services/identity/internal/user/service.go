@@ -3,6 +3,8 @@ package user
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -19,6 +21,8 @@ import (
 
 var DEFAULT_CLAIMS = []string{"user", "broadcaster"}
 
+var NotAccessTokenError = errors.New("token is not an access token")
+
 type UserServiceImpl struct {
 	privateKeyRepository   *privatekey.PrivateKeyRepository
 	userRepository         *user.UserRepository
@@ -271,6 +275,75 @@ func (s *UserServiceImpl) UserExchangeAccessToken(ctx context.Context, rawRefres
 	return tokens, nil
 }
 
+// Stream key is signed by own private key. So it's not revoked on sign out when session private key is deleted.
+// Previous stream key of the user is revoked, only the last issued one is valid
+func (s *UserServiceImpl) UserCreateStreamKey(ctx context.Context, rawAccessToken string) (string, error) {
+	verified, err := s.securityService.ValidateToken(rawAccessToken)
+	if err != nil {
+		return "", err
+	}
+
+	var payload struct {
+		TokenUse string `json:"token:use"`
+	}
+	if err = json.Unmarshal(verified, &payload); err != nil {
+		return "", fmt.Errorf("Unable parse token payload. Error: %s", err)
+	}
+	if payload.TokenUse != "access_token" {
+		return "", NotAccessTokenError
+	}
+
+	kid, err := s.securityService.GetUserKID(rawAccessToken)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.userRepository.FindUserByPrivateKey(*kid)
+	if err != nil {
+		return "", fmt.Errorf("Unable find user by private key. Error: %s", err)
+	}
+
+	privateKeyJws, err := tokenutils.CreatePrivateKeyAsJwsMessage()
+	if err != nil {
+		return "", fmt.Errorf("Unable create private key. Error: %s", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("Unable start transaction. Error: %s", err)
+	}
+	defer tx.Rollback()
+
+	if err = s.privateKeyRepository.DeleteUserStreamKeyPrivateKeys(tx, ctx, user.ID); err != nil {
+		return "", fmt.Errorf("Unable revoke previous stream key. Error: %s", err)
+	}
+
+	privateKey, err := s.privateKeyRepository.InsertStreamKeyPrivateKey(tx, ctx, privateKeyJws)
+	if err != nil {
+		return "", fmt.Errorf("Unable save private key record. Error: %s", err)
+	}
+
+	if err = s.userRepository.AttachPrivateKey(tx, ctx, user.ID, privateKey.ID); err != nil {
+		return "", fmt.Errorf("Unable store associated private key with user. Error: %s", err)
+	}
+
+	streamKey, err := s.securityService.CreateStreamKey(security.CreateStreamKeyParams{
+		PrivateKeyJwsMessage: privateKey.JwsMessage,
+		Kid:                  privateKey.ID.String(),
+		Username:             user.Username,
+		UserID:               user.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Unable create stream key. Error: %s", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return streamKey, nil
+}
+
 func (s *UserServiceImpl) UserDeleteRefreshToken(ctx context.Context, rawRefreshToken string) error {
 	kid, err := s.securityService.GetUserKID(rawRefreshToken)
 	if err != nil {
@@ -0,0 +1,2 @@
+
+ALTER TABLE private_keys DROP COLUMN IF EXISTS stream_key;
@@ -0,0 +1,5 @@
+
+ALTER TABLE private_keys ADD COLUMN stream_key BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Session private key signs the refresh token. Others are private keys of the stream keys
+UPDATE private_keys SET stream_key = TRUE WHERE id NOT IN (SELECT private_key_id FROM refresh_tokens);
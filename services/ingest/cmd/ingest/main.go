@@ -3,12 +3,24 @@ package main
 import (
 	"github.com/romashorodok/stream-platform/pkg/httputils"
 	"github.com/romashorodok/stream-platform/pkg/shutdown"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/dash"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/processors"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/restream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/thumbnail"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/whep"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/ingress/rtmp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/ingress/srt"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/ingress/whip"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/lifecycle"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	hlsprocessor "github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	recordingprocessor "github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/recording"
+	restreamprocessor "github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/restream"
+	thumbnailprocessor "github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/thumbnail"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream/webrtcstatefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
 	"github.com/romashorodok/stream-platform/services/ingest/pkg/service"
 	"go.uber.org/fx"
 )
@@ -25,15 +37,38 @@ func main() {
 
 		// Internal providing must be here
 		fx.Provide(webrtcstatefulstream.NewWebrtcAllocatorFunc),
+		fx.Provide(lifecycle.NewConfig),
+		fx.Provide(lifecycle.NewNotifier),
+		fx.Provide(statefulstream.NewConfig),
+		fx.Provide(webrtcstatefulstream.NewConfig),
 		fx.Provide(statefulstream.NewStatefulStreamGlobal),
+		fx.Provide(streamkey.NewVerifier),
 
 		// Handlers
 		fx.Provide(httputils.AsHttpHandler(whip.NewWhipHandler)),
 		fx.Provide(httputils.AsHttpHandler(whep.NewWhepHandler)),
 		fx.Provide(httputils.AsHttpHandler(hls.NewHLSHandler)),
+		fx.Provide(httputils.AsHttpHandler(dash.NewDASHHandler)),
+		fx.Provide(httputils.AsHttpHandler(restream.NewRestreamHandler)),
+		fx.Provide(httputils.AsHttpHandler(processors.NewProcessorsHandler)),
+		fx.Provide(httputils.AsHttpHandler(thumbnail.NewThumbnailHandler)),
+
+		// Ingresses which are not served over http
+		fx.Invoke(rtmp.StartRtmpIngress),
+		fx.Invoke(srt.StartSrtIngress),
 
 		// Media processors
-		fx.Provide(mediaprocessor.FxDefaultHLSMediaProcessor),
+		fx.Provide(hlsprocessor.NewConfig),
+		fx.Provide(mediaprocessor.NewConfig),
+		fx.Provide(mediaprocessor.NewRegistry),
+		fx.Provide(mediaprocessor.FxHLSMediaProcessorFactory),
+		fx.Provide(mediaprocessor.FxDASHMediaProcessorFactory),
+		fx.Provide(recordingprocessor.NewConfig),
+		fx.Provide(mediaprocessor.FxRecordingMediaProcessorFactory),
+		fx.Provide(restreamprocessor.NewConfig),
+		fx.Provide(mediaprocessor.FxRestreamMediaProcessorFactory),
+		fx.Provide(thumbnailprocessor.NewConfig),
+		fx.Provide(mediaprocessor.FxThumbnailMediaProcessorFactory),
 
 		fx.Provide(func() *shutdown.Shutdown {
 			return shdown
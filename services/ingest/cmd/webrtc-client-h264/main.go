@@ -16,6 +16,7 @@ import (
 
 type Config struct {
 	WHIPEndpoint string
+	StreamKey    string
 }
 
 func env(key, defaultVariable string) string {
@@ -28,7 +29,9 @@ func env(key, defaultVariable string) string {
 func NewConfig() *Config {
 	return &Config{
 		// WHIPEndpoint: env("WHIP_ENDPOINT", "http://127.0.0.1:8089/api/consumer/whip"),
-		WHIPEndpoint: env("WHIP_ENDPOINT", "http://127.0.0.1:8089/api/ingress/whip"),
+		WHIPEndpoint: env("WHIP_ENDPOINT", "http://127.0.0.1:8089/api/ingress/whip/admin"),
+		// Issued by identity `POST /stream-key`
+		StreamKey: env("STREAM_KEY", ""),
 	}
 }
 
@@ -44,7 +47,7 @@ func NewWebrtcAPI() *webrtc.API {
 	)
 }
 
-func WHIPRequest(sdpOffer, WHIPEndpoint string) (sdpAnswer string) {
+func WHIPRequest(sdpOffer, WHIPEndpoint, streamKey string) (sdpAnswer string) {
 
 	reader := strings.NewReader(sdpOffer)
 
@@ -57,6 +60,7 @@ func WHIPRequest(sdpOffer, WHIPEndpoint string) (sdpAnswer string) {
 	}
 
 	request.Header.Set("Content-Type", "application/sdp")
+	request.Header.Set("Authorization", "Bearer "+streamKey)
 
 	resp, err := client.Do(request)
 
@@ -153,10 +157,10 @@ func main() {
 
 	peerConnection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		if candidate != nil {
-			log.Printf("PeerConnection ice candidate", candidate.String)
-			log.Printf("PeerConnection ice candidate", candidate.Port)
-			log.Printf("PeerConnection ice candidate", candidate.Protocol)
-			log.Printf("PeerConnection ice candidate", candidate.Address)
+			log.Printf("PeerConnection ice candidate %s", candidate.String())
+			log.Printf("PeerConnection ice candidate port %d", candidate.Port)
+			log.Printf("PeerConnection ice candidate protocol %s", candidate.Protocol)
+			log.Printf("PeerConnection ice candidate address %s", candidate.Address)
 		}
 	})
 
@@ -164,7 +168,7 @@ func main() {
 	log.Println(sdpOffer.SDP)
 	log.Println("end offer")
 
-	sdpAnswer := WHIPRequest(sdpOffer.SDP, config.WHIPEndpoint)
+	sdpAnswer := WHIPRequest(sdpOffer.SDP, config.WHIPEndpoint, config.StreamKey)
 
 	answer := webrtc.SessionDescription{}
 	answer.Type = webrtc.SDPTypeAnswer
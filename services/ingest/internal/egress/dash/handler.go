@@ -0,0 +1,107 @@
+package dash
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/request"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/dash"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"go.uber.org/fx"
+)
+
+var (
+	NotFoundDASHMediaProcessorError = errors.New("stream has no dash media processor")
+)
+
+type handler struct {
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+var _ httputils.HttpHandler = (*handler)(nil)
+
+func (h *handler) GetDASHMediaProcessor(key string) (*dash.FFmpegDASHMediaProcessor, error) {
+	stream, err := h.statefulStreamGlobal.GetStatefulStream(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, processor := range stream.GetMediaProcessors() {
+		if processor, err := mediaprocessor.CastMediaProcessor[dash.FFmpegDASHMediaProcessor](processor); err == nil {
+			return processor, nil
+		}
+	}
+
+	return nil, NotFoundDASHMediaProcessorError
+}
+
+type FileRequest struct {
+	Stream string `json:"stream"`
+	File   string `json:"file"`
+}
+
+// Serve MPD manifest or CMAF segment of the stream
+func (h *handler) File(w http.ResponseWriter, r *http.Request) {
+	hls.Cors(w)
+
+	request, _ := request.UnmarshalRequest[FileRequest](mux.Vars(r))
+
+	processor, err := h.GetDASHMediaProcessor(request.Stream)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := processor.File(request.File)
+	switch {
+	case errors.Is(err, dash.InvalidFileError):
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if request.File == dash.ManifestFile {
+		w.Header().Set("Content-Type", "application/dash+xml")
+	} else {
+		w.Header().Set("Content-Type", "video/mp4")
+	}
+
+	if err := hls.MediaResourceResponseStream(w, file); err != nil {
+		log.Printf("[DASH File Handler] %s\n", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+}
+
+const dashFileHandler = "/api/egress/dash/{stream}/{file}"
+
+func (h *handler) GetOption() httputils.HttpHandlerOption {
+	return func(hand http.Handler) {
+		switch hand.(type) {
+		case *mux.Router:
+			mux := hand.(*mux.Router)
+			mux.HandleFunc(dashFileHandler, h.File)
+		default:
+			panic("unsupported dash handler")
+		}
+	}
+}
+
+type DASHHandlerParams struct {
+	fx.In
+
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+func NewDASHHandler(params DASHHandlerParams) *handler {
+	return &handler{
+		statefulStreamGlobal: params.StatefulStreamGlobal,
+	}
+}
@@ -1,17 +1,19 @@
 package hls
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
 
 	"github.com/gorilla/mux"
 	"github.com/romashorodok/stream-platform/pkg/httputils"
 	"github.com/romashorodok/stream-platform/pkg/request"
-	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
 	"go.uber.org/fx"
 )
 
@@ -40,74 +42,192 @@ func Cors(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Methods", "GET")
 }
 
+// Playlist with the start offset requested by the viewer. Without the offset playlist is served as is
+func PlaylistResponseStream(res http.ResponseWriter, r *http.Request, file string) error {
+	if r.URL.Query().Get("start") == "" {
+		return MediaResourceResponseStream(res, file)
+	}
+
+	playlist, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("media file not exists. Error: %s", err)
+	}
+
+	return PlaylistResponse(res, r, playlist)
+}
+
+func PlaylistResponse(res http.ResponseWriter, r *http.Request, playlist []byte) error {
+	if rawStart := r.URL.Query().Get("start"); rawStart != "" {
+		offset, err := hls.ParseStartOffset(rawStart)
+		if err != nil {
+			return err
+		}
+		playlist = hls.WithStartOffset(playlist, offset)
+	}
+
+	_, err := res.Write(playlist)
+	return err
+}
+
+var (
+	NotFoundHLSMediaProcessorError = errors.New("stream has no hls media processor")
+)
+
 type handler struct {
-	mediaProcessor mediaprocessor.MediaProcessor
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
 }
 
 var _ httputils.HttpHandler = (*handler)(nil)
 
-func (h *handler) GetHlsMediaProcessor() (*hls.FFmpegHLSMediaProcessor, error) {
-	return mediaprocessor.CastMediaProcessor[hls.FFmpegHLSMediaProcessor](h.mediaProcessor)
+// Transcoding and passthrough processors have the same output
+func (h *handler) GetHlsMediaProcessor(key string) (hls.Output, error) {
+	stream, err := h.statefulStreamGlobal.GetStatefulStream(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, processor := range stream.GetMediaProcessors() {
+		if output, ok := processor.(hls.Output); ok {
+			return output, nil
+		}
+	}
+
+	return nil, NotFoundHLSMediaProcessorError
 }
 
+type ManifestRequest struct {
+	Stream string `json:"stream"`
+}
+
+// Serve master playlist of the stream renditions
 func (h *handler) Manifest(w http.ResponseWriter, r *http.Request) {
 	Cors(w)
 	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
 
-	processor, err := h.GetHlsMediaProcessor()
+	request, _ := request.UnmarshalRequest[ManifestRequest](mux.Vars(r))
+
+	processor, err := h.GetHlsMediaProcessor(request.Stream)
 	if err != nil {
-		httputils.WriteErrorResponse(w, http.StatusInternalServerError)
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	if processor.ManifestFile == "" {
+	manifestFile := processor.MasterPlaylist()
+	if manifestFile == "" {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	if err := MediaResourceResponseStream(w, processor.ManifestFile); err != nil {
+	err = PlaylistResponseStream(w, r, manifestFile)
+	switch {
+	case errors.Is(err, hls.InvalidStartOffsetError):
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
 		log.Printf("[HLS Manifest Handler] %s\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 }
 
-type SegmentRequest struct {
-	Segment string `json:"segment"`
+type RenditionRequest struct {
+	Stream    string `json:"stream"`
+	Rendition string `json:"rendition"`
+	File      string `json:"file"`
 }
 
-func (h *handler) Segment(w http.ResponseWriter, r *http.Request) {
+// Serve variant playlist or segment of the rendition
+func (h *handler) Rendition(w http.ResponseWriter, r *http.Request) {
 	Cors(w)
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Transfer-Encoding", "chunked")
 
-	processor, err := h.GetHlsMediaProcessor()
+	request, _ := request.UnmarshalRequest[RenditionRequest](mux.Vars(r))
+
+	processor, err := h.GetHlsMediaProcessor(request.Stream)
 	if err != nil {
-		httputils.WriteErrorResponse(w, http.StatusInternalServerError)
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	vars := mux.Vars(r)
-
-	request, _ := request.UnmarshalRequest[SegmentRequest](vars)
+	if processor.IsLowLatency() {
+		h.lowLatencyRendition(w, r, processor, request)
+		return
+	}
 
-	if request.Segment == "" {
-		log.Println("[HLS Segment Handler] Segment file does not exist")
+	file, err := processor.RenditionFile(request.Rendition, request.File)
+	switch {
+	case errors.Is(err, hls.RenditionNotFoundError):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("[HLS Rendition Handler] %s\n", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	segment := fmt.Sprintf("%s/%s", processor.SourceDirectory, request.Segment)
+	if path.Ext(request.File) == ".m3u8" {
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+
+		var playlist []byte
+		if playlist, err = processor.Playlist(file); err == nil {
+			err = PlaylistResponse(w, r, playlist)
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Transfer-Encoding", "chunked")
+		err = MediaResourceResponseStream(w, file)
+	}
 
-	if err := MediaResourceResponseStream(w, segment); err != nil {
-		log.Printf("[HLS Segment Handler] %s\n", err)
+	switch {
+	case errors.Is(err, hls.InvalidStartOffsetError):
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		log.Printf("[HLS Rendition Handler] %s\n", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 }
 
-const hlsManifestHandler = "/api/egress/hls"
-const hlsSegmentHandler = "/api/egress/hls/{segment}"
+// Serve LL-HLS playlist, init, segment or part. Playlist request with _HLS_msn is held until the media is ready
+func (h *handler) lowLatencyRendition(w http.ResponseWriter, r *http.Request, processor hls.Output, request *RenditionRequest) {
+	playlist, err := processor.LowLatencyPlaylist(request.Rendition)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var data []byte
+	if path.Ext(request.File) == ".m3u8" {
+		query := r.URL.Query()
+		data, err = playlist.Playlist(r.Context(), query.Get("_HLS_msn"), query.Get("_HLS_part"))
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	} else {
+		data, err = playlist.Media(r.Context(), request.File)
+		w.Header().Set("Content-Type", "video/mp4")
+	}
+
+	switch {
+	case errors.Is(err, hls.InvalidBlockingRequestError):
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, hls.BlockingRequestTimeoutError):
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	case err != nil && r.Context().Err() != nil:
+		// Viewer is gone
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, err := w.Write(data); err != nil {
+		log.Printf("[HLS Rendition Handler] %s\n", err)
+	}
+}
+
+const hlsManifestHandler = "/api/egress/hls/{stream}"
+const hlsRenditionHandler = "/api/egress/hls/{stream}/{rendition}/{file}"
 
 func (h *handler) GetOption() httputils.HttpHandlerOption {
 	return func(hand http.Handler) {
@@ -115,7 +235,7 @@ func (h *handler) GetOption() httputils.HttpHandlerOption {
 		case *mux.Router:
 			mux := hand.(*mux.Router)
 			mux.HandleFunc(hlsManifestHandler, h.Manifest)
-			mux.HandleFunc(hlsSegmentHandler, h.Segment)
+			mux.HandleFunc(hlsRenditionHandler, h.Rendition)
 		default:
 			panic("unsupported hls handler")
 		}
@@ -125,11 +245,11 @@ func (h *handler) GetOption() httputils.HttpHandlerOption {
 type HLSHandlerParams struct {
 	fx.In
 
-	MediaProcessor mediaprocessor.MediaProcessor `name:"mediaprocessor.hls.default"`
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
 }
 
 func NewHLSHandler(params HLSHandlerParams) *handler {
 	return &handler{
-		mediaProcessor: params.MediaProcessor,
+		statefulStreamGlobal: params.StatefulStreamGlobal,
 	}
 }
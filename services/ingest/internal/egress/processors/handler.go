@@ -0,0 +1,65 @@
+package processors
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/request"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"go.uber.org/fx"
+)
+
+type handler struct {
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+var _ httputils.HttpHandler = (*handler)(nil)
+
+type StatusRequest struct {
+	Stream string `json:"stream"`
+}
+
+// Health of each media processor of the stream
+func (h *handler) Status(w http.ResponseWriter, r *http.Request) {
+	hls.Cors(w)
+
+	request, _ := request.UnmarshalRequest[StatusRequest](mux.Vars(r))
+
+	stream, err := h.statefulStreamGlobal.GetStatefulStream(request.Stream)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stream.GetProcessorStatuses())
+}
+
+const processorsStatusHandler = "/api/egress/processors/{stream}"
+
+func (h *handler) GetOption() httputils.HttpHandlerOption {
+	return func(hand http.Handler) {
+		switch hand.(type) {
+		case *mux.Router:
+			mux := hand.(*mux.Router)
+			mux.HandleFunc(processorsStatusHandler, h.Status).Methods(http.MethodGet)
+		default:
+			panic("unsupported processors handler")
+		}
+	}
+}
+
+type ProcessorsHandlerParams struct {
+	fx.In
+
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+func NewProcessorsHandler(params ProcessorsHandlerParams) *handler {
+	return &handler{
+		statefulStreamGlobal: params.StatefulStreamGlobal,
+	}
+}
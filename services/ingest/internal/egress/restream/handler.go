@@ -0,0 +1,87 @@
+package restream
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/request"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/restream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"go.uber.org/fx"
+)
+
+var (
+	NotFoundRestreamMediaProcessorError = errors.New("stream has no restream media processor")
+)
+
+type handler struct {
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+var _ httputils.HttpHandler = (*handler)(nil)
+
+func (h *handler) GetRestreamMediaProcessor(key string) (*restream.FFmpegRestreamMediaProcessor, error) {
+	stream, err := h.statefulStreamGlobal.GetStatefulStream(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, processor := range stream.GetMediaProcessors() {
+		if processor, err := mediaprocessor.CastMediaProcessor[restream.FFmpegRestreamMediaProcessor](processor); err == nil {
+			return processor, nil
+		}
+	}
+
+	return nil, NotFoundRestreamMediaProcessorError
+}
+
+type StatusRequest struct {
+	Stream string `json:"stream"`
+}
+
+// Status of each restream target of the stream. Stream keys are never exposed
+func (h *handler) Status(w http.ResponseWriter, r *http.Request) {
+	hls.Cors(w)
+
+	request, _ := request.UnmarshalRequest[StatusRequest](mux.Vars(r))
+
+	processor, err := h.GetRestreamMediaProcessor(request.Stream)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(processor.Statuses())
+}
+
+const restreamStatusHandler = "/api/egress/restream/{stream}"
+
+func (h *handler) GetOption() httputils.HttpHandlerOption {
+	return func(hand http.Handler) {
+		switch hand.(type) {
+		case *mux.Router:
+			mux := hand.(*mux.Router)
+			mux.HandleFunc(restreamStatusHandler, h.Status).Methods(http.MethodGet)
+		default:
+			panic("unsupported restream handler")
+		}
+	}
+}
+
+type RestreamHandlerParams struct {
+	fx.In
+
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+func NewRestreamHandler(params RestreamHandlerParams) *handler {
+	return &handler{
+		statefulStreamGlobal: params.StatefulStreamGlobal,
+	}
+}
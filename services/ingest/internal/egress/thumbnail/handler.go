@@ -0,0 +1,122 @@
+package thumbnail
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/request"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/egress/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/thumbnail"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"go.uber.org/fx"
+)
+
+var (
+	NotFoundThumbnailMediaProcessorError = errors.New("stream has no thumbnail media processor")
+)
+
+type handler struct {
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+var _ httputils.HttpHandler = (*handler)(nil)
+
+func (h *handler) GetThumbnailMediaProcessor(key string) (*thumbnail.FFmpegThumbnailMediaProcessor, error) {
+	stream, err := h.statefulStreamGlobal.GetStatefulStream(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, processor := range stream.GetMediaProcessors() {
+		if processor, err := mediaprocessor.CastMediaProcessor[thumbnail.FFmpegThumbnailMediaProcessor](processor); err == nil {
+			return processor, nil
+		}
+	}
+
+	return nil, NotFoundThumbnailMediaProcessorError
+}
+
+type FileRequest struct {
+	Stream string `json:"stream"`
+	File   string `json:"file"`
+}
+
+func (h *handler) serveFile(w http.ResponseWriter, stream string, name func(format string) string) {
+	hls.Cors(w)
+
+	processor, err := h.GetThumbnailMediaProcessor(stream)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := processor.File(name(processor.Format()))
+	switch {
+	case errors.Is(err, thumbnail.InvalidFileError):
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", thumbnail.ContentType(processor.Format()))
+
+	if err := hls.MediaResourceResponseStream(w, file); err != nil {
+		log.Printf("[Thumbnail File Handler] %s\n", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+}
+
+// Serve the latest thumbnail of the stream
+func (h *handler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	request, _ := request.UnmarshalRequest[FileRequest](mux.Vars(r))
+
+	h.serveFile(w, request.Stream, func(format string) string {
+		return thumbnail.FileName(thumbnail.ThumbnailName, format)
+	})
+}
+
+// Serve thumbnail or sprite sheet by the file name
+func (h *handler) File(w http.ResponseWriter, r *http.Request) {
+	request, _ := request.UnmarshalRequest[FileRequest](mux.Vars(r))
+
+	h.serveFile(w, request.Stream, func(string) string {
+		return request.File
+	})
+}
+
+const (
+	thumbnailHandler     = "/api/egress/thumbnail/{stream}"
+	thumbnailFileHandler = "/api/egress/thumbnail/{stream}/{file}"
+)
+
+func (h *handler) GetOption() httputils.HttpHandlerOption {
+	return func(hand http.Handler) {
+		switch hand.(type) {
+		case *mux.Router:
+			mux := hand.(*mux.Router)
+			mux.HandleFunc(thumbnailHandler, h.Thumbnail)
+			mux.HandleFunc(thumbnailFileHandler, h.File)
+		default:
+			panic("unsupported thumbnail handler")
+		}
+	}
+}
+
+type ThumbnailHandlerParams struct {
+	fx.In
+
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+}
+
+func NewThumbnailHandler(params ThumbnailHandlerParams) *handler {
+	return &handler{
+		statefulStreamGlobal: params.StatefulStreamGlobal,
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pion/webrtc/v3"
 	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/request"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream/webrtcstatefulstream"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/wrtc"
@@ -19,7 +20,8 @@ func Cors(w http.ResponseWriter) {
 	w.Header().Set("Cache-Control", "no-cache, no-store, private")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Headers", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Expose-Headers", "Location, ETag")
 }
 
 type handler struct {
@@ -36,6 +38,15 @@ func ensureWbertcStatefulStream(s statefulstream.StatefulStream) (*webrtcstatefu
 	return nil, errors.New("Support only webrtc stream type")
 }
 
+type WhepRequest struct {
+	Stream string `json:"stream"`
+}
+
+type WhepSessionRequest struct {
+	Stream  string `json:"stream"`
+	Session string `json:"session"`
+}
+
 func (h *handler) Whep(w http.ResponseWriter, r *http.Request) {
 	Cors(w)
 
@@ -43,41 +54,193 @@ func (h *handler) Whep(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	connConfig := webrtc.Configuration{}
+	if r.Method != http.MethodPost {
+		httputils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "unsupported method", r.Method)
+		return
+	}
 
-	peerConnection, err := h.webrtcAPI.NewPeerConnection(connConfig)
+	request, _ := request.UnmarshalRequest[WhepRequest](mux.Vars(r))
+
+	statefulStream, err := h.statefulStreamGlobal.GetStatefulStream(request.Stream)
 	if err != nil {
-		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable create peer connection. Err:", err.Error())
+		httputils.WriteErrorResponse(w, http.StatusNotFound, "unable find stream. Err:", err.Error())
 		return
 	}
 
-	stream, err := ensureWbertcStatefulStream(h.statefulStreamGlobal.GetStatefulStream())
+	stream, err := ensureWbertcStatefulStream(statefulStream)
 	if err != nil {
 		httputils.WriteErrorResponse(w, http.StatusConflict, "invalid stream type start . The stream with webrtc", err.Error())
 		return
 	}
 
+	connConfig := webrtc.Configuration{}
+
+	peerConnection, err := h.webrtcAPI.NewPeerConnection(connConfig)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable create peer connection. Err:", err.Error())
+		return
+	}
+
+	session := wrtc.NewSession(peerConnection)
+
+	peerConnection.OnICEConnectionStateChange(
+		func(state webrtc.ICEConnectionState) {
+			if state == webrtc.ICEConnectionStateFailed || state == webrtc.ICEConnectionStateClosed {
+				session.Close()
+			}
+		},
+	)
+
 	offer, err := io.ReadAll(r.Body)
 	defer r.Body.Close()
 	if err != nil {
+		session.Close()
 		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable read offer. Err:", err.Error())
 		return
 	}
 
-	_, _ = peerConnection.AddTrack(stream.Audio)
-	_, _ = peerConnection.AddTrack(stream.Video)
+	if stream.Audio != nil {
+		_, _ = peerConnection.AddTrack(stream.Audio)
+	}
+
+	if err := h.addVideo(peerConnection, session, stream, request.Stream, r.URL.Query().Get("layer")); err != nil {
+		session.Close()
+		httputils.WriteErrorResponse(w, http.StatusNotFound, "unable add video. Err:", err.Error())
+		return
+	}
 
-	answer, err := wrtc.Answer(peerConnection, string(offer))
+	answer, err := session.Answer(string(offer))
 	if err != nil {
+		session.Close()
 		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable handle and generate answer. Err:", err.Error())
 		return
 	}
 
+	stream.GetViewers().Add(session)
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", fmt.Sprintf("/api/egress/whep/%s/%s", request.Stream, session.ID))
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, session.ETag()))
 	w.WriteHeader(http.StatusCreated)
 	fmt.Fprint(w, answer)
 }
 
-const whepHandler = "/api/egress/whep"
+// Simulcast viewer get own track switched between layers automatically or by data channel request.
+// Viewer may pin the layer by query, then it's bound to the layer track without switching
+func (h *handler) addVideo(peerConnection *webrtc.PeerConnection, session *wrtc.Session, stream *webrtcstatefulstream.WebrtcStatefulStream, key, layer string) error {
+	if !stream.Layers.Simulcast() {
+		if stream.Video != nil {
+			_, _ = peerConnection.AddTrack(stream.Video)
+		}
+		return nil
+	}
+
+	if layer != "" && layer != wrtc.LayerAuto {
+		pinned, err := stream.Layers.Get(layer)
+		if err != nil {
+			return err
+		}
+		_, err = peerConnection.AddTrack(pinned.Track)
+		return err
+	}
+
+	selector, err := wrtc.NewLayerSelector(stream.Layers, key)
+	if errors.Is(err, wrtc.NoLayersError) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sender, err := peerConnection.AddTrack(selector.Track)
+	if err != nil {
+		selector.Close()
+		return err
+	}
+
+	go selector.ReadRTCP(sender)
+	peerConnection.OnDataChannel(selector.HandleDataChannel)
+	session.OnClose(selector.Close)
+
+	return nil
+}
+
+// Session resource of the viewer. PATCH for trickle ICE and ICE restart, DELETE to stop watching
+func (h *handler) WhepSession(w http.ResponseWriter, r *http.Request) {
+	Cors(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	request, _ := request.UnmarshalRequest[WhepSessionRequest](mux.Vars(r))
+
+	statefulStream, err := h.statefulStreamGlobal.GetStatefulStream(request.Stream)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusNotFound, "unable find stream. Err:", err.Error())
+		return
+	}
+
+	stream, err := ensureWbertcStatefulStream(statefulStream)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusConflict, "invalid stream type start . The stream with webrtc", err.Error())
+		return
+	}
+
+	session, err := stream.GetViewers().Get(request.Session)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusNotFound, "unable find session. Err:", err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		session.Close()
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPatch:
+		if r.Header.Get("Content-Type") != wrtc.TrickleICEContentType {
+			httputils.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "expected", wrtc.TrickleICEContentType)
+			return
+		}
+
+		fragment, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable read sdp fragment. Err:", err.Error())
+			return
+		}
+
+		answer, err := session.Trickle(string(fragment), r.Header.Get("If-Match"))
+		switch {
+		case errors.Is(err, wrtc.ETagMismatchError):
+			httputils.WriteErrorResponse(w, http.StatusPreconditionFailed, "unable trickle ice. Err:", err.Error())
+			return
+		case err != nil:
+			httputils.WriteErrorResponse(w, http.StatusBadRequest, "unable trickle ice. Err:", err.Error())
+			return
+		}
+
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, session.ETag()))
+
+		if answer == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", wrtc.TrickleICEContentType)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, answer)
+
+	default:
+		httputils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "unsupported method", r.Method)
+	}
+}
+
+const (
+	whepHandler        = "/api/egress/whep/{stream}"
+	whepSessionHandler = "/api/egress/whep/{stream}/{session}"
+)
 
 func (h *handler) GetOption() httputils.HttpHandlerOption {
 	return func(hand http.Handler) {
@@ -85,6 +248,7 @@ func (h *handler) GetOption() httputils.HttpHandlerOption {
 		case *mux.Router:
 			mux := hand.(*mux.Router)
 			mux.HandleFunc(whepHandler, h.Whep)
+			mux.HandleFunc(whepSessionHandler, h.WhepSession)
 		default:
 			panic("unsupported hls handler")
 		}
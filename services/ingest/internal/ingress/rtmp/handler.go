@@ -0,0 +1,133 @@
+package rtmp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream/webrtcstatefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/rtmp"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/service"
+	"go.uber.org/fx"
+)
+
+// Feed FLV tags into the stateful stream. Stream is canceled when client stop publishing
+type publisher struct {
+	key     string
+	handler *statefulstream.RtmpTagHandler
+	cancel  context.CancelFunc
+}
+
+var _ rtmp.Publisher = (*publisher)(nil)
+
+// Drop tags which cannot be handled. Only closed stream must break the rtmp connection
+func (p *publisher) handleTagError(kind string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, io.ErrClosedPipe) {
+		return err
+	}
+
+	log.Printf("[RTMP] %s dropped %s tag. Err: %s", p.key, kind, err)
+	return nil
+}
+
+func (p *publisher) WriteAudio(timestamp uint32, payload []byte) error {
+	return p.handleTagError("audio", p.handler.Audio.WriteTag(timestamp, payload))
+}
+
+func (p *publisher) WriteVideo(timestamp uint32, payload []byte) error {
+	return p.handleTagError("video", p.handler.Video.WriteTag(timestamp, payload))
+}
+
+func (p *publisher) Close() {
+	log.Printf("[RTMP] %s stop publishing", p.key)
+	p.cancel()
+}
+
+type ingress struct {
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+	streamKeyVerifier    *streamkey.Verifier
+}
+
+// Parse stream and stream key from rtmp stream name like {stream}?key={stream key}
+func ParseStreamName(name string) (stream string, key string) {
+	stream, rawQuery, _ := strings.Cut(name, "?")
+	query, _ := url.ParseQuery(rawQuery)
+	return stream, query.Get("key")
+}
+
+// The rtmp url looks like rtmp://host/{app}/{stream}. Stream is the broadcaster stream, backup app publishes backup encoder
+func (i *ingress) Publish(app, name string) (rtmp.Publisher, error) {
+	role, err := webrtcstatefulstream.ParsePublisherRole(app)
+	if err != nil {
+		role = webrtcstatefulstream.PrimaryPublisher
+	}
+
+	stream, key := ParseStreamName(name)
+
+	broadcaster, err := i.streamKeyVerifier.Verify(context.TODO(), key, stream)
+	if err != nil {
+		log.Printf("[RTMP] %s unauthorized publisher. Err: %s", stream, err)
+		if errors.Is(err, streamkey.IdentityUnavailableError) {
+			return nil, err
+		}
+		return nil, errors.Join(rtmp.PublishDeniedError, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	handler, err := i.statefulStreamGlobal.HandleRtmp(ctx, stream, broadcaster, role)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	log.Printf("[RTMP] %s start publishing to %s app as %s", stream, app, role)
+
+	return &publisher{
+		key:     stream,
+		handler: handler,
+		cancel:  cancel,
+	}, nil
+}
+
+type RtmpIngressParams struct {
+	fx.In
+
+	Config               *service.IngestRtmpConfig
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+	StreamKeyVerifier    *streamkey.Verifier
+	Lifecycle            fx.Lifecycle
+}
+
+func StartRtmpIngress(params RtmpIngressParams) {
+	ingress := &ingress{
+		statefulStreamGlobal: params.StatefulStreamGlobal,
+		streamKeyVerifier:    params.StreamKeyVerifier,
+	}
+
+	ln, err := net.Listen("tcp", params.Config.GetAddr())
+	if err != nil {
+		panic(err)
+	}
+	log.Printf("Listening RTMP on %s\n", params.Config.GetAddr())
+
+	server := rtmp.NewServer(ingress.Publish)
+
+	go server.Serve(ln)
+
+	params.Lifecycle.Append(
+		fx.StopHook(func() error {
+			return ln.Close()
+		}),
+	)
+}
@@ -0,0 +1,142 @@
+package srt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream/webrtcstatefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/service"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/srt"
+	"go.uber.org/fx"
+)
+
+const accessControlPrefix = "#!::"
+
+var (
+	EmptyStreamIDError      = errors.New("empty srt stream id")
+	UnsupportedSrtModeError = errors.New("unsupported srt mode. Support only publish")
+)
+
+// Parse stream, stream key and publisher role from srt stream id. Stream id may be plain stream or access control syntax like
+// #!::r=stream,m=publish,key=streamkey,role=backup
+func ParseStreamID(streamID string) (string, string, webrtcstatefulstream.PublisherRole, error) {
+	if !strings.HasPrefix(streamID, accessControlPrefix) {
+		if streamID == "" {
+			return "", "", webrtcstatefulstream.PrimaryPublisher, EmptyStreamIDError
+		}
+		return streamID, "", webrtcstatefulstream.PrimaryPublisher, nil
+	}
+
+	var stream, streamKey string
+	role := webrtcstatefulstream.PrimaryPublisher
+	for _, pair := range strings.Split(strings.TrimPrefix(streamID, accessControlPrefix), ",") {
+		name, value, _ := strings.Cut(pair, "=")
+
+		switch name {
+		case "r":
+			stream = value
+		case "key":
+			streamKey = value
+		case "m":
+			if value != "publish" {
+				return "", "", role, UnsupportedSrtModeError
+			}
+		case "role":
+			parsed, err := webrtcstatefulstream.ParsePublisherRole(value)
+			if err != nil {
+				return "", "", role, err
+			}
+			role = parsed
+		}
+	}
+
+	if stream == "" {
+		return "", "", role, EmptyStreamIDError
+	}
+	return stream, streamKey, role, nil
+}
+
+// Feed mpeg-ts into the stateful stream. Stream is canceled when caller disconnect
+type publisher struct {
+	io.Writer
+	cancel context.CancelFunc
+}
+
+func (p *publisher) Close() error {
+	p.cancel()
+	return nil
+}
+
+type ingress struct {
+	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+	streamKeyVerifier    *streamkey.Verifier
+}
+
+func (i *ingress) Publish(streamID string) (io.WriteCloser, error) {
+	key, streamKey, role, err := ParseStreamID(streamID)
+	if err != nil {
+		return nil, &srt.RejectError{Reason: srt.RejectionBadRequest, Err: err}
+	}
+
+	broadcaster, err := i.streamKeyVerifier.Verify(context.TODO(), streamKey, key)
+	if err != nil {
+		log.Printf("[SRT] %s unauthorized publisher. Err: %s", key, err)
+		if errors.Is(err, streamkey.IdentityUnavailableError) {
+			return nil, err
+		}
+		return nil, &srt.RejectError{Reason: srt.RejectionUnauthorized, Err: err}
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	demuxer, err := i.statefulStreamGlobal.HandleSrt(ctx, key, broadcaster, role)
+	if err != nil {
+		cancel()
+		if errors.Is(err, statefulstream.StreamAlreadyPublishingError) {
+			return nil, &srt.RejectError{Reason: srt.RejectionConflict, Err: err}
+		}
+		return nil, err
+	}
+
+	log.Printf("[SRT] %s start publishing as %s", key, role)
+
+	return &publisher{Writer: demuxer, cancel: cancel}, nil
+}
+
+type SrtIngressParams struct {
+	fx.In
+
+	Config               *service.IngestSrtConfig
+	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+	StreamKeyVerifier    *streamkey.Verifier
+	Lifecycle            fx.Lifecycle
+}
+
+func StartSrtIngress(params SrtIngressParams) {
+	ingress := &ingress{
+		statefulStreamGlobal: params.StatefulStreamGlobal,
+		streamKeyVerifier:    params.StreamKeyVerifier,
+	}
+
+	pc, err := net.ListenPacket("udp", params.Config.GetAddr())
+	if err != nil {
+		panic(err)
+	}
+	log.Printf("Listening SRT on %s\n", params.Config.GetAddr())
+
+	server := srt.NewServer(ingress.Publish, params.Config.Latency)
+
+	go server.Serve(pc)
+
+	params.Lifecycle.Append(
+		fx.StopHook(func() error {
+			return pc.Close()
+		}),
+	)
+}
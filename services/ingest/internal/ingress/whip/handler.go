@@ -2,6 +2,7 @@ package whip
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,7 +10,10 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pion/webrtc/v3"
 	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/request"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream/webrtcstatefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/wrtc"
 	"github.com/romashorodok/stream-platform/services/ingest/pkg/service"
 	"go.uber.org/fx"
@@ -18,18 +22,46 @@ import (
 func Cors(w http.ResponseWriter) {
 	w.Header().Set("Cache-Control", "no-cache, no-store, private")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST")
+	// Authorization is not covered by the wildcard
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, *")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Expose-Headers", "Location, ETag, WWW-Authenticate")
 }
 
 type handler struct {
 	webrtcAPI            *webrtc.API
 	ingestSystemConfig   *service.IngestSystemConfig
 	statefulStreamGlobal *statefulstream.StatefulStreamGlobal
+	streamKeyVerifier    *streamkey.Verifier
+	sessions             *wrtc.SessionRegistry
 }
 
 var _ httputils.HttpHandler = (*handler)(nil)
 
+type WhipRequest struct {
+	Stream string `json:"stream"`
+}
+
+type WhipSessionRequest struct {
+	Stream  string `json:"stream"`
+	Session string `json:"session"`
+}
+
+// Bearer stream key of the stream publisher. Error response is written when it's not valid
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request, stream string) (*streamkey.Broadcaster, bool) {
+	broadcaster, err := h.streamKeyVerifier.Verify(r.Context(), r.Header.Get("Authorization"), stream)
+	switch {
+	case err == nil:
+		return broadcaster, true
+	case errors.Is(err, streamkey.IdentityUnavailableError):
+		httputils.WriteErrorResponse(w, http.StatusServiceUnavailable, "unable verify stream key. Err:", err.Error())
+	default:
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		httputils.WriteErrorResponse(w, http.StatusUnauthorized, "unauthorized publisher. Err:", err.Error())
+	}
+	return nil, false
+}
+
 func (h *handler) Handler(w http.ResponseWriter, r *http.Request) {
 	Cors(w)
 
@@ -37,6 +69,29 @@ func (h *handler) Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method != http.MethodPost {
+		httputils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "unsupported method", r.Method)
+		return
+	}
+
+	request, _ := request.UnmarshalRequest[WhipRequest](mux.Vars(r))
+
+	if request.Stream == "" {
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, "empty stream key")
+		return
+	}
+
+	role, err := webrtcstatefulstream.ParsePublisherRole(r.URL.Query().Get("role"))
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	broadcaster, ok := h.authorize(w, r, request.Stream)
+	if !ok {
+		return
+	}
+
 	connConfig := webrtc.Configuration{}
 
 	peerConnection, err := h.webrtcAPI.NewPeerConnection(connConfig)
@@ -45,10 +100,19 @@ func (h *handler) Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	// Session owns the peer connection, it's closed on each failure below
+	session := wrtc.NewSession(peerConnection)
+	session.Stream = request.Stream
+	session.Publisher = broadcaster.Username
+	session.OnClose(cancel)
+
 	if _, err := peerConnection.AddTransceiverFromKind(
 		webrtc.RTPCodecTypeVideo,
 		webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly},
 	); err != nil {
+		session.Close()
 		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable set resiving only mode for video. Err:", err.Error())
 		return
 	}
@@ -57,17 +121,15 @@ func (h *handler) Handler(w http.ResponseWriter, r *http.Request) {
 		webrtc.RTPCodecTypeAudio,
 		webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly},
 	); err != nil {
+		session.Close()
 		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable set resiving only mode for audio. Err:", err.Error())
 		return
 	}
 
-	ctx, cancel := context.WithCancel(context.TODO())
-
 	peerConnection.OnICEConnectionStateChange(
 		func(state webrtc.ICEConnectionState) {
-			if state == webrtc.ICEConnectionStateDisconnected {
-				peerConnection.Close()
-				cancel()
+			if state == webrtc.ICEConnectionStateFailed || state == webrtc.ICEConnectionStateClosed {
+				session.Close()
 			}
 		},
 	)
@@ -75,19 +137,19 @@ func (h *handler) Handler(w http.ResponseWriter, r *http.Request) {
 	offer, err := io.ReadAll(r.Body)
 	defer r.Body.Close()
 	if err != nil {
+		session.Close()
 		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable read offer. Err:", err.Error())
 		return
 	}
 
-	answer, err := wrtc.Answer(peerConnection, string(offer))
-	if err != nil {
-		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable handle and generate answer. Err:", err.Error())
-		return
-	}
-
-	wrtcHandler, err := h.statefulStreamGlobal.HandleWebrtc(ctx)
+	wrtcHandler, err := h.statefulStreamGlobal.HandleWebrtc(ctx, request.Stream, broadcaster, role, peerConnection, wrtc.SimulcastRIDs(string(offer)))
 	if err != nil {
+		session.Close()
 		switch err {
+		case statefulstream.EmptyStreamKeyError:
+			httputils.WriteErrorResponse(w, http.StatusBadRequest, "unable handle webrtc. Err:", err.Error())
+		case statefulstream.StreamAlreadyPublishingError, statefulstream.SimulcastBackupError:
+			httputils.WriteErrorResponse(w, http.StatusConflict, "unable handle webrtc. Err:", err.Error())
 		case statefulstream.NewWebrtcStatefulStreamError:
 			httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable handle webrtc. Err:", err.Error())
 		default:
@@ -99,11 +161,101 @@ func (h *handler) Handler(w http.ResponseWriter, r *http.Request) {
 
 	peerConnection.OnTrack(wrtcHandler)
 
+	answer, err := session.Answer(string(offer))
+	if err != nil {
+		session.Close()
+		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable handle and generate answer. Err:", err.Error())
+		return
+	}
+
+	h.sessions.Add(session)
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", fmt.Sprintf("/api/ingress/whip/%s/%s", request.Stream, session.ID))
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, session.ETag()))
 	w.WriteHeader(http.StatusCreated)
 	fmt.Fprint(w, answer)
 }
 
-const whipHandler = "/api/ingress/whip"
+// Session resource of the publisher. PATCH for trickle ICE and ICE restart, DELETE to stop publishing.
+// Publisher authorizes by the stream key of the same subject as the publish
+func (h *handler) SessionHandler(w http.ResponseWriter, r *http.Request) {
+	Cors(w)
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	request, _ := request.UnmarshalRequest[WhipSessionRequest](mux.Vars(r))
+
+	broadcaster, ok := h.authorize(w, r, request.Stream)
+	if !ok {
+		return
+	}
+
+	// Session of the other stream isn't exposed by this resource
+	session, err := h.sessions.Get(request.Session)
+	if err == nil && session.Stream != request.Stream {
+		err = wrtc.SessionNotFoundError
+	}
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusNotFound, "unable find session. Err:", err.Error())
+		return
+	}
+
+	if session.Publisher != broadcaster.Username {
+		httputils.WriteErrorResponse(w, http.StatusForbidden, "unable access session. Err:", wrtc.ForeignSessionError.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		session.Close()
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPatch:
+		if r.Header.Get("Content-Type") != wrtc.TrickleICEContentType {
+			httputils.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "expected", wrtc.TrickleICEContentType)
+			return
+		}
+
+		fragment, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			httputils.WriteErrorResponse(w, http.StatusInternalServerError, "unable read sdp fragment. Err:", err.Error())
+			return
+		}
+
+		answer, err := session.Trickle(string(fragment), r.Header.Get("If-Match"))
+		switch {
+		case errors.Is(err, wrtc.ETagMismatchError):
+			httputils.WriteErrorResponse(w, http.StatusPreconditionFailed, "unable trickle ice. Err:", err.Error())
+			return
+		case err != nil:
+			httputils.WriteErrorResponse(w, http.StatusBadRequest, "unable trickle ice. Err:", err.Error())
+			return
+		}
+
+		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, session.ETag()))
+
+		if answer == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", wrtc.TrickleICEContentType)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, answer)
+
+	default:
+		httputils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "unsupported method", r.Method)
+	}
+}
+
+const (
+	whipHandler        = "/api/ingress/whip/{stream}"
+	whipSessionHandler = "/api/ingress/whip/{stream}/{session}"
+)
 
 func (h *handler) GetOption() httputils.HttpHandlerOption {
 	return func(hand http.Handler) {
@@ -111,6 +263,7 @@ func (h *handler) GetOption() httputils.HttpHandlerOption {
 		case *mux.Router:
 			mux := hand.(*mux.Router)
 			mux.HandleFunc(whipHandler, h.Handler)
+			mux.HandleFunc(whipSessionHandler, h.SessionHandler)
 		default:
 			panic("unsupported whip handler")
 		}
@@ -123,6 +276,7 @@ type WhipHandlerParams struct {
 	WebrtcAPI            *webrtc.API
 	IngestSystemConfig   *service.IngestSystemConfig
 	StatefulStreamGlobal *statefulstream.StatefulStreamGlobal
+	StreamKeyVerifier    *streamkey.Verifier
 }
 
 func NewWhipHandler(params WhipHandlerParams) *handler {
@@ -130,5 +284,7 @@ func NewWhipHandler(params WhipHandlerParams) *handler {
 		webrtcAPI:            params.WebrtcAPI,
 		ingestSystemConfig:   params.IngestSystemConfig,
 		statefulStreamGlobal: params.StatefulStreamGlobal,
+		streamKeyVerifier:    params.StreamKeyVerifier,
+		sessions:             wrtc.NewSessionRegistry(),
 	}
 }
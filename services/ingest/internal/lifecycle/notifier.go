@@ -0,0 +1,233 @@
+package lifecycle
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	subjectpb "github.com/romashorodok/stream-platform/gen/golang/subject/v1alpha"
+	"github.com/romashorodok/stream-platform/pkg/envutils"
+	"github.com/romashorodok/stream-platform/pkg/shutdown"
+	"github.com/romashorodok/stream-platform/pkg/subject"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/service"
+	"go.uber.org/fx"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const flushTimeout = 2 * time.Second
+
+type Config struct {
+	HeartbeatInterval time.Duration
+}
+
+func NewConfig() *Config {
+	rawInterval := envutils.Env(variables.INGEST_HEARTBEAT_INTERVAL, variables.INGEST_HEARTBEAT_INTERVAL_DEFAULT)
+	interval, err := time.ParseDuration(rawInterval)
+	if err != nil || interval < time.Second {
+		log.Printf("[ERROR] wrong heartbeat interval %s. Fallback to %s", rawInterval, variables.INGEST_HEARTBEAT_INTERVAL_DEFAULT)
+		interval, _ = time.ParseDuration(variables.INGEST_HEARTBEAT_INTERVAL_DEFAULT)
+	}
+
+	return &Config{HeartbeatInterval: interval}
+}
+
+type publishFunc func(subject string, message protoreflect.ProtoMessage) error
+
+// Stream of the broadcaster which owns its stream key
+type broadcasterStream struct {
+	meta    *subjectpb.BroadcasterMeta
+	running bool
+}
+
+// Publish Running when the stream gets media and Stopped when it's released. Events of each stream carry the meta of the
+// broadcaster of its verified stream key. Heartbeats repeat the current state, so consumer may recover missed events
+type Notifier struct {
+	publish publishFunc
+	// Broadcaster of the dedicated ingest. It's reported stopped by heartbeats while it has no stream
+	meta     *subjectpb.BroadcasterMeta
+	interval time.Duration
+
+	streams map[string]*broadcasterStream
+	mx      sync.Mutex
+}
+
+// Stream of the key is owned by the broadcaster from now. Must be called before its processors start
+func (n *Notifier) Allocated(key string, broadcaster *streamkey.Broadcaster) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	meta := &subjectpb.BroadcasterMeta{BroadcasterId: broadcaster.ID, Username: broadcaster.Username}
+	if stream, ok := n.streams[key]; ok {
+		stream.meta = meta
+		return
+	}
+	n.streams[key] = &broadcasterStream{meta: meta}
+}
+
+func (n *Notifier) Running(key string) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	stream, ok := n.streams[key]
+	if !ok || stream.running {
+		return
+	}
+	stream.running = true
+
+	n.send(subject.NewIngestRunning(stream.meta.BroadcasterId), &subject.IngestRunning{Running: true, Meta: stream.meta})
+}
+
+// Stream of the key is released. Broadcaster is notified only when the stream was running
+func (n *Notifier) Stopped(key string) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	stream, ok := n.streams[key]
+	if !ok {
+		return
+	}
+	delete(n.streams, key)
+
+	if stream.running {
+		n.send(subject.NewIngestStopped(stream.meta.BroadcasterId), &subject.IngestStopped{Stopped: true, Meta: stream.meta})
+	}
+}
+
+// Health of the stream processor, so the dashboard may show degraded egress
+func (n *Notifier) ProcessorStatus(key string, status mediaprocessor.ProcessorStatus) {
+	n.mx.Lock()
+	stream, ok := n.streams[key]
+	n.mx.Unlock()
+
+	if !ok {
+		return
+	}
+
+	n.send(subject.NewIngestProcessorStatus(stream.meta.BroadcasterId), &subject.IngestProcessorStatus{
+		Meta:      stream.meta,
+		Stream:    key,
+		Processor: status.Name,
+		State:     string(status.State),
+		Restarts:  int32(status.Restarts),
+		LastError: status.LastError,
+	})
+}
+
+func (n *Notifier) IsRunning(key string) bool {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	stream, ok := n.streams[key]
+	return ok && stream.running
+}
+
+func (n *Notifier) heartbeat() {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	reported := false
+	for _, stream := range n.streams {
+		reported = reported || (n.meta != nil && stream.meta.BroadcasterId == n.meta.BroadcasterId)
+		n.send(subject.NewIngestHeartbeat(stream.meta.BroadcasterId), &subject.IngestHeartbeat{
+			Running:         stream.running,
+			Meta:            stream.meta,
+			IntervalSeconds: int32(n.interval.Seconds()),
+		})
+	}
+
+	if n.meta != nil && !reported {
+		n.send(subject.NewIngestHeartbeat(n.meta.BroadcasterId), &subject.IngestHeartbeat{
+			Running:         false,
+			Meta:            n.meta,
+			IntervalSeconds: int32(n.interval.Seconds()),
+		})
+	}
+}
+
+func (n *Notifier) send(subject string, message protoreflect.ProtoMessage) {
+	if err := n.publish(subject, message); err != nil {
+		log.Printf("[Lifecycle] Unable publish %s. Err: %s", subject, err)
+	}
+}
+
+func (n *Notifier) Start(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	n.heartbeat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.heartbeat()
+		}
+	}
+}
+
+// Streams are destroyed on shutdown without release, so consumers are notified here
+func (n *Notifier) stopAll() {
+	n.mx.Lock()
+	keys := make([]string, 0, len(n.streams))
+	for key := range n.streams {
+		keys = append(keys, key)
+	}
+	n.mx.Unlock()
+
+	for _, key := range keys {
+		n.Stopped(key)
+	}
+}
+
+type NotifierParams struct {
+	fx.In
+
+	Lifecycle          fx.Lifecycle
+	Conn               *nats.Conn
+	Shutdown           *shutdown.Shutdown
+	IngestSystemConfig *service.IngestSystemConfig
+	Config             *Config
+}
+
+func NewNotifier(params NotifierParams) *Notifier {
+	notifier := &Notifier{
+		publish: func(subj string, message protoreflect.ProtoMessage) error {
+			return subject.PublishProtobuf(params.Conn, subj, message)
+		},
+		interval: params.Config.HeartbeatInterval,
+		streams:  make(map[string]*broadcasterStream),
+	}
+
+	if params.IngestSystemConfig.Dedicated {
+		notifier.meta = &subjectpb.BroadcasterMeta{
+			BroadcasterId: params.IngestSystemConfig.BroadcasterID,
+			Username:      params.IngestSystemConfig.Username,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go notifier.Start(ctx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+
+	params.Shutdown.AddTask(func() {
+		cancel()
+		notifier.stopAll()
+		_ = params.Conn.FlushTimeout(flushTimeout)
+	})
+
+	return notifier
+}
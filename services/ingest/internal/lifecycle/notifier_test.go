@@ -0,0 +1,82 @@
+package lifecycle
+
+import (
+	"testing"
+	"time"
+
+	subjectpb "github.com/romashorodok/stream-platform/gen/golang/subject/v1alpha"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestNotifier(t *testing.T) {
+	var subjects []string
+	notifier := &Notifier{
+		publish: func(subject string, message protoreflect.ProtoMessage) error {
+			subjects = append(subjects, subject)
+			return nil
+		},
+		interval: 10 * time.Second,
+		streams:  make(map[string]*broadcasterStream),
+	}
+
+	// Shared ingest hosts streams of many broadcasters
+	notifier.Allocated("admin", &streamkey.Broadcaster{ID: "broadcaster", Username: "admin"})
+	notifier.Allocated("guest", &streamkey.Broadcaster{ID: "guest-broadcaster", Username: "guest"})
+	notifier.ProcessorStatus("admin", mediaprocessor.ProcessorStatus{Name: "hls", State: mediaprocessor.ProcessorRunning})
+
+	// Each track of the publisher reports running
+	notifier.Running("admin")
+	notifier.Running("admin")
+	notifier.Running("guest")
+	assert.True(t, notifier.IsRunning("admin"))
+
+	notifier.Stopped("admin")
+	assert.False(t, notifier.IsRunning("admin"))
+	assert.True(t, notifier.IsRunning("guest"))
+
+	notifier.heartbeat()
+	notifier.Stopped("guest")
+	notifier.Stopped("guest")
+
+	// Stream which never got media is released silently
+	notifier.Allocated("idle", &streamkey.Broadcaster{ID: "idle-broadcaster", Username: "idle"})
+	notifier.Stopped("idle")
+
+	assert.Equal(t, []string{
+		"ingest.broadcaster.processor",
+		"ingest.broadcaster.running",
+		"ingest.guest-broadcaster.running",
+		"ingest.broadcaster.stopped",
+		"ingest.guest-broadcaster.heartbeat",
+		"ingest.guest-broadcaster.stopped",
+	}, subjects)
+}
+
+func TestNotifier_DedicatedHeartbeat(t *testing.T) {
+	var heartbeats []*subjectpb.IngestHeartbeat
+	notifier := &Notifier{
+		publish: func(subject string, message protoreflect.ProtoMessage) error {
+			if heartbeat, ok := message.(*subjectpb.IngestHeartbeat); ok {
+				heartbeats = append(heartbeats, heartbeat)
+			}
+			return nil
+		},
+		meta:     &subjectpb.BroadcasterMeta{BroadcasterId: "broadcaster", Username: "admin"},
+		interval: 10 * time.Second,
+		streams:  make(map[string]*broadcasterStream),
+	}
+
+	// Broadcaster of the dedicated ingest is reported stopped while it has no stream
+	notifier.heartbeat()
+	notifier.Allocated("admin", &streamkey.Broadcaster{ID: "broadcaster", Username: "admin"})
+	notifier.Running("admin")
+	notifier.heartbeat()
+
+	assert.Len(t, heartbeats, 2)
+	assert.False(t, heartbeats[0].Running)
+	assert.Equal(t, "broadcaster", heartbeats[0].Meta.BroadcasterId)
+	assert.True(t, heartbeats[1].Running)
+}
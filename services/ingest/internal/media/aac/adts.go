@@ -0,0 +1,124 @@
+package aac
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+const (
+	adtsHeaderSize = 7
+	// Header has CRC when protection is not absent
+	adtsCRCSize = 2
+
+	// Samples of the single AAC frame
+	SamplesPerFrame = 1024
+)
+
+// MimeType of the audio track which is not supported by webrtc
+const MimeType = "audio/aac"
+
+var (
+	InvalidADTSError = errors.New("invalid adts frame")
+
+	sampleRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+)
+
+// Raw AAC frame of the ADTS stream with its decoder configuration
+type Frame struct {
+	Data       []byte
+	ObjectType uint8
+	SampleRate int
+	Channels   uint8
+
+	frequencyIndex uint8
+}
+
+// Two bytes AudioSpecificConfig of the mp4 decoder configuration
+func (f *Frame) AudioSpecificConfig() []byte {
+	return []byte{
+		f.ObjectType<<3 | f.frequencyIndex>>1,
+		f.frequencyIndex<<7 | f.Channels<<3,
+	}
+}
+
+// Byte stream starts with 12 bit ADTS sync word
+func IsADTS(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xF0 == 0xF0
+}
+
+// Read ADTS byte stream by frames
+type ADTSReader struct {
+	r *bufio.Reader
+}
+
+func NewADTSReader(r io.Reader) *ADTSReader {
+	return &ADTSReader{r: bufio.NewReader(r)}
+}
+
+func (r *ADTSReader) Read() (*Frame, error) {
+	header := make([]byte, adtsHeaderSize)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, err
+	}
+
+	frame, headerSize, length, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, length-adtsHeaderSize)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, err
+	}
+	frame.Data = data[headerSize-adtsHeaderSize:]
+
+	return frame, nil
+}
+
+// Frame at the start of the ADTS data and its length with the header
+func ParseADTS(data []byte) (*Frame, int, error) {
+	if len(data) < adtsHeaderSize {
+		return nil, 0, InvalidADTSError
+	}
+
+	frame, headerSize, length, err := parseHeader(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length > len(data) {
+		return nil, 0, InvalidADTSError
+	}
+	frame.Data = data[headerSize:length]
+
+	return frame, length, nil
+}
+
+// Frame without data, size of the header and length of the frame with the header
+func parseHeader(header []byte) (*Frame, int, int, error) {
+	if !IsADTS(header) {
+		return nil, 0, 0, InvalidADTSError
+	}
+
+	frame := &Frame{
+		ObjectType:     header[2]>>6 + 1,
+		frequencyIndex: (header[2] >> 2) & 0x0F,
+		Channels:       (header[2]&0x01)<<2 | header[3]>>6,
+	}
+	if int(frame.frequencyIndex) >= len(sampleRates) {
+		return nil, 0, 0, InvalidADTSError
+	}
+	frame.SampleRate = sampleRates[frame.frequencyIndex]
+
+	headerSize := adtsHeaderSize
+	if header[1]&0x01 == 0 {
+		headerSize += adtsCRCSize
+	}
+
+	length := int(header[3]&0x03)<<11 | int(header[4])<<3 | int(header[5])>>5
+	if length < headerSize {
+		return nil, 0, 0, InvalidADTSError
+	}
+
+	return frame, headerSize, length, nil
+}
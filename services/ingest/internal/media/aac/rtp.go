@@ -0,0 +1,46 @@
+package aac
+
+import (
+	"math/rand"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// Audio of any source is on the 48kHz rtp clock as opus, so it's timed by the same clock of the stream
+	RtpClockRate = 48_000
+
+	rtpPayloadType = 97
+)
+
+// Rtp clock ticks of the samples at the sample rate
+func RtpTicks(samples, sampleRate int) uint32 {
+	return uint32(int64(samples) * RtpClockRate / int64(sampleRate))
+}
+
+// Packetize ADTS frames into rtp packets. Each packet carries one whole frame, the packets don't go to the network
+type RtpPacketizer struct {
+	sequence uint16
+	ssrc     uint32
+}
+
+func NewRtpPacketizer() *RtpPacketizer {
+	return &RtpPacketizer{sequence: uint16(rand.Uint32()), ssrc: rand.Uint32()}
+}
+
+func (p *RtpPacketizer) Packetize(frame []byte, timestamp uint32) ([]byte, error) {
+	packet := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			PayloadType:    rtpPayloadType,
+			SequenceNumber: p.sequence,
+			Timestamp:      timestamp,
+			SSRC:           p.ssrc,
+		},
+		Payload: frame,
+	}
+	p.sequence++
+
+	return packet.Marshal()
+}
@@ -0,0 +1,210 @@
+package media
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type DropPolicy int
+
+const (
+	// Full subscriber drops its oldest frame
+	DropOldest DropPolicy = iota
+	// Full subscriber drops buffered frames and the next ones until keyframe, so decoder never gets frame without its reference
+	DropUntilKeyframe
+)
+
+// Fan-out of media frames. Each subscriber reads own bounded buffer, so slow subscriber drops own frames
+// by its policy and never blocks the writer or other subscribers
+type Bus struct {
+	keyframe    func(frame []byte) bool
+	subscribers []*Subscriber
+	closed      bool
+
+	mx sync.RWMutex
+}
+
+var _ MediaWriter = (*Bus)(nil)
+
+// Keyframe tells which frame starts a decodable sequence. Any frame does when it's nil
+func NewBus(keyframe func(frame []byte) bool) *Bus {
+	return &Bus{keyframe: keyframe}
+}
+
+// Never blocks. Frame is copied into each subscriber, so writer may reuse the buffer
+func (b *Bus) Write(p []byte) (int, error) {
+	keyframe := b.keyframe == nil || b.keyframe(p)
+
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	for _, subscriber := range b.subscribers {
+		subscriber.push(p, keyframe)
+	}
+	return len(p), nil
+}
+
+// Subscriber gets frames written after subscribe. Size is the count of buffered frames
+func (b *Bus) Subscribe(size int, policy DropPolicy) *Subscriber {
+	subscriber := &Subscriber{
+		bus:    b,
+		policy: policy,
+		frames: make([][]byte, size),
+		ready:  make(chan struct{}, 1),
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.closed {
+		subscriber.close()
+		return subscriber
+	}
+
+	b.subscribers = append(b.subscribers, subscriber)
+	return subscriber
+}
+
+func (b *Bus) unsubscribe(subscriber *Subscriber) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for i, s := range b.subscribers {
+		if s == subscriber {
+			b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Subscribers read buffered frames and then get io.EOF
+func (b *Bus) Close() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.closed = true
+	for _, subscriber := range b.subscribers {
+		subscriber.close()
+	}
+	b.subscribers = nil
+	return nil
+}
+
+// Bounded ring of the bus frames. It's read as DemuxerReader, so it may feed muxer of the consumer
+type Subscriber struct {
+	bus    *Bus
+	policy DropPolicy
+
+	frames       [][]byte
+	head         int
+	count        int
+	waitKeyframe bool
+	closed       bool
+	dropped      atomic.Uint64
+
+	ready chan struct{}
+	mx    sync.Mutex
+}
+
+var _ DemuxerReader = (*Subscriber)(nil)
+
+func (s *Subscriber) push(frame []byte, keyframe bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if s.waitKeyframe {
+		if !keyframe {
+			s.dropped.Add(1)
+			return
+		}
+		s.waitKeyframe = false
+	}
+
+	if s.count == len(s.frames) {
+		switch s.policy {
+		case DropUntilKeyframe:
+			s.dropped.Add(uint64(s.count))
+			s.clear()
+			if !keyframe {
+				s.dropped.Add(1)
+				s.waitKeyframe = true
+				return
+			}
+		default:
+			s.frames[s.head] = nil
+			s.head = (s.head + 1) % len(s.frames)
+			s.count--
+			s.dropped.Add(1)
+		}
+	}
+
+	s.frames[(s.head+s.count)%len(s.frames)] = append([]byte(nil), frame...)
+	s.count++
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Must be called with the lock
+func (s *Subscriber) clear() {
+	for i := range s.frames {
+		s.frames[i] = nil
+	}
+	s.head, s.count = 0, 0
+}
+
+// Next frame in order of the bus. Blocks until frame is written. Returns io.EOF when subscriber is closed and drained
+func (s *Subscriber) Read() ([]byte, error) {
+	for {
+		s.mx.Lock()
+		if s.count > 0 {
+			frame := s.frames[s.head]
+			s.frames[s.head] = nil
+			s.head = (s.head + 1) % len(s.frames)
+			s.count--
+			s.mx.Unlock()
+			return frame, nil
+		}
+		closed := s.closed
+		s.mx.Unlock()
+
+		if closed {
+			return nil, io.EOF
+		}
+		<-s.ready
+	}
+}
+
+// Frames dropped because the subscriber didn't keep up
+func (s *Subscriber) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscriber) close() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.closed = true
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Stop getting frames of the bus. Buffered frames are still read
+func (s *Subscriber) Close() error {
+	s.bus.unsubscribe(s)
+	s.close()
+	return nil
+}
@@ -0,0 +1,73 @@
+package media
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFrames(t *testing.T, subscriber *Subscriber) [][]byte {
+	var frames [][]byte
+	for {
+		frame, err := subscriber.Read()
+		if err == io.EOF {
+			return frames
+		}
+		assert.NoError(t, err)
+		frames = append(frames, frame)
+	}
+}
+
+func TestBus_DropOldest(t *testing.T) {
+	assert := assert.New(t)
+
+	bus := NewBus(nil)
+	slow := bus.Subscribe(2, DropOldest)
+	fast := bus.Subscribe(8, DropOldest)
+
+	for _, frame := range []byte{1, 2, 3, 4} {
+		n, err := bus.Write([]byte{frame})
+		assert.NoError(err)
+		assert.Equal(1, n)
+	}
+	bus.Close()
+
+	assert.Equal([][]byte{{3}, {4}}, readFrames(t, slow))
+	assert.Equal(uint64(2), slow.Dropped())
+
+	assert.Equal([][]byte{{1}, {2}, {3}, {4}}, readFrames(t, fast))
+	assert.Equal(uint64(0), fast.Dropped())
+
+	_, err := bus.Write([]byte{5})
+	assert.ErrorIs(err, io.ErrClosedPipe)
+}
+
+func TestBus_DropUntilKeyframe(t *testing.T) {
+	assert := assert.New(t)
+
+	// Frame which starts with 0xFF is the keyframe
+	bus := NewBus(func(frame []byte) bool { return frame[0] == 0xFF })
+	subscriber := bus.Subscribe(2, DropUntilKeyframe)
+
+	for _, frame := range [][]byte{{0xFF, 1}, {2}, {3}, {4}, {0xFF, 5}, {6}} {
+		bus.Write(frame)
+	}
+	bus.Close()
+
+	assert.Equal([][]byte{{0xFF, 5}, {6}}, readFrames(t, subscriber))
+	assert.Equal(uint64(4), subscriber.Dropped())
+}
+
+func TestSubscriber_Close(t *testing.T) {
+	assert := assert.New(t)
+
+	bus := NewBus(nil)
+	subscriber := bus.Subscribe(4, DropOldest)
+
+	bus.Write([]byte{1})
+	subscriber.Close()
+	bus.Write([]byte{2})
+
+	assert.Equal([][]byte{{1}}, readFrames(t, subscriber))
+}
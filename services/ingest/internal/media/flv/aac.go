@@ -0,0 +1,111 @@
+package flv
+
+import (
+	"errors"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/aac"
+)
+
+const (
+	soundFormatAAC = 10
+
+	aacPacketSequenceHeader = 0
+	aacPacketRaw            = 1
+
+	adtsHeaderSize = 7
+	// 13 bit frame length include header
+	adtsMaxFrameSize = 0x1FFF
+)
+
+var (
+	UnsupportedAudioCodecError = errors.New("unsupported flv audio codec. Support only AAC")
+	InvalidAudioTagError       = errors.New("invalid flv audio tag")
+	InvalidAudioConfigError    = errors.New("invalid aac audio specific config")
+	MissingAudioConfigError    = errors.New("aac frame received before audio specific config")
+)
+
+// Take FLV AAC audio tags and return rtp packets of ADTS frames. Rtp timestamp is the tag time on the 48kHz clock,
+// so the audio is timed by the stream clock as the h264 rtp of the video. ADTS may be probed by ffmpeg without any container
+type AacToRtpDemuxerReader struct {
+	*media.PacketQueue
+
+	packetizer *aac.RtpPacketizer
+
+	objectType     uint8
+	frequencyIndex uint8
+	channelConfig  uint8
+	configured     bool
+}
+
+func (r *AacToRtpDemuxerReader) parseAudioSpecificConfig(config []byte) error {
+	if len(config) < 2 {
+		return InvalidAudioConfigError
+	}
+
+	r.objectType = config[0] >> 3
+	r.frequencyIndex = (config[0]&0x07)<<1 | config[1]>>7
+	r.channelConfig = (config[1] >> 3) & 0x0F
+
+	// ADTS able carry only first four profiles and indexed frequency
+	if r.objectType == 0 || r.objectType > 4 || r.frequencyIndex > 12 {
+		return InvalidAudioConfigError
+	}
+
+	r.configured = true
+	return nil
+}
+
+func (r *AacToRtpDemuxerReader) adtsHeader(frameSize int) []byte {
+	length := frameSize + adtsHeaderSize
+
+	return []byte{
+		0xFF,
+		// MPEG-4, layer 0, protection absent
+		0xF1,
+		(r.objectType-1)<<6 | r.frequencyIndex<<2 | (r.channelConfig>>2)&0x1,
+		(r.channelConfig&0x3)<<6 | byte(length>>11)&0x3,
+		byte(length >> 3),
+		byte(length&0x7)<<5 | 0x1F,
+		0xFC,
+	}
+}
+
+// Write FLV audio tag body with its time in milliseconds
+func (r *AacToRtpDemuxerReader) WriteTag(timestamp uint32, tag []byte) error {
+	if len(tag) < 2 {
+		return InvalidAudioTagError
+	}
+
+	if tag[0]>>4 != soundFormatAAC {
+		return UnsupportedAudioCodecError
+	}
+
+	switch tag[1] {
+	case aacPacketSequenceHeader:
+		return r.parseAudioSpecificConfig(tag[2:])
+	case aacPacketRaw:
+		if !r.configured {
+			return MissingAudioConfigError
+		}
+	default:
+		return nil
+	}
+
+	frame := tag[2:]
+	if len(frame)+adtsHeaderSize > adtsMaxFrameSize {
+		return InvalidAudioTagError
+	}
+
+	packet, err := r.packetizer.Packetize(append(r.adtsHeader(len(frame)), frame...), timestamp*(aac.RtpClockRate/1000))
+	if err != nil {
+		return err
+	}
+	return r.Push(packet)
+}
+
+var _ media.DemuxerReader = (*AacToRtpDemuxerReader)(nil)
+
+func NewAacToRtpDemuxerReader() *AacToRtpDemuxerReader {
+	return &AacToRtpDemuxerReader{PacketQueue: media.NewPacketQueue(), packetizer: aac.NewRtpPacketizer()}
+}
@@ -0,0 +1,194 @@
+package flv
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+)
+
+const (
+	codecIDAVC = 7
+
+	avcPacketSequenceHeader = 0
+	avcPacketNALU           = 1
+
+	frameTypeKeyframe = 1
+
+	h264ClockRate   = 90000
+	h264PayloadType = 102
+	rtpMTU          = 1200
+)
+
+var (
+	UnsupportedVideoCodecError = errors.New("unsupported flv video codec. Support only AVC")
+	InvalidVideoTagError       = errors.New("invalid flv video tag")
+	InvalidDecoderConfigError  = errors.New("invalid avc decoder configuration record")
+	MissingDecoderConfigError  = errors.New("avc nalu received before decoder configuration")
+)
+
+var annexBStartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// Take FLV AVC video tags and return h264 rtp packets as []byte. The same as webrtc remote track returns.
+// So the rtmp video may go by the same path as webrtc h264 track
+type AvcToRtpDemuxerReader struct {
+	*media.PacketQueue
+
+	packetizer rtp.Packetizer
+	lengthSize int
+	sps        [][]byte
+	pps        [][]byte
+}
+
+func (r *AvcToRtpDemuxerReader) parseDecoderConfig(record []byte) error {
+	if len(record) < 7 {
+		return InvalidDecoderConfigError
+	}
+
+	r.lengthSize = int(record[4]&0x03) + 1
+	r.sps, r.pps = nil, nil
+
+	offset := 5
+	readParameterSets := func(count int) ([][]byte, error) {
+		var sets [][]byte
+		for i := 0; i < count; i++ {
+			if offset+2 > len(record) {
+				return nil, InvalidDecoderConfigError
+			}
+			size := int(binary.BigEndian.Uint16(record[offset:]))
+			offset += 2
+			if offset+size > len(record) {
+				return nil, InvalidDecoderConfigError
+			}
+			sets = append(sets, append([]byte(nil), record[offset:offset+size]...))
+			offset += size
+		}
+		return sets, nil
+	}
+
+	sps, err := readParameterSets(int(record[5] & 0x1F))
+	if err != nil {
+		return err
+	}
+	if offset >= len(record) {
+		return InvalidDecoderConfigError
+	}
+	count := int(record[offset])
+	offset++
+	pps, err := readParameterSets(count)
+	if err != nil {
+		return err
+	}
+
+	r.sps, r.pps = sps, pps
+	return nil
+}
+
+// Convert length prefixed nalus into Annex-B. Keyframe always start with parameter sets
+func (r *AvcToRtpDemuxerReader) toAnnexB(data []byte, keyframe bool) ([]byte, error) {
+	var out []byte
+	var hasParameterSets bool
+
+	for offset := 0; offset < len(data); {
+		if offset+r.lengthSize > len(data) {
+			return nil, InvalidVideoTagError
+		}
+
+		var size int
+		for i := 0; i < r.lengthSize; i++ {
+			size = size<<8 | int(data[offset+i])
+		}
+		offset += r.lengthSize
+
+		if size == 0 || offset+size > len(data) {
+			return nil, InvalidVideoTagError
+		}
+
+		nalu := data[offset : offset+size]
+		offset += size
+
+		if naluType := nalu[0] & 0x1F; naluType == 7 || naluType == 8 {
+			hasParameterSets = true
+		}
+
+		out = append(out, annexBStartCode...)
+		out = append(out, nalu...)
+	}
+
+	if keyframe && !hasParameterSets {
+		var sets []byte
+		for _, set := range append(append([][]byte{}, r.sps...), r.pps...) {
+			sets = append(sets, annexBStartCode...)
+			sets = append(sets, set...)
+		}
+		out = append(sets, out...)
+	}
+
+	return out, nil
+}
+
+// Write FLV video tag body. Timestamp is FLV decode timestamp in milliseconds
+func (r *AvcToRtpDemuxerReader) WriteTag(timestamp uint32, tag []byte) error {
+	if len(tag) < 5 {
+		return InvalidVideoTagError
+	}
+
+	if tag[0]&0x0F != codecIDAVC {
+		return UnsupportedVideoCodecError
+	}
+
+	keyframe := tag[0]>>4 == frameTypeKeyframe
+	// Composition time is signed 24 bit
+	compositionTime := int32(uint32(tag[2])<<16|uint32(tag[3])<<8|uint32(tag[4])) << 8 >> 8
+
+	switch tag[1] {
+	case avcPacketSequenceHeader:
+		return r.parseDecoderConfig(tag[5:])
+	case avcPacketNALU:
+		if r.lengthSize == 0 {
+			return MissingDecoderConfigError
+		}
+	default:
+		return nil
+	}
+
+	accessUnit, err := r.toAnnexB(tag[5:], keyframe)
+	if err != nil {
+		return err
+	}
+
+	presentation := uint32(int64(timestamp)+int64(compositionTime)) * (h264ClockRate / 1000)
+
+	for _, packet := range r.packetizer.Packetize(accessUnit, 0) {
+		packet.Timestamp = presentation
+
+		raw, err := packet.Marshal()
+		if err != nil {
+			return err
+		}
+
+		if err := r.Push(raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var _ media.DemuxerReader = (*AvcToRtpDemuxerReader)(nil)
+
+func NewAvcToRtpDemuxerReader() *AvcToRtpDemuxerReader {
+	return &AvcToRtpDemuxerReader{
+		PacketQueue: media.NewPacketQueue(),
+		packetizer: rtp.NewPacketizer(
+			rtpMTU,
+			h264PayloadType,
+			0,
+			&codecs.H264Payloader{},
+			rtp.NewRandomSequencer(),
+			h264ClockRate,
+		),
+	}
+}
@@ -0,0 +1,432 @@
+package fmp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	boxHeaderSize      = 8
+	largeBoxHeaderSize = 16
+	// Fragments of the live stream are small. Bigger box means broken input
+	maxBoxSize = 64 << 20
+
+	sampleIsNonSyncSample = 0x00010000
+)
+
+var (
+	InvalidBoxError  = errors.New("invalid mp4 box")
+	MissingInitError = errors.New("mp4 fragment before init segment")
+)
+
+// Track of the init segment. Defaults are taken from trex and used when fragment doesn't override them
+type Track struct {
+	ID        uint32
+	Timescale uint32
+	Video     bool
+
+	defaultSampleDuration uint32
+	defaultSampleFlags    uint32
+}
+
+// Init segment is ftyp and moov boxes
+type Init struct {
+	Data   []byte
+	Tracks map[uint32]*Track
+}
+
+// Fragment is moof and mdat boxes. It may be served as LL-HLS part or CMAF chunk
+type Fragment struct {
+	Data     []byte
+	Duration time.Duration
+	// Video of the fragment starts with sync sample. Audio only fragments are always independent
+	Independent bool
+	// Fragment starts the media of the next publisher
+	Discontinuity bool
+	// Wall clock of the fragment start. Zero when it's unknown
+	ProgramDateTime time.Time
+}
+
+// Read fragmented mp4 stream produced by muxer with empty moov
+type Reader struct {
+	r    io.Reader
+	init *Init
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+func readBox(r io.Reader) (string, []byte, error) {
+	header := make([]byte, largeBoxHeaderSize)
+	if _, err := io.ReadFull(r, header[:boxHeaderSize]); err != nil {
+		return "", nil, err
+	}
+
+	size := uint64(binary.BigEndian.Uint32(header[0:4]))
+	boxType := string(header[4:8])
+	headerSize := uint64(boxHeaderSize)
+
+	if size == 1 {
+		if _, err := io.ReadFull(r, header[boxHeaderSize:largeBoxHeaderSize]); err != nil {
+			return "", nil, err
+		}
+		size = binary.BigEndian.Uint64(header[8:16])
+		headerSize = largeBoxHeaderSize
+	}
+
+	if size < headerSize || size > maxBoxSize {
+		return "", nil, fmt.Errorf("%w %s with %d size", InvalidBoxError, boxType, size)
+	}
+
+	box := make([]byte, size)
+	copy(box, header[:headerSize])
+	if _, err := io.ReadFull(r, box[headerSize:]); err != nil {
+		return "", nil, err
+	}
+
+	return boxType, box, nil
+}
+
+// Iterate child boxes of the container payload
+func eachBox(payload []byte, fn func(boxType string, payload []byte) error) error {
+	for len(payload) > 0 {
+		if len(payload) < boxHeaderSize {
+			return InvalidBoxError
+		}
+
+		size := uint64(binary.BigEndian.Uint32(payload[0:4]))
+		boxType := string(payload[4:8])
+		headerSize := uint64(boxHeaderSize)
+
+		if size == 1 {
+			if len(payload) < largeBoxHeaderSize {
+				return InvalidBoxError
+			}
+			size = binary.BigEndian.Uint64(payload[8:16])
+			headerSize = largeBoxHeaderSize
+		}
+
+		if size < headerSize || size > uint64(len(payload)) {
+			return fmt.Errorf("%w %s with %d size", InvalidBoxError, boxType, size)
+		}
+
+		if err := fn(boxType, payload[headerSize:size]); err != nil {
+			return err
+		}
+		payload = payload[size:]
+	}
+	return nil
+}
+
+func boxPayload(box []byte) []byte {
+	if binary.BigEndian.Uint32(box[0:4]) == 1 {
+		return box[largeBoxHeaderSize:]
+	}
+	return box[boxHeaderSize:]
+}
+
+// Full box starts with version and 24 bit flags
+type fullBox struct {
+	payload []byte
+	offset  int
+	err     error
+}
+
+func newFullBox(payload []byte) (*fullBox, uint8, uint32) {
+	b := &fullBox{payload: payload}
+	versionFlags := b.uint32()
+	return b, uint8(versionFlags >> 24), versionFlags & 0x00FFFFFF
+}
+
+func (b *fullBox) skip(n int) {
+	if b.offset+n > len(b.payload) {
+		b.err = InvalidBoxError
+		b.offset = len(b.payload)
+		return
+	}
+	b.offset += n
+}
+
+func (b *fullBox) uint32() uint32 {
+	if b.offset+4 > len(b.payload) {
+		b.err = InvalidBoxError
+		return 0
+	}
+	value := binary.BigEndian.Uint32(b.payload[b.offset:])
+	b.offset += 4
+	return value
+}
+
+func parseTrak(payload []byte) (*Track, error) {
+	track := &Track{}
+
+	err := eachBox(payload, func(boxType string, payload []byte) error {
+		switch boxType {
+		case "tkhd":
+			b, version, _ := newFullBox(payload)
+			if version == 1 {
+				b.skip(16)
+			} else {
+				b.skip(8)
+			}
+			track.ID = b.uint32()
+			return b.err
+		case "mdia":
+			return eachBox(payload, func(boxType string, payload []byte) error {
+				switch boxType {
+				case "mdhd":
+					b, version, _ := newFullBox(payload)
+					if version == 1 {
+						b.skip(16)
+					} else {
+						b.skip(8)
+					}
+					track.Timescale = b.uint32()
+					return b.err
+				case "hdlr":
+					b, _, _ := newFullBox(payload)
+					b.skip(4)
+					if b.offset+4 > len(b.payload) {
+						return InvalidBoxError
+					}
+					track.Video = string(b.payload[b.offset:b.offset+4]) == "vide"
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+
+	if err == nil && track.Timescale == 0 {
+		err = fmt.Errorf("%w trak %d without timescale", InvalidBoxError, track.ID)
+	}
+	return track, err
+}
+
+func parseMoov(payload []byte) (map[uint32]*Track, error) {
+	tracks := make(map[uint32]*Track)
+	var extends [][]byte
+
+	err := eachBox(payload, func(boxType string, payload []byte) error {
+		switch boxType {
+		case "trak":
+			track, err := parseTrak(payload)
+			if err != nil {
+				return err
+			}
+			tracks[track.ID] = track
+		case "mvex":
+			return eachBox(payload, func(boxType string, payload []byte) error {
+				if boxType == "trex" {
+					extends = append(extends, payload)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Track extends may precede tracks
+	for _, payload := range extends {
+		trex, _, _ := newFullBox(payload)
+		trackID := trex.uint32()
+		trex.skip(4)
+		duration := trex.uint32()
+		trex.skip(4)
+		flags := trex.uint32()
+		if trex.err != nil {
+			return nil, trex.err
+		}
+
+		if track, ok := tracks[trackID]; ok {
+			track.defaultSampleDuration = duration
+			track.defaultSampleFlags = flags
+		}
+	}
+
+	return tracks, nil
+}
+
+// Read ftyp and moov boxes. Must be called before fragments
+func (r *Reader) ReadInit() (*Init, error) {
+	var data []byte
+
+	for {
+		boxType, box, err := readBox(r.r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch boxType {
+		case "ftyp":
+			data = append(data, box...)
+		case "moov":
+			tracks, err := parseMoov(boxPayload(box))
+			if err != nil {
+				return nil, err
+			}
+			r.init = &Init{Data: append(data, box...), Tracks: tracks}
+			return r.init, nil
+		}
+	}
+}
+
+type trafInfo struct {
+	duration    time.Duration
+	video       bool
+	independent bool
+}
+
+func (r *Reader) parseTraf(payload []byte) (*trafInfo, error) {
+	var track *Track
+	var defaultDuration, defaultFlags uint32
+	var samplesDuration uint64
+	firstSample := true
+	info := &trafInfo{}
+
+	err := eachBox(payload, func(boxType string, payload []byte) error {
+		switch boxType {
+		case "tfhd":
+			b, _, flags := newFullBox(payload)
+			trackID := b.uint32()
+
+			var ok bool
+			if track, ok = r.init.Tracks[trackID]; !ok {
+				return fmt.Errorf("%w traf of unknown %d track", InvalidBoxError, trackID)
+			}
+			defaultDuration, defaultFlags = track.defaultSampleDuration, track.defaultSampleFlags
+
+			if flags&0x01 != 0 {
+				b.skip(8)
+			}
+			if flags&0x02 != 0 {
+				b.skip(4)
+			}
+			if flags&0x08 != 0 {
+				defaultDuration = b.uint32()
+			}
+			if flags&0x10 != 0 {
+				b.skip(4)
+			}
+			if flags&0x20 != 0 {
+				defaultFlags = b.uint32()
+			}
+			return b.err
+		case "trun":
+			if track == nil {
+				return fmt.Errorf("%w trun before tfhd", InvalidBoxError)
+			}
+
+			b, _, flags := newFullBox(payload)
+			count := b.uint32()
+			if flags&0x01 != 0 {
+				b.skip(4)
+			}
+			firstFlags, hasFirstFlags := uint32(0), flags&0x04 != 0
+			if hasFirstFlags {
+				firstFlags = b.uint32()
+			}
+
+			for i := uint32(0); i < count && b.err == nil; i++ {
+				duration, sampleFlags := defaultDuration, defaultFlags
+				if flags&0x100 != 0 {
+					duration = b.uint32()
+				}
+				if flags&0x200 != 0 {
+					b.skip(4)
+				}
+				if flags&0x400 != 0 {
+					sampleFlags = b.uint32()
+				}
+				if flags&0x800 != 0 {
+					b.skip(4)
+				}
+
+				if i == 0 && hasFirstFlags {
+					sampleFlags = firstFlags
+				}
+				if firstSample {
+					info.independent = sampleFlags&sampleIsNonSyncSample == 0
+					firstSample = false
+				}
+				samplesDuration += uint64(duration)
+			}
+			return b.err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if track == nil {
+		return nil, fmt.Errorf("%w traf without tfhd", InvalidBoxError)
+	}
+
+	info.video = track.Video
+	info.duration = time.Duration(samplesDuration * uint64(time.Second) / uint64(track.Timescale))
+	return info, nil
+}
+
+// Fragment duration is the longest track duration. Fragment is independent when its video starts with sync sample
+func (r *Reader) parseMoof(payload []byte) (time.Duration, bool, error) {
+	var duration time.Duration
+	independent := true
+
+	err := eachBox(payload, func(boxType string, payload []byte) error {
+		if boxType != "traf" {
+			return nil
+		}
+
+		info, err := r.parseTraf(payload)
+		if err != nil {
+			return err
+		}
+		if info.duration > duration {
+			duration = info.duration
+		}
+		if info.video && !info.independent {
+			independent = false
+		}
+		return nil
+	})
+
+	return duration, independent, err
+}
+
+// Read next moof and mdat boxes. Other boxes between fragments are skipped
+func (r *Reader) ReadFragment() (*Fragment, error) {
+	if r.init == nil {
+		return nil, MissingInitError
+	}
+
+	var fragment *Fragment
+
+	for {
+		boxType, box, err := readBox(r.r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch boxType {
+		case "moof":
+			duration, independent, err := r.parseMoof(boxPayload(box))
+			if err != nil {
+				return nil, err
+			}
+			fragment = &Fragment{Data: box, Duration: duration, Independent: independent}
+		case "mdat":
+			if fragment == nil {
+				continue
+			}
+			fragment.Data = append(fragment.Data, box...)
+			return fragment, nil
+		}
+	}
+}
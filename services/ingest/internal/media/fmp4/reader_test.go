@@ -0,0 +1,99 @@
+package fmp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func box(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	header := make([]byte, boxHeaderSize)
+	binary.BigEndian.PutUint32(header, uint32(boxHeaderSize+len(body)))
+	copy(header[4:], boxType)
+	return append(header, body...)
+}
+
+func fields(values ...uint32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint32(data[i*4:], value)
+	}
+	return data
+}
+
+func trak(trackID, timescale uint32, handler string) []byte {
+	return box("trak",
+		box("tkhd", fields(0, 0, 0, trackID)),
+		box("mdia",
+			box("mdhd", fields(0, 0, 0, timescale)),
+			box("hdlr", fields(0, 0), []byte(handler)),
+		),
+	)
+}
+
+// Video has per sample durations and flags. Audio uses trex defaults
+func fragment(videoFlags uint32) []byte {
+	return append(box("moof",
+		box("traf",
+			box("tfhd", fields(0, 1)),
+			box("trun", fields(0x000500, 2, 3000, videoFlags, 3000, sampleIsNonSyncSample)),
+		),
+		box("traf",
+			box("tfhd", fields(0, 2)),
+			box("trun", fields(0, 10)),
+		),
+	), box("mdat", []byte{1, 2, 3})...)
+}
+
+func TestReader(t *testing.T) {
+	assert := assert.New(t)
+
+	var stream bytes.Buffer
+	stream.Write(box("ftyp", []byte("iso6")))
+	stream.Write(box("moov",
+		box("mvex", box("trex", fields(0, 2, 1, 960, 0, 0))),
+		trak(1, 90000, "vide"),
+		trak(2, 48000, "soun"),
+	))
+	stream.Write(fragment(0))
+	stream.Write(box("styp", []byte("msdh")))
+	stream.Write(fragment(sampleIsNonSyncSample))
+
+	reader := NewReader(&stream)
+
+	_, err := NewReader(bytes.NewReader(fragment(0))).ReadFragment()
+	assert.ErrorIs(err, MissingInitError)
+
+	init, err := reader.ReadInit()
+	assert.Nil(err)
+	assert.True(init.Tracks[1].Video)
+	assert.False(init.Tracks[2].Video)
+	assert.Equal(uint32(960), init.Tracks[2].defaultSampleDuration)
+
+	first, err := reader.ReadFragment()
+	assert.Nil(err)
+	assert.True(first.Independent)
+	// Audio 10 * 960 / 48000 is longer than video 2 * 3000 / 90000
+	assert.Equal(200*time.Millisecond, first.Duration)
+	assert.Equal(fragment(0), first.Data)
+
+	second, err := reader.ReadFragment()
+	assert.Nil(err)
+	assert.False(second.Independent)
+
+	_, err = reader.ReadFragment()
+	assert.ErrorIs(err, io.EOF)
+}
+
+func TestReader_InvalidBox(t *testing.T) {
+	stream := fields(4)
+	stream = append(stream, []byte("moov")...)
+
+	_, err := NewReader(bytes.NewReader(stream)).ReadInit()
+	assert.ErrorIs(t, err, InvalidBoxError)
+}
@@ -0,0 +1,286 @@
+package fmp4
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	VideoTrackID = 1
+	AudioTrackID = 2
+
+	VideoTimescale = 90000
+
+	AudioCodecOpus = "opus"
+	AudioCodecAAC  = "aac"
+
+	syncSampleFlags    = 0x02000000
+	nonSyncSampleFlags = 0x01000000 | sampleIsNonSyncSample
+
+	tfhdDefaultBaseIsMoof = 0x020000
+	trunDataOffset        = 0x000001
+	trunSampleDuration    = 0x000100
+	trunSampleSize        = 0x000200
+	trunSampleFlags       = 0x000400
+	trunCompositionOffset = 0x000800
+)
+
+var unityMatrix = []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000}
+
+// H264 track of the init segment. Parameter sets are NAL units without start code
+type VideoConfig struct {
+	SPS    []byte
+	PPS    []byte
+	Width  uint16
+	Height uint16
+}
+
+// Audio track of the init segment. Config is AudioSpecificConfig of AAC
+type AudioConfig struct {
+	Codec      string
+	SampleRate uint32
+	Channels   uint16
+	Config     []byte
+}
+
+// Media sample of the fragment. Video sample data is length prefixed NAL units
+type Sample struct {
+	Data     []byte
+	Duration uint32
+	// Signed offset of the presentation time. It's negative for the reordered pictures
+	CompositionOffset int32
+	Sync              bool
+}
+
+type TrackFragment struct {
+	TrackID             uint32
+	BaseMediaDecodeTime uint64
+	Samples             []Sample
+}
+
+type boxWriter struct {
+	bytes.Buffer
+}
+
+func (w *boxWriter) u8(value uint8) *boxWriter {
+	w.WriteByte(value)
+	return w
+}
+
+func (w *boxWriter) u16(value uint16) *boxWriter {
+	w.Write(binary.BigEndian.AppendUint16(nil, value))
+	return w
+}
+
+func (w *boxWriter) u32(values ...uint32) *boxWriter {
+	for _, value := range values {
+		w.Write(binary.BigEndian.AppendUint32(nil, value))
+	}
+	return w
+}
+
+func (w *boxWriter) u64(value uint64) *boxWriter {
+	w.Write(binary.BigEndian.AppendUint64(nil, value))
+	return w
+}
+
+func (w *boxWriter) zeros(n int) *boxWriter {
+	w.Write(make([]byte, n))
+	return w
+}
+
+func writeBox(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := binary.BigEndian.AppendUint32(make([]byte, 0, boxHeaderSize+len(body)), uint32(boxHeaderSize+len(body)))
+	box = append(box, boxType...)
+	return append(box, body...)
+}
+
+func writeFullBox(boxType string, version uint8, flags uint32, payload ...[]byte) []byte {
+	versionFlags := binary.BigEndian.AppendUint32(nil, uint32(version)<<24|flags&0x00FFFFFF)
+	return writeBox(boxType, append([][]byte{versionFlags}, payload...)...)
+}
+
+func writeAVC1(config *VideoConfig) []byte {
+	avcC := &boxWriter{}
+	avcC.u8(1)
+	if len(config.SPS) >= 4 {
+		avcC.Write(config.SPS[1:4])
+	} else {
+		avcC.zeros(3)
+	}
+	// Four bytes NAL unit length, single parameter set of each type
+	avcC.u8(0xFF).u8(0xE1).u16(uint16(len(config.SPS)))
+	avcC.Write(config.SPS)
+	avcC.u8(1).u16(uint16(len(config.PPS)))
+	avcC.Write(config.PPS)
+
+	entry := &boxWriter{}
+	entry.zeros(6).u16(1)
+	entry.zeros(16)
+	entry.u16(config.Width).u16(config.Height)
+	// 72 dpi
+	entry.u32(0x00480000, 0x00480000, 0)
+	entry.u16(1)
+	entry.zeros(32)
+	entry.u16(0x0018).u16(0xFFFF)
+
+	return writeBox("avc1", entry.Bytes(), writeBox("avcC", avcC.Bytes()))
+}
+
+// Descriptor of the esds with single byte size
+func writeDescriptor(tag uint8, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	return append([]byte{tag, uint8(len(body))}, body...)
+}
+
+func writeAudioEntry(config *AudioConfig) []byte {
+	entry := &boxWriter{}
+	entry.zeros(6).u16(1)
+	entry.zeros(8)
+	entry.u16(config.Channels).u16(16)
+	entry.zeros(4)
+	// 16.16 fixed point rate
+	entry.u32(config.SampleRate << 16)
+
+	if config.Codec == AudioCodecOpus {
+		dOps := &boxWriter{}
+		dOps.u8(0).u8(uint8(config.Channels)).u16(0).u32(config.SampleRate).u16(0).u8(0)
+		return writeBox("Opus", entry.Bytes(), writeBox("dOps", dOps.Bytes()))
+	}
+
+	decoderConfig := &boxWriter{}
+	// MPEG-4 audio, audio stream
+	decoderConfig.u8(0x40).u8(0x15).zeros(3).u32(0, 0)
+
+	esds := writeDescriptor(0x03,
+		[]byte{0x00, AudioTrackID, 0x00},
+		writeDescriptor(0x04, decoderConfig.Bytes(), writeDescriptor(0x05, config.Config)),
+		writeDescriptor(0x06, []byte{0x02}),
+	)
+
+	return writeBox("mp4a", entry.Bytes(), writeFullBox("esds", 0, 0, esds))
+}
+
+func writeTrak(trackID, timescale uint32, handler string, width, height uint16, sampleEntry []byte) []byte {
+	tkhd := &boxWriter{}
+	tkhd.u32(0, 0, trackID, 0, 0).zeros(8)
+	// Layer, alternate group, volume
+	volume := uint16(0)
+	if handler == "soun" {
+		volume = 0x0100
+	}
+	tkhd.u16(0).u16(0).u16(volume).u16(0)
+	tkhd.u32(unityMatrix...)
+	tkhd.u32(uint32(width)<<16, uint32(height)<<16)
+
+	mdhd := &boxWriter{}
+	// Undetermined language
+	mdhd.u32(0, 0, timescale, 0).u16(0x55C4).u16(0)
+
+	hdlr := &boxWriter{}
+	hdlr.u32(0)
+	hdlr.WriteString(handler)
+	hdlr.zeros(12)
+	hdlr.WriteString("stream-platform\x00")
+
+	var mediaHeader []byte
+	if handler == "vide" {
+		mediaHeader = writeFullBox("vmhd", 0, 1, make([]byte, 8))
+	} else {
+		mediaHeader = writeFullBox("smhd", 0, 0, make([]byte, 4))
+	}
+
+	stbl := writeBox("stbl",
+		writeFullBox("stsd", 0, 0, binary.BigEndian.AppendUint32(nil, 1), sampleEntry),
+		writeFullBox("stts", 0, 0, make([]byte, 4)),
+		writeFullBox("stsc", 0, 0, make([]byte, 4)),
+		writeFullBox("stsz", 0, 0, make([]byte, 8)),
+		writeFullBox("stco", 0, 0, make([]byte, 4)),
+	)
+
+	dinf := writeBox("dinf",
+		writeFullBox("dref", 0, 0, binary.BigEndian.AppendUint32(nil, 1), writeFullBox("url ", 0, 1)),
+	)
+
+	return writeBox("trak",
+		writeFullBox("tkhd", 0, 3, tkhd.Bytes()),
+		writeBox("mdia",
+			writeFullBox("mdhd", 0, 0, mdhd.Bytes()),
+			writeFullBox("hdlr", 0, 0, hdlr.Bytes()),
+			writeBox("minf", mediaHeader, dinf, stbl),
+		),
+	)
+}
+
+// Write ftyp and moov of the fragmented stream. Track is omitted when its config is nil
+func WriteInit(video *VideoConfig, audio *AudioConfig) []byte {
+	mvhd := &boxWriter{}
+	mvhd.u32(0, 0, 1000, 0)
+	mvhd.u32(0x00010000).u16(0x0100).zeros(10)
+	mvhd.u32(unityMatrix...)
+	mvhd.zeros(24)
+	mvhd.u32(AudioTrackID + 1)
+
+	moov := [][]byte{writeFullBox("mvhd", 0, 0, mvhd.Bytes())}
+	var trex [][]byte
+
+	if video != nil {
+		moov = append(moov, writeTrak(VideoTrackID, VideoTimescale, "vide", video.Width, video.Height, writeAVC1(video)))
+		trex = append(trex, writeFullBox("trex", 0, 0, (&boxWriter{}).u32(VideoTrackID, 1, 0, 0, 0).Bytes()))
+	}
+	if audio != nil {
+		moov = append(moov, writeTrak(AudioTrackID, audio.SampleRate, "soun", 0, 0, writeAudioEntry(audio)))
+		trex = append(trex, writeFullBox("trex", 0, 0, (&boxWriter{}).u32(AudioTrackID, 1, 0, 0, 0).Bytes()))
+	}
+	moov = append(moov, writeBox("mvex", trex...))
+
+	ftyp := writeBox("ftyp", []byte("iso6"), make([]byte, 4), []byte("iso6cmfcmp41"))
+	return append(ftyp, writeBox("moov", moov...)...)
+}
+
+func writeTraf(track *TrackFragment, dataOffset uint32) []byte {
+	trun := &boxWriter{}
+	trun.u32(uint32(len(track.Samples)), dataOffset)
+	for _, sample := range track.Samples {
+		flags := uint32(nonSyncSampleFlags)
+		if sample.Sync {
+			flags = syncSampleFlags
+		}
+		trun.u32(sample.Duration, uint32(len(sample.Data)), flags, uint32(sample.CompositionOffset))
+	}
+
+	return writeBox("traf",
+		writeFullBox("tfhd", 0, tfhdDefaultBaseIsMoof, binary.BigEndian.AppendUint32(nil, track.TrackID)),
+		writeFullBox("tfdt", 1, 0, binary.BigEndian.AppendUint64(nil, track.BaseMediaDecodeTime)),
+		// Version 1 has signed composition offsets
+		writeFullBox("trun", 1, trunDataOffset|trunSampleDuration|trunSampleSize|trunSampleFlags|trunCompositionOffset, trun.Bytes()),
+	)
+}
+
+// Write moof and mdat. Samples of the tracks follow each other in the mdat
+func WriteFragment(sequence uint32, tracks ...*TrackFragment) []byte {
+	// Data offsets depend on moof size which doesn't depend on offsets values
+	build := func(offsets []uint32) []byte {
+		trafs := [][]byte{writeFullBox("mfhd", 0, 0, binary.BigEndian.AppendUint32(nil, sequence))}
+		for i, track := range tracks {
+			trafs = append(trafs, writeTraf(track, offsets[i]))
+		}
+		return writeBox("moof", trafs...)
+	}
+
+	offsets := make([]uint32, len(tracks))
+	moofSize := uint32(len(build(offsets)))
+
+	var data [][]byte
+	offset := moofSize + boxHeaderSize
+	for i, track := range tracks {
+		offsets[i] = offset
+		for _, sample := range track.Samples {
+			data = append(data, sample.Data)
+			offset += uint32(len(sample.Data))
+		}
+	}
+
+	return append(build(offsets), writeBox("mdat", data...)...)
+}
@@ -0,0 +1,48 @@
+package fmp4
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Written stream is read back by own reader
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	var stream bytes.Buffer
+	stream.Write(WriteInit(
+		&VideoConfig{SPS: []byte{0x67, 0x42, 0xC0, 0x1F}, PPS: []byte{0x68, 0xCE}, Width: 1280, Height: 720},
+		&AudioConfig{Codec: AudioCodecAAC, SampleRate: 44100, Channels: 2, Config: []byte{0x12, 0x10}},
+	))
+
+	video := &TrackFragment{TrackID: VideoTrackID, BaseMediaDecodeTime: 9000, Samples: []Sample{
+		{Data: []byte{0, 0, 0, 1, 0x65}, Duration: 3000, Sync: true},
+		{Data: []byte{0, 0, 0, 1, 0x41}, Duration: 3000, CompositionOffset: -3000},
+	}}
+	audio := &TrackFragment{TrackID: AudioTrackID, Samples: []Sample{
+		{Data: []byte{0xAA}, Duration: 1024, Sync: true},
+	}}
+	fragment := WriteFragment(1, video, audio)
+	stream.Write(fragment)
+
+	reader := NewReader(&stream)
+
+	init, err := reader.ReadInit()
+	assert.Nil(err)
+	assert.True(init.Tracks[VideoTrackID].Video)
+	assert.Equal(uint32(VideoTimescale), init.Tracks[VideoTrackID].Timescale)
+	assert.False(init.Tracks[AudioTrackID].Video)
+	assert.Equal(uint32(44100), init.Tracks[AudioTrackID].Timescale)
+
+	read, err := reader.ReadFragment()
+	assert.Nil(err)
+	assert.True(read.Independent)
+	assert.Equal(time.Second/15, read.Duration)
+	assert.Equal(fragment, read.Data)
+
+	// Samples follow the moof in the track order
+	assert.True(bytes.HasSuffix(fragment, []byte{0, 0, 0, 1, 0x65, 0, 0, 0, 1, 0x41, 0xAA}))
+}
@@ -16,4 +16,3 @@ func (w *TargetMediaWriter) Write(p []byte) (n int, err error) {
 func NewTargetMediaWriter(target io.Writer) *TargetMediaWriter {
 	return &TargetMediaWriter{target: target}
 }
-
@@ -0,0 +1,241 @@
+package h264
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+const (
+	NALUTypeSlice = 1
+	NALUTypeIDR   = 5
+	NALUTypeSEI   = 6
+	NALUTypeSPS   = 7
+	NALUTypePPS   = 8
+	NALUTypeAUD   = 9
+
+	annexBReadSize = 32 * 1024
+	// Limit of not terminated NAL unit. Bigger one means broken input
+	maxNALUSize = 8 * 1024 * 1024
+)
+
+var startCode = []byte{0x00, 0x00, 0x01}
+
+func NALUType(nalu []byte) uint8 {
+	if len(nalu) == 0 {
+		return 0
+	}
+	return nalu[0] & 0x1F
+}
+
+// Byte stream starts with three or four bytes start code
+func IsAnnexB(data []byte) bool {
+	return bytes.HasPrefix(data, startCode) || bytes.HasPrefix(data, []byte{0x00, 0x00, 0x00, 0x01})
+}
+
+// Split Annex-B byte stream into NAL units without start codes
+func SplitAnnexB(data []byte) [][]byte {
+	var nalus [][]byte
+
+	start := bytes.Index(data, startCode)
+	for start >= 0 {
+		start += len(startCode)
+
+		next := bytes.Index(data[start:], startCode)
+		if next < 0 {
+			if nalu := bytes.TrimRight(data[start:], "\x00"); len(nalu) > 0 {
+				nalus = append(nalus, nalu)
+			}
+			break
+		}
+
+		// Zero byte of the four bytes start code is trimmed with trailing zeros
+		if nalu := bytes.TrimRight(data[start:start+next], "\x00"); len(nalu) > 0 {
+			nalus = append(nalus, nalu)
+		}
+		start += next
+	}
+
+	return nalus
+}
+
+// Access unit is the NAL units of the single picture
+type AccessUnit struct {
+	NALUs [][]byte
+	// Arrival of the first NAL unit. Annex-B of the rtp track has no timestamps
+	Time time.Time
+}
+
+func (au *AccessUnit) IDR() bool {
+	for _, nalu := range au.NALUs {
+		if NALUType(nalu) == NALUTypeIDR {
+			return true
+		}
+	}
+	return false
+}
+
+func (au *AccessUnit) hasSlice() bool {
+	for _, nalu := range au.NALUs {
+		if isSlice(nalu) {
+			return true
+		}
+	}
+	return false
+}
+
+// First VCL NAL unit of the picture
+func (au *AccessUnit) Slice() []byte {
+	for _, nalu := range au.NALUs {
+		if isSlice(nalu) {
+			return nalu
+		}
+	}
+	return nil
+}
+
+func isSlice(nalu []byte) bool {
+	naluType := NALUType(nalu)
+	return naluType == NALUTypeSlice || naluType == NALUTypeIDR
+}
+
+// Next access unit starts with delimiter, parameter set, SEI or first slice of the picture
+func startsAccessUnit(nalu []byte) bool {
+	switch naluType := NALUType(nalu); {
+	case naluType == NALUTypeAUD, naluType == NALUTypeSPS, naluType == NALUTypePPS, naluType == NALUTypeSEI:
+		return true
+	case naluType >= 14 && naluType <= 18:
+		return true
+	case isSlice(nalu):
+		// first_mb_in_slice is zero when exp-golomb code starts with one bit
+		return len(nalu) > 1 && nalu[1]&0x80 != 0
+	}
+	return false
+}
+
+// Read Annex-B byte stream by access units. Access unit is returned when the next one starts, so it lags by one picture
+type AccessUnitReader struct {
+	r   io.Reader
+	buf []byte
+	// Offset of the not scanned data
+	scanned   int
+	naluStart int
+	naluTime  time.Time
+	current   *AccessUnit
+	eof       bool
+	ready     []*AccessUnit
+
+	now func() time.Time
+}
+
+func NewAccessUnitReader(r io.Reader) *AccessUnitReader {
+	return &AccessUnitReader{r: r, naluStart: -1, now: time.Now}
+}
+
+func (r *AccessUnitReader) pushNALU(nalu []byte, arrival time.Time) {
+	nalu = bytes.TrimRight(nalu, "\x00")
+	if len(nalu) == 0 {
+		return
+	}
+
+	if r.current != nil && r.current.hasSlice() && startsAccessUnit(nalu) {
+		r.ready = append(r.ready, r.current)
+		r.current = nil
+	}
+	if r.current == nil {
+		r.current = &AccessUnit{Time: arrival}
+	}
+
+	// Buffer is reused by the next reads
+	r.current.NALUs = append(r.current.NALUs, append([]byte(nil), nalu...))
+}
+
+// Split received data by start codes. NAL unit time is the arrival of its start code
+func (r *AccessUnitReader) scan(arrival time.Time) {
+	for {
+		// Start code may be split between reads
+		from := r.scanned - 2
+		if from < 0 {
+			from = 0
+		}
+		if r.naluStart > from {
+			from = r.naluStart
+		}
+
+		idx := bytes.Index(r.buf[from:], startCode)
+		if idx < 0 {
+			r.scanned = len(r.buf)
+			return
+		}
+		idx += from
+
+		if r.naluStart >= 0 {
+			r.pushNALU(r.buf[r.naluStart:idx], r.naluTime)
+		}
+		r.naluStart = idx + len(startCode)
+		r.naluTime = arrival
+		r.scanned = r.naluStart
+	}
+}
+
+func (r *AccessUnitReader) compact() {
+	offset := r.naluStart
+	if offset < 0 {
+		// Keep possible beginning of the start code
+		offset = len(r.buf) - 2
+	}
+	if offset <= 0 {
+		return
+	}
+
+	r.buf = append(r.buf[:0], r.buf[offset:]...)
+	r.scanned -= offset
+	if r.scanned < 0 {
+		r.scanned = 0
+	}
+	if r.naluStart >= 0 {
+		r.naluStart = 0
+	}
+}
+
+// Return io.EOF when stream ends and the last access unit is returned
+func (r *AccessUnitReader) Read() (*AccessUnit, error) {
+	chunk := make([]byte, annexBReadSize)
+
+	for len(r.ready) == 0 {
+		if r.eof {
+			return nil, io.EOF
+		}
+
+		n, err := r.r.Read(chunk)
+		if n > 0 {
+			r.compact()
+			r.buf = append(r.buf, chunk[:n]...)
+			r.scan(r.now())
+
+			if r.naluStart >= 0 && len(r.buf)-r.naluStart > maxNALUSize {
+				r.buf, r.naluStart, r.scanned = r.buf[:0], -1, 0
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+
+			r.eof = true
+			if r.naluStart >= 0 {
+				r.pushNALU(r.buf[r.naluStart:], r.naluTime)
+				r.naluStart = -1
+			}
+			if r.current != nil {
+				r.ready = append(r.ready, r.current)
+				r.current = nil
+			}
+		}
+	}
+
+	au := r.ready[0]
+	r.ready = r.ready[1:]
+	return au, nil
+}
@@ -0,0 +1,182 @@
+package h264
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Return each chunk by own read like the pipe of the rtp writer
+type chunkReader struct {
+	chunks [][]byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *bitWriter) bit(value uint32) {
+	if w.bits%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	if value != 0 {
+		w.data[len(w.data)-1] |= 0x80 >> (w.bits % 8)
+	}
+	w.bits++
+}
+
+func (w *bitWriter) ue(value uint32) {
+	value++
+	size := 0
+	for v := value; v > 1; v >>= 1 {
+		size++
+	}
+	for i := 0; i < size; i++ {
+		w.bit(0)
+	}
+	for i := size; i >= 0; i-- {
+		w.bit(value >> i & 1)
+	}
+}
+
+// High profile 1080p sps with bottom cropping of the last macroblock row
+func testSPS() []byte {
+	w := &bitWriter{}
+	// sps id, chroma format 4:2:0, bit depths, no transform bypass, no scaling matrix
+	w.ue(0)
+	w.ue(1)
+	w.ue(0)
+	w.ue(0)
+	w.bit(0)
+	w.bit(0)
+	// log2_max_frame_num, poc type 0 with 8 bits lsb
+	w.ue(0)
+	w.ue(0)
+	w.ue(4)
+	w.ue(2)
+	w.bit(0)
+	// 120x68 macroblocks
+	w.ue(119)
+	w.ue(67)
+	w.bit(1)
+	w.bit(1)
+	// Crop 8 lines
+	w.bit(1)
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.ue(4)
+	// No vui, stop bit
+	w.bit(0)
+	w.bit(1)
+
+	return append([]byte{0x67, 100, 0x00, 0x28}, w.data...)
+}
+
+func TestParseSPS(t *testing.T) {
+	sps, err := ParseSPS(testSPS())
+	assert.Nil(t, err)
+	assert.Equal(t, 1920, sps.Width)
+	assert.Equal(t, 1080, sps.Height)
+	assert.Equal(t, "avc1.640028", sps.Codec())
+
+	_, err = ParseSPS([]byte{0x68, 0x01})
+	assert.ErrorIs(t, err, InvalidSPSError)
+}
+
+func TestSplitAnnexB(t *testing.T) {
+	nalus := SplitAnnexB([]byte{0, 0, 0, 1, 0x67, 1, 0, 0, 1, 0x68, 2, 0, 0, 0, 1, 0x65, 3})
+	assert.Equal(t, [][]byte{{0x67, 1}, {0x68, 2}, {0x65, 3}}, nalus)
+}
+
+func TestAccessUnitReader(t *testing.T) {
+	assert := assert.New(t)
+
+	reader := NewAccessUnitReader(&chunkReader{chunks: [][]byte{
+		{0, 0, 0, 1, 0x67, 1, 0, 0, 0, 1, 0x68, 2},
+		// Start code is split between reads
+		{0, 0},
+		{0, 1, 0x65, 0x88, 3},
+		// Second slice of the same picture
+		{0, 0, 0, 1, 0x65, 0x08, 4},
+		{0, 0, 0, 1, 0x41, 0x9A, 5},
+	}})
+
+	var reads int64
+	reader.now = func() time.Time {
+		reads++
+		return time.Unix(reads, 0)
+	}
+
+	first, err := reader.Read()
+	assert.Nil(err)
+	assert.True(first.IDR())
+	assert.Len(first.NALUs, 4)
+	assert.Equal(time.Unix(1, 0), first.Time)
+	assert.True(bytes.Equal([]byte{0x65, 0x88, 3}, first.Slice()))
+
+	second, err := reader.Read()
+	assert.Nil(err)
+	assert.False(second.IDR())
+	assert.Equal([][]byte{{0x41, 0x9A, 5}}, second.NALUs)
+	assert.Equal(time.Unix(5, 0), second.Time)
+
+	_, err = reader.Read()
+	assert.ErrorIs(err, io.EOF)
+}
+
+func TestPicOrderCounter(t *testing.T) {
+	sps, err := ParseSPS(testSPS())
+	assert.Nil(t, err)
+	counter := NewPicOrderCounter(sps)
+
+	// first_mb 0, slice type, pps 0, frame_num, idr id and 8 bits lsb
+	slice := func(header byte, lsb uint32) *AccessUnit {
+		w := &bitWriter{}
+		w.ue(0)
+		w.ue(0)
+		w.ue(0)
+		w.bit(0)
+		w.bit(0)
+		w.bit(0)
+		w.bit(0)
+		if header&0x1F == NALUTypeIDR {
+			w.ue(0)
+		}
+		for i := 7; i >= 0; i-- {
+			w.bit(lsb >> i & 1)
+		}
+		w.bit(1)
+		return &AccessUnit{NALUs: [][]byte{append([]byte{header}, w.data...)}}
+	}
+
+	for _, test := range []struct {
+		au  *AccessUnit
+		poc int64
+	}{
+		{slice(0x65, 0), 0},
+		{slice(0x41, 6), 6},
+		{slice(0x01, 2), 2},
+		{slice(0x41, 100), 100},
+		{slice(0x41, 200), 200},
+		// Lsb wraps after the reference picture
+		{slice(0x41, 4), 260},
+	} {
+		poc, ok := counter.Next(test.au)
+		assert.True(t, ok)
+		assert.Equal(t, test.poc, poc)
+	}
+}
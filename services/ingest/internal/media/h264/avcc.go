@@ -0,0 +1,28 @@
+package h264
+
+// NAL units as length prefixed sample of the mp4 and matroska tracks. Delimiters are dropped
+func AVCC(nalus [][]byte) []byte {
+	var data []byte
+	for _, nalu := range nalus {
+		if NALUType(nalu) == NALUTypeAUD {
+			continue
+		}
+		size := len(nalu)
+		data = append(data, byte(size>>24), byte(size>>16), byte(size>>8), byte(size))
+		data = append(data, nalu...)
+	}
+	return data
+}
+
+// AVCDecoderConfigurationRecord with single sps and pps. NAL units of the samples have four bytes length
+func DecoderConfigurationRecord(sps, pps []byte) []byte {
+	record := []byte{1, 0, 0, 0, 0xFF, 0xE1}
+	if len(sps) >= 4 {
+		copy(record[1:4], sps[1:4])
+	}
+
+	record = append(record, byte(len(sps)>>8), byte(len(sps)))
+	record = append(record, sps...)
+	record = append(record, 1, byte(len(pps)>>8), byte(len(pps)))
+	return append(record, pps...)
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media/h264writer"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
 )
@@ -13,6 +14,13 @@ var (
 	InvalidRTPData = errors.New("Invalid rtp packet")
 )
 
+// Capability of the local track which forward h264 of non webrtc ingress to webrtc viewers
+var CodecCapability = webrtc.RTPCodecCapability{
+	MimeType:    webrtc.MimeTypeH264,
+	ClockRate:   90000,
+	SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+}
+
 type RtpToH264MediaWriter struct {
 	writer *h264writer.H264Writer
 }
@@ -0,0 +1,299 @@
+package h264
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	InvalidSPSError   = errors.New("invalid h264 sequence parameter set")
+	InvalidSliceError = errors.New("invalid h264 slice header")
+)
+
+// Remove emulation prevention bytes of the NAL unit payload
+func rbsp(nalu []byte) []byte {
+	data := make([]byte, 0, len(nalu))
+	zeros := 0
+	for _, b := range nalu {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		data = append(data, b)
+	}
+	return data
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *bitReader) bit() uint32 {
+	if r.pos >= len(r.data)*8 {
+		r.err = InvalidSPSError
+		return 0
+	}
+	bit := uint32(r.data[r.pos/8]>>(7-r.pos%8)) & 0x01
+	r.pos++
+	return bit
+}
+
+func (r *bitReader) bits(n int) uint32 {
+	var value uint32
+	for i := 0; i < n; i++ {
+		value = value<<1 | r.bit()
+	}
+	return value
+}
+
+// Unsigned exp-golomb code
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for r.bit() == 0 && r.err == nil {
+		zeros++
+		if zeros > 31 {
+			r.err = InvalidSPSError
+			return 0
+		}
+	}
+	return 1<<zeros - 1 + r.bits(zeros)
+}
+
+// Signed exp-golomb code
+func (r *bitReader) se() int32 {
+	value := r.ue()
+	if value%2 == 0 {
+		return -int32(value / 2)
+	}
+	return int32(value/2 + 1)
+}
+
+func (r *bitReader) skipScalingList(size int) {
+	last, next := int32(8), int32(8)
+	for i := 0; i < size && r.err == nil; i++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
+
+// Sequence parameter set fields needed by the muxer and picture order count
+type SPS struct {
+	ProfileIdc      uint8
+	ConstraintFlags uint8
+	LevelIdc        uint8
+	Width           int
+	Height          int
+
+	log2MaxFrameNum     int
+	pocType             uint32
+	log2MaxPocLsb       int
+	frameMbsOnly        bool
+	separateColourPlane bool
+}
+
+// RFC 6381 codec of the HLS master playlist
+func (s *SPS) Codec() string {
+	return fmt.Sprintf("avc1.%02x%02x%02x", s.ProfileIdc, s.ConstraintFlags, s.LevelIdc)
+}
+
+func highProfile(profileIdc uint8) bool {
+	switch profileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		return true
+	}
+	return false
+}
+
+func ParseSPS(nalu []byte) (*SPS, error) {
+	if NALUType(nalu) != NALUTypeSPS || len(nalu) < 4 {
+		return nil, InvalidSPSError
+	}
+
+	sps := &SPS{
+		ProfileIdc:      nalu[1],
+		ConstraintFlags: nalu[2],
+		LevelIdc:        nalu[3],
+	}
+	r := &bitReader{data: rbsp(nalu[4:])}
+
+	// seq_parameter_set_id
+	r.ue()
+
+	chromaFormatIdc := uint32(1)
+	if highProfile(sps.ProfileIdc) {
+		chromaFormatIdc = r.ue()
+		if chromaFormatIdc == 3 {
+			sps.separateColourPlane = r.bit() == 1
+		}
+		// bit_depth_luma, bit_depth_chroma, qpprime_y_zero_transform_bypass
+		r.ue()
+		r.ue()
+		r.bit()
+
+		if r.bit() == 1 {
+			lists := 8
+			if chromaFormatIdc == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if r.bit() == 0 {
+					continue
+				}
+				if i < 6 {
+					r.skipScalingList(16)
+				} else {
+					r.skipScalingList(64)
+				}
+			}
+		}
+	}
+
+	sps.log2MaxFrameNum = int(r.ue()) + 4
+	sps.pocType = r.ue()
+	switch sps.pocType {
+	case 0:
+		sps.log2MaxPocLsb = int(r.ue()) + 4
+	case 1:
+		// delta_pic_order_always_zero, offset_for_non_ref_pic, offset_for_top_to_bottom_field
+		r.bit()
+		r.se()
+		r.se()
+		cycle := r.ue()
+		for i := uint32(0); i < cycle && r.err == nil; i++ {
+			r.se()
+		}
+	}
+
+	// max_num_ref_frames, gaps_in_frame_num_value_allowed
+	r.ue()
+	r.bit()
+
+	widthInMbs := int(r.ue()) + 1
+	heightInMapUnits := int(r.ue()) + 1
+	sps.frameMbsOnly = r.bit() == 1
+	if !sps.frameMbsOnly {
+		// mb_adaptive_frame_field
+		r.bit()
+	}
+	// direct_8x8_inference
+	r.bit()
+
+	frameHeightFactor := 1
+	if !sps.frameMbsOnly {
+		frameHeightFactor = 2
+	}
+	sps.Width = widthInMbs * 16
+	sps.Height = frameHeightFactor * heightInMapUnits * 16
+
+	if r.bit() == 1 {
+		left, right, top, bottom := int(r.ue()), int(r.ue()), int(r.ue()), int(r.ue())
+
+		cropX, cropY := 1, frameHeightFactor
+		if chromaFormatIdc != 0 && !sps.separateColourPlane {
+			subWidth, subHeight := 2, 2
+			switch chromaFormatIdc {
+			case 2:
+				subHeight = 1
+			case 3:
+				subWidth, subHeight = 1, 1
+			}
+			cropX, cropY = subWidth, subHeight*frameHeightFactor
+		}
+
+		sps.Width -= cropX * (left + right)
+		sps.Height -= cropY * (top + bottom)
+	}
+
+	if r.err != nil || sps.Width <= 0 || sps.Height <= 0 {
+		return nil, InvalidSPSError
+	}
+	return sps, nil
+}
+
+// Picture order count lsb of the slice. Only type 0 carries it
+func (s *SPS) picOrderCntLsb(slice []byte) (uint32, error) {
+	if len(slice) < 2 {
+		return 0, InvalidSliceError
+	}
+
+	r := &bitReader{data: rbsp(slice[1:])}
+
+	// first_mb_in_slice, slice_type, pic_parameter_set_id
+	r.ue()
+	r.ue()
+	r.ue()
+	if s.separateColourPlane {
+		r.bits(2)
+	}
+	// frame_num
+	r.bits(s.log2MaxFrameNum)
+	if !s.frameMbsOnly && r.bit() == 1 {
+		// bottom_field_flag
+		r.bit()
+	}
+	if NALUType(slice) == NALUTypeIDR {
+		// idr_pic_id
+		r.ue()
+	}
+
+	lsb := r.bits(s.log2MaxPocLsb)
+	if r.err != nil {
+		return 0, InvalidSliceError
+	}
+	return lsb, nil
+}
+
+// Count picture order of the access units in decode order
+type PicOrderCounter struct {
+	sps     *SPS
+	prevMsb int64
+	prevLsb int64
+}
+
+func NewPicOrderCounter(sps *SPS) *PicOrderCounter {
+	return &PicOrderCounter{sps: sps}
+}
+
+// Picture order count relative to the last IDR. Without reordering it's false and picture is shown in decode order
+func (c *PicOrderCounter) Next(au *AccessUnit) (int64, bool) {
+	slice := au.Slice()
+	if c.sps.pocType != 0 || slice == nil {
+		return 0, false
+	}
+
+	lsb, err := c.sps.picOrderCntLsb(slice)
+	if err != nil {
+		return 0, false
+	}
+
+	if au.IDR() {
+		c.prevMsb, c.prevLsb = 0, 0
+	}
+
+	maxLsb := int64(1) << c.sps.log2MaxPocLsb
+	msb := c.prevMsb
+	switch {
+	case int64(lsb) < c.prevLsb && c.prevLsb-int64(lsb) >= maxLsb/2:
+		msb += maxLsb
+	case int64(lsb) > c.prevLsb && int64(lsb)-c.prevLsb > maxLsb/2:
+		msb -= maxLsb
+	}
+
+	// Only reference pictures are the base of the next count
+	if slice[0]&0x60 != 0 {
+		c.prevMsb, c.prevLsb = msb, int64(lsb)
+	}
+
+	return msb + int64(lsb), true
+}
@@ -0,0 +1,412 @@
+package matroska
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/at-wat/ebml-go/mkvcore"
+	"github.com/at-wat/ebml-go/webm"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/aac"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+)
+
+const (
+	videoTrackNumber = 1
+	audioTrackNumber = 2
+
+	videoClockRate = 90_000
+	audioClockRate = 48_000
+
+	codecH264 = "V_MPEG4/ISO/AVC"
+
+	// Header waits the audio for this media time in milliseconds since the first video keyframe.
+	// Stream goes without audio track when the audio doesn't come in time
+	audioProbe = 1000
+)
+
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// Source of the processor is matroska with both tracks, other sources have own pipe of the audio
+func IsMatroska(data []byte) bool {
+	return bytes.HasPrefix(data, ebmlMagic)
+}
+
+// Head of the matroska stream has h264 track. Head must include the tracks of the header
+func IsH264(head []byte) bool {
+	return IsMatroska(head) && bytes.Contains(head, []byte(codecH264))
+}
+
+type track struct {
+	builder *samplebuilder.SampleBuilder
+	clock   *mediartp.TrackClock
+	block   webm.BlockWriteCloser
+	// Header entry of the track. Nil until the first sample which configures the codec
+	entry *webm.TrackEntry
+	// Block timestamp of the previous sample in milliseconds, blocks of the track must not go back
+	last int64
+	// Blocks of h264 are in decode order, B-frames go back by own presentation time
+	reordered bool
+	// Parameter sets of the h264 decoder configuration
+	sps []byte
+	pps []byte
+
+	mx sync.Mutex
+}
+
+// Must be called with the track lock
+func (t *track) timestamp(sample uint32) int64 {
+	timestamp := int64(t.clock.Time(sample) / time.Millisecond)
+	if t.reordered {
+		return timestamp
+	}
+	if timestamp < t.last {
+		timestamp = t.last
+	}
+	t.last = timestamp
+	return timestamp
+}
+
+type pendingBlock struct {
+	keyframe  bool
+	timestamp int64
+	data      []byte
+}
+
+// Vp8 or h264 video and opus or AAC audio of the publisher in one matroska stream. Block timestamps are positions of the rtp
+// timestamps on the shared clock of the stream, so tracks keep sync however long the stream is.
+// Header is written when the first video keyframe and the audio codec are known, media before the keyframe is dropped.
+// Cluster starts on each video keyframe
+type RtpToMatroskaMuxWriter struct {
+	reader io.Reader
+	buff   *media.BufioWriterCloser
+
+	video *track
+	audio *track
+	// Video from the first keyframe while the header waits the audio
+	pending []pendingBlock
+	ready   bool
+	closed  bool
+
+	mx sync.Mutex
+}
+
+func (w *RtpToMatroskaMuxWriter) GetReader() io.Reader {
+	return w.reader
+}
+
+// Writer of vp8 or h264 rtp packets
+func (w *RtpToMatroskaMuxWriter) Video(mimeType string) media.MediaWriter {
+	w.video.mx.Lock()
+	defer w.video.mx.Unlock()
+
+	switch mimeType {
+	case webrtc.MimeTypeH264:
+		w.video.builder = mediartp.NewSampleBuilder(&codecs.H264Packet{}, videoClockRate)
+		w.video.reordered = true
+	default:
+		w.video.builder = mediartp.NewSampleBuilder(&codecs.VP8Packet{}, videoClockRate)
+	}
+	return &trackWriter{write: w.writeVideo}
+}
+
+// Writer of opus rtp packets or rtp packets of ADTS frames
+func (w *RtpToMatroskaMuxWriter) Audio(mimeType string) media.MediaWriter {
+	w.audio.mx.Lock()
+	defer w.audio.mx.Unlock()
+
+	if mimeType == webrtc.MimeTypeOpus {
+		w.audio.builder = mediartp.NewSampleBuilder(&codecs.OpusPacket{}, audioClockRate)
+	}
+	return &trackWriter{write: w.writeAudio}
+}
+
+// Entry is read by the header under the muxer lock
+func (w *RtpToMatroskaMuxWriter) configure(track *track, entry *webm.TrackEntry) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	track.entry = entry
+}
+
+// Must be called with the muxer lock
+func (w *RtpToMatroskaMuxWriter) header() error {
+	ebmlHeader := *webm.DefaultEBMLHeader
+	ebmlHeader.DocType = "matroska"
+
+	entries := []webm.TrackEntry{*w.video.entry}
+	if w.audio.entry != nil {
+		entries = append(entries, *w.audio.entry)
+	}
+
+	ws, err := webm.NewSimpleBlockWriter(w.buff, entries,
+		mkvcore.WithEBMLHeader(&ebmlHeader),
+		// Zero distance to the next cluster, so each video keyframe starts it
+		mkvcore.WithMaxKeyframeInterval(videoTrackNumber, 0x7FFF),
+		mkvcore.WithOnFatalHandler(onFatal),
+	)
+	if err != nil {
+		return err
+	}
+
+	w.video.block = ws[0]
+	if len(ws) > 1 {
+		w.audio.block = ws[1]
+	}
+	w.ready = true
+
+	pending := w.pending
+	w.pending = nil
+	for _, block := range pending {
+		if _, err := w.video.block.Write(block.keyframe, block.timestamp, block.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Header is written when the audio is known or the video spans the probe. Must be called with the muxer lock
+func (w *RtpToMatroskaMuxWriter) probed() bool {
+	if len(w.pending) == 0 {
+		return false
+	}
+	return w.audio.entry != nil || w.pending[len(w.pending)-1].timestamp-w.pending[0].timestamp >= audioProbe
+}
+
+// Video from the first keyframe is pending until the header, audio before it is dropped. Must be called with the track lock
+func (w *RtpToMatroskaMuxWriter) write(track *track, keyframe bool, sample uint32, data []byte) error {
+	timestamp := track.timestamp(sample)
+
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+
+	if !w.ready {
+		if track == w.video {
+			w.pending = append(w.pending, pendingBlock{keyframe: keyframe, timestamp: timestamp, data: data})
+		}
+		if !w.probed() {
+			return nil
+		}
+		if err := w.header(); err != nil {
+			return err
+		}
+		if track == w.video {
+			return nil
+		}
+	}
+
+	// Audio which misses the probe has no track
+	if track.block == nil {
+		return nil
+	}
+	_, err := track.block.Write(keyframe, timestamp, data)
+	return err
+}
+
+func (w *RtpToMatroskaMuxWriter) writeVideo(p []byte) (int, error) {
+	w.video.mx.Lock()
+	defer w.video.mx.Unlock()
+
+	var packet rtp.Packet
+	if err := packet.Unmarshal(p); err != nil {
+		return 0, err
+	}
+
+	w.video.builder.Push(&packet)
+	for sample := w.video.builder.Pop(); sample != nil; sample = w.video.builder.Pop() {
+		data, keyframe := sample.Data, false
+		if w.video.reordered {
+			data, keyframe = w.h264Frame(sample.Data)
+		} else if len(data) >= 10 {
+			keyframe = data[0]&0x1 == 0
+		} else {
+			continue
+		}
+
+		if w.video.entry == nil {
+			entry := w.videoEntry(data, keyframe)
+			if entry == nil {
+				continue
+			}
+			w.configure(w.video, entry)
+		}
+
+		if err := w.write(w.video, keyframe, sample.PacketTimestamp, data); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Annex-B access unit as length prefixed NAL units. Parameter sets are kept for the decoder configuration
+func (w *RtpToMatroskaMuxWriter) h264Frame(sample []byte) ([]byte, bool) {
+	nalus := h264.SplitAnnexB(sample)
+
+	keyframe := false
+	for _, nalu := range nalus {
+		switch h264.NALUType(nalu) {
+		case h264.NALUTypeSPS:
+			w.video.sps = nalu
+		case h264.NALUTypePPS:
+			w.video.pps = nalu
+		case h264.NALUTypeIDR:
+			keyframe = true
+		}
+	}
+
+	return h264.AVCC(nalus), keyframe
+}
+
+// Entry of the first keyframe. Nil when the frame doesn't configure the decoder
+func (w *RtpToMatroskaMuxWriter) videoEntry(frame []byte, keyframe bool) *webm.TrackEntry {
+	if !keyframe {
+		return nil
+	}
+
+	entry := &webm.TrackEntry{
+		Name:        "Video",
+		TrackNumber: videoTrackNumber,
+		TrackUID:    67890,
+		TrackType:   1,
+	}
+
+	if !w.video.reordered {
+		raw := uint(frame[6]) | uint(frame[7])<<8 | uint(frame[8])<<16 | uint(frame[9])<<24
+		entry.CodecID = "V_VP8"
+		entry.Video = &webm.Video{PixelWidth: uint64(raw & 0x3FFF), PixelHeight: uint64((raw >> 16) & 0x3FFF)}
+		return entry
+	}
+
+	if w.video.sps == nil || w.video.pps == nil {
+		return nil
+	}
+	sps, err := h264.ParseSPS(w.video.sps)
+	if err != nil {
+		log.Println("[Matroska Muxer] Skip h264 keyframe. Err:", err)
+		return nil
+	}
+
+	entry.CodecID = codecH264
+	entry.CodecPrivate = h264.DecoderConfigurationRecord(w.video.sps, w.video.pps)
+	entry.Video = &webm.Video{PixelWidth: uint64(sps.Width), PixelHeight: uint64(sps.Height)}
+	return entry
+}
+
+func (w *RtpToMatroskaMuxWriter) writeAudio(p []byte) (int, error) {
+	w.audio.mx.Lock()
+	defer w.audio.mx.Unlock()
+
+	var packet rtp.Packet
+	if err := packet.Unmarshal(p); err != nil {
+		return 0, err
+	}
+
+	// Each packet of AAC carries the whole ADTS frame
+	if w.audio.builder == nil {
+		frame, _, err := aac.ParseADTS(packet.Payload)
+		if err != nil {
+			return len(p), nil
+		}
+
+		if w.audio.entry == nil {
+			w.configure(w.audio, &webm.TrackEntry{
+				Name:         "Audio",
+				TrackNumber:  audioTrackNumber,
+				TrackUID:     12345,
+				CodecID:      "A_AAC",
+				CodecPrivate: frame.AudioSpecificConfig(),
+				TrackType:    2,
+				Audio: &webm.Audio{
+					SamplingFrequency: float64(frame.SampleRate),
+					Channels:          uint64(frame.Channels),
+				},
+			})
+		}
+
+		if err := w.write(w.audio, true, packet.Timestamp, frame.Data); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	w.audio.builder.Push(&packet)
+	for sample := w.audio.builder.Pop(); sample != nil; sample = w.audio.builder.Pop() {
+		if w.audio.entry == nil {
+			w.configure(w.audio, &webm.TrackEntry{
+				Name:            "Audio",
+				TrackNumber:     audioTrackNumber,
+				TrackUID:        12345,
+				CodecID:         "A_OPUS",
+				TrackType:       2,
+				DefaultDuration: 20000000,
+				Audio: &webm.Audio{
+					SamplingFrequency: audioClockRate,
+					Channels:          2,
+				},
+			})
+		}
+
+		if err := w.write(w.audio, true, sample.PacketTimestamp, sample.Data); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Finalize the stream. Output is closed when buffered blocks are written
+func (w *RtpToMatroskaMuxWriter) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if !w.ready {
+		return w.buff.Close()
+	}
+	if w.audio.block == nil {
+		return w.video.block.Close()
+	}
+	_ = w.video.block.Close()
+	return w.audio.block.Close()
+}
+
+type trackWriter struct {
+	write func([]byte) (int, error)
+}
+
+func (w *trackWriter) Write(p []byte) (int, error) {
+	return w.write(p)
+}
+
+// Clocks of the tracks must be of the same stream clock. Codecs are set by the writers of the tracks
+func NewRtpToMatroskaMuxWriter(videoClock, audioClock *mediartp.TrackClock) *RtpToMatroskaMuxWriter {
+	r, w := io.Pipe()
+
+	return &RtpToMatroskaMuxWriter{
+		reader: r,
+		buff:   media.NewBufioWriterCloser(w),
+		video:  &track{clock: videoClock},
+		audio:  &track{clock: audioClock},
+	}
+}
+
+// Output is closed at the stream end while the last blocks may still be written
+func onFatal(err error) {
+	log.Printf("[Matroska Muxer] unable write matroska. Err: %s", err)
+}
@@ -0,0 +1,231 @@
+package matroska
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/at-wat/ebml-go"
+	"github.com/at-wat/ebml-go/webm"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/aac"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func rtpPacket(sequence uint16, timestamp uint32, payload []byte) []byte {
+	return rtpPacketWithMarker(sequence, timestamp, payload, true)
+}
+
+func rtpPacketWithMarker(sequence uint16, timestamp uint32, payload []byte, marker bool) []byte {
+	packet := rtp.Packet{
+		Header:  rtp.Header{Version: 2, Marker: marker, SequenceNumber: sequence, Timestamp: timestamp},
+		Payload: payload,
+	}
+	b, _ := packet.Marshal()
+	return b
+}
+
+// High profile 1080p
+var (
+	testSPS = []byte{0x67, 0x64, 0x00, 0x28, 0xAC, 0xCA, 0xC0, 0x78, 0x02, 0x27, 0xE5, 0x40}
+	// Depacketizer drops NAL units shorter than three bytes
+	testPPS = []byte{0x68, 0xEB, 0xE3, 0xCB}
+)
+
+// AAC LC frame of 48kHz stereo
+func adtsFrame(data []byte) []byte {
+	length := len(data) + 7
+	header := []byte{0xFF, 0xF1, 0x4C, 0x80 | byte(length>>11), byte(length >> 3), byte(length&0x7)<<5 | 0x1F, 0xFC}
+	return append(header, data...)
+}
+
+type matroskaContainer struct {
+	Header  webm.EBMLHeader    `ebml:"EBML"`
+	Segment webm.SegmentStream `ebml:"Segment,size=unknown"`
+}
+
+func readContainer(t *testing.T, muxer *RtpToMatroskaMuxWriter) (*matroskaContainer, func() []byte) {
+	var container matroskaContainer
+	var head bytes.Buffer
+
+	done := make(chan error)
+	go func() {
+		done <- ebml.Unmarshal(io.TeeReader(muxer.GetReader(), &head), &container)
+	}()
+
+	return &container, func() []byte {
+		assert.NoError(t, muxer.Close())
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("matroska is not finalized")
+		}
+		return head.Bytes()
+	}
+}
+
+// Vp8 payload descriptor with the start of partition and the frame of 640x480
+func vp8Frame(keyframe bool) []byte {
+	frame := []byte{0x10, 0x01, 0x00, 0x00, 0x9D, 0x01, 0x2A, 0x80, 0x02, 0xE0, 0x01}
+	if keyframe {
+		frame[1] = 0x00
+	}
+	return frame
+}
+
+func TestRtpToMatroskaMuxWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := mediartp.NewClock()
+	videoClock, audioClock := clock.Track(videoClockRate), clock.Track(audioClockRate)
+
+	muxer := NewRtpToMatroskaMuxWriter(videoClock, audioClock)
+	video, audio := muxer.Video(webrtc.MimeTypeVP8), muxer.Audio(webrtc.MimeTypeOpus)
+
+	var container struct {
+		Header  webm.EBMLHeader    `ebml:"EBML"`
+		Segment webm.SegmentStream `ebml:"Segment,size=unknown"`
+	}
+	done := make(chan error)
+	go func() {
+		done <- ebml.Unmarshal(muxer.GetReader(), &container)
+	}()
+
+	// Audio rtp timestamps start far from the video ones, both tracks start at the same time
+	videoClock.Write(rtpPacket(1, 90_000, nil))
+	audioClock.Write(rtpPacket(1, 480_000, nil))
+
+	// Audio before the video keyframe is dropped
+	audio.Write(rtpPacket(1, 480_000, []byte{0xFC}))
+	for i := 0; i < 10; i++ {
+		video.Write(rtpPacket(uint16(i+1), uint32(90_000+i*9000), vp8Frame(i%5 == 0)))
+		audio.Write(rtpPacket(uint16(i*2+2), uint32(480_000+(i*2+1)*2400), []byte{0xFC}))
+		audio.Write(rtpPacket(uint16(i*2+3), uint32(480_000+(i*2+2)*2400), []byte{0xFC}))
+	}
+
+	assert.NoError(muxer.Close())
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("matroska is not finalized")
+	}
+
+	assert.Equal("matroska", container.Header.DocType)
+	assert.Len(container.Segment.Tracks.TrackEntry, 2)
+
+	last := map[uint64]int64{}
+	for _, cluster := range container.Segment.Cluster {
+		for i, block := range cluster.SimpleBlock {
+			// Cluster starts on the video keyframe
+			if i == 0 {
+				assert.Equal(uint64(videoTrackNumber), block.TrackNumber)
+				assert.True(block.Keyframe)
+			}
+
+			timestamp := int64(cluster.Timecode) + int64(block.Timecode)
+			assert.GreaterOrEqual(timestamp, last[block.TrackNumber])
+			last[block.TrackNumber] = timestamp
+		}
+	}
+
+	// The last sample of each track waits the next one in the sample builder
+	assert.Equal(int64(800), last[videoTrackNumber])
+	assert.Equal(int64(950), last[audioTrackNumber])
+}
+
+func TestRtpToMatroskaMuxWriter_H264(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := mediartp.NewClock()
+	videoClock, audioClock := clock.Track(videoClockRate), clock.Track(audioClockRate)
+
+	muxer := NewRtpToMatroskaMuxWriter(videoClock, audioClock)
+	video, audio := muxer.Video(webrtc.MimeTypeH264), muxer.Audio(aac.MimeType)
+	container, finalize := readContainer(t, muxer)
+
+	videoClock.Write(rtpPacket(1, 90_000, nil))
+	audioClock.Write(rtpPacket(1, 480_000, nil))
+
+	sequence := uint16(1)
+	for i := 0; i < 10; i++ {
+		timestamp := uint32(90_000 + i*9000)
+		nalus := [][]byte{{0x41, 0x9A, byte(i)}}
+		if i%5 == 0 {
+			nalus = [][]byte{{0x09, 0xF0, 0x00}, testSPS, testPPS, {0x65, 0x88, byte(i)}}
+		}
+		for j, nalu := range nalus {
+			video.Write(rtpPacketWithMarker(sequence, timestamp, nalu, j == len(nalus)-1))
+			sequence++
+		}
+
+		// Ten frames of 1024 samples span about 213ms of each video frame
+		for j := 0; j < 5; j++ {
+			frame := i*5 + j
+			audio.Write(rtpPacket(uint16(frame+1), uint32(480_000+frame*1024), adtsFrame([]byte{0x21, byte(frame)})))
+		}
+	}
+
+	head := finalize()
+	assert.True(IsH264(head))
+
+	assert.Len(container.Segment.Tracks.TrackEntry, 2)
+	videoEntry, audioEntry := container.Segment.Tracks.TrackEntry[0], container.Segment.Tracks.TrackEntry[1]
+	assert.Equal(codecH264, videoEntry.CodecID)
+	assert.Equal(h264.DecoderConfigurationRecord(testSPS, testPPS), videoEntry.CodecPrivate)
+	assert.Equal(uint64(1920), videoEntry.Video.PixelWidth)
+	assert.Equal(uint64(1080), videoEntry.Video.PixelHeight)
+	assert.Equal("A_AAC", audioEntry.CodecID)
+	assert.Equal([]byte{0x11, 0x90}, audioEntry.CodecPrivate)
+
+	first := true
+	for _, cluster := range container.Segment.Cluster {
+		for _, block := range cluster.SimpleBlock {
+			if block.TrackNumber != videoTrackNumber {
+				// ADTS header is stripped
+				assert.Equal(byte(0x21), block.Data[0][0])
+				continue
+			}
+			// Access unit is length prefixed without the delimiter
+			if first {
+				assert.True(block.Keyframe)
+				assert.Equal([]byte{0, 0, 0, byte(len(testSPS))}, block.Data[0][:4])
+				first = false
+			}
+		}
+	}
+	assert.False(first)
+}
+
+func TestRtpToMatroskaMuxWriter_AudioProbe(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := mediartp.NewClock()
+	videoClock, audioClock := clock.Track(videoClockRate), clock.Track(audioClockRate)
+
+	muxer := NewRtpToMatroskaMuxWriter(videoClock, audioClock)
+	video := muxer.Video(webrtc.MimeTypeVP8)
+	container, finalize := readContainer(t, muxer)
+
+	videoClock.Write(rtpPacket(1, 90_000, nil))
+
+	// Without audio the header waits the probe and goes with the video only
+	for i := 0; i < 20; i++ {
+		video.Write(rtpPacket(uint16(i+1), uint32(90_000+i*9000), vp8Frame(i%10 == 0)))
+	}
+
+	finalize()
+	assert.Len(container.Segment.Tracks.TrackEntry, 1)
+
+	blocks := 0
+	for _, cluster := range container.Segment.Cluster {
+		blocks += len(cluster.SimpleBlock)
+	}
+	// Pending video is written with the header, the last frame waits the next one in the sample builder
+	assert.Equal(19, blocks)
+}
@@ -0,0 +1,446 @@
+package mpegts
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/aac"
+)
+
+const (
+	packetSize = 188
+	syncByte   = 0x47
+
+	pidPAT = 0x0000
+
+	streamTypeAAC     = 0x0F
+	streamTypeH264    = 0x1B
+	streamTypePrivate = 0x06
+
+	descriptorRegistration = 0x05
+
+	h264ClockRate   = 90000
+	h264PayloadType = 102
+	opusClockRate   = 48000
+	opusPayloadType = 111
+	rtpMTU          = 1200
+
+	// Limit of not terminated PES. Video PES usually has zero length and ends only by next PES
+	maxPESSize = 4 * 1024 * 1024
+)
+
+// MimeType of the audio track which is not supported by webrtc
+const MimeTypeAAC = aac.MimeType
+
+var (
+	InvalidPESError         = errors.New("invalid mpeg-ts pes")
+	InvalidOpusControlError = errors.New("invalid mpeg-ts opus control header")
+)
+
+// Called once when program map table is received. Audio mime type is empty when program has no supported audio
+type ProgramFunc func(audioMimeType string)
+
+type elementaryStream struct {
+	mimeType string
+	pes      []byte
+	started  bool
+}
+
+// Take mpeg-ts byte stream and split first program into h264 rtp packets and audio rtp packets.
+// AAC is returned as rtp packets of ADTS frames. So the streams may go by the same path as webrtc and rtmp tracks
+type Demuxer struct {
+	Video *media.PacketQueue
+	Audio *media.PacketQueue
+
+	onProgram ProgramFunc
+	pending   []byte
+
+	pmtPID  uint16
+	program bool
+	streams map[uint16]*elementaryStream
+
+	videoPacketizer rtp.Packetizer
+	opusPacketizer  rtp.Packetizer
+	aacPacketizer   *aac.RtpPacketizer
+
+	mx sync.Mutex
+}
+
+func (d *Demuxer) Write(p []byte) (int, error) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.pending = append(d.pending, p...)
+
+	offset := 0
+	for offset+packetSize <= len(d.pending) {
+		if d.pending[offset] != syncByte {
+			// Lost sync. Search next packet start
+			offset++
+			continue
+		}
+
+		if err := d.readPacket(d.pending[offset : offset+packetSize]); err != nil {
+			if errors.Is(err, io.ErrClosedPipe) {
+				return 0, err
+			}
+			log.Printf("[MPEG-TS] dropped packet. Err: %s", err)
+		}
+		offset += packetSize
+	}
+
+	d.pending = append(d.pending[:0], d.pending[offset:]...)
+	return len(p), nil
+}
+
+// Payload of the packet after adaptation field. False when packet has no payload
+func packetPayload(packet []byte) ([]byte, bool) {
+	adaptation := (packet[3] >> 4) & 0x3
+
+	payload := packet[4:]
+	if adaptation&0x2 != 0 {
+		size := int(payload[0])
+		if size+1 > len(payload) {
+			return nil, false
+		}
+		payload = payload[size+1:]
+	}
+	return payload, adaptation&0x1 != 0
+}
+
+func (d *Demuxer) readPacket(packet []byte) error {
+	unitStart := packet[1]&0x40 != 0
+	pid := binary.BigEndian.Uint16(packet[1:3]) & 0x1FFF
+
+	payload, ok := packetPayload(packet)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case pid == pidPAT:
+		if unitStart {
+			d.readPAT(payload)
+		}
+	case pid == d.pmtPID && d.pmtPID != 0:
+		if unitStart && !d.program {
+			d.readPMT(payload)
+		}
+	default:
+		stream, ok := d.streams[pid]
+		if !ok {
+			return nil
+		}
+		return d.readPES(stream, unitStart, payload)
+	}
+
+	return nil
+}
+
+// Return section body without header and crc
+func readSection(payload []byte) []byte {
+	if len(payload) < 1 {
+		return nil
+	}
+	pointer := int(payload[0])
+	if 1+pointer+8 > len(payload) {
+		return nil
+	}
+
+	section := payload[1+pointer:]
+	length := int(binary.BigEndian.Uint16(section[1:3]) & 0x0FFF)
+	if 3+length > len(section) || length < 9 {
+		return nil
+	}
+
+	return section[8 : 3+length-4]
+}
+
+func (d *Demuxer) readPAT(payload []byte) {
+	section := readSection(payload)
+
+	for offset := 0; offset+4 <= len(section); offset += 4 {
+		programNumber := binary.BigEndian.Uint16(section[offset:])
+		pid := binary.BigEndian.Uint16(section[offset+2:]) & 0x1FFF
+
+		// Zero program is network information table
+		if programNumber != 0 {
+			d.pmtPID = pid
+			return
+		}
+	}
+}
+
+func isOpusDescriptor(descriptors []byte) bool {
+	for offset := 0; offset+2 <= len(descriptors); {
+		tag, size := descriptors[offset], int(descriptors[offset+1])
+		offset += 2
+		if offset+size > len(descriptors) {
+			return false
+		}
+		if tag == descriptorRegistration && size >= 4 && string(descriptors[offset:offset+4]) == "Opus" {
+			return true
+		}
+		offset += size
+	}
+	return false
+}
+
+func (d *Demuxer) readPMT(payload []byte) {
+	section := readSection(payload)
+	if len(section) < 4 {
+		return
+	}
+
+	programInfoLength := int(binary.BigEndian.Uint16(section[2:4]) & 0x0FFF)
+	offset := 4 + programInfoLength
+
+	var audioMimeType string
+	for offset+5 <= len(section) {
+		streamType := section[offset]
+		pid := binary.BigEndian.Uint16(section[offset+1:]) & 0x1FFF
+		infoLength := int(binary.BigEndian.Uint16(section[offset+3:]) & 0x0FFF)
+		offset += 5
+		if offset+infoLength > len(section) {
+			return
+		}
+		descriptors := section[offset : offset+infoLength]
+		offset += infoLength
+
+		var mimeType string
+		switch {
+		case streamType == streamTypeH264:
+			mimeType = webrtc.MimeTypeH264
+		case streamType == streamTypeAAC:
+			mimeType = MimeTypeAAC
+		case streamType == streamTypePrivate && isOpusDescriptor(descriptors):
+			mimeType = webrtc.MimeTypeOpus
+		default:
+			log.Printf("[MPEG-TS] skip unsupported stream type 0x%x on pid %d", streamType, pid)
+			continue
+		}
+
+		// Only first stream of each kind
+		if mimeType != webrtc.MimeTypeH264 {
+			if audioMimeType != "" {
+				continue
+			}
+			audioMimeType = mimeType
+		}
+
+		d.streams[pid] = &elementaryStream{mimeType: mimeType}
+	}
+
+	d.program = true
+	if d.onProgram != nil {
+		d.onProgram(audioMimeType)
+	}
+}
+
+func (d *Demuxer) readPES(stream *elementaryStream, unitStart bool, payload []byte) error {
+	if unitStart {
+		if stream.started {
+			if err := d.flushPES(stream); err != nil {
+				return err
+			}
+		}
+		stream.pes = append(stream.pes[:0], payload...)
+		stream.started = true
+		return nil
+	}
+
+	if !stream.started {
+		return nil
+	}
+
+	if len(stream.pes)+len(payload) > maxPESSize {
+		stream.started = false
+		return InvalidPESError
+	}
+	stream.pes = append(stream.pes, payload...)
+	return nil
+}
+
+// Return payload and presentation timestamp in 90kHz
+func parsePES(pes []byte) ([]byte, uint64, error) {
+	if len(pes) < 9 || pes[0] != 0 || pes[1] != 0 || pes[2] != 1 {
+		return nil, 0, InvalidPESError
+	}
+
+	headerLength := int(pes[8])
+	if 9+headerLength > len(pes) {
+		return nil, 0, InvalidPESError
+	}
+
+	var pts uint64
+	if pes[7]&0x80 != 0 && headerLength >= 5 {
+		pts = readTimestamp(pes[9:14])
+	}
+
+	payload := pes[9+headerLength:]
+	if length := int(binary.BigEndian.Uint16(pes[4:6])); length != 0 && 6+length <= len(pes) && 6+length >= 9+headerLength {
+		payload = pes[9+headerLength : 6+length]
+	}
+
+	return payload, pts, nil
+}
+
+// 33 bit timestamp of the PES header
+func readTimestamp(b []byte) uint64 {
+	return uint64(b[0]>>1&0x07)<<30 | uint64(b[1])<<22 | uint64(b[2]>>1)<<15 | uint64(b[3])<<7 | uint64(b[4]>>1)
+}
+
+func (d *Demuxer) flushPES(stream *elementaryStream) error {
+	payload, pts, err := parsePES(stream.pes)
+	if err != nil {
+		return err
+	}
+
+	switch stream.mimeType {
+	case webrtc.MimeTypeH264:
+		return d.writeRtp(d.Video, d.videoPacketizer, append([]byte(nil), payload...), uint32(pts))
+	case MimeTypeAAC:
+		return d.writeAAC(payload, pts)
+	case webrtc.MimeTypeOpus:
+		return d.writeOpus(payload, pts)
+	}
+
+	return nil
+}
+
+func (d *Demuxer) writeRtp(queue *media.PacketQueue, packetizer rtp.Packetizer, sample []byte, timestamp uint32) error {
+	for _, packet := range packetizer.Packetize(sample, 0) {
+		packet.Timestamp = timestamp
+
+		raw, err := packet.Marshal()
+		if err != nil {
+			return err
+		}
+
+		if err := queue.Push(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PES of AAC already contain ADTS frames. Next frames of the same PES follow by the duration of the frame
+func (d *Demuxer) writeAAC(payload []byte, pts uint64) error {
+	timestamp := uint32(pts * aac.RtpClockRate / h264ClockRate)
+
+	for offset := 0; offset < len(payload); {
+		frame, length, err := aac.ParseADTS(payload[offset:])
+		if err != nil {
+			return err
+		}
+
+		packet, err := d.aacPacketizer.Packetize(append([]byte(nil), payload[offset:offset+length]...), timestamp)
+		if err != nil {
+			return err
+		}
+		if err := d.Audio.Push(packet); err != nil {
+			return err
+		}
+
+		offset += length
+		timestamp += aac.RtpTicks(aac.SamplesPerFrame, frame.SampleRate)
+	}
+
+	return nil
+}
+
+// Opus access units start with control header. It has 0x7FE prefix, trim flags and size of the packet
+func (d *Demuxer) writeOpus(payload []byte, pts uint64) error {
+	// Next packets of the same PES follow by 20ms
+	timestamp := uint32(pts * opusClockRate / h264ClockRate)
+
+	for offset := 0; offset < len(payload); {
+		if offset+2 > len(payload) || payload[offset] != 0x7F || payload[offset+1]&0xE0 != 0xE0 {
+			return InvalidOpusControlError
+		}
+		flags := payload[offset+1]
+		offset += 2
+
+		size := 0
+		for {
+			if offset >= len(payload) {
+				return InvalidOpusControlError
+			}
+			b := payload[offset]
+			offset++
+			size += int(b)
+			if b != 0xFF {
+				break
+			}
+		}
+
+		// Start and end trim
+		if flags&0x10 != 0 {
+			offset += 2
+		}
+		if flags&0x08 != 0 {
+			offset += 2
+		}
+		// Control extension
+		if flags&0x04 != 0 {
+			if offset >= len(payload) {
+				return InvalidOpusControlError
+			}
+			offset += 1 + int(payload[offset])
+		}
+
+		if offset+size > len(payload) {
+			return InvalidOpusControlError
+		}
+
+		if err := d.writeRtp(d.Audio, d.opusPacketizer, append([]byte(nil), payload[offset:offset+size]...), timestamp); err != nil {
+			return err
+		}
+		offset += size
+		timestamp += opusClockRate / 50
+	}
+
+	return nil
+}
+
+// Stop readers. Not terminated PES is discarded
+func (d *Demuxer) Close() error {
+	_ = d.Video.Close()
+	_ = d.Audio.Close()
+	return nil
+}
+
+var _ io.WriteCloser = (*Demuxer)(nil)
+
+func NewDemuxer(onProgram ProgramFunc) *Demuxer {
+	return &Demuxer{
+		Video:     media.NewPacketQueue(),
+		Audio:     media.NewPacketQueue(),
+		onProgram: onProgram,
+		streams:   make(map[uint16]*elementaryStream),
+		videoPacketizer: rtp.NewPacketizer(
+			rtpMTU,
+			h264PayloadType,
+			0,
+			&codecs.H264Payloader{},
+			rtp.NewRandomSequencer(),
+			h264ClockRate,
+		),
+		opusPacketizer: rtp.NewPacketizer(
+			rtpMTU,
+			opusPayloadType,
+			0,
+			&codecs.OpusPayloader{},
+			rtp.NewRandomSequencer(),
+			opusClockRate,
+		),
+		aacPacketizer: aac.NewRtpPacketizer(),
+	}
+}
@@ -0,0 +1,37 @@
+package mpegts
+
+import (
+	"errors"
+	"io"
+)
+
+// Presentation timestamps of 90kHz wrap in 33 bits, it's about 26 hours
+const PTSWrap = 1 << 33
+
+var MissingPTSError = errors.New("mpeg-ts has no pes with pts")
+
+// Presentation timestamp in 90kHz of the first PES which has it. It's the start of the segment written by ffmpeg
+func ReadFirstPTS(r io.Reader) (uint64, error) {
+	packet := make([]byte, packetSize)
+
+	for {
+		if _, err := io.ReadFull(r, packet); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, MissingPTSError
+			}
+			return 0, err
+		}
+
+		if packet[0] != syncByte || packet[1]&0x40 == 0 {
+			continue
+		}
+
+		pes, ok := packetPayload(packet)
+		if !ok || len(pes) < 14 || pes[0] != 0 || pes[1] != 0 || pes[2] != 1 {
+			continue
+		}
+		if pes[7]&0x80 != 0 && pes[8] >= 5 {
+			return readTimestamp(pes[9:14]), nil
+		}
+	}
+}
@@ -27,12 +27,25 @@ func (m *MuxerBuilder) Write(p []byte) (int, error) {
 	return n, err
 }
 
-// TODO: Possible directly write in writer function but i need read from m.muxerReader which is io.Pipe and this will block in writer.
-// NOTE: Possible read by `io.ReadAtLeast' to escape blocking from pipe
+// Copy muxed media into writers until muxer is closed
 func (m *MuxerBuilder) Mux() {
-	for {
-		io.Copy(m.writer, m.muxerReader)
+	Copy(m.writer, m.muxerReader)
+}
+
+// Copy output of the muxer until it's closed. Output is discarded after the writer fails, so muxer never blocks its writes
+func Copy(writer io.Writer, muxerReader io.Reader) {
+	if _, err := io.Copy(writer, muxerReader); err == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, muxerReader)
+}
+
+// Stop Mux. Muxer writes after close fail
+func (m *MuxerBuilder) Close() error {
+	if closer, ok := m.muxerReader.(io.Closer); ok {
+		return closer.Close()
 	}
+	return nil
 }
 
 var _ Muxer = (*MuxerBuilder)(nil)
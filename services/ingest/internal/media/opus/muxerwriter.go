@@ -3,20 +3,32 @@ package opus
 import (
 	"errors"
 	"io"
+	"log"
 	"sync"
 	"time"
 
+	"github.com/at-wat/ebml-go/mkvcore"
 	"github.com/at-wat/ebml-go/webm"
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
 )
 
 var (
 	EmptySampleError = errors.New("Empty Opus sample")
 )
 
+// Capability of the local track which forward opus of non webrtc ingress to webrtc viewers
+var CodecCapability = webrtc.RTPCodecCapability{
+	MimeType:    webrtc.MimeTypeOpus,
+	ClockRate:   48_000,
+	Channels:    2,
+	SDPFmtpLine: "minptime=10;useinbandfec=1",
+}
+
 type RtpToWebmOpusMuxWriter struct {
 	reader      io.Reader
 	opusBuilder *samplebuilder.SampleBuilder
@@ -55,7 +67,7 @@ func (w *RtpToWebmOpusMuxWriter) GetReader() io.Reader {
 
 func NewRtpToWebmOpusWriter() *RtpToWebmOpusMuxWriter {
 	rtpToWebmOpusWriter := &RtpToWebmOpusMuxWriter{}
-	rtpToWebmOpusWriter.opusBuilder = samplebuilder.New(10, &codecs.OpusPacket{}, 48_000)
+	rtpToWebmOpusWriter.opusBuilder = mediartp.NewSampleBuilder(&codecs.OpusPacket{}, 48_000)
 
 	r, w := io.Pipe()
 	buff := media.NewBufioWriterCloser(w)
@@ -73,8 +85,13 @@ func NewRtpToWebmOpusWriter() *RtpToWebmOpusMuxWriter {
 				Channels:          2,
 			},
 		},
-	})
+	}, mkvcore.WithOnFatalHandler(onFatal))
 	rtpToWebmOpusWriter.webmBuilder = ws[0]
 	rtpToWebmOpusWriter.reader = r
 	return rtpToWebmOpusWriter
 }
+
+// Muxed media can't be written when the reader is closed at the stream end. Ebml writer panics by default
+func onFatal(err error) {
+	log.Printf("[Opus Muxer] unable write webm. Err: %s", err)
+}
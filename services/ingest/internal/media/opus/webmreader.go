@@ -0,0 +1,64 @@
+package opus
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/at-wat/ebml-go/mkvcore"
+)
+
+var (
+	MissingOpusTrackError = errors.New("webm has no opus track")
+
+	ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+)
+
+// Byte stream starts with EBML header
+func IsWebm(data []byte) bool {
+	return bytes.HasPrefix(data, ebmlMagic)
+}
+
+// Read opus frames of the webm produced by RtpToWebmOpusMuxWriter. Timestamps are in milliseconds
+type WebmOpusReader struct {
+	track mkvcore.BlockReadCloser
+	err   error
+}
+
+func NewWebmOpusReader(r io.Reader) (*WebmOpusReader, error) {
+	reader := &WebmOpusReader{}
+
+	tracks, err := mkvcore.NewSimpleBlockReader(r, mkvcore.WithOnFatalHandler(func(err error) {
+		// Blocks are closed after the handler, so Read returns the error instead of EOF
+		reader.err = err
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, track := range tracks {
+		if track.TrackEntry().CodecID == "A_OPUS" {
+			reader.track = track
+			continue
+		}
+		// Block reader waits each track. Drop the others
+		_ = track.Close()
+	}
+
+	if reader.track == nil {
+		return nil, MissingOpusTrackError
+	}
+	return reader, nil
+}
+
+func (r *WebmOpusReader) Read() ([]byte, int64, error) {
+	frame, _, timestamp, err := r.track.Read()
+	if err == io.EOF && r.err != nil {
+		return nil, 0, r.err
+	}
+	return frame, timestamp, err
+}
+
+func (r *WebmOpusReader) Close() error {
+	return r.track.Close()
+}
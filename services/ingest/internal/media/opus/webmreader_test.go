@@ -0,0 +1,37 @@
+package opus
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Read back the webm of the rtp muxer
+func TestWebmOpusReader(t *testing.T) {
+	writer := NewRtpToWebmOpusWriter()
+
+	go func() {
+		for i := 0; i < 40; i++ {
+			packet := &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: uint16(i), Timestamp: uint32(i * 960), PayloadType: 111}, Payload: []byte{0xFC, byte(i)}}
+			data, _ := packet.Marshal()
+			_, _ = writer.Write(data)
+		}
+		_ = writer.webmBuilder.Close()
+	}()
+
+	reader, err := NewWebmOpusReader(writer.GetReader())
+	assert.Nil(t, err)
+
+	var timestamps []int64
+	for {
+		_, timestamp, err := reader.Read()
+		if err != nil {
+			break
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	// Sample builder holds the last packet
+	assert.Len(t, timestamps, 39)
+	assert.Equal(t, int64(20), timestamps[1]-timestamps[0])
+}
@@ -0,0 +1,55 @@
+package media
+
+import (
+	"io"
+	"sync"
+)
+
+const packetQueueSize = 512
+
+// Pass samples from connection goroutine to demuxer goroutine. Read return io.EOF when queue closed
+type PacketQueue struct {
+	packets chan []byte
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (q *PacketQueue) Push(packet []byte) error {
+	select {
+	case <-q.done:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	select {
+	case q.packets <- packet:
+		return nil
+	case <-q.done:
+		return io.ErrClosedPipe
+	}
+}
+
+func (q *PacketQueue) Read() ([]byte, error) {
+	select {
+	case packet := <-q.packets:
+		return packet, nil
+	case <-q.done:
+		return nil, io.EOF
+	}
+}
+
+func (q *PacketQueue) Close() error {
+	q.once.Do(func() {
+		close(q.done)
+	})
+	return nil
+}
+
+var _ DemuxerReader = (*PacketQueue)(nil)
+
+func NewPacketQueue() *PacketQueue {
+	return &PacketQueue{
+		packets: make(chan []byte, packetQueueSize),
+		done:    make(chan struct{}),
+	}
+}
@@ -0,0 +1,167 @@
+package rtp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+)
+
+// Seconds between ntp epoch 1900 and unix epoch
+const ntpEpochOffset = 2_208_988_800
+
+// Sender wall clock of the rtcp sender report
+func NtpTime(ntp uint64) time.Time {
+	seconds := int64(ntp>>32) - ntpEpochOffset
+	nanos := (int64(ntp&0xFFFFFFFF) * int64(time.Second)) >> 32
+	return time.Unix(seconds, nanos)
+}
+
+// Timeline shared by tracks of the stream. Rtp timestamps of each track are mapped on it, so tracks muxed together keep sync.
+// Track is placed on the timeline by arrival of its first packet until its sender report comes. Sender reports of all tracks
+// are on the same sender wall clock, which is bound to the local one by the first report
+type Clock struct {
+	start time.Time
+	// Local time minus sender time
+	ntpOffset time.Duration
+	synced    bool
+	now       func() time.Time
+
+	mx sync.Mutex
+}
+
+func NewClock() *Clock {
+	return &Clock{now: time.Now}
+}
+
+// Position on the timeline of the local time. Timeline starts on the first call
+func (c *Clock) position(local time.Time) time.Duration {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.start.IsZero() {
+		c.start = local
+	}
+	return local.Sub(c.start)
+}
+
+// Local time of the sender wall clock
+func (c *Clock) local(sender time.Time) time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.synced {
+		c.ntpOffset = c.now().Sub(sender)
+		c.synced = true
+	}
+	return sender.Add(c.ntpOffset)
+}
+
+// Next sender report binds the sender wall clock again. Publisher which takes over the stream has own wall clock
+func (c *Clock) Resync() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.synced = false
+}
+
+// Position of now on the timeline
+func (c *Clock) Elapsed() time.Duration {
+	return c.position(c.now())
+}
+
+// Sender wall clock of the timeline position. It's the local clock until the first sender report
+func (c *Clock) WallTime(position time.Duration) time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.start.IsZero() {
+		c.start = c.now()
+	}
+	return c.start.Add(position).Add(-c.ntpOffset)
+}
+
+// Sender wall clock of the local time
+func (c *Clock) SenderTime(local time.Time) time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return local.Add(-c.ntpOffset)
+}
+
+func (c *Clock) Track(clockRate uint32) *TrackClock {
+	return &TrackClock{clock: c, rate: int64(clockRate)}
+}
+
+// Rtp timestamps of the track on the stream timeline. It's written with rtp packets of the track as they arrive,
+// so timestamps of lagging readers are mapped by the same anchor
+type TrackClock struct {
+	clock *Clock
+	rate  int64
+
+	observed bool
+	// Extended timestamp of the latest packet, rtp timestamp wraps in hours
+	last int64
+	// Timeline position of the anchor timestamp
+	anchorRTP int64
+	anchor    time.Duration
+
+	mx sync.Mutex
+}
+
+var _ media.MediaWriter = (*TrackClock)(nil)
+
+// Extend 32 bit timestamp near the latest one. Must be called with the lock
+func (t *TrackClock) extend(timestamp uint32) int64 {
+	if !t.observed {
+		return int64(timestamp)
+	}
+	return t.last + int64(int32(timestamp-uint32(t.last)))
+}
+
+// Must be called with the lock
+func (t *TrackClock) observe(timestamp uint32) int64 {
+	extended := t.extend(timestamp)
+	if !t.observed {
+		t.observed = true
+		t.anchorRTP = extended
+		t.anchor = t.clock.position(t.clock.now())
+	}
+	if extended > t.last {
+		t.last = extended
+	}
+	return extended
+}
+
+// Observe the rtp packet. Packet which is not rtp is skipped, so writers after it still get it
+func (t *TrackClock) Write(p []byte) (int, error) {
+	var header rtp.Header
+	if _, err := header.Unmarshal(p); err != nil {
+		return len(p), nil
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.observe(header.Timestamp)
+	return len(p), nil
+}
+
+// Anchor the track by the sender report. Ntp time is the 64 bit fixed point of the report
+func (t *TrackClock) SenderReport(ntp uint64, timestamp uint32) {
+	position := t.clock.position(t.clock.local(NtpTime(ntp)))
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.anchorRTP = t.observe(timestamp)
+	t.anchor = position
+}
+
+// Position of the rtp timestamp on the stream timeline. Timestamp must be near the observed ones
+func (t *TrackClock) Time(timestamp uint32) time.Duration {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	ticks := t.observe(timestamp) - t.anchorRTP
+	return t.anchor + time.Duration(ticks/t.rate)*time.Second + time.Duration(ticks%t.rate)*time.Second/time.Duration(t.rate)
+}
@@ -0,0 +1,103 @@
+package rtp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func rtpPacket(timestamp uint32) []byte {
+	packet := rtp.Packet{Header: rtp.Header{Version: 2, Timestamp: timestamp}}
+	b, _ := packet.Marshal()
+	return b
+}
+
+// Ntp time of the sender report
+func ntpTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+func TestNtpTime(t *testing.T) {
+	now := time.Unix(1_700_000_000, 500_000_000)
+	assert.WithinDuration(t, now, NtpTime(ntpTime(now)), time.Microsecond)
+}
+
+func TestTrackClock_Arrival(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	clock := NewClock()
+	clock.now = func() time.Time { return now }
+
+	video, audio := clock.Track(90_000), clock.Track(48_000)
+
+	video.Write(rtpPacket(1000))
+	now = now.Add(100 * time.Millisecond)
+	audio.Write(rtpPacket(5000))
+
+	assert.Equal(time.Duration(0), video.Time(1000))
+	assert.Equal(time.Second, video.Time(91_000))
+	assert.Equal(100*time.Millisecond, audio.Time(5000))
+	assert.Equal(600*time.Millisecond, audio.Time(29_000))
+
+	// Timestamp wraps around after the latest packet. Track is anchored at 100ms of the timeline
+	wrap := clock.Track(90_000)
+	wrap.Write(rtpPacket(0xFFFFFFFF - 8999))
+	assert.Equal(300*time.Millisecond, wrap.Time(9000))
+}
+
+func TestTrackClock_SenderReport(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	clock := NewClock()
+	clock.now = func() time.Time { return now }
+
+	video, audio := clock.Track(90_000), clock.Track(48_000)
+
+	// Audio arrives later than the video, but sender captured them at the same time
+	video.Write(rtpPacket(90_000))
+	now = now.Add(300 * time.Millisecond)
+	audio.Write(rtpPacket(48_000))
+
+	sender := time.Unix(1_600_000_000, 0)
+	video.SenderReport(ntpTime(sender), 90_000)
+	audio.SenderReport(ntpTime(sender), 48_000)
+
+	assert.Equal(video.Time(90_000), audio.Time(48_000))
+	assert.Equal(video.Time(180_000), audio.Time(96_000))
+}
+
+func TestClock_WallTime(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	clock := NewClock()
+	clock.now = func() time.Time { return now }
+
+	video := clock.Track(90_000)
+	video.Write(rtpPacket(0))
+
+	// Local clock until the first report
+	assert.Equal(now.Add(time.Second), clock.WallTime(time.Second))
+
+	now = now.Add(time.Second)
+	sender := time.Unix(1_600_000_000, 0)
+	video.SenderReport(ntpTime(sender), 90_000)
+
+	assert.Equal(sender, clock.WallTime(video.Time(90_000)))
+	assert.Equal(sender.Add(time.Second), clock.SenderTime(now.Add(time.Second)))
+
+	// Publisher which takes over has own wall clock
+	clock.Resync()
+	now = now.Add(time.Second)
+	backup := time.Unix(1_650_000_000, 0)
+	video.SenderReport(ntpTime(backup), 500_000)
+
+	assert.Equal(backup, clock.WallTime(video.Time(500_000)))
+	assert.Equal(2*time.Second, clock.Elapsed())
+}
@@ -9,26 +9,76 @@ import (
 
 type PionWebrtcRemoteTrack = webrtc.TrackRemote
 
-// Read webrtc rtp track and return rtp packet as []byte.
+// Read webrtc rtp track through the jitter buffer and return rtp packet as []byte.
 // This packet may be restored with all metadata if needed
 type RtpTrackDemuxerReader struct {
-	track *PionWebrtcRemoteTrack
-	mx    sync.Mutex
+	track  *PionWebrtcRemoteTrack
+	buffer *JitterBuffer
+	// Rtx payload type of the track. Zero when rtx isn't negotiated
+	repairPayloadType uint8
+	once              sync.Once
 }
 
 func (r *RtpTrackDemuxerReader) Read() ([]byte, error) {
-	r.mx.Lock()
-	defer r.mx.Unlock()
+	r.once.Do(func() {
+		go r.receive()
+		go r.buffer.Run()
+	})
 
-	pkt, _, err := r.track.ReadRTP()
+	pkt, err := r.buffer.Read()
 	if err != nil {
 		return nil, err
 	}
 	return pkt.Marshal()
 }
 
+// Push packets of the track and its retransmissions into the jitter buffer until the track ends
+func (r *RtpTrackDemuxerReader) receive() {
+	defer r.buffer.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var repair *RepairStream
+	for {
+		pkt, attributes, err := r.track.ReadRTP()
+		if err != nil {
+			return
+		}
+
+		if repair == nil && r.repairPayloadType != 0 {
+			if repair = RepairStreamOf(attributes); repair != nil {
+				repair.Expect(r.repairPayloadType, r.track.RID())
+				go r.receiveRepaired(repair, done)
+			}
+		}
+
+		r.buffer.Push(pkt)
+	}
+}
+
+func (r *RtpTrackDemuxerReader) receiveRepaired(repair *RepairStream, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case pkt := <-repair.Packets():
+			r.buffer.Push(pkt)
+		}
+	}
+}
+
+// Retransmissions of the rtx payload type restore the packets lost by the track
+func (r *RtpTrackDemuxerReader) Repair(payloadType uint8) {
+	r.repairPayloadType = payloadType
+}
+
+func (r *RtpTrackDemuxerReader) Codec() webrtc.RTPCodecParameters {
+	return r.track.Codec()
+}
+
 var _ media.DemuxerReader = (*RtpTrackDemuxerReader)(nil)
 
-func NewRtpTrackDemuxerReader(track *PionWebrtcRemoteTrack) *RtpTrackDemuxerReader {
-	return &RtpTrackDemuxerReader{track: track}
+func NewRtpTrackDemuxerReader(track *PionWebrtcRemoteTrack, buffer *JitterBuffer) *RtpTrackDemuxerReader {
+	return &RtpTrackDemuxerReader{track: track, buffer: buffer}
 }
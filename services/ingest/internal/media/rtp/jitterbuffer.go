@@ -0,0 +1,235 @@
+package rtp
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// Packets held after the missing one. Wider gap skips the held packets at once
+	jitterBufferSize = 512
+	// Missing packet may be just reordered, it's requested when it's missing for this time
+	reorderWindow = 20 * time.Millisecond
+	// Request of the missing packet is repeated while it's waited
+	nackInterval = 100 * time.Millisecond
+	// Period of the missing packets check
+	jitterBufferTick = 10 * time.Millisecond
+)
+
+type missingPacket struct {
+	since     time.Time
+	requested time.Time
+}
+
+// Pass rtp packets of the track in the sequence order. Packet which comes in order is passed at once. Missing one is requested
+// from the sender and the packets after it are held for the latency. Packet which isn't recovered in time is skipped
+type JitterBuffer struct {
+	latency time.Duration
+	onNack  func(sequenceNumbers []uint16)
+	onLoss  func()
+
+	packets map[uint16]*rtp.Packet
+	missing map[uint16]*missingPacket
+	ready   []*rtp.Packet
+	// Sequence number of the next passed packet
+	next    uint16
+	highest uint16
+	started bool
+	closed  bool
+	now     func() time.Time
+
+	mx   sync.Mutex
+	cond *sync.Cond
+}
+
+func NewJitterBuffer(latency time.Duration) *JitterBuffer {
+	buffer := &JitterBuffer{
+		latency: latency,
+		packets: make(map[uint16]*rtp.Packet),
+		missing: make(map[uint16]*missingPacket),
+		now:     time.Now,
+	}
+	buffer.cond = sync.NewCond(&buffer.mx)
+	return buffer
+}
+
+// Called with the missing sequence numbers to retransmit. Buffer of the sender which doesn't retransmit only reorders
+func (b *JitterBuffer) OnNack(nack func(sequenceNumbers []uint16)) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.onNack = nack
+}
+
+// Called when missing packets are skipped. Frame of them can't be decoded
+func (b *JitterBuffer) OnLoss(loss func()) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.onLoss = loss
+}
+
+func (b *JitterBuffer) Push(packet *rtp.Packet) {
+	b.mx.Lock()
+	lost := b.push(packet, b.now())
+	loss := b.onLoss
+	b.mx.Unlock()
+
+	if lost && loss != nil {
+		loss()
+	}
+}
+
+func (b *JitterBuffer) push(packet *rtp.Packet, now time.Time) (lost bool) {
+	if b.closed {
+		return false
+	}
+
+	seq := packet.SequenceNumber
+	if !b.started {
+		b.started = true
+		b.next = seq
+		b.highest = seq - 1
+	}
+
+	// Packet is already passed or skipped
+	if seq-b.next >= 0x8000 {
+		return false
+	}
+	if _, ok := b.packets[seq]; ok {
+		return false
+	}
+
+	if seq-b.next >= jitterBufferSize {
+		lost = b.flush()
+		b.next = seq
+		b.highest = seq - 1
+	}
+
+	if ahead := seq - b.highest; ahead > 0 && ahead < 0x8000 {
+		for missing := b.highest + 1; missing != seq; missing++ {
+			b.missing[missing] = &missingPacket{since: now}
+		}
+		b.highest = seq
+	}
+
+	delete(b.missing, seq)
+	b.packets[seq] = packet
+	b.drain()
+
+	return lost
+}
+
+// Pass the packets which follow the passed ones
+func (b *JitterBuffer) drain() {
+	passed := false
+	for {
+		packet, ok := b.packets[b.next]
+		if !ok {
+			break
+		}
+		delete(b.packets, b.next)
+		b.ready = append(b.ready, packet)
+		b.next++
+		passed = true
+	}
+
+	if passed {
+		b.cond.Broadcast()
+	}
+}
+
+// Pass all held packets skipping the missing ones
+func (b *JitterBuffer) flush() (lost bool) {
+	for b.next != b.highest+1 {
+		if _, ok := b.missing[b.next]; ok {
+			delete(b.missing, b.next)
+			lost = true
+		}
+		b.next++
+		b.drain()
+	}
+	return lost
+}
+
+// Skip the missing packets waited for the latency and collect the ones to request
+func (b *JitterBuffer) expire(now time.Time) (nack []uint16, lost bool) {
+	for {
+		missing, ok := b.missing[b.next]
+		if !ok || now.Sub(missing.since) < b.latency {
+			break
+		}
+		delete(b.missing, b.next)
+		b.next++
+		b.drain()
+		lost = true
+	}
+
+	if b.onNack == nil {
+		return nil, lost
+	}
+
+	for seq, missing := range b.missing {
+		if now.Sub(missing.since) >= reorderWindow && now.Sub(missing.requested) >= nackInterval {
+			missing.requested = now
+			nack = append(nack, seq)
+		}
+	}
+	sort.Slice(nack, func(i, j int) bool { return nack[i]-b.next < nack[j]-b.next })
+
+	return nack, lost
+}
+
+// Request and skip the missing packets until the buffer is closed
+func (b *JitterBuffer) Run() {
+	ticker := time.NewTicker(jitterBufferTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.mx.Lock()
+		if b.closed {
+			b.mx.Unlock()
+			return
+		}
+		nack, lost := b.expire(b.now())
+		onNack, onLoss := b.onNack, b.onLoss
+		b.mx.Unlock()
+
+		if len(nack) > 0 {
+			onNack(nack)
+		}
+		if lost && onLoss != nil {
+			onLoss()
+		}
+	}
+}
+
+// Next packet in the sequence order. Returns io.EOF when the buffer is closed and passed packets are read
+func (b *JitterBuffer) Read() (*rtp.Packet, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for len(b.ready) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+
+	if len(b.ready) == 0 {
+		return nil, io.EOF
+	}
+
+	packet := b.ready[0]
+	b.ready[0] = nil
+	b.ready = b.ready[1:]
+	return packet, nil
+}
+
+// Held packets are dropped, the passed ones are still read
+func (b *JitterBuffer) Close() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.closed = true
+	b.cond.Broadcast()
+}
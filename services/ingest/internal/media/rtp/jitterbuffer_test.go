@@ -0,0 +1,93 @@
+package rtp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func sequencePacket(seq uint16) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: seq}}
+}
+
+func readSequence(buffer *JitterBuffer) []uint16 {
+	var sequence []uint16
+	for _, packet := range buffer.ready {
+		sequence = append(sequence, packet.SequenceNumber)
+	}
+	buffer.ready = nil
+	return sequence
+}
+
+func TestJitterBuffer_Reorder(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	buffer := NewJitterBuffer(200 * time.Millisecond)
+
+	for _, seq := range []uint16{65534, 65535, 1, 2, 0, 3} {
+		buffer.push(sequencePacket(seq), now)
+	}
+	assert.Equal([]uint16{65534, 65535, 0, 1, 2, 3}, readSequence(buffer))
+
+	// Duplicate and late packets are passed once
+	buffer.push(sequencePacket(2), now)
+	buffer.push(sequencePacket(4), now)
+	assert.Equal([]uint16{4}, readSequence(buffer))
+}
+
+func TestJitterBuffer_Loss(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	buffer := NewJitterBuffer(200 * time.Millisecond)
+
+	// Sender retransmits
+	buffer.OnNack(func([]uint16) {})
+
+	for _, seq := range []uint16{10, 11, 14, 15} {
+		buffer.push(sequencePacket(seq), now)
+	}
+	assert.Equal([]uint16{10, 11}, readSequence(buffer))
+
+	// Missing packet may be reordered, it's requested after the reorder window
+	nack, lost := buffer.expire(now)
+	assert.Empty(nack)
+	assert.False(lost)
+
+	now = now.Add(reorderWindow)
+	nack, _ = buffer.expire(now)
+	assert.Equal([]uint16{12, 13}, nack)
+
+	// Retransmitted packet is passed, the one which isn't recovered in time is skipped
+	buffer.push(sequencePacket(12), now)
+	assert.Equal([]uint16{12}, readSequence(buffer))
+
+	now = now.Add(nackInterval)
+	nack, lost = buffer.expire(now)
+	assert.Equal([]uint16{13}, nack)
+	assert.False(lost)
+
+	now = now.Add(200 * time.Millisecond)
+	_, lost = buffer.expire(now)
+	assert.True(lost)
+	assert.Equal([]uint16{14, 15}, readSequence(buffer))
+}
+
+func TestJitterBuffer_Jump(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	buffer := NewJitterBuffer(200 * time.Millisecond)
+
+	buffer.push(sequencePacket(100), now)
+	buffer.push(sequencePacket(102), now)
+	assert.Equal([]uint16{100}, readSequence(buffer))
+
+	// Gap wider than the buffer passes the held packets at once
+	assert.True(buffer.push(sequencePacket(102+jitterBufferSize), now))
+	assert.Equal([]uint16{102, 102 + jitterBufferSize}, readSequence(buffer))
+	assert.Empty(buffer.missing)
+}
@@ -0,0 +1,215 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	rridExtensionURI = "urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id"
+
+	rtxMimeType = "video/rtx"
+	// Restored packets waiting the jitter buffer of the stream
+	repairQueueSize = 256
+)
+
+type repairStreamKey struct{}
+
+// Media stream which takes the retransmissions of its rtx stream
+type RepairStream struct {
+	interceptor *RepairInterceptor
+	ssrc        uint32
+	payloadType uint8
+	rid         string
+	packets     chan *rtp.Packet
+}
+
+// Rtx packets of the payload type restore the stream. Simulcast layer is restored by rtx of its rid
+func (s *RepairStream) Expect(payloadType uint8, rid string) {
+	s.interceptor.mx.Lock()
+	defer s.interceptor.mx.Unlock()
+
+	s.rid = rid
+	s.interceptor.payloadTypes[payloadType] = s.payloadType
+}
+
+// Restored packets of the stream. Packets are dropped when they aren't read
+func (s *RepairStream) Packets() <-chan *rtp.Packet {
+	return s.packets
+}
+
+// Repair stream of the track which packet is read with the attributes
+func RepairStreamOf(attributes interceptor.Attributes) *RepairStream {
+	if attributes == nil {
+		return nil
+	}
+	stream, _ := attributes.Get(repairStreamKey{}).(*RepairStream)
+	return stream
+}
+
+// Rtx payload type of the media payload type negotiated by the receiver
+func RepairPayloadType(codecs []webrtc.RTPCodecParameters, payloadType webrtc.PayloadType) (uint8, bool) {
+	apt := fmt.Sprintf("apt=%d", payloadType)
+	for _, codec := range codecs {
+		if !strings.EqualFold(codec.MimeType, rtxMimeType) {
+			continue
+		}
+		for _, param := range strings.Split(codec.SDPFmtpLine, ";") {
+			if strings.TrimSpace(param) == apt {
+				return uint8(codec.PayloadType), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Unwrap rtx retransmissions of the publisher into the packets of the media stream. Pion reads rtx streams, but only for the
+// congestion control. Media stream is attached to the attributes of its packets, so the track reader finds its retransmissions
+type RepairInterceptor struct {
+	interceptor.NoOp
+
+	// Rtx payload type to the payload type of its media
+	payloadTypes map[uint8]uint8
+	streams      map[uint32]*RepairStream
+
+	mx sync.Mutex
+}
+
+func (i *RepairInterceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	var rridID uint8
+	for _, extension := range info.RTPHeaderExtensions {
+		if extension.URI == rridExtensionURI {
+			rridID = uint8(extension.ID)
+		}
+	}
+
+	// Rtx stream keeps the media stream of its first packet, rrid extension isn't sent for long
+	var repaired *RepairStream
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, a, err := reader.Read(b, a)
+		if err != nil {
+			return n, a, err
+		}
+		if a == nil {
+			a = interceptor.Attributes{}
+		}
+
+		header, err := a.GetRTPHeader(b[:n])
+		if err != nil {
+			return n, a, nil
+		}
+
+		if apt, ok := i.mediaPayloadType(header.PayloadType); ok {
+			if repaired == nil {
+				var rid string
+				if rridID != 0 {
+					rid = string(header.GetExtension(rridID))
+				}
+				repaired = i.repairedStream(apt, rid)
+			}
+			if repaired != nil {
+				repaired.restore(b[:n], apt)
+			}
+			return n, a, nil
+		}
+
+		a.Set(repairStreamKey{}, i.stream(header))
+		return n, a, nil
+	})
+}
+
+func (i *RepairInterceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+	delete(i.streams, info.SSRC)
+}
+
+func (i *RepairInterceptor) mediaPayloadType(payloadType uint8) (uint8, bool) {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+	apt, ok := i.payloadTypes[payloadType]
+	return apt, ok
+}
+
+func (i *RepairInterceptor) stream(header *rtp.Header) *RepairStream {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	stream, ok := i.streams[header.SSRC]
+	if !ok {
+		stream = &RepairStream{
+			interceptor: i,
+			ssrc:        header.SSRC,
+			payloadType: header.PayloadType,
+			packets:     make(chan *rtp.Packet, repairQueueSize),
+		}
+		i.streams[header.SSRC] = stream
+	}
+	return stream
+}
+
+// Media stream of the rtx. Without rid it's the only stream of the media payload type
+func (i *RepairInterceptor) repairedStream(payloadType uint8, rid string) *RepairStream {
+	i.mx.Lock()
+	defer i.mx.Unlock()
+
+	var repaired *RepairStream
+	for _, stream := range i.streams {
+		if stream.payloadType != payloadType || (rid != "" && stream.rid != rid) {
+			continue
+		}
+		if repaired != nil {
+			return nil
+		}
+		repaired = stream
+	}
+	return repaired
+}
+
+// Rtx payload starts with the original sequence number. Padding only packets probe the bandwidth and restore nothing
+func (s *RepairStream) restore(b []byte, payloadType uint8) {
+	rtx := &rtp.Packet{}
+	if err := rtx.Unmarshal(b); err != nil || len(rtx.Payload) < 2 {
+		return
+	}
+
+	payload := make([]byte, len(rtx.Payload)-2)
+	copy(payload, rtx.Payload[2:])
+
+	packet := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtx.Version,
+			Marker:         rtx.Marker,
+			PayloadType:    payloadType,
+			SequenceNumber: binary.BigEndian.Uint16(rtx.Payload[:2]),
+			Timestamp:      rtx.Timestamp,
+			SSRC:           s.ssrc,
+		},
+		Payload: payload,
+	}
+
+	select {
+	case s.packets <- packet:
+	default:
+	}
+}
+
+type RepairInterceptorFactory struct{}
+
+func (f *RepairInterceptorFactory) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return &RepairInterceptor{
+		payloadTypes: make(map[uint8]uint8),
+		streams:      make(map[uint32]*RepairStream),
+	}, nil
+}
+
+func NewRepairInterceptorFactory() *RepairInterceptorFactory {
+	return &RepairInterceptorFactory{}
+}
@@ -0,0 +1,17 @@
+package rtp
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+// Frame which misses a packet is dropped when the next frames span this time
+const sampleBuilderMaxDelay = 100 * time.Millisecond
+
+// Sample builder of the packets passed by the jitter buffer. Packets come in order, so the window spans the largest keyframe
+// and adds no latency. Frame broken by a skipped packet is dropped by time instead of waiting the whole window
+func NewSampleBuilder(depacketizer rtp.Depacketizer, sampleRate uint32) *samplebuilder.SampleBuilder {
+	return samplebuilder.New(jitterBufferSize, depacketizer, sampleRate, samplebuilder.WithMaxTimeDelay(sampleBuilderMaxDelay))
+}
@@ -0,0 +1,25 @@
+package rtp
+
+import (
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+)
+
+// Rtcp of the remote track. Simulcast layer is read by own rid
+type RTCPReader func() ([]rtcp.Packet, interceptor.Attributes, error)
+
+// Pass sender reports of the remote track until its receiver is stopped
+func ReadSenderReports(read RTCPReader, report func(*rtcp.SenderReport)) {
+	for {
+		packets, _, err := read()
+		if err != nil {
+			return
+		}
+
+		for _, packet := range packets {
+			if sr, ok := packet.(*rtcp.SenderReport); ok {
+				report(sr)
+			}
+		}
+	}
+}
@@ -2,11 +2,14 @@ package vp8
 
 import (
 	"errors"
+	"log"
 	"sync"
 	"time"
 
+	"github.com/at-wat/ebml-go/mkvcore"
 	"github.com/at-wat/ebml-go/webm"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
 
 	"io"
 
@@ -75,7 +78,7 @@ func (w *RtpToWebmVP8MuxWriter) Write(p []byte) (n int, err error) {
 					PixelHeight: uint64(height),
 				},
 			},
-		})
+		}, mkvcore.WithOnFatalHandler(onFatal))
 		w.webmBuilder = ws[0]
 
 	}
@@ -88,7 +91,7 @@ var _ media.MuxerWriter = (*RtpToWebmVP8MuxWriter)(nil)
 
 func NewRtpToWebmVP8Writer() *RtpToWebmVP8MuxWriter {
 	rtpToWebmVP8writer := &RtpToWebmVP8MuxWriter{}
-	rtpToWebmVP8writer.vp8builder = samplebuilder.New(10, &codecs.VP8Packet{}, 90000)
+	rtpToWebmVP8writer.vp8builder = mediartp.NewSampleBuilder(&codecs.VP8Packet{}, 90000)
 
 	r, w := io.Pipe()
 	rtpToWebmVP8writer.buff = media.NewBufioWriterCloser(w)
@@ -96,3 +99,8 @@ func NewRtpToWebmVP8Writer() *RtpToWebmVP8MuxWriter {
 	rtpToWebmVP8writer.reader = r
 	return rtpToWebmVP8writer
 }
+
+// Writes fail once the muxer reader is closed, so they are logged instead of the default panic
+func onFatal(err error) {
+	log.Printf("[VP8 Muxer] unable write webm. Err: %s", err)
+}
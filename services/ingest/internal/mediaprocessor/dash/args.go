@@ -0,0 +1,73 @@
+package dash
+
+import (
+	"fmt"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+)
+
+const (
+	ManifestFile = "manifest.mpd"
+
+	// Segments kept in the manifest and removed ones which are kept on disk for late players
+	windowSize      = 8
+	extraWindowSize = 2
+)
+
+// Ffmpeg args of the DASH output with CMAF segments. Video renditions of the ladder share one adaptation set,
+// audio is transcoded once with the highest bitrate of the ladder
+func ffmpegArgs(ladder hls.Ladder, audio, manifestFile string) []string {
+	var args []string
+
+	filter, labels := ladder.VideoFilter()
+	if filter != "" {
+		args = append(args, "-filter_complex", filter)
+	}
+
+	var audioBitrate int32
+	videoIdx := 0
+	for i, rendition := range ladder {
+		if rendition.AudioBitrate > audioBitrate {
+			audioBitrate = rendition.AudioBitrate
+		}
+
+		label, ok := labels[i]
+		if !ok {
+			continue
+		}
+		args = append(args,
+			"-map", label,
+			fmt.Sprintf("-b:v:%d", videoIdx), fmt.Sprintf("%dk", rendition.VideoBitrate),
+			fmt.Sprintf("-maxrate:v:%d", videoIdx), fmt.Sprintf("%dk", rendition.VideoBitrate),
+			fmt.Sprintf("-bufsize:v:%d", videoIdx), fmt.Sprintf("%dk", rendition.VideoBitrate*3/4),
+		)
+		videoIdx++
+	}
+
+	adaptationSets := "id=0,streams=a"
+	if videoIdx > 0 {
+		args = append(args, hls.VideoCodecArgs(hls.KeyframeInterval)...)
+		adaptationSets = "id=0,streams=v id=1,streams=a"
+	}
+
+	args = append(args,
+		"-map", audio,
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", audioBitrate),
+		"-muxdelay", "0",
+		"-f", "dash",
+		"-seg_duration", fmt.Sprint(hls.KeyframeInterval),
+		"-use_template", "1",
+		"-use_timeline", "1",
+		"-window_size", fmt.Sprint(windowSize),
+		"-extra_window_size", fmt.Sprint(extraWindowSize),
+		"-streaming", "1",
+		"-dash_segment_type", "mp4",
+		"-adaptation_sets", adaptationSets,
+		"-init_seg_name", "init_$RepresentationID$.m4s",
+		"-media_seg_name", "chunk_$RepresentationID$_$Number$.m4s",
+		manifestFile,
+	)
+
+	return args
+}
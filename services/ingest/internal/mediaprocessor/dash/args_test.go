@@ -0,0 +1,35 @@
+package dash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFfmpegArgs(t *testing.T) {
+	ladder, err := hls.ParseLadder(`[
+		{"name":"720p","height":720,"videoBitrate":2800,"audioBitrate":128},
+		{"name":"360p","height":360,"videoBitrate":800,"audioBitrate":64},
+		{"name":"audio","audioBitrate":96}
+	]`)
+	assert.Nil(t, err)
+
+	args := strings.Join(ffmpegArgs(ladder, "1:a", "out/manifest.mpd"), " ")
+
+	assert.Contains(t, args, "-filter_complex [0:v]split=2[v0][v1];[v0]scale=-2:720[v0out];[v1]scale=-2:360[v1out]")
+	assert.Contains(t, args, "-map [v0out] -b:v:0 2800k")
+	assert.Contains(t, args, "-map [v1out] -b:v:1 800k")
+	assert.Contains(t, args, "-map 1:a -c:a aac -b:a 128k")
+	assert.Contains(t, args, "-adaptation_sets id=0,streams=v id=1,streams=a")
+	assert.True(t, strings.HasSuffix(args, "out/manifest.mpd"))
+
+	audioOnly, err := hls.ParseLadder(`[{"name":"audio","audioBitrate":96}]`)
+	assert.Nil(t, err)
+
+	args = strings.Join(ffmpegArgs(audioOnly, "1:a", "out/manifest.mpd"), " ")
+	assert.NotContains(t, args, "-filter_complex")
+	assert.NotContains(t, args, "libx264")
+	assert.Contains(t, args, "-adaptation_sets id=0,streams=a")
+}
@@ -0,0 +1,137 @@
+package dash
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/namedpipe"
+	"go.uber.org/fx"
+)
+
+var InvalidFileError = errors.New("invalid dash file")
+
+// Transcode the stream by the HLS ladder into MPEG-DASH manifest with CMAF segments
+type FFmpegDASHMediaProcessor struct {
+	SourceDirectory string
+	Ladder          hls.Ladder
+	audioNamedPipe  *namedpipe.NamedPipe
+}
+
+// Resolve manifest or segment of the stream
+func (processor *FFmpegDASHMediaProcessor) File(file string) (string, error) {
+	if file == "" || file != filepath.Base(file) || file == "." || file == ".." {
+		return "", InvalidFileError
+	}
+
+	if processor.SourceDirectory == "" {
+		return "", os.ErrNotExist
+	}
+
+	return filepath.Join(processor.SourceDirectory, file), nil
+}
+
+func (processor *FFmpegDASHMediaProcessor) Transcode(ctx context.Context, videoSourcePipe *io.PipeReader, audioSourcePipe *io.PipeReader) error {
+	defer processor.Destroy()
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("%s-*", uuid.New()))
+	if err != nil {
+		log.Println("[DASH Processor] Cannot create temp dir. Err:", err)
+		return err
+	}
+	processor.SourceDirectory = dir
+
+	log.Println("[DASH Processor] Setup output directory to", processor.SourceDirectory)
+
+	input, err := hls.NewInput(videoSourcePipe)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"-fflags", "nobuffer+genpts",
+		"-threads", "0",
+		"-re",
+	}
+	args = append(args, input.Args()...)
+	args = append(args, "-loglevel", "info")
+	args = append(args, ffmpegArgs(processor.Ladder, input.Audio(), filepath.Join(processor.SourceDirectory, ManifestFile))...)
+
+	ffmpeg := exec.Command("ffmpeg", args...)
+	ffmpeg.Stdin = input.Video
+
+	audioPipe, audioFiles, err := input.OpenAudio(audioSourcePipe)
+	if err != nil {
+		log.Println("[DASH Processor] Cannot open audio pipe. Err:", err)
+		return err
+	}
+	processor.audioNamedPipe = audioPipe
+	ffmpeg.ExtraFiles = audioFiles
+
+	stderr, err := ffmpeg.StderrPipe()
+	if err != nil {
+		log.Println("[DASH Processor] Cannot open stderr. Err:", err)
+		return err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Println("[DASH]", scanner.Text())
+		}
+	}()
+
+	if err := ffmpeg.Start(); err != nil {
+		log.Println("[DASH Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Println("[DASH Processor] Stop by context")
+			_ = ffmpeg.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	if err := ffmpeg.Wait(); err != nil {
+		log.Println("[DASH Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (processor *FFmpegDASHMediaProcessor) Destroy() {
+	if processor.SourceDirectory != "" {
+		log.Println("[DASH Processor] Removing", processor.SourceDirectory)
+		os.RemoveAll(processor.SourceDirectory)
+	}
+	if processor.audioNamedPipe != nil {
+		processor.audioNamedPipe.Close()
+	}
+}
+
+type FFmpegDASHMediaProcessorParams struct {
+	fx.In
+
+	HLSConfig *hls.Config
+}
+
+func NewFFmpegDASHMediaProcessor(params FFmpegDASHMediaProcessorParams) *FFmpegDASHMediaProcessor {
+	return &FFmpegDASHMediaProcessor{
+		Ladder: params.HLSConfig.Ladder,
+	}
+}
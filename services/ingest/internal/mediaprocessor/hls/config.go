@@ -0,0 +1,61 @@
+package hls
+
+import (
+	"log"
+	"time"
+
+	"github.com/romashorodok/stream-platform/pkg/envutils"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+)
+
+// HLS output of the ingest streams
+type Config struct {
+	Ladder Ladder
+	// Serve LL-HLS with parts and blocking playlist reload instead of MPEG-TS segments
+	LowLatency bool
+	// Pack H264 source without transcoding. Ladder is used only for other codecs
+	Passthrough bool
+	// Segments of the window are kept on disk, so viewers may rewind the live stream. Zero keeps only the live edge
+	DVRWindow time.Duration
+}
+
+func NewConfig() *Config {
+	rawLadder := envutils.Env(variables.INGEST_HLS_LADDER, variables.INGEST_HLS_LADDER_DEFAULT)
+	ladder, err := ParseLadder(rawLadder)
+	if err != nil {
+		log.Printf("[ERROR] wrong hls ladder %s. Err: %s. Fallback to %s", rawLadder, err, variables.INGEST_HLS_LADDER_DEFAULT)
+		ladder, _ = ParseLadder(variables.INGEST_HLS_LADDER_DEFAULT)
+	}
+
+	rawLowLatency := envutils.Env(variables.INGEST_HLS_LOW_LATENCY, variables.INGEST_HLS_LOW_LATENCY_DEFAULT)
+	lowLatency, err := envutils.ParseBool(rawLowLatency)
+	if err != nil {
+		log.Printf("[ERROR] wrong hls low latency %s. Fallback to %s", rawLowLatency, variables.INGEST_HLS_LOW_LATENCY_DEFAULT)
+		lowLatency, _ = envutils.ParseBool(variables.INGEST_HLS_LOW_LATENCY_DEFAULT)
+	}
+
+	rawPassthrough := envutils.Env(variables.INGEST_HLS_PASSTHROUGH, variables.INGEST_HLS_PASSTHROUGH_DEFAULT)
+	passthrough, err := envutils.ParseBool(rawPassthrough)
+	if err != nil {
+		log.Printf("[ERROR] wrong hls passthrough %s. Fallback to %s", rawPassthrough, variables.INGEST_HLS_PASSTHROUGH_DEFAULT)
+		passthrough, _ = envutils.ParseBool(variables.INGEST_HLS_PASSTHROUGH_DEFAULT)
+	}
+
+	rawDVRWindow := envutils.Env(variables.INGEST_HLS_DVR_WINDOW, variables.INGEST_HLS_DVR_WINDOW_DEFAULT)
+	dvrWindow, err := time.ParseDuration(rawDVRWindow)
+	if err != nil || dvrWindow < 0 {
+		log.Printf("[ERROR] wrong hls dvr window %s. Fallback to %s", rawDVRWindow, variables.INGEST_HLS_DVR_WINDOW_DEFAULT)
+		dvrWindow, _ = time.ParseDuration(variables.INGEST_HLS_DVR_WINDOW_DEFAULT)
+	}
+	if dvrWindow > 0 && *lowLatency {
+		log.Printf("[ERROR] hls dvr window is not supported by low latency. Fallback to %s", variables.INGEST_HLS_DVR_WINDOW_DEFAULT)
+		dvrWindow, _ = time.ParseDuration(variables.INGEST_HLS_DVR_WINDOW_DEFAULT)
+	}
+
+	return &Config{
+		Ladder:      ladder,
+		LowLatency:  *lowLatency,
+		Passthrough: *passthrough,
+		DVRWindow:   dvrWindow,
+	}
+}
@@ -0,0 +1,66 @@
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var InvalidStartOffsetError = errors.New("invalid hls start offset")
+
+// Segments of the playlist which cover the dvr window. Disabled window keeps only the live edge
+func playlistSize(window, segmentDuration time.Duration) int {
+	if window <= 0 || segmentDuration <= 0 {
+		return segmentPlaylistSize
+	}
+
+	size := int(math.Ceil(float64(window) / float64(segmentDuration)))
+	if size < segmentPlaylistSize {
+		return segmentPlaylistSize
+	}
+	return size
+}
+
+// Start offset in seconds. Negative offset is counted back from the live edge, positive from the oldest segment
+func ParseStartOffset(raw string) (float64, error) {
+	offset, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(offset) || math.IsInf(offset, 0) {
+		return 0, fmt.Errorf("%w %s. Must be seconds", InvalidStartOffsetError, raw)
+	}
+	return offset, nil
+}
+
+// Tell the player where to start the playback of the window. Offset is passed to the variant playlists
+// of the master playlist, because players take the start only from the playlist they play
+func WithStartOffset(playlist []byte, offset float64) []byte {
+	var result bytes.Buffer
+
+	value := strconv.FormatFloat(offset, 'f', -1, 64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-START:"):
+			// Replaced by the requested offset
+			continue
+		case line == "#EXTM3U":
+			result.WriteString(line)
+			result.WriteString(fmt.Sprintf("\n#EXT-X-START:TIME-OFFSET=%s,PRECISE=YES\n", value))
+			continue
+		case line != "" && !strings.HasPrefix(line, "#") && strings.HasSuffix(line, ".m3u8"):
+			line = fmt.Sprintf("%s?start=%s", line, value)
+		}
+
+		result.WriteString(line)
+		result.WriteString("\n")
+	}
+
+	return result.Bytes()
+}
@@ -0,0 +1,84 @@
+package hls
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaylistSize(t *testing.T) {
+	assert.Equal(t, segmentPlaylistSize, playlistSize(0, 4*time.Second))
+	assert.Equal(t, segmentPlaylistSize, playlistSize(10*time.Second, 4*time.Second))
+	assert.Equal(t, 1800, playlistSize(2*time.Hour, 4*time.Second))
+	assert.Equal(t, 16, playlistSize(62*time.Second, 4*time.Second))
+}
+
+func TestParseStartOffset(t *testing.T) {
+	offset, err := ParseStartOffset("-600")
+	assert.Nil(t, err)
+	assert.Equal(t, -600.0, offset)
+
+	offset, err = ParseStartOffset("12.5")
+	assert.Nil(t, err)
+	assert.Equal(t, 12.5, offset)
+
+	_, err = ParseStartOffset("10m")
+	assert.ErrorIs(t, err, InvalidStartOffsetError)
+	_, err = ParseStartOffset("NaN")
+	assert.ErrorIs(t, err, InvalidStartOffsetError)
+}
+
+func TestWithStartOffset(t *testing.T) {
+	master := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2128000\n" +
+		"stream/source/index.m3u8\n"
+
+	assert.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-START:TIME-OFFSET=-600,PRECISE=YES\n"+
+		"#EXT-X-VERSION:3\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=2128000\n"+
+		"stream/source/index.m3u8?start=-600\n", string(WithStartOffset([]byte(master), -600)))
+
+	variant := "#EXTM3U\n" +
+		"#EXT-X-START:TIME-OFFSET=-30\n" +
+		"#EXT-X-TARGETDURATION:4\n" +
+		"#EXTINF:4.000000,\n" +
+		"segment_10.ts\n"
+
+	assert.Equal(t, "#EXTM3U\n"+
+		"#EXT-X-START:TIME-OFFSET=90.5,PRECISE=YES\n"+
+		"#EXT-X-TARGETDURATION:4\n"+
+		"#EXTINF:4.000000,\n"+
+		"segment_10.ts\n", string(WithStartOffset([]byte(variant), 90.5)))
+}
+
+func TestSegmentPlaylist_DVRWindow(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	playlist := newSegmentPlaylist(dir, 60*time.Second)
+	playlist.SetInit([]byte("init"))
+	for i := 0; i < 30; i++ {
+		playlist.AddPart(&fmp4.Fragment{Data: []byte("segment"), Duration: 4 * time.Second, Independent: true})
+	}
+
+	// 15 segments cover the window
+	assert.Len(playlist.segments, 15)
+	assert.Equal(60*time.Second, playlist.duration)
+
+	rendered, err := os.ReadFile(filepath.Join(dir, variantPlaylistFile))
+	assert.Nil(err)
+	assert.Contains(string(rendered), "#EXT-X-MEDIA-SEQUENCE:15\n")
+	assert.Equal(15, strings.Count(string(rendered), "#EXTINF"))
+
+	_, err = os.Stat(filepath.Join(dir, "segment_13.m4s"))
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "segment_14.m4s"))
+	assert.Nil(err)
+}
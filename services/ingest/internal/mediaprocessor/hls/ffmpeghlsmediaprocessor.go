@@ -3,22 +3,162 @@ package hls
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/mpegts"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
 	"github.com/romashorodok/stream-platform/services/ingest/pkg/namedpipe"
 	"go.uber.org/fx"
 )
 
+const (
+	masterPlaylistFile  = "master.m3u8"
+	variantPlaylistFile = "index.m3u8"
+)
+
+var InvalidRenditionFileError = errors.New("invalid hls rendition file")
+
 type FFmpegHLSMediaProcessor struct {
-	SourceDirectory  string
-	ManifestFile     string
-	SegmentPrefixURL string
-	audioNamedPipe   *namedpipe.NamedPipe
+	SourceDirectory string
+	ManifestFile    string
+	// Master playlist route refer to the variant playlists with this prefix
+	PlaylistPrefixURL string
+	Ladder            Ladder
+	LowLatency        bool
+	DVRWindow         time.Duration
+	audioNamedPipe    *namedpipe.NamedPipe
+
+	lowLatencyPlaylists map[string]*LowLatencyPlaylist
+
+	wallClock *mediartp.Clock
+	// Segments of the matroska input have pts of the stream timeline
+	timeline bool
+	// Rendition directory to wall clock of its listed segments
+	segmentTimes map[string]map[string]time.Time
+
+	mx sync.Mutex
+}
+
+func (processor *FFmpegHLSMediaProcessor) SetWallClock(clock *mediartp.Clock) {
+	processor.mx.Lock()
+	defer processor.mx.Unlock()
+	processor.wallClock = clock
+}
+
+func (processor *FFmpegHLSMediaProcessor) MasterPlaylist() string {
+	return processor.ManifestFile
+}
+
+func (processor *FFmpegHLSMediaProcessor) IsLowLatency() bool {
+	return processor.LowLatency
+}
+
+func (processor *FFmpegHLSMediaProcessor) LowLatencyPlaylist(rendition string) (*LowLatencyPlaylist, error) {
+	playlist, ok := processor.lowLatencyPlaylists[rendition]
+	if !ok {
+		return nil, RenditionNotFoundError
+	}
+	return playlist, nil
+}
+
+// Split fragmented mp4 output of the rendition into LL-HLS parts
+func (processor *FFmpegHLSMediaProcessor) packLowLatency(rendition string, output *os.File) {
+	defer output.Close()
+
+	playlist := processor.lowLatencyPlaylists[rendition]
+	reader := fmp4.NewReader(output)
+
+	init, err := reader.ReadInit()
+	if err != nil {
+		log.Printf("[HLS Proceessor] unable read %s init segment. Err: %s", rendition, err)
+		return
+	}
+	playlist.SetInit(init.Data)
+
+	for {
+		fragment, err := reader.ReadFragment()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("[HLS Proceessor] unable read %s fragment. Err: %s", rendition, err)
+			}
+			return
+		}
+		playlist.AddPart(fragment)
+	}
+}
+
+// Variant playlist of the rendition file. Segments are tagged with the wall clock of their pts when input is on the stream timeline
+func (processor *FFmpegHLSMediaProcessor) Playlist(file string) ([]byte, error) {
+	playlist, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	processor.mx.Lock()
+	defer processor.mx.Unlock()
+
+	if !processor.timeline || processor.wallClock == nil {
+		return playlist, nil
+	}
+
+	dir := filepath.Dir(file)
+	known := processor.segmentTimes[dir]
+	times := make(map[string]time.Time, len(known))
+
+	playlist = withProgramDateTime(playlist, func(segment string) (time.Time, bool) {
+		programDateTime, ok := known[segment]
+		if !ok {
+			programDateTime, ok = processor.segmentTime(filepath.Join(dir, segment))
+		}
+		if ok {
+			times[segment] = programDateTime
+		}
+		return programDateTime, ok
+	})
+
+	// Removed segments are forgotten
+	processor.segmentTimes[dir] = times
+	return playlist, nil
+}
+
+// Wall clock of the first pts of the segment. Must be called with the lock
+func (processor *FFmpegHLSMediaProcessor) segmentTime(file string) (time.Time, bool) {
+	segment, err := os.Open(file)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer segment.Close()
+
+	pts, err := mpegts.ReadFirstPTS(segment)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	position := timelinePosition(pts, processor.wallClock.Elapsed())
+	return processor.wallClock.WallTime(position), true
+}
+
+// Resolve variant playlist or segment of the rendition
+func (processor *FFmpegHLSMediaProcessor) RenditionFile(rendition, file string) (string, error) {
+	if _, err := processor.Ladder.Get(rendition); err != nil {
+		return "", err
+	}
+
+	if file == "" || file != filepath.Base(file) || file == "." || file == ".." {
+		return "", InvalidRenditionFileError
+	}
+
+	return filepath.Join(processor.SourceDirectory, rendition, file), nil
 }
 
 func (processor *FFmpegHLSMediaProcessor) Transcode(ctx context.Context, videoSourcePipe *io.PipeReader, audioSourcePipe *io.PipeReader) (err error) {
@@ -30,52 +170,66 @@ func (processor *FFmpegHLSMediaProcessor) Transcode(ctx context.Context, videoSo
 		log.Println("[HLS Proceessor] Cannot create temp dir")
 	}
 	processor.SourceDirectory = dir
-	processor.ManifestFile = fmt.Sprintf("%s/%s.m3u8",
-		processor.SourceDirectory,
-		uuid.NewString(),
-	)
-	processor.SegmentPrefixURL = "hls/"
+
+	for _, rendition := range processor.Ladder {
+		if err := os.MkdirAll(filepath.Join(processor.SourceDirectory, rendition.Name), 0o755); err != nil {
+			log.Println("[HLS Proceessor] Cannot create rendition dir. Err:", err)
+			return err
+		}
+	}
+
+	manifestFile := filepath.Join(processor.SourceDirectory, masterPlaylistFile)
+	audioCodec := "opus"
+	if processor.LowLatency {
+		audioCodec = "mp4a.40.2"
+	}
+
+	if err := os.WriteFile(manifestFile, []byte(processor.Ladder.MasterPlaylist(processor.PlaylistPrefixURL, audioCodec)), 0o644); err != nil {
+		log.Println("[HLS Proceessor] Cannot write master playlist. Err:", err)
+		return err
+	}
+	processor.ManifestFile = manifestFile
 
 	log.Println("[HLS Proceessor] Setup output directory to", processor.SourceDirectory)
 
-	ffmpeg := exec.Command("ffmpeg",
+	input, err := NewInput(videoSourcePipe)
+	if err != nil {
+		return err
+	}
+
+	processor.mx.Lock()
+	processor.timeline = input.Muxed
+	processor.mx.Unlock()
+
+	args := []string{
 		"-fflags", "nobuffer+genpts",
 		"-threads", "0",
 		"-re",
-		"-i", "pipe:0",
-		"-i", "pipe:3",
-		"-loglevel", "info",
-		"-c:v", "libx264",
-		"-preset", "ultrafast",
-		"-tune", "zerolatency",
-		"-crf", "30",
-		"-maxrate", "2000k",
-		"-bufsize", "1500k",
-		"-pix_fmt", "yuv420p",
-		"-c:a", "libopus",
-		"-err_detect", "ignore_err",
-		"-muxdelay", "0",
-		"-map_metadata", "0",
-		"-copyts",
-		"-copytb", "0",
-		"-strftime", "1",
-		"-f", "hls",
-		"-hls_time", "4",
-		"-hls_list_size", "8",
-		"-hls_flags", "delete_segments+independent_segments",
-		"-hls_start_number_source", "datetime",
-		"-hls_allow_cache", "0",
-		"-hls_base_url", processor.SegmentPrefixURL,
-		"-hls_segment_filename", "output_%03d.ts",
-		"-hls_segment_filename", fmt.Sprintf("%s/%s", processor.SourceDirectory, "%Y-%m-%d-%s.ts"),
-		processor.ManifestFile,
-	)
-
-	ffmpeg.Stdin = videoSourcePipe
-
-	audioPipe, err := namedpipe.NewNamedPipe()
+	}
+	args = append(args, input.Args()...)
+	args = append(args, "-loglevel", "info")
+	if processor.LowLatency {
+		// Low latency outputs follow the audio pipe
+		args = append(args, processor.Ladder.lowLatencyArgs(input.NextFD(), input.Audio())...)
+	} else {
+		args = append(args, processor.Ladder.ffmpegArgs(
+			input.Audio(),
+			filepath.Join(processor.SourceDirectory, "%v", variantPlaylistFile),
+			filepath.Join(processor.SourceDirectory, "%v", "segment_%d.ts"),
+			processor.DVRWindow,
+		)...)
+	}
+
+	ffmpeg := exec.Command("ffmpeg", args...)
+
+	ffmpeg.Stdin = input.Video
+
+	audioPipe, audioFiles, err := input.OpenAudio(audioSourcePipe)
+	if err != nil {
+		log.Println("[HLS Proceessor] Cannot open audio pipe. Err:", err)
+		return err
+	}
 	processor.audioNamedPipe = audioPipe
-	audioPipeFile, err := audioPipe.OpenAsWriteOnly()
 
 	videoStderr, err := ffmpeg.StderrPipe()
 
@@ -83,28 +237,56 @@ func (processor *FFmpegHLSMediaProcessor) Transcode(ctx context.Context, videoSo
 		log.Println("Failed to open pipes. Err", err)
 	}
 
-	ffmpeg.ExtraFiles = []*os.File{audioPipeFile}
+	ffmpeg.ExtraFiles = audioFiles
+
+	var lowLatencyOutputs []*os.File
+	if processor.LowLatency {
+		for range processor.Ladder {
+			output, input, err := os.Pipe()
+			if err != nil {
+				log.Println("[HLS Proceessor] Cannot create low latency pipe. Err:", err)
+				return err
+			}
+			lowLatencyOutputs = append(lowLatencyOutputs, output)
+			ffmpeg.ExtraFiles = append(ffmpeg.ExtraFiles, input)
+		}
+	}
 
 	go func() {
-		io.Copy(audioPipeFile, audioSourcePipe)
+		scanner := bufio.NewScanner(videoStderr)
+		for scanner.Scan() {
+			log.Println("[HLS]", scanner.Text())
+		}
 	}()
 
+	if err := ffmpeg.Start(); err != nil {
+		log.Println("Error when running ffmpeg. Err:", err)
+		for i, output := range lowLatencyOutputs {
+			_ = output.Close()
+			_ = ffmpeg.ExtraFiles[len(audioFiles)+i].Close()
+		}
+		return err
+	}
+
+	// Ffmpeg owns write ends of the outputs. Packing stops on EOF when ffmpeg exits
+	for i, output := range lowLatencyOutputs {
+		_ = ffmpeg.ExtraFiles[len(audioFiles)+i].Close()
+		go processor.packLowLatency(processor.Ladder[i].Name, output)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
 	go func() {
 		select {
 		case <-ctx.Done():
 			log.Println("[HLS Proceessor] Stop by context")
 			_ = ffmpeg.Process.Kill()
+		case <-done:
 		}
 	}()
 
-	go func() {
-		scanner := bufio.NewScanner(videoStderr)
-		for scanner.Scan() {
-			log.Println("[HLS]", scanner.Text())
-		}
-	}()
-
-	if err := ffmpeg.Run(); err != nil {
+	if err := ffmpeg.Wait(); err != nil {
 		log.Println("Error when running ffmpeg. Err:", err)
 		return err
 	}
@@ -118,12 +300,32 @@ func (processor *FFmpegHLSMediaProcessor) Destroy() {
 	if processor.audioNamedPipe != nil {
 		processor.audioNamedPipe.Close()
 	}
+	for _, playlist := range processor.lowLatencyPlaylists {
+		playlist.Close()
+	}
 }
 
 type FFmpegHLSMediaProcessorParams struct {
 	fx.In
+
+	Config *Config
 }
 
 func NewFFmpegHLSMediaProcessor(params FFmpegHLSMediaProcessorParams) *FFmpegHLSMediaProcessor {
-	return &FFmpegHLSMediaProcessor{}
+	processor := &FFmpegHLSMediaProcessor{
+		Ladder:     params.Config.Ladder,
+		LowLatency: params.Config.LowLatency,
+		DVRWindow:  params.Config.DVRWindow,
+
+		segmentTimes: make(map[string]map[string]time.Time),
+	}
+
+	if processor.LowLatency {
+		processor.lowLatencyPlaylists = make(map[string]*LowLatencyPlaylist, len(processor.Ladder))
+		for _, rendition := range processor.Ladder {
+			processor.lowLatencyPlaylists[rendition.Name] = NewLowLatencyPlaylist()
+		}
+	}
+
+	return processor
 }
@@ -0,0 +1,92 @@
+package hls
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/matroska"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/namedpipe"
+)
+
+// Head of the matroska stream which includes its tracks
+const matroskaHeadSize = 512
+
+// Ffmpeg inputs of the stream pipes. Video and audio of the publisher come muxed into one matroska stream of the
+// video pipe with timestamps of the shared clock. Raw h264 has the audio in own pipe, which is the first extra file
+type Input struct {
+	// Video pipe with the sniffed head
+	Video *bufio.Reader
+	Head  []byte
+	Muxed bool
+	H264  bool
+}
+
+// Blocks until the video arrives
+func NewInput(videoSourcePipe io.Reader) (*Input, error) {
+	video := bufio.NewReader(videoSourcePipe)
+	head, err := video.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &Input{Video: video, Head: head, Muxed: matroska.IsMatroska(head), H264: h264.IsAnnexB(head)}
+	if input.Muxed {
+		// Short stream may end before the head, its tracks are in the peeked part anyway
+		tracks, _ := video.Peek(matroskaHeadSize)
+		input.H264 = matroska.IsH264(tracks)
+	}
+	return input, nil
+}
+
+// Ffmpeg inputs. Options are set for each input of the separate pipes, muxed input needs none
+func (in *Input) Args(options ...string) []string {
+	args := []string{}
+	if in.Muxed {
+		return append(args, "-i", "pipe:0")
+	}
+
+	args = append(args, options...)
+	args = append(args, "-i", "pipe:0")
+	args = append(args, options...)
+	return append(args, "-i", "pipe:3")
+}
+
+// Stream specifier of the audio
+func (in *Input) Audio() string {
+	if in.Muxed {
+		return "0:a"
+	}
+	return "1:a"
+}
+
+// File descriptor of the first extra file after the audio pipe
+func (in *Input) NextFD() int {
+	if in.Muxed {
+		return 3
+	}
+	return 4
+}
+
+// Named pipe of the audio with ffmpeg extra files of it. Audio pipe of the muxed input is only drained, so its relay is not blocked
+func (in *Input) OpenAudio(audioSourcePipe io.Reader) (*namedpipe.NamedPipe, []*os.File, error) {
+	if in.Muxed {
+		go io.Copy(io.Discard, audioSourcePipe)
+		return nil, nil, nil
+	}
+
+	audioPipe, err := namedpipe.NewNamedPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	audioPipeFile, err := audioPipe.OpenAsWriteOnly()
+	if err != nil {
+		audioPipe.Close()
+		return nil, nil, err
+	}
+
+	go io.Copy(audioPipeFile, audioSourcePipe)
+	return audioPipe, []*os.File{audioPipeFile}, nil
+}
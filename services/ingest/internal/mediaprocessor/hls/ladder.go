@@ -0,0 +1,264 @@
+package hls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAudioBitrate = 128
+	// Segments of all renditions must start on keyframe at the same time, so players may switch between them
+	KeyframeInterval = 4
+)
+
+var (
+	EmptyLadderError          = errors.New("hls ladder has no renditions")
+	InvalidRenditionNameError = errors.New("invalid hls rendition name")
+	DuplicateRenditionError   = errors.New("duplicate hls rendition name")
+	RenditionNotFoundError    = errors.New("hls rendition not found")
+
+	renditionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+// Variant of the HLS output. Bitrates are in kbit/s.
+// Rendition without video bitrate is audio only. Without height it keeps the source resolution
+type Rendition struct {
+	Name         string `json:"name"`
+	Width        int32  `json:"width,omitempty"`
+	Height       int32  `json:"height,omitempty"`
+	VideoBitrate int32  `json:"videoBitrate,omitempty"`
+	AudioBitrate int32  `json:"audioBitrate,omitempty"`
+}
+
+func (r Rendition) AudioOnly() bool {
+	return r.VideoBitrate <= 0
+}
+
+func (r Rendition) Bandwidth() int64 {
+	bandwidth := int64(r.AudioBitrate)
+	if !r.AudioOnly() {
+		bandwidth += int64(r.VideoBitrate)
+	}
+	return bandwidth * 1000
+}
+
+func (r Rendition) scaleFilter() string {
+	switch {
+	case r.Width > 0 && r.Height > 0:
+		return fmt.Sprintf("scale=%d:%d", r.Width, r.Height)
+	case r.Height > 0:
+		return fmt.Sprintf("scale=-2:%d", r.Height)
+	}
+	return "null"
+}
+
+// Renditions of the stream in order declared by ingest template
+type Ladder []Rendition
+
+func (l Ladder) Get(name string) (*Rendition, error) {
+	for _, rendition := range l {
+		if rendition.Name == name {
+			return &rendition, nil
+		}
+	}
+	return nil, RenditionNotFoundError
+}
+
+// Master playlist refer to the variant playlists by prefix. It's relative to the master route
+func (l Ladder) MasterPlaylist(prefixURL, audioCodec string) string {
+	var playlist strings.Builder
+
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:3\n")
+	playlist.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	for _, rendition := range l {
+		playlist.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d", rendition.Bandwidth()))
+		if rendition.Width > 0 && rendition.Height > 0 && !rendition.AudioOnly() {
+			playlist.WriteString(fmt.Sprintf(",RESOLUTION=%dx%d", rendition.Width, rendition.Height))
+		}
+		if rendition.AudioOnly() {
+			playlist.WriteString(fmt.Sprintf(`,CODECS="%s"`, audioCodec))
+		}
+		playlist.WriteString(fmt.Sprintf("\n%s%s/%s\n", prefixURL, rendition.Name, variantPlaylistFile))
+	}
+
+	return playlist.String()
+}
+
+// Split the video input into scaled renditions. Labels of the filter outputs are keyed by rendition index
+func (l Ladder) VideoFilter() (string, map[int]string) {
+	labels := make(map[int]string)
+	var filters []string
+
+	for i, rendition := range l {
+		if rendition.AudioOnly() {
+			continue
+		}
+		label := fmt.Sprintf("[v%dout]", len(labels))
+		filters = append(filters, fmt.Sprintf("[v%d]%s%s", len(labels), rendition.scaleFilter(), label))
+		labels[i] = label
+	}
+
+	if len(labels) == 0 {
+		return "", labels
+	}
+
+	split := fmt.Sprintf("[0:v]split=%d", len(labels))
+	for i := 0; i < len(labels); i++ {
+		split += fmt.Sprintf("[v%d]", i)
+	}
+
+	return strings.Join(append([]string{split}, filters...), ";"), labels
+}
+
+func VideoCodecArgs(keyframeInterval int) []string {
+	return []string{
+		"-c:v", "libx264",
+		"-preset", "ultrafast",
+		"-tune", "zerolatency",
+		"-crf", "30",
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", keyframeInterval),
+		"-pix_fmt", "yuv420p",
+	}
+}
+
+// Ffmpeg args which split the video input into scaled renditions. Each rendition has own audio output.
+// Output patterns must contain %v which ffmpeg replaces by the rendition name. Playlist keeps segments of the dvr window.
+// Audio is the stream specifier of the input audio
+func (l Ladder) ffmpegArgs(audio, playlistPattern, segmentPattern string, dvrWindow time.Duration) []string {
+	var maps, codecs, streams []string
+
+	filter, labels := l.VideoFilter()
+
+	videoIdx := 0
+	for audioIdx, rendition := range l {
+		stream := fmt.Sprintf("a:%d,name:%s", audioIdx, rendition.Name)
+
+		if label, ok := labels[audioIdx]; ok {
+			maps = append(maps, "-map", label)
+			codecs = append(codecs,
+				fmt.Sprintf("-maxrate:v:%d", videoIdx), fmt.Sprintf("%dk", rendition.VideoBitrate),
+				fmt.Sprintf("-bufsize:v:%d", videoIdx), fmt.Sprintf("%dk", rendition.VideoBitrate*3/4),
+			)
+			stream = fmt.Sprintf("v:%d,%s", videoIdx, stream)
+			videoIdx++
+		}
+
+		maps = append(maps, "-map", audio)
+		codecs = append(codecs, fmt.Sprintf("-b:a:%d", audioIdx), fmt.Sprintf("%dk", rendition.AudioBitrate))
+		streams = append(streams, stream)
+	}
+
+	flags := "delete_segments+independent_segments"
+	if dvrWindow > 0 {
+		// Players show the wall clock of the rewound position
+		flags += "+program_date_time"
+	}
+
+	var args []string
+	if filter != "" {
+		args = append(args, "-filter_complex", filter)
+	}
+	args = append(args, maps...)
+	args = append(args, VideoCodecArgs(KeyframeInterval)...)
+	args = append(args, "-c:a", "libopus")
+	args = append(args, codecs...)
+	args = append(args,
+		"-err_detect", "ignore_err",
+		"-muxdelay", "0",
+		"-map_metadata", "0",
+		"-copyts",
+		"-copytb", "0",
+		"-f", "hls",
+		"-hls_time", fmt.Sprint(KeyframeInterval),
+		"-hls_list_size", fmt.Sprint(playlistSize(dvrWindow, KeyframeInterval*time.Second)),
+		"-hls_flags", flags,
+		"-hls_start_number_source", "datetime",
+		"-hls_allow_cache", "0",
+		"-hls_segment_filename", segmentPattern,
+		"-var_stream_map", strings.Join(streams, " "),
+		playlistPattern,
+	)
+
+	return args
+}
+
+// Ffmpeg args of the fragmented mp4 output per rendition. Output of the rendition is written into own file descriptor
+// starting from the first one. Audio is AAC because LL-HLS players expect CMAF with it
+func (l Ladder) lowLatencyArgs(firstFD int, audio string) []string {
+	var args []string
+
+	filter, labels := l.VideoFilter()
+	if filter != "" {
+		args = append(args, "-filter_complex", filter)
+	}
+
+	for i, rendition := range l {
+		if label, ok := labels[i]; ok {
+			args = append(args, "-map", label)
+			args = append(args, VideoCodecArgs(int(lowLatencySegmentTarget.Seconds()))...)
+			args = append(args,
+				"-maxrate", fmt.Sprintf("%dk", rendition.VideoBitrate),
+				"-bufsize", fmt.Sprintf("%dk", rendition.VideoBitrate*3/4),
+			)
+		}
+
+		args = append(args,
+			"-map", audio,
+			"-c:a", "aac",
+			"-b:a", fmt.Sprintf("%dk", rendition.AudioBitrate),
+			"-muxdelay", "0",
+			"-f", "mp4",
+			"-movflags", "empty_moov+default_base_moof+frag_keyframe",
+			"-frag_duration", fmt.Sprint(lowLatencyFragmentDuration.Microseconds()),
+			fmt.Sprintf("pipe:%d", firstFD+i),
+		)
+	}
+
+	return args
+}
+
+// Rendition names are used as directories and routes
+func (l Ladder) Validate() error {
+	if len(l) == 0 {
+		return EmptyLadderError
+	}
+
+	names := make(map[string]struct{}, len(l))
+	for _, rendition := range l {
+		if !renditionNamePattern.MatchString(rendition.Name) {
+			return fmt.Errorf("%w %q", InvalidRenditionNameError, rendition.Name)
+		}
+		if _, exists := names[rendition.Name]; exists {
+			return fmt.Errorf("%w %q", DuplicateRenditionError, rendition.Name)
+		}
+		names[rendition.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+func ParseLadder(raw string) (Ladder, error) {
+	var ladder Ladder
+	if err := json.Unmarshal([]byte(raw), &ladder); err != nil {
+		return nil, err
+	}
+
+	for i := range ladder {
+		if ladder[i].AudioBitrate <= 0 {
+			ladder[i].AudioBitrate = defaultAudioBitrate
+		}
+	}
+
+	if err := ladder.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ladder, nil
+}
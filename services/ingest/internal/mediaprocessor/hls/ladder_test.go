@@ -0,0 +1,93 @@
+package hls
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLadder = `[
+	{"name":"1080p","width":1920,"height":1080,"videoBitrate":5000,"audioBitrate":128},
+	{"name":"480p","height":480,"videoBitrate":1400},
+	{"name":"audio","audioBitrate":96}
+]`
+
+func TestParseLadder(t *testing.T) {
+	ladder, err := ParseLadder(testLadder)
+	assert.Nil(t, err)
+	assert.Len(t, ladder, 3)
+	assert.Equal(t, int32(defaultAudioBitrate), ladder[1].AudioBitrate)
+	assert.True(t, ladder[2].AudioOnly())
+
+	_, err = ParseLadder(`[]`)
+	assert.ErrorIs(t, err, EmptyLadderError)
+
+	_, err = ParseLadder(`[{"name":"../720p","videoBitrate":2800}]`)
+	assert.ErrorIs(t, err, InvalidRenditionNameError)
+
+	_, err = ParseLadder(`[{"name":"720p","videoBitrate":2800},{"name":"720p","videoBitrate":1400}]`)
+	assert.ErrorIs(t, err, DuplicateRenditionError)
+}
+
+func TestLadder_MasterPlaylist(t *testing.T) {
+	ladder, err := ParseLadder(testLadder)
+	assert.Nil(t, err)
+
+	expected := "#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-INDEPENDENT-SEGMENTS\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=5128000,RESOLUTION=1920x1080\n" +
+		"stream/1080p/index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1528000\n" +
+		"stream/480p/index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=96000,CODECS=\"opus\"\n" +
+		"stream/audio/index.m3u8\n"
+
+	assert.Equal(t, expected, ladder.MasterPlaylist("stream/", "opus"))
+}
+
+func TestLadder_FfmpegArgs(t *testing.T) {
+	ladder, err := ParseLadder(testLadder)
+	assert.Nil(t, err)
+
+	args := strings.Join(ladder.ffmpegArgs("1:a", "out/%v/index.m3u8", "out/%v/segment_%d.ts", 0), " ")
+
+	assert.Contains(t, args, "-filter_complex [0:v]split=2[v0][v1];[v0]scale=1920:1080[v0out];[v1]scale=-2:480[v1out]")
+	assert.Contains(t, args, "-map [v0out] -map 1:a -map [v1out] -map 1:a -map 1:a")
+	assert.Contains(t, args, "-maxrate:v:1 1400k")
+	assert.Contains(t, args, "-b:a:2 96k")
+	assert.Contains(t, args, "-var_stream_map v:0,a:0,name:1080p v:1,a:1,name:480p a:2,name:audio out/%v/index.m3u8")
+}
+
+func TestFFmpegHLSMediaProcessor_RenditionFile(t *testing.T) {
+	ladder, err := ParseLadder(testLadder)
+	assert.Nil(t, err)
+
+	processor := NewFFmpegHLSMediaProcessor(FFmpegHLSMediaProcessorParams{Config: &Config{Ladder: ladder}})
+	processor.SourceDirectory = "/tmp/stream"
+
+	file, err := processor.RenditionFile("480p", "index.m3u8")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join("/tmp/stream", "480p", "index.m3u8"), file)
+
+	_, err = processor.RenditionFile("720p", "index.m3u8")
+	assert.ErrorIs(t, err, RenditionNotFoundError)
+
+	_, err = processor.RenditionFile("480p", "..")
+	assert.ErrorIs(t, err, InvalidRenditionFileError)
+}
+
+func TestLadder_LowLatencyArgs(t *testing.T) {
+	ladder, err := ParseLadder(testLadder)
+	assert.Nil(t, err)
+
+	args := strings.Join(ladder.lowLatencyArgs(4, "1:a"), " ")
+
+	assert.Contains(t, args, "-filter_complex [0:v]split=2[v0][v1];[v0]scale=1920:1080[v0out];[v1]scale=-2:480[v1out]")
+	assert.Contains(t, args, "-map [v1out] -c:v libx264")
+	assert.Contains(t, args, "-maxrate 1400k -bufsize 1050k -map 1:a -c:a aac -b:a 128k")
+	assert.Contains(t, args, "-frag_duration 400000 pipe:5 -map 1:a -c:a aac -b:a 96k")
+	assert.True(t, strings.HasSuffix(args, "pipe:6"))
+}
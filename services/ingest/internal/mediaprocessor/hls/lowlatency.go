@@ -0,0 +1,341 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+)
+
+const (
+	lowLatencyPartTarget = 500 * time.Millisecond
+	// Muxer may exceed fragment duration by one frame. It must fit into the part target
+	lowLatencyFragmentDuration = 400 * time.Millisecond
+	lowLatencySegmentTarget    = 2 * time.Second
+	lowLatencyListSize         = 6
+	// Parts are listed only for the latest segments
+	lowLatencyPartSegments = 3
+	// Blocking request must be answered within three target durations
+	lowLatencyBlockingTimeout = 3 * lowLatencySegmentTarget
+
+	// Init segment of the fMP4 renditions
+	initSegmentFile = "init.mp4"
+)
+
+var (
+	PlaylistNotReadyError       = errors.New("low latency playlist has no segments yet")
+	PlaylistClosedError         = errors.New("low latency playlist closed")
+	InvalidBlockingRequestError = errors.New("invalid blocking playlist request")
+	BlockingRequestTimeoutError = errors.New("blocking playlist request timeout")
+	MediaNotFoundError          = errors.New("low latency media not found")
+)
+
+type lowLatencyPart struct {
+	data        []byte
+	duration    time.Duration
+	independent bool
+}
+
+type lowLatencySegment struct {
+	msn             uint64
+	parts           []*lowLatencyPart
+	duration        time.Duration
+	programDateTime time.Time
+}
+
+func (s *lowLatencySegment) data() []byte {
+	var data bytes.Buffer
+	for _, part := range s.parts {
+		data.Write(part.data)
+	}
+	return data.Bytes()
+}
+
+// LL-HLS media playlist of the single rendition. Parts are fragments of the muxer, segments are cut on independent part
+type LowLatencyPlaylist struct {
+	init     []byte
+	segments []*lowLatencySegment
+	current  *lowLatencySegment
+	nextMSN  uint64
+	closed   bool
+
+	// Closed and replaced on each update to wake blocking requests
+	updated chan struct{}
+
+	mx sync.RWMutex
+}
+
+func NewLowLatencyPlaylist() *LowLatencyPlaylist {
+	return &LowLatencyPlaylist{updated: make(chan struct{})}
+}
+
+func (p *LowLatencyPlaylist) notify() {
+	close(p.updated)
+	p.updated = make(chan struct{})
+}
+
+func (p *LowLatencyPlaylist) SetInit(data []byte) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.init = data
+	p.notify()
+}
+
+func (p *LowLatencyPlaylist) AddPart(fragment *fmp4.Fragment) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	if p.current == nil {
+		// Playlist must start from independent part
+		if !fragment.Independent {
+			return
+		}
+		p.current = &lowLatencySegment{msn: p.nextMSN, programDateTime: fragment.ProgramDateTime}
+	} else if fragment.Independent && p.current.duration >= lowLatencySegmentTarget-lowLatencyPartTarget/2 {
+		p.segments = append(p.segments, p.current)
+		if len(p.segments) > lowLatencyListSize {
+			p.segments = p.segments[len(p.segments)-lowLatencyListSize:]
+		}
+		p.nextMSN++
+		p.current = &lowLatencySegment{msn: p.nextMSN, programDateTime: fragment.ProgramDateTime}
+	}
+
+	p.current.parts = append(p.current.parts, &lowLatencyPart{
+		data:        fragment.Data,
+		duration:    fragment.Duration,
+		independent: fragment.Independent,
+	})
+	p.current.duration += fragment.Duration
+	p.notify()
+}
+
+// Wake blocking requests. Playlist doesn't accept parts anymore
+func (p *LowLatencyPlaylist) Close() {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.notify()
+}
+
+// Request is satisfied when playlist has the segment or the part of the current segment
+func (p *LowLatencyPlaylist) satisfied(msn uint64, part int) bool {
+	if p.current == nil {
+		return false
+	}
+	if part < 0 {
+		return len(p.segments) > 0 && p.segments[len(p.segments)-1].msn >= msn
+	}
+	return p.current.msn > msn || (p.current.msn == msn && len(p.current.parts) > part)
+}
+
+// Hold request until the condition or timeout. Request too far from the live edge is rejected
+func (p *LowLatencyPlaylist) wait(ctx context.Context, ready func() bool, far func() bool) error {
+	timeout := time.NewTimer(lowLatencyBlockingTimeout)
+	defer timeout.Stop()
+
+	for {
+		p.mx.RLock()
+		closed, updated := p.closed, p.updated
+		if ready() {
+			p.mx.RUnlock()
+			return nil
+		}
+		tooFar := far()
+		p.mx.RUnlock()
+
+		if closed {
+			return PlaylistClosedError
+		}
+		if tooFar {
+			return fmt.Errorf("%w. Requested media is too far from the live edge", InvalidBlockingRequestError)
+		}
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			return BlockingRequestTimeoutError
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func parseBlockingParam(value string) (int64, error) {
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%w. Wrong %q value", InvalidBlockingRequestError, value)
+	}
+	return parsed, nil
+}
+
+// Render playlist. With msn it's blocking reload request of _HLS_msn and _HLS_part query params
+func (p *LowLatencyPlaylist) Playlist(ctx context.Context, rawMSN, rawPart string) ([]byte, error) {
+	if rawMSN == "" && rawPart != "" {
+		return nil, fmt.Errorf("%w. Part without msn", InvalidBlockingRequestError)
+	}
+
+	if rawMSN != "" {
+		msn, err := parseBlockingParam(rawMSN)
+		if err != nil {
+			return nil, err
+		}
+
+		part := int64(-1)
+		if rawPart != "" {
+			if part, err = parseBlockingParam(rawPart); err != nil {
+				return nil, err
+			}
+		}
+
+		err = p.wait(ctx,
+			func() bool { return p.satisfied(uint64(msn), int(part)) },
+			func() bool { return uint64(msn) > p.nextMSN+2 },
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+
+	if p.current == nil || p.init == nil {
+		return nil, PlaylistNotReadyError
+	}
+	return []byte(p.render()), nil
+}
+
+func formatDuration(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', 5, 64)
+}
+
+func (p *LowLatencyPlaylist) render() string {
+	var playlist strings.Builder
+
+	targetDuration := lowLatencySegmentTarget
+	for _, segment := range p.segments {
+		if segment.duration > targetDuration {
+			targetDuration = segment.duration
+		}
+	}
+
+	firstMSN := p.current.msn
+	if len(p.segments) > 0 {
+		firstMSN = p.segments[0].msn
+	}
+
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:6\n")
+	playlist.WriteString(fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", int(math.Round(targetDuration.Seconds()))))
+	playlist.WriteString(fmt.Sprintf("#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%s\n", formatDuration(3*lowLatencyPartTarget)))
+	playlist.WriteString(fmt.Sprintf("#EXT-X-PART-INF:PART-TARGET=%s\n", formatDuration(lowLatencyPartTarget)))
+	playlist.WriteString(fmt.Sprintf("#EXT-X-MEDIA-SEQUENCE:%d\n", firstMSN))
+	playlist.WriteString(fmt.Sprintf("#EXT-X-MAP:URI=\"%s\"\n", initSegmentFile))
+
+	writeParts := func(segment *lowLatencySegment) {
+		writeProgramDateTime(&playlist, segment.programDateTime)
+		for i, part := range segment.parts {
+			playlist.WriteString(fmt.Sprintf("#EXT-X-PART:DURATION=%s,URI=\"%s\"", formatDuration(part.duration), partFile(segment.msn, i)))
+			if part.independent {
+				playlist.WriteString(",INDEPENDENT=YES")
+			}
+			playlist.WriteString("\n")
+		}
+	}
+
+	for i, segment := range p.segments {
+		if len(p.segments)-i <= lowLatencyPartSegments {
+			writeParts(segment)
+		} else {
+			writeProgramDateTime(&playlist, segment.programDateTime)
+		}
+		playlist.WriteString(fmt.Sprintf("#EXTINF:%s,\n%s\n", formatDuration(segment.duration), segmentFile(segment.msn)))
+	}
+
+	writeParts(p.current)
+	playlist.WriteString(fmt.Sprintf("#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", partFile(p.current.msn, len(p.current.parts))))
+
+	return playlist.String()
+}
+
+func segmentFile(msn uint64) string {
+	return fmt.Sprintf("segment_%d.m4s", msn)
+}
+
+func partFile(msn uint64, part int) string {
+	return fmt.Sprintf("part_%d_%d.m4s", msn, part)
+}
+
+func (p *LowLatencyPlaylist) segment(msn uint64) *lowLatencySegment {
+	for _, segment := range p.segments {
+		if segment.msn == msn {
+			return segment
+		}
+	}
+	return nil
+}
+
+// Serve init, segment or part. Request of the preload hint part is held until the part is ready
+func (p *LowLatencyPlaylist) Media(ctx context.Context, file string) ([]byte, error) {
+	if file == initSegmentFile {
+		p.mx.RLock()
+		defer p.mx.RUnlock()
+
+		if p.init == nil {
+			return nil, MediaNotFoundError
+		}
+		return p.init, nil
+	}
+
+	var msn uint64
+	var part int
+	if _, err := fmt.Sscanf(file, "part_%d_%d.m4s", &msn, &part); err == nil && file == partFile(msn, part) {
+		err := p.wait(ctx,
+			func() bool { return p.satisfied(msn, part) },
+			// Only the preload hint part may be awaited
+			func() bool { return p.current == nil || msn != p.current.msn || part > len(p.current.parts) },
+		)
+		if err != nil {
+			return nil, MediaNotFoundError
+		}
+
+		p.mx.RLock()
+		defer p.mx.RUnlock()
+
+		segment := p.segment(msn)
+		if p.current.msn == msn {
+			segment = p.current
+		}
+		if segment == nil || part >= len(segment.parts) {
+			return nil, MediaNotFoundError
+		}
+		return segment.parts[part].data, nil
+	}
+
+	if _, err := fmt.Sscanf(file, "segment_%d.m4s", &msn); err == nil && file == segmentFile(msn) {
+		p.mx.RLock()
+		defer p.mx.RUnlock()
+
+		if segment := p.segment(msn); segment != nil {
+			return segment.data(), nil
+		}
+	}
+
+	return nil, MediaNotFoundError
+}
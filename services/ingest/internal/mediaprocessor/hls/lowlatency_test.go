@@ -0,0 +1,136 @@
+package hls
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+	"github.com/stretchr/testify/assert"
+)
+
+func part(data string, independent bool) *fmp4.Fragment {
+	return &fmp4.Fragment{Data: []byte(data), Duration: 500 * time.Millisecond, Independent: independent}
+}
+
+// First segment has four parts, second one has started
+func filledPlaylist() *LowLatencyPlaylist {
+	playlist := NewLowLatencyPlaylist()
+	playlist.SetInit([]byte("init"))
+
+	playlist.AddPart(part("skipped", false))
+	playlist.AddPart(part("a", true))
+	playlist.AddPart(part("b", false))
+	playlist.AddPart(part("c", true))
+	playlist.AddPart(part("d", false))
+	playlist.AddPart(part("e", true))
+
+	return playlist
+}
+
+func TestLowLatencyPlaylist_Playlist(t *testing.T) {
+	playlist := filledPlaylist()
+
+	rendered, err := playlist.Playlist(context.Background(), "", "")
+	assert.Nil(t, err)
+
+	expected := strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:6",
+		"#EXT-X-TARGETDURATION:2",
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.50000",
+		"#EXT-X-PART-INF:PART-TARGET=0.50000",
+		"#EXT-X-MEDIA-SEQUENCE:0",
+		`#EXT-X-MAP:URI="init.mp4"`,
+		`#EXT-X-PART:DURATION=0.50000,URI="part_0_0.m4s",INDEPENDENT=YES`,
+		`#EXT-X-PART:DURATION=0.50000,URI="part_0_1.m4s"`,
+		`#EXT-X-PART:DURATION=0.50000,URI="part_0_2.m4s",INDEPENDENT=YES`,
+		`#EXT-X-PART:DURATION=0.50000,URI="part_0_3.m4s"`,
+		"#EXTINF:2.00000,",
+		"segment_0.m4s",
+		`#EXT-X-PART:DURATION=0.50000,URI="part_1_0.m4s",INDEPENDENT=YES`,
+		`#EXT-X-PRELOAD-HINT:TYPE=PART,URI="part_1_1.m4s"`,
+	}, "\n") + "\n"
+
+	assert.Equal(t, expected, string(rendered))
+
+	_, err = playlist.Playlist(context.Background(), "", "1")
+	assert.ErrorIs(t, err, InvalidBlockingRequestError)
+
+	_, err = playlist.Playlist(context.Background(), "4", "")
+	assert.ErrorIs(t, err, InvalidBlockingRequestError)
+
+	_, err = NewLowLatencyPlaylist().Playlist(context.Background(), "", "")
+	assert.ErrorIs(t, err, PlaylistNotReadyError)
+}
+
+func TestLowLatencyPlaylist_BlockingReload(t *testing.T) {
+	playlist := filledPlaylist()
+
+	// Already available part is served at once
+	_, err := playlist.Playlist(context.Background(), "1", "0")
+	assert.Nil(t, err)
+
+	result := make(chan string)
+	go func() {
+		rendered, err := playlist.Playlist(context.Background(), "1", "1")
+		assert.Nil(t, err)
+		result <- string(rendered)
+	}()
+
+	select {
+	case <-result:
+		t.Fatal("blocking request is answered before the part")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	playlist.AddPart(part("f", false))
+
+	select {
+	case rendered := <-result:
+		assert.Contains(t, rendered, `URI="part_1_1.m4s"`)
+		assert.Contains(t, rendered, `#EXT-X-PRELOAD-HINT:TYPE=PART,URI="part_1_2.m4s"`)
+	case <-time.After(time.Second):
+		t.Fatal("blocking request is not answered after the part")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = playlist.Playlist(ctx, "2", "")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLowLatencyPlaylist_Media(t *testing.T) {
+	assert := assert.New(t)
+	playlist := filledPlaylist()
+	ctx := context.Background()
+
+	init, err := playlist.Media(ctx, "init.mp4")
+	assert.Nil(err)
+	assert.Equal("init", string(init))
+
+	segment, err := playlist.Media(ctx, "segment_0.m4s")
+	assert.Nil(err)
+	assert.Equal("abcd", string(segment))
+
+	data, err := playlist.Media(ctx, "part_0_2.m4s")
+	assert.Nil(err)
+	assert.Equal("c", string(data))
+
+	_, err = playlist.Media(ctx, "segment_1.m4s")
+	assert.ErrorIs(err, MediaNotFoundError)
+	_, err = playlist.Media(ctx, "part_1_5.m4s")
+	assert.ErrorIs(err, MediaNotFoundError)
+	_, err = playlist.Media(ctx, "../part_1_0.m4s")
+	assert.ErrorIs(err, MediaNotFoundError)
+
+	// Preload hint part is held until it's ready
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		playlist.AddPart(part("f", false))
+	}()
+	data, err = playlist.Media(ctx, "part_1_1.m4s")
+	assert.Nil(err)
+	assert.Equal("f", string(data))
+}
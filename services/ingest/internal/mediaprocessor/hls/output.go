@@ -0,0 +1,19 @@
+package hls
+
+// HLS of the media processor served by hls egress
+type Output interface {
+	// Master playlist file. Empty until it's written
+	MasterPlaylist() string
+	// Variant playlist or segment file of the rendition
+	RenditionFile(rendition, file string) (string, error)
+	// Variant playlist of the rendition file
+	Playlist(file string) ([]byte, error)
+	// Low latency renditions are served from memory by LowLatencyPlaylist
+	IsLowLatency() bool
+	LowLatencyPlaylist(rendition string) (*LowLatencyPlaylist, error)
+}
+
+var (
+	_ Output = (*FFmpegHLSMediaProcessor)(nil)
+	_ Output = (*PassthroughHLSMediaProcessor)(nil)
+)
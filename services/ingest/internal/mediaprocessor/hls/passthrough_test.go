@@ -0,0 +1,175 @@
+package hls
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	"github.com/stretchr/testify/assert"
+)
+
+// High profile 1080p
+var testSPS = []byte{0x67, 0x64, 0x00, 0x28, 0xAC, 0xCA, 0xC0, 0x78, 0x02, 0x27, 0xE5, 0x40}
+
+type recordSink struct {
+	init      []byte
+	fragments []*fmp4.Fragment
+}
+
+func (s *recordSink) SetInit(data []byte) {
+	s.init = data
+}
+
+func (s *recordSink) AddPart(fragment *fmp4.Fragment) {
+	s.fragments = append(s.fragments, fragment)
+}
+
+// Picture each 100ms, IDR each second
+func pushPictures(segmenter *passthroughSegmenter, start time.Time, count int) {
+	for i := 0; i < count; i++ {
+		au := &h264.AccessUnit{
+			NALUs: [][]byte{{0x41, 0x9A, byte(i)}},
+			Time:  start.Add(time.Duration(i) * 100 * time.Millisecond),
+		}
+		if i%10 == 0 {
+			au.NALUs = [][]byte{testSPS, {0x68, 0xCE}, {0x65, 0x88, byte(i)}}
+		}
+		segmenter.Push(au)
+	}
+	segmenter.Finish()
+}
+
+func TestPassthroughSegmenter(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Now()
+
+	audio := newPassthroughAudio(start)
+	audio.setConfig(&fmp4.AudioConfig{Codec: fmp4.AudioCodecAAC, SampleRate: 48000, Channels: 2, Config: []byte{0x11, 0x90}})
+	for i := 0; i < 100; i++ {
+		mediaTime := uint64(i * 1024)
+		audio.push(&audioSample{data: []byte{0xAA}, mediaTime: mediaTime, duration: 1024}, start.Add(fromTimescale(mediaTime, 48000)))
+	}
+
+	sink := &recordSink{}
+	segmenter := newPassthroughSegmenter(start, sink, audio, time.Second, 0)
+
+	var codecs string
+	segmenter.onReady = func(c string, width, height int, bandwidth int64) {
+		codecs = c
+		assert.Equal(1920, width)
+		assert.Equal(1080, height)
+		assert.Greater(bandwidth, int64(0))
+	}
+
+	pushPictures(segmenter, start, 21)
+
+	assert.Equal("avc1.640028,mp4a.40.2", codecs)
+	assert.Len(sink.fragments, 3)
+	assert.Equal(time.Second, sink.fragments[0].Duration)
+	assert.Equal(time.Second, sink.fragments[1].Duration)
+	assert.Equal(100*time.Millisecond, sink.fragments[2].Duration)
+	for _, fragment := range sink.fragments {
+		assert.True(fragment.Independent)
+	}
+
+	reader := fmp4.NewReader(bytes.NewReader(append(append([]byte{}, sink.init...), sink.fragments[0].Data...)))
+	init, err := reader.ReadInit()
+	assert.Nil(err)
+	assert.Len(init.Tracks, 2)
+
+	fragment, err := reader.ReadFragment()
+	assert.Nil(err)
+	// 47 AAC frames reach the first second
+	assert.Equal(fromTimescale(47*1024, 48000), fragment.Duration)
+}
+
+func TestPassthroughSegmenter_LowLatency(t *testing.T) {
+	start := time.Now()
+
+	audio := newPassthroughAudio(start)
+	audio.setConfig(&fmp4.AudioConfig{Codec: fmp4.AudioCodecOpus, SampleRate: 48000, Channels: 2})
+
+	sink := &recordSink{}
+	pushPictures(newPassthroughSegmenter(start, sink, audio, 0, lowLatencyFragmentDuration), start, 12)
+
+	var durations []time.Duration
+	var independent []bool
+	for _, fragment := range sink.fragments {
+		durations = append(durations, fragment.Duration)
+		independent = append(independent, fragment.Independent)
+	}
+
+	assert.Equal(t, []time.Duration{400 * time.Millisecond, 400 * time.Millisecond, 200 * time.Millisecond, 200 * time.Millisecond}, durations)
+	assert.Equal(t, []bool{true, false, false, true}, independent)
+}
+
+func TestSegmentPlaylist(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	playlist := newSegmentPlaylist(dir, 0)
+	playlist.SetInit([]byte("init"))
+	for i := 0; i < segmentPlaylistSize+2; i++ {
+		playlist.AddPart(&fmp4.Fragment{Data: []byte("segment"), Duration: 4200 * time.Millisecond, Independent: true})
+	}
+
+	rendered, err := os.ReadFile(filepath.Join(dir, variantPlaylistFile))
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(string(rendered), strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:7",
+		"#EXT-X-TARGETDURATION:4",
+		"#EXT-X-MEDIA-SEQUENCE:2",
+		"#EXT-X-INDEPENDENT-SEGMENTS",
+		`#EXT-X-MAP:URI="init.mp4"`,
+		"#EXTINF:4.20000,",
+		"segment_2.m4s",
+	}, "\n")))
+
+	_, err = os.Stat(filepath.Join(dir, "segment_0.m4s"))
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "segment_1.m4s"))
+	assert.Nil(err)
+}
+
+func TestSegmentPlaylist_Discontinuity(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	start := time.Now()
+	audio := newPassthroughAudio(start)
+	audio.setConfig(&fmp4.AudioConfig{Codec: fmp4.AudioCodecOpus, SampleRate: 48000, Channels: 2})
+
+	// Next publisher is marked after the first IDR, its IDR is cut before the minimal duration
+	sink := &recordSink{}
+	segmenter := newPassthroughSegmenter(start, sink, audio, 3*time.Second, 0)
+	for i := 0; i < 5; i++ {
+		segmenter.Push(&h264.AccessUnit{NALUs: [][]byte{testSPS, {0x68, 0xCE}, {0x65, 0x88, byte(i)}}, Time: start.Add(time.Duration(i) * time.Second)})
+		if i == 0 {
+			segmenter.MarkDiscontinuity()
+		}
+	}
+	segmenter.Finish()
+
+	var discontinuity []bool
+	for _, fragment := range sink.fragments {
+		discontinuity = append(discontinuity, fragment.Discontinuity)
+	}
+	assert.Equal([]bool{false, true, false}, discontinuity)
+
+	playlist := newSegmentPlaylist(dir, 0)
+	for i := 0; i < segmentPlaylistSize+1; i++ {
+		playlist.AddPart(&fmp4.Fragment{Data: []byte("segment"), Duration: 4 * time.Second, Discontinuity: i == 0 || i == 3})
+	}
+
+	rendered, err := os.ReadFile(filepath.Join(dir, variantPlaylistFile))
+	assert.Nil(err)
+	assert.Contains(string(rendered), "#EXT-X-DISCONTINUITY-SEQUENCE:1\n")
+	assert.Contains(string(rendered), "#EXT-X-DISCONTINUITY\n#EXTINF:4.00000,\nsegment_3.m4s\n")
+	assert.Equal(1, strings.Count(string(rendered), "#EXT-X-DISCONTINUITY\n"))
+}
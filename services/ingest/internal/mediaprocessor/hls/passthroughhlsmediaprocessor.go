@@ -0,0 +1,287 @@
+package hls
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+	"go.uber.org/fx"
+)
+
+const (
+	// Single rendition of the source stream
+	passthroughRendition = "source"
+	// Access units read ahead while init segment waits the audio
+	passthroughQueueSize = 256
+)
+
+// Pack H264 of the source into fMP4 segments without transcoding. Segments are cut on IDR of the source.
+// Not H264 source is transcoded by FFmpegHLSMediaProcessor
+type PassthroughHLSMediaProcessor struct {
+	SourceDirectory string
+	// Master playlist route refer to the variant playlist with this prefix
+	PlaylistPrefixURL string
+	LowLatency        bool
+
+	config             *Config
+	manifestFile       string
+	lowLatencyPlaylist *LowLatencyPlaylist
+	fallback           *FFmpegHLSMediaProcessor
+	segmenter          *passthroughSegmenter
+	wallClock          *mediartp.Clock
+
+	mx sync.RWMutex
+}
+
+func (processor *PassthroughHLSMediaProcessor) SetWallClock(clock *mediartp.Clock) {
+	processor.mx.Lock()
+	defer processor.mx.Unlock()
+	processor.wallClock = clock
+}
+
+// Segmenter reads raw h264 of the video pipe and the audio of own pipe, vp8 source is transcoded by the fallback
+func (processor *PassthroughHLSMediaProcessor) SeparateInput() {}
+
+func (processor *PassthroughHLSMediaProcessor) getFallback() *FFmpegHLSMediaProcessor {
+	processor.mx.RLock()
+	defer processor.mx.RUnlock()
+	return processor.fallback
+}
+
+func (processor *PassthroughHLSMediaProcessor) MasterPlaylist() string {
+	if fallback := processor.getFallback(); fallback != nil {
+		return fallback.MasterPlaylist()
+	}
+
+	processor.mx.RLock()
+	defer processor.mx.RUnlock()
+	return processor.manifestFile
+}
+
+func (processor *PassthroughHLSMediaProcessor) IsLowLatency() bool {
+	return processor.LowLatency
+}
+
+func (processor *PassthroughHLSMediaProcessor) LowLatencyPlaylist(rendition string) (*LowLatencyPlaylist, error) {
+	if fallback := processor.getFallback(); fallback != nil {
+		return fallback.LowLatencyPlaylist(rendition)
+	}
+
+	if rendition != passthroughRendition || processor.lowLatencyPlaylist == nil {
+		return nil, RenditionNotFoundError
+	}
+	return processor.lowLatencyPlaylist, nil
+}
+
+func (processor *PassthroughHLSMediaProcessor) RenditionFile(rendition, file string) (string, error) {
+	if fallback := processor.getFallback(); fallback != nil {
+		return fallback.RenditionFile(rendition, file)
+	}
+
+	if rendition != passthroughRendition {
+		return "", RenditionNotFoundError
+	}
+
+	if file == "" || file != filepath.Base(file) || file == "." || file == ".." {
+		return "", InvalidRenditionFileError
+	}
+
+	processor.mx.RLock()
+	defer processor.mx.RUnlock()
+	return filepath.Join(processor.SourceDirectory, rendition, file), nil
+}
+
+// Segments are tagged with the wall clock by the playlist writer
+func (processor *PassthroughHLSMediaProcessor) Playlist(file string) ([]byte, error) {
+	if fallback := processor.getFallback(); fallback != nil {
+		return fallback.Playlist(file)
+	}
+	return os.ReadFile(file)
+}
+
+// Master playlist is written when the first segments show the bandwidth of the source
+func (processor *PassthroughHLSMediaProcessor) writeMasterPlaylist(codecs string, width, height int, bandwidth int64) {
+	var playlist strings.Builder
+
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:7\n")
+	playlist.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	playlist.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\"\n", bandwidth, width, height, codecs))
+	playlist.WriteString(fmt.Sprintf("%s%s/%s\n", processor.PlaylistPrefixURL, passthroughRendition, variantPlaylistFile))
+
+	manifestFile := filepath.Join(processor.SourceDirectory, masterPlaylistFile)
+	if err := os.WriteFile(manifestFile, []byte(playlist.String()), 0o644); err != nil {
+		log.Println("[HLS Passthrough] Cannot write master playlist. Err:", err)
+		return
+	}
+
+	processor.mx.Lock()
+	processor.manifestFile = manifestFile
+	processor.mx.Unlock()
+}
+
+// Source which is not Annex-B is transcoded by ffmpeg. Sniffed bytes are passed with the rest of the source
+func (processor *PassthroughHLSMediaProcessor) transcodeFallback(ctx context.Context, video io.Reader, audio *io.PipeReader) error {
+	log.Println("[HLS Passthrough] Source is not h264. Fallback to ffmpeg transcoding")
+
+	fallback := NewFFmpegHLSMediaProcessor(FFmpegHLSMediaProcessorParams{Config: processor.config})
+	fallback.PlaylistPrefixURL = processor.PlaylistPrefixURL
+
+	processor.mx.Lock()
+	processor.fallback = fallback
+	if processor.wallClock != nil {
+		fallback.SetWallClock(processor.wallClock)
+	}
+	processor.mx.Unlock()
+
+	reader, writer := io.Pipe()
+	// Stop the copy when ffmpeg doesn't read anymore
+	defer reader.Close()
+
+	go func() {
+		_, err := io.Copy(writer, video)
+		_ = writer.CloseWithError(err)
+	}()
+
+	return fallback.Transcode(ctx, reader, audio)
+}
+
+func (processor *PassthroughHLSMediaProcessor) Transcode(ctx context.Context, videoSourcePipe *io.PipeReader, audioSourcePipe *io.PipeReader) error {
+	defer processor.Destroy()
+
+	go func() {
+		<-ctx.Done()
+		// Unblock readers of the stopped stream
+		_ = videoSourcePipe.Close()
+		_ = audioSourcePipe.Close()
+	}()
+
+	video := bufio.NewReader(videoSourcePipe)
+	head, err := video.Peek(4)
+	if err != nil {
+		return err
+	}
+
+	if !h264.IsAnnexB(head) {
+		return processor.transcodeFallback(ctx, video, audioSourcePipe)
+	}
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("%s-*", uuid.New()))
+	if err != nil {
+		log.Println("[HLS Passthrough] Cannot create temp dir. Err:", err)
+		return err
+	}
+
+	processor.mx.Lock()
+	processor.SourceDirectory = dir
+	processor.mx.Unlock()
+
+	renditionDir := filepath.Join(dir, passthroughRendition)
+	if err := os.MkdirAll(renditionDir, 0o755); err != nil {
+		log.Println("[HLS Passthrough] Cannot create rendition dir. Err:", err)
+		return err
+	}
+
+	log.Println("[HLS Passthrough] Setup output directory to", dir)
+
+	start := time.Now()
+	audio := newPassthroughAudio(start)
+	go audio.read(audioSourcePipe)
+
+	var segmenter *passthroughSegmenter
+	if processor.LowLatency {
+		segmenter = newPassthroughSegmenter(start, processor.lowLatencyPlaylist, audio, 0, lowLatencyFragmentDuration)
+	} else {
+		segmenter = newPassthroughSegmenter(start, newSegmentPlaylist(renditionDir, processor.config.DVRWindow), audio, KeyframeInterval*time.Second, 0)
+	}
+	segmenter.onReady = processor.writeMasterPlaylist
+
+	processor.mx.Lock()
+	processor.segmenter = segmenter
+	if processor.wallClock != nil {
+		segmenter.senderTime = processor.wallClock.SenderTime
+	}
+	processor.mx.Unlock()
+
+	units := make(chan *h264.AccessUnit, passthroughQueueSize)
+	go func() {
+		defer close(units)
+
+		reader := h264.NewAccessUnitReader(video)
+		for {
+			au, err := reader.Read()
+			if err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					log.Println("[HLS Passthrough] Cannot read h264. Err:", err)
+				}
+				return
+			}
+			units <- au
+		}
+	}()
+
+	for au := range units {
+		segmenter.Push(au)
+	}
+	segmenter.Finish()
+
+	return nil
+}
+
+// Segment of the next publisher starts with discontinuity tag. Transcoded fallback has continuous timeline of ffmpeg
+func (processor *PassthroughHLSMediaProcessor) MarkDiscontinuity() {
+	processor.mx.RLock()
+	defer processor.mx.RUnlock()
+
+	if processor.segmenter != nil {
+		processor.segmenter.MarkDiscontinuity()
+	}
+}
+
+func (processor *PassthroughHLSMediaProcessor) Destroy() {
+	if fallback := processor.getFallback(); fallback != nil {
+		fallback.Destroy()
+	}
+
+	processor.mx.RLock()
+	dir := processor.SourceDirectory
+	processor.mx.RUnlock()
+
+	if dir != "" {
+		log.Println("[HLS Passthrough] Removing", dir)
+		os.RemoveAll(dir)
+	}
+	if processor.lowLatencyPlaylist != nil {
+		processor.lowLatencyPlaylist.Close()
+	}
+}
+
+type PassthroughHLSMediaProcessorParams struct {
+	fx.In
+
+	Config *Config
+}
+
+func NewPassthroughHLSMediaProcessor(params PassthroughHLSMediaProcessorParams) *PassthroughHLSMediaProcessor {
+	processor := &PassthroughHLSMediaProcessor{
+		LowLatency: params.Config.LowLatency,
+		config:     params.Config,
+	}
+
+	if processor.LowLatency {
+		processor.lowLatencyPlaylist = NewLowLatencyPlaylist()
+	}
+
+	return processor
+}
@@ -0,0 +1,516 @@
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/aac"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/opus"
+)
+
+const (
+	opusSampleRate     = 48000
+	opusChannels       = 2
+	opusDefaultSamples = 960
+
+	// Init segment waits the audio of the publisher which starts slightly after video
+	passthroughAudioProbe = time.Second
+	// Audio is dropped when video doesn't take it
+	passthroughMaxQueuedAudio = 1024
+	// Master playlist is written when the bandwidth is known
+	passthroughBandwidthProbe = 2 * time.Second
+	// Reordered picture can't be far from its decode position
+	maxReorderDistance = 16
+)
+
+// Output of the segmenter. LowLatencyPlaylist takes fragments as parts, segmentPlaylist as segments
+type fragmentSink interface {
+	SetInit(data []byte)
+	AddPart(fragment *fmp4.Fragment)
+}
+
+type audioSample struct {
+	data []byte
+	// Continuous time of the source in the track timescale
+	mediaTime uint64
+	duration  uint32
+}
+
+// Audio of the passthrough. Source timestamps are placed on the video timeline by the least delayed arrival
+type passthroughAudio struct {
+	start  time.Time
+	config *fmp4.AudioConfig
+	// Closed when config is known
+	ready chan struct{}
+
+	samples   []*audioSample
+	offset    time.Duration
+	hasOffset bool
+	frozen    bool
+	discarded bool
+
+	mx sync.Mutex
+}
+
+func newPassthroughAudio(start time.Time) *passthroughAudio {
+	return &passthroughAudio{start: start, ready: make(chan struct{})}
+}
+
+func (a *passthroughAudio) setConfig(config *fmp4.AudioConfig) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	a.config = config
+	close(a.ready)
+}
+
+func (a *passthroughAudio) push(sample *audioSample, arrival time.Time) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if a.discarded {
+		return
+	}
+
+	// Arrival is late by the muxer buffering, the least late one is the closest to the capture
+	if !a.frozen {
+		offset := arrival.Sub(a.start) - fromTimescale(sample.mediaTime, uint64(a.config.SampleRate))
+		if offset < 0 {
+			offset = 0
+		}
+		if !a.hasOffset || offset < a.offset {
+			a.offset, a.hasOffset = offset, true
+		}
+	}
+
+	a.samples = append(a.samples, sample)
+	if len(a.samples) > passthroughMaxQueuedAudio {
+		a.samples = a.samples[len(a.samples)-passthroughMaxQueuedAudio:]
+	}
+}
+
+// Stop collecting audio which is not a part of the init segment
+func (a *passthroughAudio) discard() {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	a.discarded = true
+	a.samples = nil
+}
+
+// Take samples which start before until. Samples before from are dropped. Timeline offset is fixed on the first take
+func (a *passthroughAudio) take(from, until time.Duration) (uint64, []fmp4.Sample) {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	a.frozen = true
+	rate := uint64(a.config.SampleRate)
+	offset := toTimescale(a.offset, rate)
+	fromTime := toTimescale(from, rate)
+	untilTime := toTimescale(until, rate)
+
+	var base uint64
+	var samples []fmp4.Sample
+
+	taken := 0
+	for _, sample := range a.samples {
+		dts := offset + sample.mediaTime
+		if dts >= untilTime {
+			break
+		}
+		taken++
+		if dts < fromTime {
+			continue
+		}
+
+		if len(samples) == 0 {
+			base = dts
+		}
+		samples = append(samples, fmp4.Sample{Data: sample.data, Duration: sample.duration, Sync: true})
+	}
+	a.samples = a.samples[taken:]
+
+	return base, samples
+}
+
+// Read WebM opus of the webrtc and srt ingress or ADTS of the rtmp and srt ingress.
+// Unknown audio is drained, so it doesn't block the ingress
+func (a *passthroughAudio) read(source io.Reader) {
+	reader := bufio.NewReader(source)
+	defer io.Copy(io.Discard, reader)
+
+	head, err := reader.Peek(4)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case opus.IsWebm(head):
+		a.readOpus(reader)
+	case aac.IsADTS(head):
+		a.readAAC(reader)
+	default:
+		log.Println("[HLS Passthrough] Unsupported audio. Stream has video only")
+	}
+}
+
+func (a *passthroughAudio) readOpus(source io.Reader) {
+	reader, err := opus.NewWebmOpusReader(source)
+	if err != nil {
+		log.Println("[HLS Passthrough] Cannot read opus. Err:", err)
+		return
+	}
+	defer reader.Close()
+
+	a.setConfig(&fmp4.AudioConfig{Codec: fmp4.AudioCodecOpus, SampleRate: opusSampleRate, Channels: opusChannels})
+
+	// Frame duration is known on the next frame
+	var held *audioSample
+	var heldTimestamp int64
+	var heldArrival time.Time
+	var mediaTime uint64
+
+	for {
+		frame, timestamp, err := reader.Read()
+		if err != nil {
+			return
+		}
+		arrival := time.Now()
+
+		if held != nil {
+			duration := uint32(opusDefaultSamples)
+			if delta := timestamp - heldTimestamp; delta > 0 {
+				duration = uint32(delta * opusSampleRate / 1000)
+			}
+			held.duration = duration
+			a.push(held, heldArrival)
+			mediaTime += uint64(duration)
+		}
+
+		held = &audioSample{data: frame, mediaTime: mediaTime}
+		heldTimestamp, heldArrival = timestamp, arrival
+	}
+}
+
+func (a *passthroughAudio) readAAC(source io.Reader) {
+	reader := aac.NewADTSReader(source)
+
+	var mediaTime uint64
+	for {
+		frame, err := reader.Read()
+		if err != nil {
+			return
+		}
+
+		if mediaTime == 0 {
+			a.setConfig(&fmp4.AudioConfig{
+				Codec:      fmp4.AudioCodecAAC,
+				SampleRate: uint32(frame.SampleRate),
+				Channels:   uint16(frame.Channels),
+				Config:     frame.AudioSpecificConfig(),
+			})
+		}
+
+		a.push(&audioSample{data: frame.Data, mediaTime: mediaTime, duration: aac.SamplesPerFrame}, time.Now())
+		mediaTime += aac.SamplesPerFrame
+	}
+}
+
+// Picture waits the next one to know its duration
+type passthroughPicture struct {
+	data []byte
+	dts  uint64
+	sync bool
+	// Display position relative to the decode position of the same GOP
+	reorder int64
+	// First IDR of the next publisher
+	discontinuity bool
+}
+
+// Pack H264 access units and audio into fMP4 fragments. Fragment is cut on IDR after minDuration
+// or before it exceeds maxDuration. Zero maxDuration is not limited
+type passthroughSegmenter struct {
+	start       time.Time
+	sink        fragmentSink
+	audio       *passthroughAudio
+	minDuration time.Duration
+	maxDuration time.Duration
+
+	// Called once with codecs and bandwidth of the output
+	onReady func(codecs string, width, height int, bandwidth int64)
+	// Wall clock of the publisher for the local arrival time
+	senderTime func(local time.Time) time.Time
+
+	sps         *h264.SPS
+	spsNALU     []byte
+	ppsNALU     []byte
+	poc         *h264.PicOrderCounter
+	initialized bool
+	withAudio   bool
+
+	held        *passthroughPicture
+	lastPOC     int64
+	decodeIndex int64
+	reordered   bool
+
+	pending         []fmp4.Sample
+	pendingBase     uint64
+	pendingDuration uint64
+	sequence        uint32
+	flushed         bool
+
+	probeBytes    int
+	probeDuration time.Duration
+	ready         bool
+
+	// Marked by the next publisher. Its first IDR starts the discontinuous fragment
+	discontinuity        atomic.Bool
+	pendingDiscontinuity bool
+}
+
+func newPassthroughSegmenter(start time.Time, sink fragmentSink, audio *passthroughAudio, minDuration, maxDuration time.Duration) *passthroughSegmenter {
+	return &passthroughSegmenter{
+		start:       start,
+		sink:        sink,
+		audio:       audio,
+		minDuration: minDuration,
+		maxDuration: maxDuration,
+		senderTime:  func(local time.Time) time.Time { return local },
+	}
+}
+
+// Conversions are split by seconds, so long streams don't overflow
+func toTimescale(duration time.Duration, timescale uint64) uint64 {
+	return uint64(duration/time.Second)*timescale + uint64(duration%time.Second)*timescale/uint64(time.Second)
+}
+
+func fromTimescale(ticks, timescale uint64) time.Duration {
+	return time.Duration(ticks/timescale)*time.Second + time.Duration(ticks%timescale*uint64(time.Second)/timescale)
+}
+
+func ticksToDuration(ticks uint64) time.Duration {
+	return fromTimescale(ticks, fmp4.VideoTimescale)
+}
+
+func (s *passthroughSegmenter) updateParameterSets(au *h264.AccessUnit) {
+	for _, nalu := range au.NALUs {
+		switch h264.NALUType(nalu) {
+		case h264.NALUTypeSPS:
+			sps, err := h264.ParseSPS(nalu)
+			if err != nil {
+				log.Println("[HLS Passthrough] Skip sps. Err:", err)
+				continue
+			}
+			if s.sps == nil {
+				s.sps, s.poc = sps, h264.NewPicOrderCounter(sps)
+			}
+			if s.spsNALU == nil {
+				s.spsNALU = nalu
+			}
+		case h264.NALUTypePPS:
+			if s.ppsNALU == nil {
+				s.ppsNALU = nalu
+			}
+		}
+	}
+}
+
+// Init segment is written on the first IDR. Audio is included when it's known in the probe time
+func (s *passthroughSegmenter) initialize() {
+	select {
+	case <-s.audio.ready:
+		s.withAudio = true
+	case <-time.After(passthroughAudioProbe):
+		s.audio.discard()
+	}
+
+	video := &fmp4.VideoConfig{
+		SPS:    s.spsNALU,
+		PPS:    s.ppsNALU,
+		Width:  uint16(s.sps.Width),
+		Height: uint16(s.sps.Height),
+	}
+
+	var audio *fmp4.AudioConfig
+	if s.withAudio {
+		audio = s.audio.config
+	}
+
+	s.sink.SetInit(fmp4.WriteInit(video, audio))
+	s.initialized = true
+
+	log.Printf("[HLS Passthrough] Packing %dx%d %s with audio %t", s.sps.Width, s.sps.Height, s.sps.Codec(), s.withAudio)
+}
+
+func (s *passthroughSegmenter) Push(au *h264.AccessUnit) {
+	s.updateParameterSets(au)
+
+	if !s.initialized {
+		// Output must start from IDR with known parameter sets
+		if !au.IDR() || s.sps == nil || s.ppsNALU == nil {
+			return
+		}
+		s.initialize()
+	}
+
+	dts := toTimescale(au.Time.Sub(s.start), fmp4.VideoTimescale)
+	if s.held != nil && dts <= s.held.dts {
+		dts = s.held.dts + 1
+	}
+
+	picture := &passthroughPicture{data: h264.AVCC(au.NALUs), dts: dts, sync: au.IDR()}
+	if picture.sync && s.discontinuity.CompareAndSwap(true, false) {
+		picture.discontinuity = true
+	}
+
+	if picture.sync {
+		s.decodeIndex = 0
+	}
+	if poc, ok := s.poc.Next(au); ok {
+		// Pictures are in decode order until the first one shown before previous
+		if !picture.sync && poc < s.lastPOC {
+			s.reordered = true
+		}
+		s.lastPOC = poc
+		// Frame pictures count by two
+		picture.reorder = poc/2 - s.decodeIndex
+	}
+	s.decodeIndex++
+
+	if s.held != nil {
+		s.append(s.held, dts-s.held.dts)
+	}
+	s.held = picture
+}
+
+func (s *passthroughSegmenter) MarkDiscontinuity() {
+	s.discontinuity.Store(true)
+}
+
+func (s *passthroughSegmenter) append(picture *passthroughPicture, duration uint64) {
+	if len(s.pending) > 0 {
+		pendingDuration := ticksToDuration(s.pendingDuration)
+		cut := picture.sync && pendingDuration >= s.minDuration
+		if s.maxDuration > 0 && pendingDuration+ticksToDuration(duration) > s.maxDuration {
+			cut = true
+		}
+		if cut || picture.discontinuity {
+			s.flush(picture.dts)
+		}
+	}
+
+	if picture.discontinuity {
+		s.pendingDiscontinuity = true
+	}
+
+	if len(s.pending) == 0 {
+		s.pendingBase = picture.dts
+	}
+
+	var offset int32
+	if s.reordered && picture.reorder > -maxReorderDistance && picture.reorder < maxReorderDistance {
+		offset = int32(picture.reorder * int64(duration))
+	}
+
+	s.pending = append(s.pending, fmp4.Sample{
+		Data:              picture.data,
+		Duration:          uint32(duration),
+		CompositionOffset: offset,
+		Sync:              picture.sync,
+	})
+	s.pendingDuration += duration
+}
+
+func (s *passthroughSegmenter) flush(end uint64) {
+	tracks := []*fmp4.TrackFragment{{
+		TrackID:             fmp4.VideoTrackID,
+		BaseMediaDecodeTime: s.pendingBase,
+		Samples:             s.pending,
+	}}
+
+	if s.withAudio {
+		// Audio before the first picture can't be played
+		from := time.Duration(0)
+		if !s.flushed {
+			from = ticksToDuration(s.pendingBase)
+		}
+
+		base, samples := s.audio.take(from, ticksToDuration(end))
+		if len(samples) > 0 {
+			tracks = append(tracks, &fmp4.TrackFragment{
+				TrackID:             fmp4.AudioTrackID,
+				BaseMediaDecodeTime: base,
+				Samples:             samples,
+			})
+		}
+	}
+
+	fragment := &fmp4.Fragment{
+		Data:            fmp4.WriteFragment(s.sequence, tracks...),
+		Duration:        ticksToDuration(end - s.pendingBase),
+		Independent:     s.pending[0].Sync,
+		Discontinuity:   s.pendingDiscontinuity,
+		ProgramDateTime: s.senderTime(s.start.Add(ticksToDuration(s.pendingBase))),
+	}
+	s.sink.AddPart(fragment)
+	s.probe(fragment)
+
+	s.sequence++
+	s.flushed = true
+	s.pendingDiscontinuity = false
+	s.pending = nil
+	s.pendingDuration = 0
+}
+
+func (s *passthroughSegmenter) probe(fragment *fmp4.Fragment) {
+	if s.ready {
+		return
+	}
+
+	s.probeBytes += len(fragment.Data)
+	s.probeDuration += fragment.Duration
+	if s.probeDuration < passthroughBandwidthProbe {
+		return
+	}
+	s.ready = true
+
+	codecs := s.sps.Codec()
+	if s.withAudio {
+		codecs += "," + audioCodecString(s.audio.config)
+	}
+	bandwidth := int64(s.probeBytes) * 8 * int64(time.Second) / int64(s.probeDuration)
+
+	if s.onReady != nil {
+		s.onReady(codecs, s.sps.Width, s.sps.Height, bandwidth)
+	}
+}
+
+// Flush the held picture with the duration of the previous one
+func (s *passthroughSegmenter) Finish() {
+	if s.held == nil {
+		return
+	}
+
+	duration := uint64(fmp4.VideoTimescale / 30)
+	if len(s.pending) > 0 {
+		duration = uint64(s.pending[len(s.pending)-1].Duration)
+	}
+	s.append(s.held, duration)
+	s.held = nil
+
+	s.flush(s.pendingBase + s.pendingDuration)
+}
+
+func audioCodecString(config *fmp4.AudioConfig) string {
+	if config.Codec == fmp4.AudioCodecAAC && len(config.Config) > 0 {
+		return fmt.Sprintf("mp4a.40.%d", config.Config[0]>>3)
+	}
+	return "opus"
+}
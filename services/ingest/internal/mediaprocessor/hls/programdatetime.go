@@ -0,0 +1,76 @@
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/mpegts"
+)
+
+const (
+	programDateTimeTag = "#EXT-X-PROGRAM-DATE-TIME:"
+	// Milliseconds are enough for players
+	programDateTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+	mpegtsTimescale = 90_000
+)
+
+// Tag of the next segment. Zero time is unknown and has no tag
+func writeProgramDateTime(playlist *strings.Builder, programDateTime time.Time) {
+	if programDateTime.IsZero() {
+		return
+	}
+	playlist.WriteString(programDateTimeTag)
+	playlist.WriteString(programDateTime.UTC().Format(programDateTimeFormat))
+	playlist.WriteString("\n")
+}
+
+// Tag each segment of the playlist with its wall clock. Segment which time is unknown keeps the tags of the playlist
+func withProgramDateTime(playlist []byte, segmentTime func(segment string) (time.Time, bool)) []byte {
+	var result strings.Builder
+	// Tags of the next segment
+	var tags []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			tags = append(tags, line)
+			continue
+		}
+
+		programDateTime, ok := segmentTime(line)
+		for _, tag := range tags {
+			if ok && strings.HasPrefix(tag, programDateTimeTag) {
+				continue
+			}
+			result.WriteString(tag)
+			result.WriteString("\n")
+		}
+		if ok {
+			writeProgramDateTime(&result, programDateTime)
+		}
+		result.WriteString(line)
+		result.WriteString("\n")
+		tags = nil
+	}
+
+	for _, tag := range tags {
+		result.WriteString(tag)
+		result.WriteString("\n")
+	}
+
+	return []byte(result.String())
+}
+
+// Position of the segment pts on the stream timeline. Pts wraps in 33 bits, segment is just behind the live edge
+func timelinePosition(pts uint64, elapsed time.Duration) time.Duration {
+	ticks := toTimescale(elapsed, mpegtsTimescale)
+	if ticks > pts {
+		pts += (ticks - pts + mpegts.PTSWrap/2) / mpegts.PTSWrap * mpegts.PTSWrap
+	}
+	return fromTimescale(pts, mpegtsTimescale)
+}
@@ -0,0 +1,122 @@
+package hls
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/fmp4"
+)
+
+const segmentPlaylistSize = 8
+
+type playlistSegment struct {
+	msn             uint64
+	duration        time.Duration
+	discontinuity   bool
+	programDateTime time.Time
+}
+
+// Media playlist of fMP4 segments written into the rendition directory. Each fragment is a whole segment.
+// Segments are kept while they are in the dvr window
+type segmentPlaylist struct {
+	dir            string
+	window         time.Duration
+	segments       []playlistSegment
+	duration       time.Duration
+	nextMSN        uint64
+	targetDuration int
+	// Count of discontinuities removed from the playlist
+	discontinuitySequence uint64
+}
+
+func newSegmentPlaylist(dir string, window time.Duration) *segmentPlaylist {
+	return &segmentPlaylist{dir: dir, window: window}
+}
+
+// Oldest segment is removed when the rest of the playlist still covers the window
+func (p *segmentPlaylist) expired() bool {
+	if len(p.segments) <= segmentPlaylistSize {
+		return false
+	}
+	return p.window <= 0 || p.duration-p.segments[0].duration >= p.window
+}
+
+func (p *segmentPlaylist) SetInit(data []byte) {
+	if err := os.WriteFile(filepath.Join(p.dir, initSegmentFile), data, 0o644); err != nil {
+		log.Println("[HLS Passthrough] Cannot write init segment. Err:", err)
+	}
+}
+
+func (p *segmentPlaylist) AddPart(fragment *fmp4.Fragment) {
+	segment := playlistSegment{
+		msn:             p.nextMSN,
+		duration:        fragment.Duration,
+		discontinuity:   fragment.Discontinuity,
+		programDateTime: fragment.ProgramDateTime,
+	}
+	p.nextMSN++
+
+	if err := os.WriteFile(filepath.Join(p.dir, segmentFile(segment.msn)), fragment.Data, 0o644); err != nil {
+		log.Println("[HLS Passthrough] Cannot write segment. Err:", err)
+		return
+	}
+
+	p.segments = append(p.segments, segment)
+	p.duration += segment.duration
+	for p.expired() {
+		// Keep the removed segment one more playlist, players may still load it
+		if p.segments[0].msn > 0 {
+			_ = os.Remove(filepath.Join(p.dir, segmentFile(p.segments[0].msn-1)))
+		}
+		if p.segments[0].discontinuity {
+			p.discontinuitySequence++
+		}
+		p.duration -= p.segments[0].duration
+		p.segments = p.segments[1:]
+	}
+
+	// Target duration must not decrease
+	if duration := int(math.Round(segment.duration.Seconds())); duration > p.targetDuration {
+		p.targetDuration = duration
+	}
+
+	// Rename is atomic, so the player never reads partial playlist
+	playlist := filepath.Join(p.dir, variantPlaylistFile)
+	temp := playlist + ".tmp"
+	if err := os.WriteFile(temp, []byte(p.render()), 0o644); err != nil {
+		log.Println("[HLS Passthrough] Cannot write playlist. Err:", err)
+		return
+	}
+	if err := os.Rename(temp, playlist); err != nil {
+		log.Println("[HLS Passthrough] Cannot write playlist. Err:", err)
+	}
+}
+
+func (p *segmentPlaylist) render() string {
+	var playlist strings.Builder
+
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:7\n")
+	playlist.WriteString(fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", p.targetDuration))
+	playlist.WriteString(fmt.Sprintf("#EXT-X-MEDIA-SEQUENCE:%d\n", p.segments[0].msn))
+	if p.discontinuitySequence > 0 {
+		playlist.WriteString(fmt.Sprintf("#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", p.discontinuitySequence))
+	}
+	playlist.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	playlist.WriteString(fmt.Sprintf("#EXT-X-MAP:URI=\"%s\"\n", initSegmentFile))
+
+	for _, segment := range p.segments {
+		if segment.discontinuity {
+			playlist.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		writeProgramDateTime(&playlist, segment.programDateTime)
+		playlist.WriteString(fmt.Sprintf("#EXTINF:%s,\n%s\n", formatDuration(segment.duration), segmentFile(segment.msn)))
+	}
+
+	return playlist.String()
+}
@@ -3,9 +3,16 @@ package mediaprocessor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
+	"github.com/romashorodok/stream-platform/pkg/variables"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/dash"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/recording"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/restream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/thumbnail"
 	"go.uber.org/fx"
 )
 
@@ -14,7 +21,38 @@ type MediaProcessor interface {
 	Destroy()
 }
 
-var _ MediaProcessor = (*hls.FFmpegHLSMediaProcessor)(nil)
+// Processor which output shows that the next publisher continues the stream
+type DiscontinuityMarker interface {
+	MarkDiscontinuity()
+}
+
+// Processor which tags its output with the wall clock of the publisher. Clock is set before the processor starts
+type WallClockReceiver interface {
+	SetWallClock(clock *rtp.Clock)
+}
+
+// Processor which reads raw h264 and audio of separate pipes. Other processors read them in one matroska stream
+// of the video pipe timed by the stream clock
+type SeparateInputReceiver interface {
+	SeparateInput()
+}
+
+var (
+	_ MediaProcessor = (*hls.FFmpegHLSMediaProcessor)(nil)
+	_ MediaProcessor = (*hls.PassthroughHLSMediaProcessor)(nil)
+	_ MediaProcessor = (*dash.FFmpegDASHMediaProcessor)(nil)
+	_ MediaProcessor = (*recording.FFmpegRecordingMediaProcessor)(nil)
+	_ MediaProcessor = (*restream.FFmpegRestreamMediaProcessor)(nil)
+	_ MediaProcessor = (*thumbnail.FFmpegThumbnailMediaProcessor)(nil)
+
+	_ DiscontinuityMarker = (*hls.PassthroughHLSMediaProcessor)(nil)
+
+	_ SeparateInputReceiver = (*hls.PassthroughHLSMediaProcessor)(nil)
+
+	_ WallClockReceiver = (*hls.FFmpegHLSMediaProcessor)(nil)
+	_ WallClockReceiver = (*hls.PassthroughHLSMediaProcessor)(nil)
+	_ WallClockReceiver = (*recording.FFmpegRecordingMediaProcessor)(nil)
+)
 
 func CastMediaProcessor[F any](target any) (*F, error) {
 	processor, ok := target.(*F)
@@ -24,19 +62,74 @@ func CastMediaProcessor[F any](target any) (*F, error) {
 	return processor, nil
 }
 
-func AsMediaProcessor[F any](mediaProcessor F, labels ...string) any {
+// Provide the factory into the registry group
+func AsMediaProcessorFactory[F any](factory F) any {
 	return fx.Annotate(
-		mediaProcessor,
-		fx.As(new(MediaProcessor)),
-		// NOTE: cannot use fx.From with fx.ResultTags to provide it as typed struct
-		fx.ResultTags(labels...),
+		factory,
+		fx.ResultTags(`group:"mediaprocessor"`),
 	)
 }
 
-var FxDefaultHLSMediaProcessor = AsMediaProcessor(
-	hls.NewFFmpegHLSMediaProcessor,
-	`name:"mediaprocessor.hls.default"`,
+const (
+	HLSMediaProcessor  = variables.INGEST_MEDIA_PROCESSOR_HLS
+	DASHMediaProcessor = variables.INGEST_MEDIA_PROCESSOR_DASH
 
-	`group:"mediaprocessor.hls"`,
-	`group:"mediaprocessor"`,
+	RecordingMediaProcessor = variables.INGEST_MEDIA_PROCESSOR_RECORDING
+	RestreamMediaProcessor  = variables.INGEST_MEDIA_PROCESSOR_RESTREAM
+	ThumbnailMediaProcessor = variables.INGEST_MEDIA_PROCESSOR_THUMBNAIL
 )
+
+// Processors of the stream serve routes scoped by the stream key
+func NewHLSMediaProcessorFactory(config *hls.Config) MediaProcessorFactory {
+	return NewMediaProcessorFactory(HLSMediaProcessor, func(key string) (MediaProcessor, error) {
+		// Variant playlists are relative to the stream manifest route
+		if config.Passthrough {
+			processor := hls.NewPassthroughHLSMediaProcessor(hls.PassthroughHLSMediaProcessorParams{Config: config})
+			processor.PlaylistPrefixURL = fmt.Sprintf("%s/", key)
+			return processor, nil
+		}
+
+		processor := hls.NewFFmpegHLSMediaProcessor(hls.FFmpegHLSMediaProcessorParams{Config: config})
+		processor.PlaylistPrefixURL = fmt.Sprintf("%s/", key)
+		return processor, nil
+	})
+}
+
+// DASH renditions are the same as of the HLS ladder
+func NewDASHMediaProcessorFactory(config *hls.Config) MediaProcessorFactory {
+	return NewMediaProcessorFactory(DASHMediaProcessor, func(key string) (MediaProcessor, error) {
+		return dash.NewFFmpegDASHMediaProcessor(dash.FFmpegDASHMediaProcessorParams{HLSConfig: config}), nil
+	})
+}
+
+// Recordings are grouped by the stream key
+func NewRecordingMediaProcessorFactory(config *recording.Config) MediaProcessorFactory {
+	return NewMediaProcessorFactory(RecordingMediaProcessor, func(key string) (MediaProcessor, error) {
+		processor := recording.NewFFmpegRecordingMediaProcessor(recording.FFmpegRecordingMediaProcessorParams{Config: config})
+		processor.Key = key
+		return processor, nil
+	})
+}
+
+// Each stream relays to the same targets of the ingest
+func NewRestreamMediaProcessorFactory(config *restream.Config) MediaProcessorFactory {
+	return NewMediaProcessorFactory(RestreamMediaProcessor, func(key string) (MediaProcessor, error) {
+		return restream.NewFFmpegRestreamMediaProcessor(restream.FFmpegRestreamMediaProcessorParams{Config: config}), nil
+	})
+}
+
+func NewThumbnailMediaProcessorFactory(config *thumbnail.Config) MediaProcessorFactory {
+	return NewMediaProcessorFactory(ThumbnailMediaProcessor, func(key string) (MediaProcessor, error) {
+		return thumbnail.NewFFmpegThumbnailMediaProcessor(thumbnail.FFmpegThumbnailMediaProcessorParams{Config: config}), nil
+	})
+}
+
+var FxHLSMediaProcessorFactory = AsMediaProcessorFactory(NewHLSMediaProcessorFactory)
+
+var FxDASHMediaProcessorFactory = AsMediaProcessorFactory(NewDASHMediaProcessorFactory)
+
+var FxRecordingMediaProcessorFactory = AsMediaProcessorFactory(NewRecordingMediaProcessorFactory)
+
+var FxRestreamMediaProcessorFactory = AsMediaProcessorFactory(NewRestreamMediaProcessorFactory)
+
+var FxThumbnailMediaProcessorFactory = AsMediaProcessorFactory(NewThumbnailMediaProcessorFactory)
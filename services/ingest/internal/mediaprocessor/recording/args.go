@@ -0,0 +1,44 @@
+package recording
+
+import (
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+)
+
+const (
+	FormatMKV = "mkv"
+	FormatMP4 = "mp4"
+)
+
+var formatContentTypes = map[string]string{
+	FormatMKV: "video/x-matroska",
+	FormatMP4: "video/mp4",
+}
+
+// Ffmpeg args of the recording. Matroska keeps codecs of the source. Fragmented mp4 keeps h264,
+// other video is transcoded because mp4 has no VP8, audio is AAC
+func ffmpegArgs(format string, h264 bool, audio, output string) []string {
+	args := []string{"-map", "0:v", "-map", audio}
+
+	switch format {
+	case FormatMP4:
+		if h264 {
+			args = append(args, "-c:v", "copy")
+		} else {
+			args = append(args, hls.VideoCodecArgs(hls.KeyframeInterval)...)
+		}
+		args = append(args,
+			"-c:a", "aac",
+			"-f", "mp4",
+			// Fragments are written as they arrive. Recording is playable even when ffmpeg is killed
+			"-movflags", "frag_keyframe+empty_moov+default_base_moof",
+		)
+	default:
+		args = append(args,
+			"-c:v", "copy",
+			"-c:a", "copy",
+			"-f", "matroska",
+		)
+	}
+
+	return append(args, "-y", output)
+}
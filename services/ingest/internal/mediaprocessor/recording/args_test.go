@@ -0,0 +1,22 @@
+package recording
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFfmpegArgs(t *testing.T) {
+	// Muxed webrtc input has the audio along the video
+	args := strings.Join(ffmpegArgs(FormatMKV, false, "0:a", "out.mkv"), " ")
+	assert.Equal(t, "-map 0:v -map 0:a -c:v copy -c:a copy -f matroska -y out.mkv", args)
+
+	args = strings.Join(ffmpegArgs(FormatMP4, true, "1:a", "out.mp4"), " ")
+	assert.Contains(t, args, "-c:v copy -c:a aac -f mp4")
+	assert.Contains(t, args, "-movflags frag_keyframe+empty_moov+default_base_moof")
+
+	// Mp4 has no VP8
+	args = strings.Join(ffmpegArgs(FormatMP4, false, "0:a", "out.mp4"), " ")
+	assert.Contains(t, args, "-c:v libx264")
+}
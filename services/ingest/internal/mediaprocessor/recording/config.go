@@ -0,0 +1,51 @@
+package recording
+
+import (
+	"log"
+	"os"
+
+	"github.com/romashorodok/stream-platform/pkg/envutils"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/s3"
+)
+
+// Recordings of the broadcasts. Finalized recording is uploaded when storage is set
+type Config struct {
+	Format    string
+	Directory string
+	Storage   *s3.Client
+}
+
+func NewConfig() *Config {
+	format := envutils.Env(variables.INGEST_RECORDING_FORMAT, variables.INGEST_RECORDING_FORMAT_DEFAULT)
+	if _, ok := formatContentTypes[format]; !ok {
+		log.Printf("[ERROR] wrong recording format %s. Fallback to %s", format, variables.INGEST_RECORDING_FORMAT_DEFAULT)
+		format = variables.INGEST_RECORDING_FORMAT_DEFAULT
+	}
+
+	config := &Config{
+		Format:    format,
+		Directory: envutils.Env(variables.INGEST_RECORDING_DIRECTORY, variables.INGEST_RECORDING_DIRECTORY_DEFAULT),
+	}
+
+	endpoint := envutils.Env(variables.INGEST_RECORDING_S3_ENDPOINT, variables.INGEST_RECORDING_S3_ENDPOINT_DEFAULT)
+	if endpoint == "" {
+		return config
+	}
+
+	storage, err := s3.NewClient(
+		endpoint,
+		envutils.Env(variables.INGEST_RECORDING_S3_REGION, variables.INGEST_RECORDING_S3_REGION_DEFAULT),
+		envutils.Env(variables.INGEST_RECORDING_S3_BUCKET, variables.INGEST_RECORDING_S3_BUCKET_DEFAULT),
+		// Credentials must not be logged by envutils
+		os.Getenv(variables.INGEST_RECORDING_S3_ACCESS_KEY),
+		os.Getenv(variables.INGEST_RECORDING_S3_SECRET_KEY),
+	)
+	if err != nil {
+		log.Printf("[ERROR] wrong recording storage. Err: %s. Recordings are kept only on disk", err)
+		return config
+	}
+	config.Storage = storage
+
+	return config
+}
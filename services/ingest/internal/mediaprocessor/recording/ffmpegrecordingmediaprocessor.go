@@ -0,0 +1,193 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/namedpipe"
+	"go.uber.org/fx"
+)
+
+const (
+	// Ffmpeg finalizes the recording on interrupt. It's killed when finalizing takes longer
+	finalizeTimeout = 10 * time.Second
+	fileTimeFormat  = "20060102T150405Z"
+	// Upload must keep at least 1 MiB per second after the minute of the start
+	uploadStartTimeout = time.Minute
+	uploadRate         = 1024 * 1024
+)
+
+// Time of the upload of the recording with the size
+func uploadTimeout(size int64) time.Duration {
+	return uploadStartTimeout + time.Duration(size/uploadRate)*time.Second
+}
+
+// Record the broadcast into the file of the stream directory. Recording survives the stream and is uploaded into storage
+type FFmpegRecordingMediaProcessor struct {
+	Key       string
+	Format    string
+	Directory string
+	// Recording file of the broadcast. Empty until recording started
+	File string
+
+	config         *Config
+	audioNamedPipe *namedpipe.NamedPipe
+	wallClock      *mediartp.Clock
+}
+
+func (processor *FFmpegRecordingMediaProcessor) SetWallClock(clock *mediartp.Clock) {
+	processor.wallClock = clock
+}
+
+// Wall clock of the publisher when it's known
+func (processor *FFmpegRecordingMediaProcessor) now() time.Time {
+	if processor.wallClock != nil {
+		return processor.wallClock.SenderTime(time.Now())
+	}
+	return time.Now()
+}
+
+func (processor *FFmpegRecordingMediaProcessor) Transcode(ctx context.Context, videoSourcePipe *io.PipeReader, audioSourcePipe *io.PipeReader) error {
+	defer processor.Destroy()
+
+	dir := filepath.Join(processor.Directory, processor.Key)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Println("[Recording Processor] Cannot create recording dir. Err:", err)
+		return err
+	}
+
+	input, err := hls.NewInput(videoSourcePipe)
+	if err != nil {
+		return err
+	}
+
+	processor.File = filepath.Join(dir, fmt.Sprintf("%s.%s", time.Now().UTC().Format(fileTimeFormat), processor.Format))
+
+	log.Println("[Recording Processor] Recording to", processor.File)
+
+	// Tracks of the matroska input are timed by the stream clock
+	args := input.Args()
+	args = append(args, "-loglevel", "info", "-metadata", "creation_time="+processor.now().UTC().Format(time.RFC3339Nano))
+	args = append(args, ffmpegArgs(processor.Format, input.H264, input.Audio(), processor.File)...)
+
+	ffmpeg := exec.Command("ffmpeg", args...)
+	ffmpeg.Stdin = input.Video
+
+	audioPipe, audioFiles, err := input.OpenAudio(audioSourcePipe)
+	if err != nil {
+		log.Println("[Recording Processor] Cannot open audio pipe. Err:", err)
+		return err
+	}
+	processor.audioNamedPipe = audioPipe
+	ffmpeg.ExtraFiles = audioFiles
+
+	stderr, err := ffmpeg.StderrPipe()
+	if err != nil {
+		log.Println("[Recording Processor] Cannot open stderr. Err:", err)
+		return err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Println("[Recording]", scanner.Text())
+		}
+	}()
+
+	if err := ffmpeg.Start(); err != nil {
+		log.Println("[Recording Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			return
+		}
+
+		log.Println("[Recording Processor] Finalize by context")
+		_ = ffmpeg.Process.Signal(os.Interrupt)
+
+		select {
+		case <-time.After(finalizeTimeout):
+			log.Println("[Recording Processor] Finalize timeout. Kill ffmpeg")
+			_ = ffmpeg.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	err = ffmpeg.Wait()
+	if err != nil && ctx.Err() == nil {
+		log.Println("[Recording Processor] Error when running ffmpeg. Err:", err)
+	}
+
+	// Interrupted ffmpeg exits with error, but the recording is finalized
+	processor.upload()
+
+	return err
+}
+
+// Upload finalized recording. Local file is kept, upload failure must not lose the broadcast
+func (processor *FFmpegRecordingMediaProcessor) upload() {
+	storage := processor.config.Storage
+	if storage == nil {
+		return
+	}
+
+	file, err := os.Open(processor.File)
+	if err != nil {
+		log.Println("[Recording Processor] Cannot open recording. Err:", err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Println("[Recording Processor] Cannot stat recording. Err:", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout(info.Size()))
+	defer cancel()
+
+	object := fmt.Sprintf("%s/%s", processor.Key, filepath.Base(processor.File))
+	if err := storage.UploadObject(ctx, object, file, info.Size(), formatContentTypes[processor.Format]); err != nil {
+		log.Println("[Recording Processor] Cannot upload recording. Err:", err)
+		return
+	}
+
+	log.Printf("[Recording Processor] Uploaded %s into %s bucket", object, storage.Bucket)
+}
+
+func (processor *FFmpegRecordingMediaProcessor) Destroy() {
+	if processor.audioNamedPipe != nil {
+		processor.audioNamedPipe.Close()
+	}
+}
+
+type FFmpegRecordingMediaProcessorParams struct {
+	fx.In
+
+	Config *Config
+}
+
+func NewFFmpegRecordingMediaProcessor(params FFmpegRecordingMediaProcessorParams) *FFmpegRecordingMediaProcessor {
+	return &FFmpegRecordingMediaProcessor{
+		Format:    params.Config.Format,
+		Directory: params.Config.Directory,
+		config:    params.Config,
+	}
+}
@@ -0,0 +1,165 @@
+package mediaprocessor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romashorodok/stream-platform/pkg/envutils"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+	"go.uber.org/fx"
+)
+
+var (
+	NotFoundMediaProcessorFactoryError  = errors.New("media processor factory not found")
+	DuplicateMediaProcessorFactoryError = errors.New("duplicate media processor factory")
+)
+
+// Build new processor for each stream. Processor must not be shared between streams
+type MediaProcessorFactory interface {
+	Name() string
+	New(key string) (MediaProcessor, error)
+}
+
+type mediaProcessorFactory struct {
+	name string
+	new  func(key string) (MediaProcessor, error)
+}
+
+func (f *mediaProcessorFactory) Name() string {
+	return f.name
+}
+
+func (f *mediaProcessorFactory) New(key string) (MediaProcessor, error) {
+	return f.new(key)
+}
+
+func NewMediaProcessorFactory(name string, new func(key string) (MediaProcessor, error)) MediaProcessorFactory {
+	return &mediaProcessorFactory{name: name, new: new}
+}
+
+// Processors which run on each stream, in order
+type Config struct {
+	Processors  []string
+	MaxRestarts int
+	StopTimeout time.Duration
+}
+
+func ParseProcessors(raw string) []string {
+	var processors []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			processors = append(processors, name)
+		}
+	}
+	return processors
+}
+
+func NewConfig() *Config {
+	rawMaxRestarts := envutils.Env(variables.INGEST_MEDIA_PROCESSOR_MAX_RESTARTS, variables.INGEST_MEDIA_PROCESSOR_MAX_RESTARTS_DEFAULT)
+	maxRestarts, err := strconv.Atoi(rawMaxRestarts)
+	if err != nil || maxRestarts < 0 {
+		log.Printf("[ERROR] wrong media processor max restarts %s. Fallback to %s", rawMaxRestarts, variables.INGEST_MEDIA_PROCESSOR_MAX_RESTARTS_DEFAULT)
+		maxRestarts, _ = strconv.Atoi(variables.INGEST_MEDIA_PROCESSOR_MAX_RESTARTS_DEFAULT)
+	}
+
+	rawStopTimeout := envutils.Env(variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT, variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT_DEFAULT)
+	stopTimeout, err := time.ParseDuration(rawStopTimeout)
+	if err != nil || stopTimeout <= 0 {
+		log.Printf("[ERROR] wrong media processor stop timeout %s. Fallback to %s", rawStopTimeout, variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT_DEFAULT)
+		stopTimeout, _ = time.ParseDuration(variables.INGEST_MEDIA_PROCESSOR_STOP_TIMEOUT_DEFAULT)
+	}
+
+	return &Config{
+		Processors:  ParseProcessors(envutils.Env(variables.INGEST_MEDIA_PROCESSORS, variables.INGEST_MEDIA_PROCESSORS_DEFAULT)),
+		MaxRestarts: maxRestarts,
+		StopTimeout: stopTimeout,
+	}
+}
+
+type Registry struct {
+	factories   map[string]MediaProcessorFactory
+	processors  []string
+	maxRestarts int
+	stopTimeout time.Duration
+}
+
+// Fresh processors of the stream in order of the config
+func (r *Registry) Build(key string) ([]MediaProcessor, error) {
+	var processors []MediaProcessor
+	for _, name := range r.processors {
+		processor, err := r.factories[name].New(key)
+		if err != nil {
+			for _, processor := range processors {
+				processor.Destroy()
+			}
+			return nil, fmt.Errorf("unable build %s media processor. Err: %w", name, err)
+		}
+		processors = append(processors, processor)
+	}
+	return processors, nil
+}
+
+// Processors of the stream under supervisors. Crashed processor is replaced by a fresh one of the same factory
+func (r *Registry) Supervise(key string) ([]*Supervisor, error) {
+	processors, err := r.Build(key)
+	if err != nil {
+		return nil, err
+	}
+
+	supervisors := make([]*Supervisor, len(processors))
+	for i, processor := range processors {
+		factory := r.factories[r.processors[i]]
+		supervisors[i] = NewSupervisor(factory.Name(), processor, func() (MediaProcessor, error) {
+			return factory.New(key)
+		}, r.maxRestarts, r.stopTimeout)
+	}
+	return supervisors, nil
+}
+
+// Names of the processors which run on each stream
+func (r *Registry) Processors() []string {
+	return r.processors
+}
+
+type RegistryParams struct {
+	fx.In
+
+	Config    *Config
+	Factories []MediaProcessorFactory `group:"mediaprocessor"`
+}
+
+// Unknown processor of the config fails the start, otherwise stream would silently miss its egress
+func NewRegistry(params RegistryParams) (*Registry, error) {
+	registry := &Registry{
+		factories:   make(map[string]MediaProcessorFactory, len(params.Factories)),
+		maxRestarts: params.Config.MaxRestarts,
+		stopTimeout: params.Config.StopTimeout,
+	}
+
+	for _, factory := range params.Factories {
+		if _, exists := registry.factories[factory.Name()]; exists {
+			return nil, fmt.Errorf("%w %q", DuplicateMediaProcessorFactoryError, factory.Name())
+		}
+		registry.factories[factory.Name()] = factory
+	}
+
+	configured := make(map[string]struct{}, len(params.Config.Processors))
+	for _, name := range params.Config.Processors {
+		if _, exists := registry.factories[name]; !exists {
+			return nil, fmt.Errorf("%w %q", NotFoundMediaProcessorFactoryError, name)
+		}
+		if _, exists := configured[name]; exists {
+			continue
+		}
+		configured[name] = struct{}{}
+		registry.processors = append(registry.processors, name)
+	}
+
+	log.Println("[MediaProcessorRegistry] Stream processors", registry.processors)
+
+	return registry, nil
+}
@@ -0,0 +1,56 @@
+package mediaprocessor
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testProcessor struct {
+	key string
+}
+
+func (p *testProcessor) Transcode(context.Context, *io.PipeReader, *io.PipeReader) error { return nil }
+func (p *testProcessor) Destroy()                                                        {}
+
+func testFactory(name string) MediaProcessorFactory {
+	return NewMediaProcessorFactory(name, func(key string) (MediaProcessor, error) {
+		return &testProcessor{key: key}, nil
+	})
+}
+
+func TestRegistry_Build(t *testing.T) {
+	registry, err := NewRegistry(RegistryParams{
+		Config:    &Config{Processors: ParseProcessors(" dash, hls,dash,")},
+		Factories: []MediaProcessorFactory{testFactory("hls"), testFactory("dash"), testFactory("thumbnail")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dash", "hls"}, registry.Processors())
+
+	first, err := registry.Build("first")
+	assert.Nil(t, err)
+	assert.Len(t, first, 2)
+	assert.Equal(t, "first", first[0].(*testProcessor).key)
+
+	// Each stream gets fresh processors
+	second, err := registry.Build("second")
+	assert.Nil(t, err)
+	assert.NotSame(t, first[0], second[0])
+	assert.Equal(t, "second", second[1].(*testProcessor).key)
+}
+
+func TestNewRegistry(t *testing.T) {
+	_, err := NewRegistry(RegistryParams{
+		Config:    &Config{Processors: []string{"recording"}},
+		Factories: []MediaProcessorFactory{testFactory("hls")},
+	})
+	assert.ErrorIs(t, err, NotFoundMediaProcessorFactoryError)
+
+	_, err = NewRegistry(RegistryParams{
+		Config:    &Config{Processors: []string{"hls"}},
+		Factories: []MediaProcessorFactory{testFactory("hls"), testFactory("hls")},
+	})
+	assert.ErrorIs(t, err, DuplicateMediaProcessorFactoryError)
+}
@@ -0,0 +1,94 @@
+package restream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/romashorodok/stream-platform/pkg/variables"
+)
+
+var InvalidTargetError = errors.New("invalid restream target")
+
+// Outbound RTMP destination of the broadcast
+type Target struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	Key string `json:"key"`
+}
+
+// Publish url of the destination. It contains the key and must not be logged
+func (t Target) PublishURL() string {
+	if t.Key == "" {
+		return t.URL
+	}
+	return strings.TrimSuffix(t.URL, "/") + "/" + t.Key
+}
+
+// Hide the key in the ffmpeg output. Ffmpeg echoes the publish url on connection errors
+func (t Target) Redact(line string) string {
+	if t.Key == "" {
+		return line
+	}
+	line = strings.ReplaceAll(line, t.Key, "<key>")
+	if escaped := url.PathEscape(t.Key); escaped != t.Key {
+		line = strings.ReplaceAll(line, escaped, "<key>")
+	}
+	return line
+}
+
+func (t Target) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("%w. Empty id", InvalidTargetError)
+	}
+
+	target, err := url.Parse(t.URL)
+	if err != nil || target.Host == "" || (target.Scheme != "rtmp" && target.Scheme != "rtmps") {
+		return fmt.Errorf("%w %s. Url must be rtmp or rtmps", InvalidTargetError, t.ID)
+	}
+
+	return nil
+}
+
+func ParseTargets(raw string) ([]Target, error) {
+	var targets []Target
+	if err := json.Unmarshal([]byte(raw), &targets); err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		if err := target.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return targets, nil
+}
+
+type Config struct {
+	Targets []Target
+}
+
+// Targets have stream keys, so they are not logged by envutils
+func NewConfig() *Config {
+	raw := os.Getenv(variables.INGEST_RESTREAM_TARGETS)
+	if raw == "" {
+		raw = variables.INGEST_RESTREAM_TARGETS_DEFAULT
+	}
+
+	targets, err := ParseTargets(raw)
+	if err != nil {
+		log.Printf("[ERROR] wrong restream targets. Err: %s. Restream is disabled", err)
+		return &Config{}
+	}
+
+	for _, target := range targets {
+		log.Printf("[%s]: %s %s", variables.INGEST_RESTREAM_TARGETS, target.ID, target.URL)
+	}
+
+	return &Config{Targets: targets}
+}
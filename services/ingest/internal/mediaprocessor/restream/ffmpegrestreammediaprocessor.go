@@ -0,0 +1,172 @@
+package restream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor/hls"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/namedpipe"
+	"go.uber.org/fx"
+)
+
+const (
+	// Common limit of the streaming platforms
+	maxVideoBitrate = 6000
+	audioBitrate    = 160
+	// Whole transport stream packets, relay may start from any chunk
+	transportChunkSize = 188 * 7
+)
+
+// Transcode the broadcast once into transport stream and relay it to each target by own ffmpeg
+type FFmpegRestreamMediaProcessor struct {
+	Targets []Target
+
+	relays         []*targetRelay
+	audioNamedPipe *namedpipe.NamedPipe
+	mx             sync.RWMutex
+}
+
+func (processor *FFmpegRestreamMediaProcessor) Statuses() []TargetStatus {
+	processor.mx.RLock()
+	defer processor.mx.RUnlock()
+
+	statuses := make([]TargetStatus, 0, len(processor.relays))
+	for _, relay := range processor.relays {
+		statuses = append(statuses, relay.Status())
+	}
+	return statuses
+}
+
+// Tracks of the matroska input are timed by the stream clock
+func ffmpegArgs(input *hls.Input) []string {
+	args := input.Args()
+	args = append(args,
+		"-loglevel", "warning",
+		"-map", "0:v",
+		"-map", input.Audio(),
+	)
+	args = append(args, hls.VideoCodecArgs(hls.KeyframeInterval)...)
+	args = append(args,
+		"-maxrate", fmt.Sprintf("%dk", maxVideoBitrate),
+		"-bufsize", fmt.Sprintf("%dk", maxVideoBitrate*3/4),
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", audioBitrate),
+		"-ar", "48000",
+		"-f", "mpegts",
+		"pipe:1",
+	)
+	return args
+}
+
+func (processor *FFmpegRestreamMediaProcessor) Transcode(ctx context.Context, videoSourcePipe *io.PipeReader, audioSourcePipe *io.PipeReader) error {
+	defer processor.Destroy()
+
+	// Pipes must be read, otherwise other processors of the stream are blocked
+	if len(processor.Targets) == 0 {
+		go io.Copy(io.Discard, audioSourcePipe)
+		_, _ = io.Copy(io.Discard, videoSourcePipe)
+		return nil
+	}
+
+	input, err := hls.NewInput(videoSourcePipe)
+	if err != nil {
+		return err
+	}
+
+	ffmpeg := exec.Command("ffmpeg", ffmpegArgs(input)...)
+	ffmpeg.Stdin = input.Video
+
+	audioPipe, audioFiles, err := input.OpenAudio(audioSourcePipe)
+	if err != nil {
+		log.Println("[Restream Processor] Cannot open audio pipe. Err:", err)
+		return err
+	}
+	processor.audioNamedPipe = audioPipe
+	ffmpeg.ExtraFiles = audioFiles
+
+	stdout, err := ffmpeg.StdoutPipe()
+	if err != nil {
+		log.Println("[Restream Processor] Cannot open stdout. Err:", err)
+		return err
+	}
+	stderr, err := ffmpeg.StderrPipe()
+	if err != nil {
+		log.Println("[Restream Processor] Cannot open stderr. Err:", err)
+		return err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Println("[Restream]", scanner.Text())
+		}
+	}()
+
+	if err := ffmpeg.Start(); err != nil {
+		log.Println("[Restream Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Println("[Restream Processor] Stop by context")
+			_ = ffmpeg.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	relays := make([]*targetRelay, 0, len(processor.Targets))
+	for _, target := range processor.Targets {
+		relay := newTargetRelay(target)
+		relays = append(relays, relay)
+		go relay.Run(ctx)
+	}
+
+	processor.mx.Lock()
+	processor.relays = relays
+	processor.mx.Unlock()
+
+	for {
+		chunk := make([]byte, transportChunkSize)
+		if _, err := io.ReadFull(stdout, chunk); err != nil {
+			break
+		}
+		for _, relay := range relays {
+			relay.Push(chunk)
+		}
+	}
+
+	if err := ffmpeg.Wait(); err != nil && ctx.Err() == nil {
+		log.Println("[Restream Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (processor *FFmpegRestreamMediaProcessor) Destroy() {
+	if processor.audioNamedPipe != nil {
+		processor.audioNamedPipe.Close()
+	}
+}
+
+type FFmpegRestreamMediaProcessorParams struct {
+	fx.In
+
+	Config *Config
+}
+
+func NewFFmpegRestreamMediaProcessor(params FFmpegRestreamMediaProcessorParams) *FFmpegRestreamMediaProcessor {
+	return &FFmpegRestreamMediaProcessor{
+		Targets: params.Config.Targets,
+	}
+}
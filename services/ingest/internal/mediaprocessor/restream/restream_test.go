@@ -0,0 +1,63 @@
+package restream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets(`[
+		{"id":"twitch","url":"rtmp://live.twitch.tv/app/","key":"live_123"},
+		{"id":"youtube","url":"rtmps://a.rtmp.youtube.com/live2","key":"abcd"}
+	]`)
+	assert.Nil(t, err)
+	assert.Len(t, targets, 2)
+	assert.Equal(t, "rtmp://live.twitch.tv/app/live_123", targets[0].PublishURL())
+	assert.Equal(t, "rtmps://a.rtmp.youtube.com/live2/abcd", targets[1].PublishURL())
+
+	_, err = ParseTargets(`[{"id":"web","url":"https://example.com/live","key":"abcd"}]`)
+	assert.ErrorIs(t, err, InvalidTargetError)
+
+	_, err = ParseTargets(`[{"url":"rtmp://example.com/live"}]`)
+	assert.ErrorIs(t, err, InvalidTargetError)
+}
+
+func TestTarget_Redact(t *testing.T) {
+	target := Target{ID: "twitch", URL: "rtmp://live.twitch.tv/app", Key: "live_1?2"}
+
+	assert.Equal(t,
+		"[flv @ 0x1] Failed to connect to rtmp://live.twitch.tv/app/<key>",
+		target.Redact("[flv @ 0x1] Failed to connect to "+target.PublishURL()),
+	)
+	assert.Equal(t, "rtmp://live.twitch.tv/app/<key>", target.Redact("rtmp://live.twitch.tv/app/live_1%3F2"))
+	assert.Equal(t, "Connection refused", Target{ID: "web"}.Redact("Connection refused"))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 2*time.Second, backoff(2))
+	assert.Equal(t, 16*time.Second, backoff(5))
+	assert.Equal(t, maxBackoff, backoff(6))
+	assert.Equal(t, maxBackoff, backoff(100))
+}
+
+func TestTargetRelay_Push(t *testing.T) {
+	relay := newTargetRelay(Target{ID: "twitch", URL: "rtmp://live.twitch.tv/app", Key: "live_123"})
+
+	// Full queue drops chunks instead of blocking the source
+	for i := 0; i < relayQueueSize+10; i++ {
+		relay.Push([]byte{byte(i)})
+	}
+	assert.Len(t, relay.chunks, relayQueueSize)
+
+	relay.drain()
+	assert.Len(t, relay.chunks, 0)
+
+	relay.setState(StateConnecting, nil)
+	status := relay.Status()
+	assert.Equal(t, StateConnecting, status.State)
+	assert.Equal(t, 1, status.Attempts)
+	assert.Equal(t, "rtmp://live.twitch.tv/app", status.URL)
+}
@@ -0,0 +1,206 @@
+package restream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	StateConnecting = "connecting"
+	StateLive       = "live"
+	StateRetrying   = "retrying"
+	StateStopped    = "stopped"
+
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	// Target which was live longer than this starts the backoff from the beginning
+	stableDuration = 30 * time.Second
+
+	// Transport stream chunks buffered for the relay. Chunks are dropped while the relay is down
+	relayQueueSize = 512
+)
+
+// Status of the restream target. Url has no stream key
+type TargetStatus struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	State     string    `json:"state"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// Exponential backoff of the failed attempt. First retry waits initialBackoff
+func backoff(failures int) time.Duration {
+	delay := initialBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// Relay of the transcoded stream to one destination. Each relay retries independently of other targets
+type targetRelay struct {
+	target Target
+	chunks chan []byte
+
+	mx     sync.RWMutex
+	status TargetStatus
+}
+
+func newTargetRelay(target Target) *targetRelay {
+	return &targetRelay{
+		target: target,
+		chunks: make(chan []byte, relayQueueSize),
+		status: TargetStatus{ID: target.ID, URL: target.URL, State: StateConnecting, Since: time.Now()},
+	}
+}
+
+func (r *targetRelay) Status() TargetStatus {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+	return r.status
+}
+
+func (r *targetRelay) setState(state string, err error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.status.State = state
+	r.status.Since = time.Now()
+	if state == StateConnecting {
+		r.status.Attempts++
+	}
+	if err != nil {
+		r.status.LastError = err.Error()
+	}
+}
+
+// Never blocks the source. Chunk is dropped when relay doesn't keep up
+func (r *targetRelay) Push(chunk []byte) {
+	select {
+	case r.chunks <- chunk:
+	default:
+	}
+}
+
+// Stale chunks are dropped before the next attempt, destination gets the live edge
+func (r *targetRelay) drain() {
+	for {
+		select {
+		case <-r.chunks:
+		default:
+			return
+		}
+	}
+}
+
+func (r *targetRelay) Run(ctx context.Context) {
+	failures := 0
+	for ctx.Err() == nil {
+		r.drain()
+		r.setState(StateConnecting, nil)
+
+		started := time.Now()
+		err := r.attempt(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		if time.Since(started) > stableDuration {
+			failures = 0
+		}
+		failures++
+
+		delay := backoff(failures)
+		log.Printf("[Restream] %s target failed. Retry in %s. Err: %s", r.target.ID, delay, err)
+		r.setState(StateRetrying, err)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+
+	r.setState(StateStopped, nil)
+}
+
+// Single ffmpeg run which copies the transport stream into flv of the destination
+func (r *targetRelay) attempt(ctx context.Context) error {
+	ffmpeg := exec.Command("ffmpeg",
+		"-f", "mpegts",
+		"-i", "pipe:0",
+		"-c", "copy",
+		"-loglevel", "warning",
+		"-f", "flv",
+		r.target.PublishURL(),
+	)
+
+	stdin, err := ffmpeg.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := ffmpeg.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := ffmpeg.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Printf("[Restream %s] %s", r.target.ID, r.target.Redact(scanner.Text()))
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer stdin.Close()
+
+		live := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case chunk := <-r.chunks:
+				if _, err := stdin.Write(chunk); err != nil {
+					return
+				}
+				if !live {
+					live = true
+					r.setState(StateLive, nil)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = ffmpeg.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	err = ffmpeg.Wait()
+	close(done)
+
+	if err == nil {
+		err = io.EOF
+	}
+	return fmt.Errorf("ffmpeg exited. %w", err)
+}
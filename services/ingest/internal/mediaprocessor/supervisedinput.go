@@ -0,0 +1,180 @@
+package mediaprocessor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/opus"
+)
+
+const (
+	relayBufferSize = 32 * 1024
+	// Webm without cluster in this size is not joined by header
+	maxWebmHeaderSize = 64 * 1024
+)
+
+var webmClusterID = []byte{0x1F, 0x43, 0xB6, 0x75}
+
+type streamFormat int
+
+const (
+	unknownStream streamFormat = iota
+	// Each chunk starts on the frame, like ADTS frames
+	rawStream
+	webmStream
+	annexBStream
+)
+
+// Restarted processor can't read the stream from any byte. Webm is joined by its header and the next cluster,
+// Annex-B from the next SPS, so the decoder gets parameters before the frames
+type streamJoiner struct {
+	format streamFormat
+	header []byte
+	ready  bool
+}
+
+// Remember the stream head. Must see each chunk of the stream
+func (j *streamJoiner) observe(p []byte) {
+	if j.format == unknownStream {
+		switch {
+		case opus.IsWebm(p):
+			j.format = webmStream
+		case h264.IsAnnexB(p):
+			j.format = annexBStream
+		default:
+			j.format = rawStream
+		}
+	}
+
+	if j.format != webmStream || j.ready {
+		return
+	}
+
+	if i := bytes.Index(p, webmClusterID); i >= 0 {
+		j.header = append(j.header, p[:i]...)
+		j.ready = true
+		return
+	}
+
+	j.header = append(j.header, p...)
+	if len(j.header) > maxWebmHeaderSize {
+		j.header = nil
+		j.format = rawStream
+	}
+}
+
+// Media of the chunk for the joining reader. False when the chunk has no sync point
+func (j *streamJoiner) join(p []byte) ([]byte, bool) {
+	switch j.format {
+	case webmStream:
+		i := bytes.Index(p, webmClusterID)
+		if !j.ready || i < 0 {
+			return nil, false
+		}
+		return append(append([]byte(nil), j.header...), p[i:]...), true
+	case annexBStream:
+		if i := spsIndex(p); i >= 0 {
+			return p[i:], true
+		}
+		return nil, false
+	}
+	return p, true
+}
+
+func spsIndex(p []byte) int {
+	offset := 0
+	for {
+		i := bytes.Index(p[offset:], []byte{0x00, 0x00, 0x01})
+		if i < 0 || offset+i+3 >= len(p) {
+			return -1
+		}
+		start := offset + i
+		if h264.NALUType(p[start+3:]) == h264.NALUTypeSPS {
+			return start
+		}
+		offset = start + 3
+	}
+}
+
+// Stream pipe of the processor which outlives its restarts. Media is dropped while processor is down
+type supervisedInput struct {
+	source *io.PipeReader
+	joiner streamJoiner
+
+	writer *io.PipeWriter
+	joined bool
+	ended  bool
+
+	mx sync.Mutex
+}
+
+func newSupervisedInput(source *io.PipeReader) *supervisedInput {
+	return &supervisedInput{source: source}
+}
+
+// Pipe of the processor run. Restarted processor joins the stream from its sync point
+func (in *supervisedInput) attach(restart bool) *io.PipeReader {
+	reader, writer := io.Pipe()
+
+	in.mx.Lock()
+	defer in.mx.Unlock()
+
+	if in.ended {
+		_ = writer.Close()
+		return reader
+	}
+
+	in.writer = writer
+	in.joined = !restart
+	return reader
+}
+
+func (in *supervisedInput) detach() {
+	in.mx.Lock()
+	defer in.mx.Unlock()
+
+	if in.writer != nil {
+		_ = in.writer.CloseWithError(ProcessorExitedError)
+		in.writer = nil
+	}
+}
+
+func (in *supervisedInput) relay() {
+	buf := make([]byte, relayBufferSize)
+	for {
+		n, err := in.source.Read(buf)
+		if n > 0 {
+			in.forward(buf[:n])
+		}
+		if err != nil {
+			in.mx.Lock()
+			in.ended = true
+			if in.writer != nil {
+				_ = in.writer.Close()
+			}
+			in.mx.Unlock()
+			return
+		}
+	}
+}
+
+func (in *supervisedInput) forward(p []byte) {
+	in.mx.Lock()
+	in.joiner.observe(p)
+
+	writer := in.writer
+	if writer != nil && !in.joined {
+		p, in.joined = in.joiner.join(p)
+	}
+	joined := in.joined
+	in.mx.Unlock()
+
+	if writer == nil || !joined {
+		return
+	}
+
+	// Processor which doesn't read blocks the relay until it's detached
+	_, _ = writer.Write(p)
+}
@@ -0,0 +1,276 @@
+package mediaprocessor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+type ProcessorState string
+
+const (
+	ProcessorStarting ProcessorState = "starting"
+	ProcessorRunning  ProcessorState = "running"
+	ProcessorDegraded ProcessorState = "degraded"
+	ProcessorFailed   ProcessorState = "failed"
+
+	initialRestartBackoff = time.Second
+	maxRestartBackoff     = 30 * time.Second
+	// Processor which runs longer than this is running and its crash loop starts from the beginning
+	stableDuration = 10 * time.Second
+)
+
+var ProcessorExitedError = errors.New("media processor exited")
+
+// Health of the stream processor
+type ProcessorStatus struct {
+	Name      string         `json:"name"`
+	State     ProcessorState `json:"state"`
+	Restarts  int            `json:"restarts"`
+	LastError string         `json:"lastError,omitempty"`
+	Since     time.Time      `json:"since"`
+	// Frames the processor didn't keep up with
+	DroppedFrames uint64 `json:"droppedFrames"`
+}
+
+// Exponential backoff of the crashed processor. First restart waits initialRestartBackoff
+func restartBackoff(failures int) time.Duration {
+	delay := initialRestartBackoff
+	for i := 1; i < failures && delay < maxRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRestartBackoff {
+		return maxRestartBackoff
+	}
+	return delay
+}
+
+// Keep processor of the stream alive. Crashed processor is replaced by a fresh one after backoff and the stream goes on.
+// Processor is starting until it runs stableDuration, then running. Crashed one is degraded until the restarted one is stable.
+// Processor which crashes more than maxRestarts times in a row is failed and stays down till the stream end
+type Supervisor struct {
+	new         func() (MediaProcessor, error)
+	maxRestarts int
+	// Destroy waits the processor exit this long, so it may finalize its output
+	stopTimeout time.Duration
+
+	onStatus  func(ProcessorStatus)
+	onRestart func()
+	onStart   func(MediaProcessor)
+
+	processor MediaProcessor
+	status    ProcessorStatus
+	// Incremented on each crash, so crashed processor is never promoted to running
+	attempt uint64
+
+	started bool
+	// Closed when Run returns
+	done chan struct{}
+
+	mx sync.RWMutex
+}
+
+func NewSupervisor(name string, processor MediaProcessor, new func() (MediaProcessor, error), maxRestarts int, stopTimeout time.Duration) *Supervisor {
+	return &Supervisor{
+		new:         new,
+		maxRestarts: maxRestarts,
+		stopTimeout: stopTimeout,
+		done:        make(chan struct{}),
+		processor:   processor,
+		status:      ProcessorStatus{Name: name, State: ProcessorStarting, Since: time.Now()},
+	}
+}
+
+// Current processor. Nil while it's down
+func (s *Supervisor) Processor() MediaProcessor {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.processor
+}
+
+func (s *Supervisor) Status() ProcessorStatus {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.status
+}
+
+// Called on each state change of the processor
+func (s *Supervisor) OnStatus(onStatus func(ProcessorStatus)) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.onStatus = onStatus
+}
+
+// Called when restarted processor starts reading media, so source may send a keyframe
+func (s *Supervisor) OnRestart(onRestart func()) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.onRestart = onRestart
+}
+
+// Called with each processor before it starts reading media
+func (s *Supervisor) OnStart(onStart func(MediaProcessor)) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.onStart = onStart
+}
+
+// Must be called with the lock. Returns callback which must be called without the lock
+func (s *Supervisor) setState(state ProcessorState, err error) func() {
+	s.status.State = state
+	s.status.Since = time.Now()
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+
+	status, onStatus := s.status, s.onStatus
+	return func() {
+		log.Printf("[Supervisor] %s processor is %s", status.Name, status.State)
+		if onStatus != nil {
+			onStatus(status)
+		}
+	}
+}
+
+func (s *Supervisor) promote(attempt uint64) {
+	s.mx.Lock()
+	if s.attempt != attempt {
+		s.mx.Unlock()
+		return
+	}
+	notify := s.setState(ProcessorRunning, nil)
+	s.mx.Unlock()
+
+	notify()
+}
+
+// Crashed processor is already destroyed by its Transcode
+func (s *Supervisor) crash(state ProcessorState, err error) {
+	s.mx.Lock()
+	s.attempt++
+	s.processor = nil
+	notify := s.setState(state, err)
+	s.mx.Unlock()
+
+	notify()
+}
+
+func (s *Supervisor) restart(processor MediaProcessor) (uint64, func()) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.processor = processor
+	s.status.Restarts++
+	return s.attempt, s.onRestart
+}
+
+// Run processor on the stream pipes until context is done. Pipes are read all the time and media is dropped while
+// processor is down, so other processors of the stream are not blocked
+func (s *Supervisor) Run(ctx context.Context, video, audio *io.PipeReader) {
+	s.mx.Lock()
+	s.started = true
+	s.mx.Unlock()
+	defer close(s.done)
+
+	videoInput, audioInput := newSupervisedInput(video), newSupervisedInput(audio)
+
+	go func() {
+		<-ctx.Done()
+		_ = video.Close()
+		_ = audio.Close()
+	}()
+
+	go videoInput.relay()
+	go audioInput.relay()
+
+	failures := 0
+	for {
+		started := time.Now()
+		err := s.run(ctx, videoInput, audioInput, failures > 0)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(started) >= stableDuration {
+			failures = 0
+		}
+		failures++
+
+		if failures > s.maxRestarts {
+			log.Printf("[Supervisor] %s processor crashed %d times in a row. Err: %s", s.Status().Name, failures, err)
+			s.crash(ProcessorFailed, err)
+			return
+		}
+
+		delay := restartBackoff(failures)
+		log.Printf("[Supervisor] %s processor crashed. Restart in %s. Err: %s", s.Status().Name, delay, err)
+		s.crash(ProcessorDegraded, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Single run of the processor. Restarted processor is a fresh one, it joins media of the stream from its next sync point
+func (s *Supervisor) run(ctx context.Context, video, audio *supervisedInput, restart bool) error {
+	s.mx.RLock()
+	processor, attempt, onStart := s.processor, s.attempt, s.onStart
+	s.mx.RUnlock()
+
+	var onRestart func()
+	if restart {
+		var err error
+		if processor, err = s.new(); err != nil {
+			return err
+		}
+		attempt, onRestart = s.restart(processor)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	videoReader, audioReader := video.attach(restart), audio.attach(restart)
+	defer video.detach()
+	defer audio.detach()
+
+	if onStart != nil {
+		onStart(processor)
+	}
+	if onRestart != nil {
+		go onRestart()
+	}
+
+	stable := time.AfterFunc(stableDuration, func() { s.promote(attempt) })
+	defer stable.Stop()
+
+	if err := processor.Transcode(runCtx, videoReader, audioReader); err != nil {
+		return err
+	}
+	return ProcessorExitedError
+}
+
+// Wait the processor exit at the stream end, context of Run must be done. Processor which doesn't exit in stopTimeout is destroyed
+func (s *Supervisor) Destroy() {
+	s.mx.RLock()
+	started := s.started
+	s.mx.RUnlock()
+
+	if started {
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.stopTimeout):
+			log.Printf("[Supervisor] %s processor didn't stop in %s. Destroy it", s.Status().Name, s.stopTimeout)
+		}
+	}
+
+	if processor := s.Processor(); processor != nil {
+		processor.Destroy()
+	}
+}
@@ -0,0 +1,157 @@
+package mediaprocessor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, restartBackoff(1))
+	assert.Equal(t, 4*time.Second, restartBackoff(3))
+	assert.Equal(t, maxRestartBackoff, restartBackoff(10))
+}
+
+var crashError = errors.New("ffmpeg exited")
+
+type crashingProcessor struct{}
+
+func (p *crashingProcessor) Transcode(context.Context, *io.PipeReader, *io.PipeReader) error {
+	return crashError
+}
+func (p *crashingProcessor) Destroy() {}
+
+func TestSupervisor_CrashLoop(t *testing.T) {
+	assert := assert.New(t)
+
+	builds := 0
+	supervisor := NewSupervisor("hls", &crashingProcessor{}, func() (MediaProcessor, error) {
+		builds++
+		return &crashingProcessor{}, nil
+	}, 1, time.Second)
+
+	var states []ProcessorState
+	var mx sync.Mutex
+	supervisor.OnStatus(func(status ProcessorStatus) {
+		mx.Lock()
+		defer mx.Unlock()
+		states = append(states, status.State)
+	})
+
+	videoReader, _ := io.Pipe()
+	audioReader, _ := io.Pipe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	supervisor.Run(ctx, videoReader, audioReader)
+
+	assert.Equal(1, builds)
+	assert.Equal([]ProcessorState{ProcessorDegraded, ProcessorFailed}, states)
+
+	status := supervisor.Status()
+	assert.Equal(1, status.Restarts)
+	assert.Equal(crashError.Error(), status.LastError)
+	assert.Nil(supervisor.Processor())
+}
+
+// Finalizes its output after the context is done. Stuck one never exits until destroyed
+type finalizingProcessor struct {
+	stuck     bool
+	finalized chan struct{}
+	destroyed chan struct{}
+}
+
+func (p *finalizingProcessor) Transcode(ctx context.Context, _ *io.PipeReader, _ *io.PipeReader) error {
+	<-ctx.Done()
+	if p.stuck {
+		<-p.destroyed
+		return nil
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(p.finalized)
+	return nil
+}
+func (p *finalizingProcessor) Destroy() { close(p.destroyed) }
+
+func TestSupervisor_Destroy(t *testing.T) {
+	tests := []struct {
+		name      string
+		stuck     bool
+		finalized bool
+	}{
+		{name: "waits processor finalize", finalized: true},
+		{name: "destroys stuck processor after stop timeout", stuck: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			processor := &finalizingProcessor{stuck: test.stuck, finalized: make(chan struct{}), destroyed: make(chan struct{})}
+			supervisor := NewSupervisor("recording", processor, func() (MediaProcessor, error) {
+				return nil, crashError
+			}, 0, 200*time.Millisecond)
+
+			videoReader, _ := io.Pipe()
+			audioReader, _ := io.Pipe()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go supervisor.Run(ctx, videoReader, audioReader)
+			time.Sleep(10 * time.Millisecond)
+
+			cancel()
+			supervisor.Destroy()
+
+			select {
+			case <-processor.finalized:
+				assert.True(test.finalized)
+			default:
+				assert.False(test.finalized)
+			}
+
+			select {
+			case <-processor.destroyed:
+				assert.True(test.stuck)
+			default:
+				assert.False(test.stuck)
+			}
+		})
+	}
+}
+
+func TestStreamJoiner(t *testing.T) {
+	assert := assert.New(t)
+
+	var webm streamJoiner
+	header := []byte{0x1A, 0x45, 0xDF, 0xA3, 0x01}
+	webm.observe(append(append([]byte(nil), header...), 0x1F, 0x43, 0xB6, 0x75, 0x02))
+
+	_, ok := webm.join([]byte{0x03, 0x04})
+	assert.False(ok)
+
+	joined, ok := webm.join([]byte{0x05, 0x1F, 0x43, 0xB6, 0x75, 0x06})
+	assert.True(ok)
+	assert.Equal([]byte{0x1A, 0x45, 0xDF, 0xA3, 0x01, 0x1F, 0x43, 0xB6, 0x75, 0x06}, joined)
+
+	var annexB streamJoiner
+	annexB.observe([]byte{0x00, 0x00, 0x00, 0x01, 0x67})
+
+	_, ok = annexB.join([]byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a})
+	assert.False(ok)
+
+	joined, ok = annexB.join([]byte{0x9a, 0x00, 0x00, 0x00, 0x01, 0x67, 0x42})
+	assert.True(ok)
+	assert.Equal([]byte{0x00, 0x00, 0x01, 0x67, 0x42}, joined)
+
+	// Frame of adts is joined from the chunk start
+	var adts streamJoiner
+	adts.observe([]byte{0xFF, 0xF1})
+	joined, ok = adts.join([]byte{0xFF, 0xF1, 0x50})
+	assert.True(ok)
+	assert.Equal([]byte{0xFF, 0xF1, 0x50}, joined)
+}
@@ -0,0 +1,72 @@
+package thumbnail
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	FormatJPG  = "jpg"
+	FormatWebP = "webp"
+
+	ThumbnailName = "thumbnail"
+	SpriteName    = "sprite"
+
+	// Sprite keeps the last columns*rows thumbnails, the newest is the last tile
+	SpriteColumns = 5
+	SpriteRows    = 5
+)
+
+var formatContentTypes = map[string]string{
+	FormatJPG:  "image/jpeg",
+	FormatWebP: "image/webp",
+}
+
+var formatCodecArgs = map[string][]string{
+	FormatJPG:  {"-c:v", "mjpeg", "-q:v", "4"},
+	FormatWebP: {"-c:v", "libwebp", "-quality", "75"},
+}
+
+func ContentType(format string) string {
+	return formatContentTypes[format]
+}
+
+func FileName(name, format string) string {
+	return fmt.Sprintf("%s.%s", name, format)
+}
+
+// Image is overwritten on each update. Atomic writing keeps readers from partial images
+func imageArgs(format, output string) []string {
+	args := append([]string{}, formatCodecArgs[format]...)
+	return append(args,
+		"-f", "image2",
+		"-update", "1",
+		"-atomic_writing", "1",
+		output,
+	)
+}
+
+func ffmpegArgs(config *Config, dir string) []string {
+	fps := strconv.FormatFloat(1/config.Interval.Seconds(), 'f', -1, 64)
+	thumbnail := filepath.Join(dir, FileName(ThumbnailName, config.Format))
+
+	if !config.Sprite {
+		args := []string{
+			"-map", "0:v",
+			"-vf", fmt.Sprintf("fps=%s,scale=%d:-2", fps, config.Width),
+		}
+		return append(args, imageArgs(config.Format, thumbnail)...)
+	}
+
+	tiles := SpriteColumns * SpriteRows
+	filter := fmt.Sprintf(
+		"[0:v]fps=%s,scale=%d:-2,split=2[thumb][tiles];[tiles]scale=%d:-2,tile=%dx%d:overlap=%d:init_padding=%d[sprite]",
+		fps, config.Width, config.Width/2, SpriteColumns, SpriteRows, tiles-1, tiles-1,
+	)
+
+	args := []string{"-filter_complex", filter, "-map", "[thumb]"}
+	args = append(args, imageArgs(config.Format, thumbnail)...)
+	args = append(args, "-map", "[sprite]")
+	return append(args, imageArgs(config.Format, filepath.Join(dir, FileName(SpriteName, config.Format)))...)
+}
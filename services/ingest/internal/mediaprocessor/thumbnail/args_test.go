@@ -0,0 +1,28 @@
+package thumbnail
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFfmpegArgs(t *testing.T) {
+	config := &Config{Interval: 10 * time.Second, Width: 320, Format: FormatJPG}
+
+	args := strings.Join(ffmpegArgs(config, "out"), " ")
+	assert.Contains(t, args, "-map 0:v -vf fps=0.1,scale=320:-2")
+	assert.Contains(t, args, "-c:v mjpeg")
+	assert.NotContains(t, args, "tile=")
+	assert.True(t, strings.HasSuffix(args, "-update 1 -atomic_writing 1 out/thumbnail.jpg"))
+
+	config.Sprite = true
+	config.Format = FormatWebP
+
+	args = strings.Join(ffmpegArgs(config, "out"), " ")
+	assert.Contains(t, args, "[0:v]fps=0.1,scale=320:-2,split=2[thumb][tiles];[tiles]scale=160:-2,tile=5x5:overlap=24:init_padding=24[sprite]")
+	assert.Contains(t, args, "-map [thumb] -c:v libwebp")
+	assert.Contains(t, args, "out/thumbnail.webp -map [sprite]")
+	assert.True(t, strings.HasSuffix(args, "out/sprite.webp"))
+}
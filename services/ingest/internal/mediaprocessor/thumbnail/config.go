@@ -0,0 +1,54 @@
+package thumbnail
+
+import (
+	"log"
+	"time"
+
+	"github.com/romashorodok/stream-platform/pkg/envutils"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+)
+
+// Preview images of the live streams
+type Config struct {
+	Interval time.Duration
+	Width    int
+	Format   string
+	// Also produce a rolling sprite sheet of the last thumbnails
+	Sprite bool
+}
+
+func NewConfig() *Config {
+	rawInterval := envutils.Env(variables.INGEST_THUMBNAIL_INTERVAL, variables.INGEST_THUMBNAIL_INTERVAL_DEFAULT)
+	interval, err := time.ParseDuration(rawInterval)
+	if err != nil || interval < time.Second {
+		log.Printf("[ERROR] wrong thumbnail interval %s. Fallback to %s", rawInterval, variables.INGEST_THUMBNAIL_INTERVAL_DEFAULT)
+		interval, _ = time.ParseDuration(variables.INGEST_THUMBNAIL_INTERVAL_DEFAULT)
+	}
+
+	rawWidth := envutils.Env(variables.INGEST_THUMBNAIL_WIDTH, variables.INGEST_THUMBNAIL_WIDTH_DEFAULT)
+	width, err := envutils.ParseUint16(rawWidth)
+	if err != nil || *width < 2 {
+		log.Printf("[ERROR] wrong thumbnail width %s. Fallback to %s", rawWidth, variables.INGEST_THUMBNAIL_WIDTH_DEFAULT)
+		width, _ = envutils.ParseUint16(variables.INGEST_THUMBNAIL_WIDTH_DEFAULT)
+	}
+
+	format := envutils.Env(variables.INGEST_THUMBNAIL_FORMAT, variables.INGEST_THUMBNAIL_FORMAT_DEFAULT)
+	if _, ok := formatContentTypes[format]; !ok {
+		log.Printf("[ERROR] wrong thumbnail format %s. Fallback to %s", format, variables.INGEST_THUMBNAIL_FORMAT_DEFAULT)
+		format = variables.INGEST_THUMBNAIL_FORMAT_DEFAULT
+	}
+
+	rawSprite := envutils.Env(variables.INGEST_THUMBNAIL_SPRITE, variables.INGEST_THUMBNAIL_SPRITE_DEFAULT)
+	sprite, err := envutils.ParseBool(rawSprite)
+	if err != nil {
+		log.Printf("[ERROR] wrong thumbnail sprite %s. Fallback to %s", rawSprite, variables.INGEST_THUMBNAIL_SPRITE_DEFAULT)
+		sprite, _ = envutils.ParseBool(variables.INGEST_THUMBNAIL_SPRITE_DEFAULT)
+	}
+
+	return &Config{
+		Interval: interval,
+		Width:    int(*width),
+		Format:   format,
+		Sprite:   *sprite,
+	}
+}
@@ -0,0 +1,131 @@
+package thumbnail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+)
+
+var InvalidFileError = errors.New("invalid thumbnail file")
+
+// Periodic preview of the stream decoded only from keyframes
+type FFmpegThumbnailMediaProcessor struct {
+	SourceDirectory string
+	config          *Config
+}
+
+func (processor *FFmpegThumbnailMediaProcessor) Format() string {
+	return processor.config.Format
+}
+
+// Resolve thumbnail or sprite of the stream
+func (processor *FFmpegThumbnailMediaProcessor) File(file string) (string, error) {
+	switch file {
+	case FileName(ThumbnailName, processor.config.Format):
+	case FileName(SpriteName, processor.config.Format):
+		if !processor.config.Sprite {
+			return "", os.ErrNotExist
+		}
+	default:
+		return "", InvalidFileError
+	}
+
+	if processor.SourceDirectory == "" {
+		return "", os.ErrNotExist
+	}
+
+	return filepath.Join(processor.SourceDirectory, file), nil
+}
+
+func (processor *FFmpegThumbnailMediaProcessor) Transcode(ctx context.Context, videoSourcePipe *io.PipeReader, audioSourcePipe *io.PipeReader) error {
+	defer processor.Destroy()
+
+	// Audio isn't used, but the pipe must be read to not block other processors
+	go io.Copy(io.Discard, audioSourcePipe)
+
+	dir, err := os.MkdirTemp("", fmt.Sprintf("%s-*", uuid.New()))
+	if err != nil {
+		log.Println("[Thumbnail Processor] Cannot create temp dir. Err:", err)
+		return err
+	}
+	processor.SourceDirectory = dir
+
+	log.Println("[Thumbnail Processor] Setup output directory to", processor.SourceDirectory)
+
+	// Video of the matroska input is timed by the stream clock
+	args := []string{
+		"-skip_frame", "nokey",
+		"-i", "pipe:0",
+		"-an",
+		"-loglevel", "warning",
+	}
+	args = append(args, ffmpegArgs(processor.config, processor.SourceDirectory)...)
+
+	ffmpeg := exec.Command("ffmpeg", args...)
+	ffmpeg.Stdin = videoSourcePipe
+
+	stderr, err := ffmpeg.StderrPipe()
+	if err != nil {
+		log.Println("[Thumbnail Processor] Cannot open stderr. Err:", err)
+		return err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Println("[Thumbnail]", scanner.Text())
+		}
+	}()
+
+	if err := ffmpeg.Start(); err != nil {
+		log.Println("[Thumbnail Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Println("[Thumbnail Processor] Stop by context")
+			_ = ffmpeg.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	if err := ffmpeg.Wait(); err != nil && ctx.Err() == nil {
+		log.Println("[Thumbnail Processor] Error when running ffmpeg. Err:", err)
+		return err
+	}
+
+	return nil
+}
+
+func (processor *FFmpegThumbnailMediaProcessor) Destroy() {
+	if processor.SourceDirectory != "" {
+		log.Println("[Thumbnail Processor] Removing", processor.SourceDirectory)
+		os.RemoveAll(processor.SourceDirectory)
+	}
+}
+
+type FFmpegThumbnailMediaProcessorParams struct {
+	fx.In
+
+	Config *Config
+}
+
+func NewFFmpegThumbnailMediaProcessor(params FFmpegThumbnailMediaProcessorParams) *FFmpegThumbnailMediaProcessor {
+	return &FFmpegThumbnailMediaProcessor{
+		config: params.Config,
+	}
+}
@@ -5,84 +5,387 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
+	"github.com/romashorodok/stream-platform/pkg/envutils"
 	"github.com/romashorodok/stream-platform/pkg/shutdown"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/lifecycle"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/flv"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/mpegts"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/opus"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/statefulstream/webrtcstatefulstream"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/streamkey"
 	"go.uber.org/fx"
 )
 
 type StatefulStream interface {
 	Ingest(context.Context) error
+	Attach(role webrtcstatefulstream.PublisherRole) *webrtcstatefulstream.Publisher
+	Detach(publisher *webrtcstatefulstream.Publisher)
 	Destroy() error
 	SetVideoTrack(track *webrtc.TrackLocalStaticRTP)
+	GetMediaProcessors() []mediaprocessor.MediaProcessor
+	GetProcessorStatuses() []mediaprocessor.ProcessorStatus
 }
 
 var EmptyStatefulStream = (StatefulStream)(nil)
 
 var (
 	NewWebrtcStatefulStreamError = errors.New("failed create stateful stream")
+	StatefulStreamNotFoundError  = errors.New("stateful stream not found")
+	EmptyStreamKeyError          = errors.New("empty stream key")
+	StreamAlreadyPublishingError = errors.New("stream already has live publisher")
+	SimulcastBackupError         = errors.New("simulcast stream can't have backup publisher")
 )
 
+type Config struct {
+	ReconnectWindow     time.Duration
+	JitterBufferLatency time.Duration
+}
+
+func NewConfig() *Config {
+	rawWindow := envutils.Env(variables.INGEST_RECONNECT_WINDOW, variables.INGEST_RECONNECT_WINDOW_DEFAULT)
+	window, err := time.ParseDuration(rawWindow)
+	if err != nil || window < 0 {
+		log.Printf("[ERROR] wrong reconnect window %s. Fallback to %s", rawWindow, variables.INGEST_RECONNECT_WINDOW_DEFAULT)
+		window, _ = time.ParseDuration(variables.INGEST_RECONNECT_WINDOW_DEFAULT)
+	}
+
+	rawLatency := envutils.Env(variables.INGEST_JITTER_BUFFER_LATENCY, variables.INGEST_JITTER_BUFFER_LATENCY_DEFAULT)
+	latency, err := time.ParseDuration(rawLatency)
+	if err != nil || latency <= 0 {
+		log.Printf("[ERROR] wrong jitter buffer latency %s. Fallback to %s", rawLatency, variables.INGEST_JITTER_BUFFER_LATENCY_DEFAULT)
+		latency, _ = time.ParseDuration(variables.INGEST_JITTER_BUFFER_LATENCY_DEFAULT)
+	}
+
+	return &Config{ReconnectWindow: window, JitterBufferLatency: latency}
+}
+
+// Keep stream with own ingestion context. Destroy may be called from the ingestion goroutine and on shutdown.
+// Stream outlives the publishers for the reconnect window, publishers only attach to it
+type statefulStreamEntry struct {
+	stream StatefulStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+
+	// Guarded by the registry lock
+	layers   []string
+	attached map[webrtcstatefulstream.PublisherRole]context.CancelFunc
+	// Incremented on each attach, so expired window of the previous detach is ignored
+	attachments uint64
+	window      *time.Timer
+}
+
+// Next publisher continues the stream only with the same simulcast layers
+func (e *statefulStreamEntry) resumable(layers []string) bool {
+	if len(e.layers) != len(layers) {
+		return false
+	}
+	for i := range layers {
+		if e.layers[i] != layers[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *statefulStreamEntry) stopWindow() {
+	if e.window != nil {
+		e.window.Stop()
+		e.window = nil
+	}
+}
+
+func (e *statefulStreamEntry) destroy() {
+	e.cancel()
+	e.once.Do(func() {
+		if err := e.stream.Destroy(); err != nil {
+			log.Printf("[StatefulStream]: stream destroy error. Err: %s", err)
+		}
+	})
+}
+
+// Registry of all streams hosted by the ingest. Each stream is keyed by broadcaster stream key and has own lifecycle
 type StatefulStreamGlobal struct {
+	config          *Config
 	shutdown        *shutdown.Shutdown
 	webrtcAllocator webrtcstatefulstream.WebrtcAllocatorFunc
-	statefulStream  StatefulStream
+	notifier        *lifecycle.Notifier
+	streams         map[string]*statefulStreamEntry
+
+	mx sync.RWMutex
 }
 
 type WebrtcTrackHandler func(*webrtc.TrackRemote, *webrtc.RTPReceiver)
 
-func (s *StatefulStreamGlobal) HandleWebrtc(ctx context.Context) (WebrtcTrackHandler, error) {
-	if s.statefulStream != EmptyStatefulStream {
-		err := s.statefulStream.Destroy()
-		if err != nil {
-			log.Printf("[HandleWebrc]: stream destory error. Err: %s", err)
+// Allocate stream and start ingestion or attach to the stream which has publisher of other role or waits reconnect.
+// Returned context is done when publisher leaves or the stream is released.
+// Only one publisher of each role may be live for the stream key, next publish of the role is rejected until the previous one leaves
+func (s *StatefulStreamGlobal) allocate(ctx context.Context, key string, broadcaster *streamkey.Broadcaster, role webrtcstatefulstream.PublisherRole, layers []string) (*webrtcstatefulstream.WebrtcStatefulStream, *webrtcstatefulstream.Publisher, context.Context, context.CancelFunc, error) {
+	if key == "" {
+		return nil, nil, nil, nil, EmptyStreamKeyError
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if entry, exists := s.streams[key]; exists {
+		if _, taken := entry.attached[role]; taken {
+			log.Printf("[StatefulStream]: %s rejected second %s publisher", key, role)
+			return nil, nil, nil, nil, StreamAlreadyPublishingError
+		}
+
+		stream, ok := entry.stream.(*webrtcstatefulstream.WebrtcStatefulStream)
+
+		if len(entry.attached) > 0 {
+			// Layer set is shared by the viewers, publishers can't replace layers of each other
+			if len(entry.layers) > 0 || len(layers) > 0 {
+				return nil, nil, nil, nil, SimulcastBackupError
+			}
+
+			log.Printf("[StatefulStream]: %s %s publisher joined", key, role)
+			publisher, ctx, cancel := s.attach(ctx, key, entry, role)
+			return stream, publisher, ctx, cancel, nil
+		}
+
+		if ok && entry.resumable(layers) {
+			log.Printf("[StatefulStream]: %s %s publisher reconnected", key, role)
+			publisher, ctx, cancel := s.attach(ctx, key, entry, role)
+			return stream, publisher, ctx, cancel, nil
 		}
-		s.statefulStream = nil
+
+		// Stream is released without stopped notification, the next one is live at once
+		log.Printf("[StatefulStream]: %s publisher changed simulcast layers. Restart stream", key)
+		delete(s.streams, key)
+		entry.stopWindow()
+		entry.cancel()
 	}
 
-	stream, err := s.webrtcAllocator()
+	s.notifier.Allocated(key, broadcaster)
+
+	stream, err := s.webrtcAllocator(key, layers)
 	if err != nil {
-		return nil, NewWebrtcStatefulStreamError
+		s.notifier.Stopped(key)
+		return nil, nil, nil, nil, NewWebrtcStatefulStreamError
 	}
 
-	s.statefulStream = stream
+	streamCtx, streamCancel := context.WithCancel(context.Background())
 
-	s.shutdown.AddTask(func() {
-		stream.Destroy()
-	})
-
-	ctx, cancel := context.WithCancel(ctx)
+	entry := &statefulStreamEntry{
+		stream:   stream,
+		ctx:      streamCtx,
+		cancel:   streamCancel,
+		layers:   layers,
+		attached: make(map[webrtcstatefulstream.PublisherRole]context.CancelFunc),
+	}
+	s.streams[key] = entry
 
 	go func() {
+		defer s.release(key, entry)
 
-		s.shutdown.AddTask(func() {
-			cancel()
-		})
+		_ = stream.Ingest(streamCtx)
+
+		<-streamCtx.Done()
+	}()
 
-		s.statefulStream.Ingest(ctx)
+	publisher, ctx, cancel := s.attach(ctx, key, entry, role)
+	return stream, publisher, ctx, cancel, nil
+}
+
+// Publisher context is done with the stream. When the last publisher leaves the stream waits the next one. Must be called with the lock
+func (s *StatefulStreamGlobal) attach(ctx context.Context, key string, entry *statefulStreamEntry, role webrtcstatefulstream.PublisherRole) (*webrtcstatefulstream.Publisher, context.Context, context.CancelFunc) {
+	entry.stopWindow()
+	entry.attachments++
+
+	ctx, cancel := context.WithCancel(ctx)
+	entry.attached[role] = cancel
+	publisher := entry.stream.Attach(role)
 
+	go func() {
 		select {
 		case <-ctx.Done():
-			_ = s.statefulStream.Destroy()
+		case <-entry.ctx.Done():
 			cancel()
 		}
+		s.detach(key, entry, publisher)
 	}()
 
+	return publisher, ctx, cancel
+}
+
+// Outputs repeat held media for the reconnect window. Stream is released when nobody continues it
+func (s *StatefulStreamGlobal) detach(key string, entry *statefulStreamEntry, publisher *webrtcstatefulstream.Publisher) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	entry.stream.Detach(publisher)
+	delete(entry.attached, publisher.Role)
+
+	if len(entry.attached) > 0 {
+		log.Printf("[StatefulStream]: %s %s publisher left", key, publisher.Role)
+		return
+	}
+
+	if entry.ctx.Err() != nil || s.config.ReconnectWindow == 0 {
+		entry.cancel()
+		return
+	}
+
+	log.Printf("[StatefulStream]: %s publisher left. Waiting reconnect %s", key, s.config.ReconnectWindow)
+
+	attachments := entry.attachments
+	entry.window = time.AfterFunc(s.config.ReconnectWindow, func() {
+		s.mx.Lock()
+		defer s.mx.Unlock()
+
+		if len(entry.attached) > 0 || entry.attachments != attachments {
+			return
+		}
+
+		log.Printf("[StatefulStream]: %s publisher didn't reconnect", key)
+		entry.cancel()
+	})
+}
+
+func (s *StatefulStreamGlobal) pipe(key string, role webrtcstatefulstream.PublisherRole, pipe func() error) {
+	if err := pipe(); err != nil {
+		s.fail(key, role, err)
+	}
+}
+
+// Publisher which can't continue the stream releases it, so its next publish starts the new one.
+// When other publisher is live only the failed one leaves
+func (s *StatefulStreamGlobal) fail(key string, role webrtcstatefulstream.PublisherRole, err error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	entry, ok := s.streams[key]
+	if !ok {
+		return
+	}
+
+	if cancel, attached := entry.attached[role]; attached && len(entry.attached) > 1 {
+		log.Printf("[StatefulStream]: %s %s publisher can't feed the stream. Err: %s", key, role, err)
+		cancel()
+		return
+	}
+
+	log.Printf("[StatefulStream]: %s stream can't be continued. Err: %s", key, err)
+	entry.cancel()
+}
+
+// Viewers stay bound to the local track, so the next publisher of the same codec writes into it
+func localTrack(current *webrtc.TrackLocalStaticRTP, capability webrtc.RTPCodecCapability, id, key string) (*webrtc.TrackLocalStaticRTP, error) {
+	if current != nil && strings.EqualFold(current.Codec().MimeType, capability.MimeType) {
+		return current, nil
+	}
+	return webrtc.NewTrackLocalStaticRTP(capability, id, key)
+}
+
+// Track is read through the jitter buffer. Lost packets are requested from the publisher when the codec negotiated nack,
+// video which lost them for good is recovered by the keyframe
+func (s *StatefulStreamGlobal) trackReader(peer *webrtc.PeerConnection, track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) *rtp.RtpTrackDemuxerReader {
+	ssrc := uint32(track.SSRC())
+	buffer := rtp.NewJitterBuffer(s.config.JitterBufferLatency)
+
+	for _, feedback := range track.Codec().RTCPFeedback {
+		if feedback.Type == "nack" && feedback.Parameter == "" {
+			buffer.OnNack(func(sequenceNumbers []uint16) {
+				_ = peer.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{MediaSSRC: ssrc, Nacks: rtcp.NackPairsFromSequenceNumbers(sequenceNumbers)}})
+			})
+		}
+	}
+
+	if track.Kind() == webrtc.RTPCodecTypeVideo {
+		buffer.OnLoss(func() {
+			_ = peer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+		})
+	}
+
+	reader := rtp.NewRtpTrackDemuxerReader(track, buffer)
+	if payloadType, ok := rtp.RepairPayloadType(receiver.GetParameters().Codecs, track.PayloadType()); ok {
+		reader.Repair(payloadType)
+	}
+	return reader
+}
+
+// Sender reports of the track place its media on the wall clock of the publisher
+func readSenderReports(stream *webrtcstatefulstream.WebrtcStatefulStream, publisher *webrtcstatefulstream.Publisher, kind webrtc.RTPCodecType, read rtp.RTCPReader) {
+	rtp.ReadSenderReports(read, func(report *rtcp.SenderReport) {
+		stream.SenderReport(publisher, kind, report)
+	})
+}
+
+// Layers are simulcast RIDs declared by publisher offer. Each video layer is received as own track
+func (s *StatefulStreamGlobal) HandleWebrtc(ctx context.Context, key string, broadcaster *streamkey.Broadcaster, role webrtcstatefulstream.PublisherRole, peer *webrtc.PeerConnection, layers []string) (WebrtcTrackHandler, error) {
+	stream, publisher, ctx, cancel, err := s.allocate(ctx, key, broadcaster, role, layers)
+	if err != nil {
+		return nil, err
+	}
+
 	return func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
-		log.Println("Received track ", track.Codec())
+		log.Printf("[%s] Received %s track %s %s", key, role, track.Codec().MimeType, track.RID())
 
 		mime := track.Codec().MimeType
 
+		if err := stream.Compatible(mime); err != nil {
+			s.fail(key, role, err)
+			return
+		}
+
+		s.notifier.Running(key)
+
+		if strings.HasPrefix(mime, "video") && track.RID() != "" && stream.Layers.Simulcast() {
+			var current *webrtc.TrackLocalStaticRTP
+			if layer, err := stream.Layers.Get(track.RID()); err == nil {
+				current = layer.Track
+			}
+
+			video, err := localTrack(current, track.Codec().RTPCodecCapability, "video", key)
+			if err != nil {
+				cancel()
+				return
+			}
+
+			layer := stream.Layers.Add(track.RID(), video, func() {
+				_ = peer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}})
+			})
+
+			// Only primary layer goes to media processors
+			if stream.Layers.IsPrimary(track.RID()) {
+				rid := track.RID()
+				go readSenderReports(stream, publisher, track.Kind(), func() ([]rtcp.Packet, interceptor.Attributes, error) {
+					return receiver.ReadSimulcastRTCP(rid)
+				})
+			}
+
+			reader := s.trackReader(peer, track, receiver)
+			s.pipe(key, role, func() error { return stream.PipeLayerRemoteTrack(ctx, publisher, reader, layer) })
+			return
+		}
+
 		if strings.HasPrefix(mime, "video") {
-			video, err := webrtc.NewTrackLocalStaticRTP(track.Codec().RTPCodecCapability, "video", "pion")
+			video, err := localTrack(stream.Video, track.Codec().RTPCodecCapability, "video", key)
 			if err != nil {
 				cancel()
 				return
 			}
-			stream.Video = video
+			stream.SetVideoTrack(video)
+
+			publisher.OnKeyframeRequest(func() {
+				_ = peer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}})
+			})
 		} else if strings.HasPrefix(mime, "audio") {
-			audio, err := webrtc.NewTrackLocalStaticRTP(track.Codec().RTPCodecCapability, "audio", "pion")
+			audio, err := localTrack(stream.Audio, track.Codec().RTPCodecCapability, "audio", key)
 			if err != nil {
 				cancel()
 				return
@@ -93,32 +396,189 @@ func (s *StatefulStreamGlobal) HandleWebrtc(ctx context.Context) (WebrtcTrackHan
 			return
 		}
 
+		go readSenderReports(stream, publisher, track.Kind(), receiver.ReadRTCP)
+
+		reader := s.trackReader(peer, track, receiver)
+
 		switch mime {
 		case webrtc.MimeTypeOpus, "audio/OPUS":
-			stream.PipeOpusRemoteTrack(ctx, track)
+			s.pipe(key, role, func() error { return stream.PipeOpusRemoteTrack(ctx, publisher, reader) })
 		case webrtc.MimeTypeVP8:
-			stream.PipeVP8RemoteTrack(ctx, track)
+			s.pipe(key, role, func() error { return stream.PipeVP8RemoteTrack(ctx, publisher, reader) })
 		case webrtc.MimeTypeH264:
-			stream.PipeH264RemoteTrack(ctx, track)
+			s.pipe(key, role, func() error { return stream.PipeH264RemoteTrack(ctx, publisher, reader) })
 		}
 	}, nil
 }
 
-func (s *StatefulStreamGlobal) GetStatefulStream() StatefulStream {
-	return s.statefulStream
+// Accept FLV tags of the rtmp publish
+type RtmpTagHandler struct {
+	Video *flv.AvcToRtpDemuxerReader
+	Audio *flv.AacToRtpDemuxerReader
+}
+
+func (s *StatefulStreamGlobal) HandleRtmp(ctx context.Context, key string, broadcaster *streamkey.Broadcaster, role webrtcstatefulstream.PublisherRole) (*RtmpTagHandler, error) {
+	stream, publisher, ctx, cancel, err := s.allocate(ctx, key, broadcaster, role, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.Compatible(webrtc.MimeTypeH264); err != nil {
+		s.fail(key, role, err)
+		return nil, err
+	}
+
+	video, err := localTrack(stream.Video, h264.CodecCapability, "video", key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stream.SetVideoTrack(video)
+
+	s.notifier.Running(key)
+
+	handler := &RtmpTagHandler{
+		Video: flv.NewAvcToRtpDemuxerReader(),
+		Audio: flv.NewAacToRtpDemuxerReader(),
+	}
+
+	go s.pipe(key, role, func() error { return stream.PipeH264(ctx, publisher, handler.Video) })
+	go s.pipe(key, role, func() error { return stream.PipeAAC(ctx, publisher, handler.Audio) })
+
+	go func() {
+		<-ctx.Done()
+		_ = handler.Video.Close()
+		_ = handler.Audio.Close()
+	}()
+
+	return handler, nil
+}
+
+// Take mpeg-ts of the srt publish. Audio pipeline is chosen when program map is received
+func (s *StatefulStreamGlobal) HandleSrt(ctx context.Context, key string, broadcaster *streamkey.Broadcaster, role webrtcstatefulstream.PublisherRole) (*mpegts.Demuxer, error) {
+	stream, publisher, ctx, cancel, err := s.allocate(ctx, key, broadcaster, role, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.Compatible(webrtc.MimeTypeH264); err != nil {
+		s.fail(key, role, err)
+		return nil, err
+	}
+
+	video, err := localTrack(stream.Video, h264.CodecCapability, "video", key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stream.SetVideoTrack(video)
+
+	s.notifier.Running(key)
+
+	var demuxer *mpegts.Demuxer
+	demuxer = mpegts.NewDemuxer(func(audioMimeType string) {
+		log.Printf("[%s] Received mpeg-ts program with %s audio", key, audioMimeType)
+
+		if err := stream.Compatible(audioMimeType); err != nil {
+			s.fail(key, role, err)
+			return
+		}
+
+		switch audioMimeType {
+		case webrtc.MimeTypeOpus:
+			audio, err := localTrack(stream.Audio, opus.CodecCapability, "audio", key)
+			if err != nil {
+				cancel()
+				return
+			}
+			stream.Audio = audio
+			go s.pipe(key, role, func() error { return stream.PipeOpus(ctx, publisher, demuxer.Audio) })
+		case mpegts.MimeTypeAAC:
+			go s.pipe(key, role, func() error { return stream.PipeAAC(ctx, publisher, demuxer.Audio) })
+		}
+	})
+
+	go s.pipe(key, role, func() error { return stream.PipeH264(ctx, publisher, demuxer.Video) })
+
+	go func() {
+		<-ctx.Done()
+		_ = demuxer.Close()
+	}()
+
+	return demuxer, nil
+}
+
+// Remove stream from registry only if it's not destroyed by shutdown or replaced by the next stream already
+func (s *StatefulStreamGlobal) release(key string, entry *statefulStreamEntry) {
+	s.mx.Lock()
+	current, registered := s.streams[key]
+	registered = registered && current == entry
+	if registered {
+		delete(s.streams, key)
+	}
+	entry.stopWindow()
+	s.mx.Unlock()
+
+	entry.destroy()
+	if registered {
+		s.notifier.Stopped(key)
+	}
+	log.Printf("[StatefulStream]: %s stream released", key)
+}
+
+func (s *StatefulStreamGlobal) GetStatefulStream(key string) (StatefulStream, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	entry, ok := s.streams[key]
+	if !ok {
+		return EmptyStatefulStream, StatefulStreamNotFoundError
+	}
+
+	return entry.stream, nil
+}
+
+func (s *StatefulStreamGlobal) destroyAll() {
+	s.mx.Lock()
+	entries := make([]*statefulStreamEntry, 0, len(s.streams))
+	for key, entry := range s.streams {
+		entry.stopWindow()
+		entries = append(entries, entry)
+		delete(s.streams, key)
+	}
+	s.mx.Unlock()
+
+	// Each stream waits its processors, so shutdown takes the longest of them
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		wg.Add(1)
+		go func(entry *statefulStreamEntry) {
+			defer wg.Done()
+			entry.destroy()
+		}(entry)
+	}
+	wg.Wait()
 }
 
 type StatefulStreamGlobalParams struct {
 	fx.In
 
+	Config              *Config
 	Shutdown            *shutdown.Shutdown
 	WebrtcAllocatorFunc webrtcstatefulstream.WebrtcAllocatorFunc
+	Notifier            *lifecycle.Notifier
 }
 
 func NewStatefulStreamGlobal(params StatefulStreamGlobalParams) *StatefulStreamGlobal {
-	return &StatefulStreamGlobal{
-		statefulStream:  EmptyStatefulStream,
+	global := &StatefulStreamGlobal{
+		config:          params.Config,
+		streams:         make(map[string]*statefulStreamEntry),
 		webrtcAllocator: params.WebrtcAllocatorFunc,
+		notifier:        params.Notifier,
 		shutdown:        params.Shutdown,
 	}
+
+	global.shutdown.AddTask(global.destroyAll)
+
+	return global
 }
@@ -0,0 +1,275 @@
+package webrtcstatefulstream
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/wrtc"
+)
+
+const (
+	videoClockRate = 90_000
+	opusClockRate  = 48_000
+
+	// Raw h264 of the passthrough processor is read as 25 fps, so held picture is repeated with the same rate
+	videoHoldInterval = 40 * time.Millisecond
+	audioHoldInterval = 20 * time.Millisecond
+	// Outputs get held media when the active publisher is silent for this time
+	holdDelay = time.Second
+)
+
+// Single 20ms frame of silence. Look at RFC 6716 section 3.1, it's fullband CELT frame without data
+var opusSilence = []byte{0xf8, 0xff, 0xfe}
+
+type heldPacket struct {
+	payload []byte
+	marker  bool
+}
+
+// Keep rtp sequence and timestamp of the track continuous across publishers. Packets of the next publisher
+// are rebased after the last written one, so muxers and viewers of the stream see a single source.
+// While there is no media of the active publisher held media is repeated: last keyframe of the video or silence of the audio
+type continuityWriter struct {
+	target    io.Writer
+	mimeType  string
+	clockRate uint32
+	interval  time.Duration
+
+	// Only the active source may write. Writes of other publishers are dropped
+	sources   uint64
+	active    uint64
+	fresh     bool
+	seqOffset uint16
+	tsOffset  uint32
+
+	started     bool
+	ssrc        uint32
+	payloadType uint8
+	lastSeq     uint16
+	lastTS      uint32
+	lastAt      time.Time
+
+	held      []heldPacket
+	holding   bool
+	capture   []heldPacket
+	captureTS uint32
+	capturing bool
+
+	mx sync.Mutex
+}
+
+func newContinuityWriter(target io.Writer, mimeType string, clockRate uint32, interval time.Duration) *continuityWriter {
+	return &continuityWriter{
+		target:    target,
+		mimeType:  mimeType,
+		clockRate: clockRate,
+		interval:  interval,
+	}
+}
+
+// Audio without publisher is filled by silence
+func newOpusContinuityWriter(target io.Writer, mimeType string) *continuityWriter {
+	writer := newContinuityWriter(target, mimeType, opusClockRate, audioHoldInterval)
+	writer.held = []heldPacket{{payload: opusSilence}}
+	return writer
+}
+
+// Writer of the publisher. It's dropped until activated
+func (w *continuityWriter) Source() *continuitySource {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.sources++
+	return &continuitySource{writer: w, source: w.sources}
+}
+
+// First packet of the activated source continues the timeline of the previous one. Nil source drops all writes
+func (w *continuityWriter) Activate(source *continuitySource) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.active = 0
+	if source != nil {
+		w.active = source.source
+	}
+	w.fresh = true
+}
+
+type continuitySource struct {
+	writer *continuityWriter
+	source uint64
+}
+
+func (s *continuitySource) Write(p []byte) (int, error) {
+	return s.writer.write(s.source, p)
+}
+
+// Rtp timestamp of the source on the written timeline. False until the active source is rebased
+func (s *continuitySource) Timestamp(timestamp uint32) (uint32, bool) {
+	w := s.writer
+
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if s.source != w.active || w.fresh || !w.started {
+		return 0, false
+	}
+	return timestamp + w.tsOffset, true
+}
+
+func (w *continuityWriter) ticks(duration time.Duration) uint32 {
+	return uint32(int64(duration) * int64(w.clockRate) / int64(time.Second))
+}
+
+func (w *continuityWriter) write(source uint64, p []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if source != w.active {
+		return len(p), nil
+	}
+
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(p); err != nil {
+		return 0, err
+	}
+
+	keyframe := wrtc.IsKeyframe(w.mimeType, pkt.Payload)
+
+	if w.fresh {
+		// Decoders of the outputs may continue the next publisher only from its keyframe
+		if w.started && w.video() && !keyframe {
+			return len(p), nil
+		}
+		w.rebase(&pkt)
+		w.holding = false
+	}
+
+	pkt.SequenceNumber += w.seqOffset
+	pkt.Timestamp += w.tsOffset
+	pkt.SSRC = w.ssrc
+
+	if w.video() {
+		w.captureKeyframe(&pkt, keyframe)
+	}
+
+	if err := w.forward(&pkt); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *continuityWriter) video() bool {
+	return w.clockRate == videoClockRate
+}
+
+// First packet of the source continues after the last written packet with the time passed since it
+func (w *continuityWriter) rebase(pkt *rtp.Packet) {
+	w.fresh = false
+
+	if !w.started {
+		w.ssrc = pkt.SSRC
+		return
+	}
+
+	elapsed := w.ticks(time.Since(w.lastAt))
+	if elapsed == 0 {
+		elapsed = 1
+	}
+
+	w.seqOffset = w.lastSeq + 1 - pkt.SequenceNumber
+	w.tsOffset = w.lastTS + elapsed - pkt.Timestamp
+}
+
+// Keyframe is held when all its packets are received. Packets of the same frame share the timestamp
+func (w *continuityWriter) captureKeyframe(pkt *rtp.Packet, keyframe bool) {
+	if keyframe && (!w.capturing || pkt.Timestamp != w.captureTS) {
+		w.capture = nil
+		w.captureTS = pkt.Timestamp
+		w.capturing = true
+	}
+
+	if !w.capturing {
+		return
+	}
+
+	if pkt.Timestamp != w.captureTS {
+		// Marker of the keyframe is lost
+		w.capture = nil
+		w.capturing = false
+		return
+	}
+
+	w.capture = append(w.capture, heldPacket{payload: append([]byte(nil), pkt.Payload...), marker: pkt.Marker})
+	if pkt.Marker {
+		w.held = w.capture
+		w.capture = nil
+		w.capturing = false
+	}
+}
+
+func (w *continuityWriter) forward(pkt *rtp.Packet) error {
+	if !w.started || int16(pkt.SequenceNumber-w.lastSeq) > 0 {
+		w.lastSeq = pkt.SequenceNumber
+	}
+	if !w.started || int32(pkt.Timestamp-w.lastTS) > 0 {
+		w.lastTS = pkt.Timestamp
+	}
+	w.started = true
+	w.payloadType = pkt.PayloadType
+	w.lastAt = time.Now()
+
+	b, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = w.target.Write(b)
+	return err
+}
+
+// Repeat held media while the active publisher is silent until context is done. Outputs keep the timeline without publisher
+func (w *continuityWriter) Hold(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.repeat()
+		}
+	}
+}
+
+func (w *continuityWriter) repeat() {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if !w.started || len(w.held) == 0 || time.Since(w.lastAt) < holdDelay && !w.holding {
+		return
+	}
+
+	// Publisher continues after the held media as the next one
+	w.holding = true
+	w.fresh = true
+
+	timestamp := w.lastTS + w.ticks(w.interval)
+	for _, held := range w.held {
+		pkt := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				Marker:         held.marker,
+				PayloadType:    w.payloadType,
+				SequenceNumber: w.lastSeq + 1,
+				Timestamp:      timestamp,
+				SSRC:           w.ssrc,
+			},
+			Payload: held.payload,
+		}
+		_ = w.forward(pkt)
+	}
+}
@@ -0,0 +1,118 @@
+package webrtcstatefulstream
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type packetRecorder struct {
+	packets []rtp.Packet
+}
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(p); err != nil {
+		return 0, err
+	}
+	r.packets = append(r.packets, pkt)
+	return len(p), nil
+}
+
+func (r *packetRecorder) last() rtp.Packet {
+	return r.packets[len(r.packets)-1]
+}
+
+var (
+	h264Keyframe = []byte{0x65, 0x88}
+	h264Delta    = []byte{0x41, 0x9a}
+)
+
+func writePacket(t *testing.T, source io.Writer, ssrc uint32, seq uint16, timestamp uint32, payload []byte) error {
+	pkt := rtp.Packet{
+		Header:  rtp.Header{Version: 2, Marker: true, PayloadType: 102, SequenceNumber: seq, Timestamp: timestamp, SSRC: ssrc},
+		Payload: payload,
+	}
+	b, err := pkt.Marshal()
+	assert.Nil(t, err)
+
+	_, err = source.Write(b)
+	return err
+}
+
+func TestContinuityWriter_NextSource(t *testing.T) {
+	assert := assert.New(t)
+	recorder := &packetRecorder{}
+	writer := newContinuityWriter(recorder, webrtc.MimeTypeH264, videoClockRate, videoHoldInterval)
+
+	first := writer.Source()
+	second := writer.Source()
+	writer.Activate(first)
+
+	assert.Nil(writePacket(t, first, 1, 100, 9000, h264Keyframe))
+	assert.Nil(writePacket(t, first, 1, 101, 12000, h264Delta))
+	// Inactive publisher is dropped
+	assert.Nil(writePacket(t, second, 2, 4999, 600, h264Keyframe))
+	assert.Len(recorder.packets, 2)
+
+	writer.Activate(second)
+	assert.Nil(writePacket(t, first, 1, 102, 15000, h264Delta))
+
+	// Next publisher starts from the keyframe
+	assert.Nil(writePacket(t, second, 2, 5000, 700, h264Delta))
+	assert.Len(recorder.packets, 2)
+
+	assert.Nil(writePacket(t, second, 2, 5001, 800, h264Keyframe))
+	assert.Nil(writePacket(t, second, 2, 5002, 3800, h264Delta))
+	assert.Len(recorder.packets, 4)
+
+	rebased := recorder.packets[2]
+	assert.Equal(uint16(102), rebased.SequenceNumber)
+	assert.Greater(rebased.Timestamp, uint32(12000))
+	assert.Equal(uint32(1), rebased.SSRC)
+
+	assert.Equal(uint16(103), recorder.last().SequenceNumber)
+	assert.Equal(rebased.Timestamp+3000, recorder.last().Timestamp)
+}
+
+func TestContinuityWriter_Hold(t *testing.T) {
+	assert := assert.New(t)
+	recorder := &packetRecorder{}
+	writer := newContinuityWriter(recorder, webrtc.MimeTypeH264, videoClockRate, videoHoldInterval)
+
+	source := writer.Source()
+	writer.Activate(source)
+	assert.Nil(writePacket(t, source, 1, 10, 9000, h264Keyframe))
+	assert.Nil(writePacket(t, source, 1, 11, 12000, h264Delta))
+
+	// Publisher isn't silent yet
+	writer.repeat()
+	assert.Len(recorder.packets, 2)
+
+	writer.lastAt = writer.lastAt.Add(-holdDelay)
+	writer.repeat()
+	writer.repeat()
+
+	held := recorder.packets[2:]
+	assert.Len(held, 2)
+	assert.Equal(h264Keyframe, held[0].Payload)
+	assert.Equal(uint16(12), held[0].SequenceNumber)
+	assert.Equal(uint32(12000+3600), held[0].Timestamp)
+	assert.Equal(uint16(13), held[1].SequenceNumber)
+	assert.Equal(uint32(12000+7200), held[1].Timestamp)
+
+	// Silence fills the audio without publisher
+	recorder = &packetRecorder{}
+	audio := newOpusContinuityWriter(recorder, webrtc.MimeTypeOpus)
+	audioSource := audio.Source()
+	audio.Activate(audioSource)
+	assert.Nil(writePacket(t, audioSource, 3, 1, 960, []byte{0x78}))
+
+	audio.lastAt = audio.lastAt.Add(-holdDelay)
+	audio.repeat()
+	assert.Equal(opusSilence, recorder.last().Payload)
+	assert.Equal(uint32(1920), recorder.last().Timestamp)
+}
@@ -0,0 +1,182 @@
+package webrtcstatefulstream
+
+import (
+	"io"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/matroska"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/opus"
+	mediartp "github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/wrtc"
+)
+
+const (
+	// Rtp packets of the video buffered for the slow processor. Holds a few seconds of video with keyframes
+	processorVideoBuffer = 1024
+	// Rtp packets or frames of the audio buffered for the slow processor
+	processorAudioBuffer = 256
+)
+
+// Each processor must read own pipe, readers of the shared pipe would split the media between them
+func newPipes(count int) (readers []*io.PipeReader, writers []*io.PipeWriter) {
+	for i := 0; i < count; i++ {
+		reader, writer := io.Pipe()
+		readers = append(readers, reader)
+		writers = append(writers, writer)
+	}
+	return readers, writers
+}
+
+// Rtp packet which starts the keyframe of the video
+func rtpKeyframe(mimeType string) func([]byte) bool {
+	return func(frame []byte) bool {
+		var packet rtp.Packet
+		if err := packet.Unmarshal(frame); err != nil {
+			return false
+		}
+		return wrtc.IsKeyframe(mimeType, packet.Payload)
+	}
+}
+
+// Media of the processors. Each processor reads the bus by own subscriber and muxes it into own pipe,
+// so slow processor drops own frames and never blocks viewers or other processors
+type processorBus struct {
+	bus         *media.Bus
+	subscribers []*media.Subscriber
+}
+
+// Writer of the index is fed by the subscriber of the processor and closed when the bus is closed
+func newProcessorBus(keyframe func([]byte) bool, writers []media.MediaWriter, size int, policy media.DropPolicy) *processorBus {
+	bus := &processorBus{bus: media.NewBus(keyframe)}
+
+	for _, writer := range writers {
+		subscriber, writer := bus.bus.Subscribe(size, policy), writer
+		bus.subscribers = append(bus.subscribers, subscriber)
+
+		go func() {
+			media.NewDemuxerBuilder(subscriber, writer).Demux()
+
+			if closer, ok := writer.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		}()
+	}
+
+	return bus
+}
+
+func (b *processorBus) Write(p []byte) (int, error) {
+	return b.bus.Write(p)
+}
+
+// Frames dropped for the processor of the index
+func (b *processorBus) dropped(i int) uint64 {
+	if b == nil || i >= len(b.subscribers) {
+		return 0
+	}
+	return b.subscribers[i].Dropped()
+}
+
+func (b *processorBus) close() {
+	if b != nil {
+		_ = b.bus.Close()
+	}
+}
+
+// Muxer of the processor which takes rtp packets
+func rtpMuxer(muxerWriter func() media.MuxerWriter, mediaWriter func(io.Writer) media.MediaWriter) func(io.Writer) media.MediaWriter {
+	return func(pipe io.Writer) media.MediaWriter {
+		muxer := media.NewMuxerBuilder(muxerWriter(), mediaWriter(pipe))
+		go muxer.Mux()
+		return muxer
+	}
+}
+
+func targetWriter(pipe io.Writer) media.MediaWriter {
+	return media.NewTargetMediaWriter(pipe)
+}
+
+// Payload of each rtp packet into the pipe. Packets of AAC carry whole ADTS frames
+func rtpPayloadWriter(pipe io.Writer) media.MediaWriter {
+	return &payloadWriter{pipe: pipe}
+}
+
+type payloadWriter struct {
+	pipe io.Writer
+}
+
+func (w *payloadWriter) Write(p []byte) (int, error) {
+	var packet rtp.Packet
+	if err := packet.Unmarshal(p); err != nil {
+		return 0, err
+	}
+	if _, err := w.pipe.Write(packet.Payload); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Muxer into the processor pipe. Pipe is closed after the muxer
+type pipeMuxer struct {
+	media.MediaWriter
+	pipe *io.PipeWriter
+}
+
+func (m *pipeMuxer) Close() error {
+	if closer, ok := m.MediaWriter.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	return m.pipe.Close()
+}
+
+// Video and audio of the processor muxed into one matroska stream of its video pipe
+func newMatroskaMuxer(pipe *io.PipeWriter, videoClock, audioClock *mediartp.TrackClock) *matroska.RtpToMatroskaMuxWriter {
+	muxer := matroska.NewRtpToMatroskaMuxWriter(videoClock, audioClock)
+
+	go func() {
+		media.Copy(pipe, muxer.GetReader())
+		_ = pipe.Close()
+	}()
+	return muxer
+}
+
+// Audio of the processor goes into its matroska stream when it has one. Otherwise it's muxed into own audio pipe:
+// opus as webm and ADTS frames of AAC as they are
+type processorAudioWriter struct {
+	stream   *WebrtcStatefulStream
+	index    int
+	pipe     *io.PipeWriter
+	mimeType string
+
+	audio    media.MediaWriter
+	separate media.MediaWriter
+}
+
+func (w *processorAudioWriter) Write(p []byte) (int, error) {
+	if w.audio == nil {
+		if muxer := w.stream.matroskaMuxer(w.index); muxer != nil {
+			w.audio = muxer.Audio(w.mimeType)
+		}
+	}
+	if w.audio != nil {
+		return w.audio.Write(p)
+	}
+
+	if w.separate == nil {
+		if w.mimeType == webrtc.MimeTypeOpus {
+			w.separate = rtpMuxer(func() media.MuxerWriter { return opus.NewRtpToWebmOpusWriter() }, targetWriter)(w.pipe)
+		} else {
+			w.separate = rtpPayloadWriter(w.pipe)
+		}
+	}
+	return w.separate.Write(p)
+}
+
+func (w *processorAudioWriter) Close() error {
+	if closer, ok := w.separate.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	return w.pipe.Close()
+}
@@ -0,0 +1,116 @@
+package webrtcstatefulstream
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/romashorodok/stream-platform/pkg/envutils"
+	"github.com/romashorodok/stream-platform/pkg/variables"
+)
+
+type PublisherRole int
+
+const (
+	PrimaryPublisher PublisherRole = iota
+	BackupPublisher
+)
+
+var InvalidPublisherRoleError = errors.New("invalid publisher role. Expected primary or backup")
+
+func (r PublisherRole) String() string {
+	if r == BackupPublisher {
+		return "backup"
+	}
+	return "primary"
+}
+
+// Empty role is primary
+func ParsePublisherRole(raw string) (PublisherRole, error) {
+	switch raw {
+	case "", "primary":
+		return PrimaryPublisher, nil
+	case "backup":
+		return BackupPublisher, nil
+	}
+	return PrimaryPublisher, InvalidPublisherRoleError
+}
+
+type Config struct {
+	FailoverTimeout time.Duration
+}
+
+func NewConfig() *Config {
+	rawTimeout := envutils.Env(variables.INGEST_FAILOVER_TIMEOUT, variables.INGEST_FAILOVER_TIMEOUT_DEFAULT)
+	timeout, err := time.ParseDuration(rawTimeout)
+	if err != nil || timeout < minFailoverTimeout {
+		log.Printf("[ERROR] wrong failover timeout %s. Fallback to %s", rawTimeout, variables.INGEST_FAILOVER_TIMEOUT_DEFAULT)
+		timeout, _ = time.ParseDuration(variables.INGEST_FAILOVER_TIMEOUT_DEFAULT)
+	}
+
+	return &Config{FailoverTimeout: timeout}
+}
+
+const minFailoverTimeout = 100 * time.Millisecond
+
+// Publisher of the stream. Only the active publisher feeds outputs, backup one is active while the primary doesn't deliver media
+type Publisher struct {
+	Role PublisherRole
+
+	active     atomic.Bool
+	lastPacket atomic.Int64
+	// Guarded by the stream lock
+	sources      map[*continuityWriter]*continuitySource
+	healthySince time.Time
+
+	keyframe func()
+	mx       sync.Mutex
+}
+
+func newPublisher(role PublisherRole) *Publisher {
+	return &Publisher{Role: role, sources: make(map[*continuityWriter]*continuitySource)}
+}
+
+// Keyframe is requested when the publisher becomes active, so outputs switch to it at once
+func (p *Publisher) OnKeyframeRequest(keyframe func()) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.keyframe = keyframe
+}
+
+func (p *Publisher) requestKeyframe() {
+	p.mx.Lock()
+	keyframe := p.keyframe
+	p.mx.Unlock()
+
+	if keyframe != nil {
+		keyframe()
+	}
+}
+
+func (p *Publisher) delivering(now time.Time, timeout time.Duration) bool {
+	lastPacket := p.lastPacket.Load()
+	return lastPacket != 0 && now.Sub(time.Unix(0, lastPacket)) < timeout
+}
+
+// Measure media of the publisher. Media of inactive publisher is dropped
+type publisherWriter struct {
+	publisher *Publisher
+	target    io.Writer
+}
+
+func (w *publisherWriter) Write(p []byte) (int, error) {
+	w.publisher.lastPacket.Store(time.Now().UnixNano())
+
+	if !w.publisher.active.Load() {
+		return len(p), nil
+	}
+	return w.target.Write(p)
+}
+
+func (p *Publisher) writer(target io.Writer) io.Writer {
+	return &publisherWriter{publisher: p, target: target}
+}
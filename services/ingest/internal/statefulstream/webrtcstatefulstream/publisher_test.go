@@ -0,0 +1,57 @@
+package webrtcstatefulstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePublisherRole(t *testing.T) {
+	assert := assert.New(t)
+
+	role, err := ParsePublisherRole("")
+	assert.Nil(err)
+	assert.Equal(PrimaryPublisher, role)
+
+	role, err = ParsePublisherRole("backup")
+	assert.Nil(err)
+	assert.Equal(BackupPublisher, role)
+
+	_, err = ParsePublisherRole("secondary")
+	assert.ErrorIs(err, InvalidPublisherRoleError)
+}
+
+func TestWebrtcStatefulStream_Failover(t *testing.T) {
+	assert := assert.New(t)
+	timeout := 2 * time.Second
+	stream := &WebrtcStatefulStream{publishers: make(map[PublisherRole]*Publisher), failoverTimeout: timeout}
+
+	primary := stream.Attach(PrimaryPublisher)
+	backup := stream.Attach(BackupPublisher)
+	assert.True(primary.active.Load())
+	assert.False(backup.active.Load())
+
+	now := time.Now()
+	primary.lastPacket.Store(now.Add(-timeout).UnixNano())
+	backup.lastPacket.Store(now.UnixNano())
+
+	stream.failover(now)
+	assert.False(primary.active.Load())
+	assert.True(backup.active.Load())
+
+	// Primary must be healthy for the timeout before switch back
+	primary.lastPacket.Store(now.UnixNano())
+	stream.failover(now)
+	assert.True(backup.active.Load())
+
+	later := now.Add(timeout)
+	primary.lastPacket.Store(later.UnixNano())
+	stream.failover(later)
+	assert.True(primary.active.Load())
+	assert.False(backup.active.Load())
+
+	// Backup takes the stream when primary is gone
+	stream.Detach(primary)
+	assert.True(backup.active.Load())
+}
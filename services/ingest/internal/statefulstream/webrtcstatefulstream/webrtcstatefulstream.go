@@ -2,144 +2,599 @@ package webrtcstatefulstream
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/lifecycle"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/media"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/aac"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/media/h264"
-	"github.com/romashorodok/stream-platform/services/ingest/internal/media/opus"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/matroska"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
-	"github.com/romashorodok/stream-platform/services/ingest/internal/media/vp8"
 	"github.com/romashorodok/stream-platform/services/ingest/internal/mediaprocessor"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/wrtc"
 	"go.uber.org/fx"
 )
 
 type WebrtcStatefulStream struct {
-	Audio           *webrtc.TrackLocalStaticRTP
-	Video           *webrtc.TrackLocalStaticRTP
-	audioPipeReader *io.PipeReader
-	audioPipeWriter *io.PipeWriter
-	videoPipeReader *io.PipeReader
-	videoPipeWriter *io.PipeWriter
+	Audio *webrtc.TrackLocalStaticRTP
+	Video *webrtc.TrackLocalStaticRTP
+	// Pipes are ordered as media processors
+	audioPipeReaders []*io.PipeReader
+	audioPipeWriters []*io.PipeWriter
+	videoPipeReaders []*io.PipeReader
+	videoPipeWriters []*io.PipeWriter
+	// Built with the codec pipeline, processors read media of the bus into own pipe
+	audioBus *processorBus
+	videoBus *processorBus
+	// Video and audio of each processor go into one matroska stream timed by the stream clock. Processor which reads
+	// separate inputs has it only when video is not h264
+	matroskaMuxers []*matroska.RtpToMatroskaMuxWriter
 
-	mediaProcessors []mediaprocessor.MediaProcessor
+	// Rtp timestamps of the tracks on the shared timeline of the stream
+	clock      *rtp.Clock
+	videoClock *rtp.TrackClock
+	audioClock *rtp.TrackClock
+
+	// Simulcast layers of the video. Empty when publisher is not simulcast
+	Layers       *wrtc.LayerSet
+	primaryLayer *wrtc.Layer
+
+	// Codec pipelines outlive the publisher, so the next one continues outputs of the previous
+	videoInput *continuityWriter
+	audioInput *continuityWriter
+
+	// Media kind to codec of the first publisher
+	codecs          map[string]string
+	publishers      map[PublisherRole]*Publisher
+	active          *Publisher
+	activated       bool
+	failoverTimeout time.Duration
+
+	supervisors []*mediaprocessor.Supervisor
+	viewers     *wrtc.SessionRegistry
+
+	// Done when the stream is destroyed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mx sync.Mutex
+}
+
+var CodecChangedError = errors.New("publisher codec differs from the stream codec")
+
+// Outputs keep codecs of the first publisher, the next publishers must have the same
+func (s *WebrtcStatefulStream) Compatible(mimeType string) error {
+	kind, _, _ := strings.Cut(mimeType, "/")
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	codec, ok := s.codecs[kind]
+	if !ok {
+		s.codecs[kind] = mimeType
+		return nil
+	}
+	if !strings.EqualFold(codec, mimeType) {
+		return CodecChangedError
+	}
+	return nil
+}
+
+// Attach publisher of the role. The first publisher becomes active, the next one takes over by failover
+func (s *WebrtcStatefulStream) Attach(role PublisherRole) *Publisher {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	publisher := newPublisher(role)
+	s.publishers[role] = publisher
+	if s.active == nil {
+		s.activate(publisher)
+	}
+
+	return publisher
+}
+
+// Left active publisher is replaced by the other one at once
+func (s *WebrtcStatefulStream) Detach(publisher *Publisher) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.publishers[publisher.Role] == publisher {
+		delete(s.publishers, publisher.Role)
+	}
+
+	if s.active == publisher {
+		var next *Publisher
+		for _, other := range s.publishers {
+			next = other
+		}
+		s.activate(next)
+	}
+}
+
+// Point codec pipelines to sources of the publisher. Nil publisher drops all media. Must be called with the lock
+func (s *WebrtcStatefulStream) activate(publisher *Publisher) {
+	if s.active != nil {
+		s.active.active.Store(false)
+	}
+	s.active = publisher
+
+	for _, input := range []*continuityWriter{s.videoInput, s.audioInput} {
+		if input == nil {
+			continue
+		}
+		var source *continuitySource
+		if publisher != nil {
+			source = publisher.sources[input]
+		}
+		input.Activate(source)
+	}
+
+	if publisher == nil {
+		return
+	}
+	publisher.active.Store(true)
+	go publisher.requestKeyframe()
+
+	if s.activated {
+		s.markDiscontinuity()
+		if s.clock != nil {
+			s.clock.Resync()
+		}
+	}
+	s.activated = true
+}
+
+// Backup publisher is active while the primary one doesn't deliver media for the failover timeout.
+// Primary is active again when it delivers media for the same time
+func (s *WebrtcStatefulStream) failover(now time.Time) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	primary, backup := s.publishers[PrimaryPublisher], s.publishers[BackupPublisher]
+	if primary == nil || backup == nil {
+		return
+	}
+
+	healthy := primary.delivering(now, s.failoverTimeout)
+	if !healthy {
+		primary.healthySince = time.Time{}
+	} else if primary.healthySince.IsZero() {
+		primary.healthySince = now
+	}
+
+	switch s.active {
+	case primary:
+		if !healthy && backup.delivering(now, s.failoverTimeout) {
+			log.Println("[StatefulStream] Primary publisher doesn't deliver media. Switch to backup")
+			s.activate(backup)
+		}
+	case backup:
+		if healthy && now.Sub(primary.healthySince) >= s.failoverTimeout {
+			log.Println("[StatefulStream] Primary publisher recovered. Switch to primary")
+			s.activate(primary)
+		}
+	}
+}
+
+func (s *WebrtcStatefulStream) monitorFailover(ctx context.Context) {
+	ticker := time.NewTicker(s.failoverTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.failover(now)
+		}
+	}
 }
 
 func (s *WebrtcStatefulStream) Ingest(ctx context.Context) error {
 	defer log.Println("[StatefulStream] Ingestion process stopped")
 
-	ingestionCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	go s.monitorFailover(ctx)
 
-	for _, processor := range s.mediaProcessors {
-		go func(processor mediaprocessor.MediaProcessor, video, audio *io.PipeReader) {
-			defer cancel()
-			_ = processor.Transcode(ingestionCtx, video, audio)
-		}(processor, s.videoPipeReader, s.audioPipeReader)
+	// Crashed processor is restarted by its supervisor, the stream and other processors go on
+	for i, supervisor := range s.supervisors {
+		go supervisor.Run(ctx, s.videoPipeReaders[i], s.audioPipeReaders[i])
 	}
 
+	<-ctx.Done()
+	return nil
+}
+
+func (s *WebrtcStatefulStream) PipeH264RemoteTrack(ctx context.Context, publisher *Publisher, track *rtp.RtpTrackDemuxerReader) error {
+	defer log.Println("[PipeH264RemoteTrack] canceled")
+
+	return s.PipeH264(ctx, publisher, track)
+}
+
+// Take source of the codec pipeline. Pipeline is built by the first publisher and continued by the next ones
+func (s *WebrtcStatefulStream) source(input **continuityWriter, mimeType string, publisher *Publisher, build func() *continuityWriter) (io.Writer, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if *input == nil {
+		*input = build()
+		go (*input).Hold(s.ctx)
+	}
+
+	if !strings.EqualFold((*input).mimeType, mimeType) {
+		return nil, CodecChangedError
+	}
+
+	source := (*input).Source()
+	publisher.sources[*input] = source
+	if s.active == publisher {
+		(*input).Activate(source)
+	}
+
+	return publisher.writer(source), nil
+}
+
+// Pipe h264 rtp packets from any ingress into webrtc video track and media processors.
+// Processor which reads separate inputs gets raw Annex-B, others get it in own matroska stream
+func (s *WebrtcStatefulStream) PipeH264(ctx context.Context, publisher *Publisher, reader media.DemuxerReader) error {
+	input, err := s.source(&s.videoInput, webrtc.MimeTypeH264, publisher, func() *continuityWriter {
+		annexB := rtpMuxer(
+			func() media.MuxerWriter { return rtp.NewRtpToRtpMuxerWriter() },
+			func(pipe io.Writer) media.MediaWriter { return h264.NewRtpToH264MediaWriter(pipe) },
+		)
+
+		writers := make([]media.MediaWriter, len(s.videoPipeWriters))
+		for i, pipe := range s.videoPipeWriters {
+			if muxer := s.matroskaMuxers[i]; muxer != nil {
+				writers[i] = muxer.Video(webrtc.MimeTypeH264)
+				continue
+			}
+			writers[i] = &pipeMuxer{MediaWriter: annexB(pipe), pipe: pipe}
+		}
+		s.videoBus = newProcessorBus(rtpKeyframe(webrtc.MimeTypeH264), writers, processorVideoBuffer, media.DropUntilKeyframe)
+
+		return newContinuityWriter(io.MultiWriter(s.videoTrackWriter(), s.videoClock, s.videoBus), webrtc.MimeTypeH264, videoClockRate, videoHoldInterval)
+	})
+	if err != nil {
+		return err
+	}
+
+	rtp := media.NewDemuxerBuilder(reader, media.NewTargetMediaWriter(input))
+
+	go rtp.Demux()
+
 	select {
 	case <-ctx.Done():
-		cancel()
-	case <-ingestionCtx.Done():
 	}
-
 	return nil
 }
 
-func (s *WebrtcStatefulStream) PipeH264RemoteTrack(ctx context.Context, track *webrtc.TrackRemote) {
-	defer log.Println("[PipeH264RemoteTrack] canceled")
+func (s *WebrtcStatefulStream) PipeVP8RemoteTrack(ctx context.Context, publisher *Publisher, track *rtp.RtpTrackDemuxerReader) error {
+	defer log.Println("[PipeVP8RemoteTrack] canceled")
+
+	input, err := s.source(&s.videoInput, webrtc.MimeTypeVP8, publisher, func() *continuityWriter {
+		writers := make([]media.MediaWriter, len(s.matroskaMuxers))
+		for i, muxer := range s.matroskaMuxers {
+			if muxer == nil {
+				muxer = newMatroskaMuxer(s.videoPipeWriters[i], s.videoClock, s.audioClock)
+				s.matroskaMuxers[i] = muxer
+			}
+			writers[i] = muxer.Video(webrtc.MimeTypeVP8)
+		}
+		s.videoBus = newProcessorBus(rtpKeyframe(webrtc.MimeTypeVP8), writers, processorVideoBuffer, media.DropUntilKeyframe)
 
-	h264 := media.NewMuxerBuilder(rtp.NewRtpToRtpMuxerWriter(),
-		h264.NewRtpToH264MediaWriter(s.videoPipeWriter),
-	)
+		return newContinuityWriter(io.MultiWriter(s.videoTrackWriter(), s.videoClock, s.videoBus), webrtc.MimeTypeVP8, videoClockRate, videoHoldInterval)
+	})
+	if err != nil {
+		return err
+	}
 
-	rtp := media.NewDemuxerBuilder(rtp.NewRtpTrackDemuxerReader(track),
-		rtp.NewRtpTrackWriter(s.Video),
-		media.NewTargetMediaWriter(h264),
-	)
+	rtp := media.NewDemuxerBuilder(track, media.NewTargetMediaWriter(input))
 
-	go h264.Mux()
 	go rtp.Demux()
 
 	select {
 	case <-ctx.Done():
 	}
+	return nil
 }
 
-func (s *WebrtcStatefulStream) PipeVP8RemoteTrack(ctx context.Context, track *webrtc.TrackRemote) {
-	defer log.Println("[PipeVP8RemoteTrack] canceled")
+// Write into the track of the current publisher. Next publisher of the simulcast stream brings own primary layer
+type videoTrackWriter struct {
+	stream *WebrtcStatefulStream
+}
+
+func (w *videoTrackWriter) Write(p []byte) (int, error) {
+	layer, video := w.stream.videoTrack()
+	if layer != nil {
+		return layer.Write(p)
+	}
+	return video.Write(p)
+}
 
-	vp8 := media.NewMuxerBuilder(vp8.NewRtpToWebmVP8Writer(),
-		media.NewTargetMediaWriter(s.videoPipeWriter),
-	)
+// Viewers get video from the primary simulcast layer when stream is simulcast
+func (s *WebrtcStatefulStream) videoTrackWriter() media.MediaWriter {
+	return &videoTrackWriter{stream: s}
+}
+
+func (s *WebrtcStatefulStream) videoTrack() (*wrtc.Layer, *webrtc.TrackLocalStaticRTP) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.primaryLayer, s.Video
+}
 
-	rtp := media.NewDemuxerBuilder(rtp.NewRtpTrackDemuxerReader(track),
-		rtp.NewRtpTrackWriter(s.Video),
-		media.NewTargetMediaWriter(vp8),
-	)
+// Pipe simulcast layer of the remote track. Only primary layer goes to media processors, other layers only to viewers
+func (s *WebrtcStatefulStream) PipeLayerRemoteTrack(ctx context.Context, publisher *Publisher, track *rtp.RtpTrackDemuxerReader, layer *wrtc.Layer) error {
+	defer log.Printf("[PipeLayerRemoteTrack] %s layer canceled", layer.RID)
+
+	if s.Layers.IsPrimary(layer.RID) {
+		s.mx.Lock()
+		s.Video = layer.Track
+		s.primaryLayer = layer
+		s.mx.Unlock()
+
+		switch track.Codec().MimeType {
+		case webrtc.MimeTypeVP8:
+			return s.PipeVP8RemoteTrack(ctx, publisher, track)
+		case webrtc.MimeTypeH264:
+			return s.PipeH264RemoteTrack(ctx, publisher, track)
+		}
+		return nil
+	}
+
+	rtp := media.NewDemuxerBuilder(track, publisher.writer(layer))
 
-	go vp8.Mux()
 	go rtp.Demux()
 
 	select {
 	case <-ctx.Done():
 	}
+	return nil
 }
 
-func (s *WebrtcStatefulStream) PipeOpusRemoteTrack(ctx context.Context, track *webrtc.TrackRemote) {
+func (s *WebrtcStatefulStream) PipeOpusRemoteTrack(ctx context.Context, publisher *Publisher, track *rtp.RtpTrackDemuxerReader) error {
 	defer log.Println("[PipeOpusRemoteTrack] canceled")
 
-	opus := media.NewMuxerBuilder(opus.NewRtpToWebmOpusWriter(),
-		media.NewTargetMediaWriter(s.audioPipeWriter),
-	)
+	return s.PipeOpus(ctx, publisher, track)
+}
 
-	rtp := media.NewDemuxerBuilder(rtp.NewRtpTrackDemuxerReader(track),
-		rtp.NewRtpTrackWriter(s.Audio),
-		media.NewTargetMediaWriter(opus),
-	)
+// Pipe opus rtp packets from any ingress into webrtc audio track and media processors
+func (s *WebrtcStatefulStream) PipeOpus(ctx context.Context, publisher *Publisher, reader media.DemuxerReader) error {
+	input, err := s.source(&s.audioInput, webrtc.MimeTypeOpus, publisher, func() *continuityWriter {
+		writers := make([]media.MediaWriter, len(s.audioPipeWriters))
+		for i, pipe := range s.audioPipeWriters {
+			writers[i] = &processorAudioWriter{stream: s, index: i, pipe: pipe, mimeType: webrtc.MimeTypeOpus}
+		}
+		s.audioBus = newProcessorBus(nil, writers, processorAudioBuffer, media.DropOldest)
+
+		return newOpusContinuityWriter(io.MultiWriter(rtp.NewRtpTrackWriter(s.Audio), s.audioClock, s.audioBus), webrtc.MimeTypeOpus)
+	})
+	if err != nil {
+		return err
+	}
+
+	rtp := media.NewDemuxerBuilder(reader, media.NewTargetMediaWriter(input))
 
-	go opus.Mux()
 	go rtp.Demux()
 
 	select {
 	case <-ctx.Done():
 	}
+	return nil
+}
+
+// Pipe rtp packets of ADTS frames into media processors. Webrtc audio track is not populated.
+// Nothing is held between publishers, frames of the next one just follow
+func (s *WebrtcStatefulStream) PipeAAC(ctx context.Context, publisher *Publisher, reader media.DemuxerReader) error {
+	defer log.Println("[PipeAAC] canceled")
+
+	input, err := s.source(&s.audioInput, aac.MimeType, publisher, func() *continuityWriter {
+		writers := make([]media.MediaWriter, len(s.audioPipeWriters))
+		for i, pipe := range s.audioPipeWriters {
+			writers[i] = &processorAudioWriter{stream: s, index: i, pipe: pipe, mimeType: aac.MimeType}
+		}
+		s.audioBus = newProcessorBus(nil, writers, processorAudioBuffer, media.DropOldest)
+
+		return newContinuityWriter(io.MultiWriter(s.audioClock, s.audioBus), aac.MimeType, aac.RtpClockRate, audioHoldInterval)
+	})
+	if err != nil {
+		return err
+	}
+
+	demuxer := media.NewDemuxerBuilder(reader, media.NewTargetMediaWriter(input))
+
+	go demuxer.Demux()
+
+	select {
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// Processors which support it mark in own output that the next publisher continues the stream
+func (s *WebrtcStatefulStream) markDiscontinuity() {
+	for _, processor := range s.GetMediaProcessors() {
+		if marker, ok := processor.(mediaprocessor.DiscontinuityMarker); ok {
+			marker.MarkDiscontinuity()
+		}
+	}
 }
 
 func (s *WebrtcStatefulStream) Destroy() error {
-	for _, processor := range s.mediaProcessors {
-		processor.Destroy()
+	s.cancel()
+	s.viewers.CloseAll()
+
+	s.mx.Lock()
+	s.videoBus.close()
+	s.audioBus.close()
+	for _, muxer := range s.matroskaMuxers {
+		if muxer != nil {
+			_ = muxer.Close()
+		}
+	}
+	s.mx.Unlock()
+
+	// Processors finalize their output in parallel
+	var wg sync.WaitGroup
+	for _, supervisor := range s.supervisors {
+		wg.Add(1)
+		go func(supervisor *mediaprocessor.Supervisor) {
+			defer wg.Done()
+			supervisor.Destroy()
+		}(supervisor)
 	}
+	wg.Wait()
 	return nil
 }
 
+// Sender report of the publisher track anchors the track on the stream timeline. Reports of inactive publisher are skipped
+func (s *WebrtcStatefulStream) SenderReport(publisher *Publisher, kind webrtc.RTPCodecType, report *rtcp.SenderReport) {
+	s.mx.Lock()
+	input, clock := s.audioInput, s.audioClock
+	if kind == webrtc.RTPCodecTypeVideo {
+		input, clock = s.videoInput, s.videoClock
+	}
+	source := publisher.sources[input]
+	s.mx.Unlock()
+
+	if source == nil || clock == nil {
+		return
+	}
+
+	timestamp, ok := source.Timestamp(report.RTPTime)
+	if !ok {
+		return
+	}
+	clock.SenderReport(report.NTPTime, timestamp)
+}
+
+// Matroska stream of the processor. Nil for the processor which reads separate inputs until vp8 video comes
+func (s *WebrtcStatefulStream) matroskaMuxer(i int) *matroska.RtpToMatroskaMuxWriter {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if i >= len(s.matroskaMuxers) {
+		return nil
+	}
+	return s.matroskaMuxers[i]
+}
+
+// Restarted processor gets the keyframe of the active publisher
+func (s *WebrtcStatefulStream) requestKeyframe() {
+	s.mx.Lock()
+	publisher := s.active
+	s.mx.Unlock()
+
+	if publisher != nil {
+		publisher.requestKeyframe()
+	}
+}
+
 func (s *WebrtcStatefulStream) SetVideoTrack(track *webrtc.TrackLocalStaticRTP) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
 	s.Video = track
 }
 
-type WebrtcAllocatorFunc func() (*WebrtcStatefulStream, error)
+// WHEP sessions of the stream viewers. All of them are closed when stream destroyed
+func (s *WebrtcStatefulStream) GetViewers() *wrtc.SessionRegistry {
+	return s.viewers
+}
+
+// Running processors of the stream. Processor which is down until restart is skipped
+func (s *WebrtcStatefulStream) GetMediaProcessors() []mediaprocessor.MediaProcessor {
+	processors := make([]mediaprocessor.MediaProcessor, 0, len(s.supervisors))
+	for _, supervisor := range s.supervisors {
+		if processor := supervisor.Processor(); processor != nil {
+			processors = append(processors, processor)
+		}
+	}
+	return processors
+}
+
+func (s *WebrtcStatefulStream) GetProcessorStatuses() []mediaprocessor.ProcessorStatus {
+	s.mx.Lock()
+	videoBus, audioBus := s.videoBus, s.audioBus
+	s.mx.Unlock()
+
+	statuses := make([]mediaprocessor.ProcessorStatus, len(s.supervisors))
+	for i, supervisor := range s.supervisors {
+		statuses[i] = supervisor.Status()
+		statuses[i].DroppedFrames = videoBus.dropped(i) + audioBus.dropped(i)
+	}
+	return statuses
+}
+
+// Layers are simulcast RIDs declared by publisher. Empty for not simulcast publish
+type WebrtcAllocatorFunc func(key string, layers []string) (*WebrtcStatefulStream, error)
 
 type WebrtcAllocatorFuncParams struct {
 	fx.In
 
-	HLSMediaProcessor mediaprocessor.MediaProcessor `name:"mediaprocessor.hls.default"`
+	Config   *Config
+	Registry *mediaprocessor.Registry
+	Notifier *lifecycle.Notifier
 }
 
 func NewWebrtcAllocatorFunc(params WebrtcAllocatorFuncParams) WebrtcAllocatorFunc {
-	return func() (*WebrtcStatefulStream, error) {
-		audioPipeReader, audioPipeWriter := io.Pipe()
-		videoPipeReader, videoPipeWriter := io.Pipe()
-
-		return &WebrtcStatefulStream{
-			audioPipeReader: audioPipeReader,
-			audioPipeWriter: audioPipeWriter,
-			videoPipeReader: videoPipeReader,
-			videoPipeWriter: videoPipeWriter,
-			mediaProcessors: []mediaprocessor.MediaProcessor{
-				params.HLSMediaProcessor,
-			},
-		}, nil
+	return func(key string, layers []string) (*WebrtcStatefulStream, error) {
+		// Each stream must have own processors
+		supervisors, err := params.Registry.Supervise(key)
+		if err != nil {
+			return nil, err
+		}
+
+		audioPipeReaders, audioPipeWriters := newPipes(len(supervisors))
+		videoPipeReaders, videoPipeWriters := newPipes(len(supervisors))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		clock := rtp.NewClock()
+
+		stream := &WebrtcStatefulStream{
+			audioPipeReaders: audioPipeReaders,
+			audioPipeWriters: audioPipeWriters,
+			videoPipeReaders: videoPipeReaders,
+			videoPipeWriters: videoPipeWriters,
+			supervisors:      supervisors,
+			Layers:           wrtc.NewLayerSet(layers),
+			viewers:          wrtc.NewSessionRegistry(),
+			codecs:           make(map[string]string),
+			publishers:       make(map[PublisherRole]*Publisher),
+			failoverTimeout:  params.Config.FailoverTimeout,
+			clock:            clock,
+			videoClock:       clock.Track(videoClockRate),
+			audioClock:       clock.Track(opusClockRate),
+			ctx:              ctx,
+			cancel:           cancel,
+		}
+
+		// Processor which reads separate inputs gets them unless video is vp8
+		stream.matroskaMuxers = make([]*matroska.RtpToMatroskaMuxWriter, len(supervisors))
+		for i, supervisor := range supervisors {
+			if _, ok := supervisor.Processor().(mediaprocessor.SeparateInputReceiver); !ok {
+				stream.matroskaMuxers[i] = newMatroskaMuxer(videoPipeWriters[i], stream.videoClock, stream.audioClock)
+			}
+		}
+
+		for _, supervisor := range supervisors {
+			supervisor.OnRestart(stream.requestKeyframe)
+			supervisor.OnStart(func(processor mediaprocessor.MediaProcessor) {
+				if receiver, ok := processor.(mediaprocessor.WallClockReceiver); ok {
+					receiver.SetWallClock(clock)
+				}
+			})
+			supervisor.OnStatus(func(status mediaprocessor.ProcessorStatus) {
+				params.Notifier.ProcessorStatus(key, status)
+			})
+		}
+
+		return stream, nil
 	}
 }
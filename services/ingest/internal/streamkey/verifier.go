@@ -0,0 +1,142 @@
+package streamkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/romashorodok/stream-platform/pkg/tokenutils"
+	"github.com/romashorodok/stream-platform/services/ingest/pkg/service"
+	"go.uber.org/fx"
+)
+
+const (
+	streamKeyTokenUse = "stream_key"
+	keysRoute         = "/keys"
+	identityTimeout   = 5 * time.Second
+)
+
+var (
+	EmptyStreamKeyError        = errors.New("empty stream key")
+	InvalidStreamKeyError      = errors.New("invalid stream key")
+	IdentityUnavailableError   = errors.New("identity service unavailable")
+	ForeignBroadcasterKeyError = errors.New("stream key issued for another broadcaster")
+	ForeignStreamKeyError      = errors.New("stream key issued for another stream")
+)
+
+type streamKeyClaims struct {
+	Subject  string `json:"sub"`
+	UserID   string `json:"user:id"`
+	TokenUse string `json:"token:use"`
+}
+
+// Owner of the verified stream key. Stream of the broadcaster is its username
+type Broadcaster struct {
+	ID       string
+	Username string
+}
+
+// Check the stream key bearer of the publisher. Key must be signed by identity and issued for the stream it publishes.
+// Dedicated ingest also accepts only keys of its broadcaster
+type Verifier struct {
+	client        *http.Client
+	identityURL   string
+	dedicated     bool
+	broadcasterID string
+}
+
+// Identity resolve public keys by kid of the passed token
+func (v *Verifier) getKeySet(ctx context.Context, plainToken string) (jwk.Set, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.identityURL+keysRoute, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+plainToken)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Err: %s", IdentityUnavailableError, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Err: %s", IdentityUnavailableError, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w. Unable get public keys: %s", InvalidStreamKeyError, body)
+	}
+
+	keyset, err := jwk.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Unable parse public keys. Err: %s", InvalidStreamKeyError, err)
+	}
+
+	return keyset, nil
+}
+
+// Verify raw stream key of the stream publisher. It may be passed with `Bearer ` prefix
+func (v *Verifier) Verify(ctx context.Context, rawStreamKey string, stream string) (*Broadcaster, error) {
+	plainToken := tokenutils.TrimTokenBearer(rawStreamKey)
+	if plainToken == "" {
+		return nil, EmptyStreamKeyError
+	}
+
+	if _, err := tokenutils.InsecureJwsMessage(plainToken); err != nil {
+		return nil, fmt.Errorf("%w. Err: %s", InvalidStreamKeyError, err)
+	}
+
+	keyset, err := v.getKeySet(ctx, plainToken)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := tokenutils.VerifyTokenByKeySet(keyset, plainToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Not verified. Err: %s", InvalidStreamKeyError, err)
+	}
+
+	if err = tokenutils.ValidateToken(plainToken); err != nil {
+		return nil, fmt.Errorf("%w. Err: %s", InvalidStreamKeyError, err)
+	}
+
+	var claims streamKeyClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w. Err: %s", InvalidStreamKeyError, err)
+	}
+
+	if claims.TokenUse != streamKeyTokenUse {
+		return nil, fmt.Errorf("%w. Unexpected token use %s", InvalidStreamKeyError, claims.TokenUse)
+	}
+
+	if claims.Subject != stream {
+		return nil, ForeignStreamKeyError
+	}
+
+	if v.dedicated && claims.UserID != v.broadcasterID {
+		return nil, ForeignBroadcasterKeyError
+	}
+
+	return &Broadcaster{ID: claims.UserID, Username: claims.Subject}, nil
+}
+
+type VerifierParams struct {
+	fx.In
+
+	IngestSystemConfig *service.IngestSystemConfig
+}
+
+func NewVerifier(params VerifierParams) *Verifier {
+	return &Verifier{
+		client:        &http.Client{Timeout: identityTimeout},
+		identityURL:   params.IngestSystemConfig.IdentityURL,
+		dedicated:     params.IngestSystemConfig.Dedicated,
+		broadcasterID: params.IngestSystemConfig.BroadcasterID,
+	}
+}
@@ -0,0 +1,108 @@
+package streamkey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testKid           = "2b1f7a3e-8c4d-4d6e-9f10-6a7b8c9d0e1f"
+	testBroadcasterID = "6f1c2e3d-4b5a-4c6d-8e7f-9a0b1c2d3e4f"
+)
+
+// Serve public keys like identity does. Keys are resolved by token kid
+func identityServer(t *testing.T, key jwk.Key) *httptest.Server {
+	public, err := jwk.PublicKeyOf(key)
+	assert.Nil(t, err)
+	_ = public.Set(jwk.AlgorithmKey, jwa.RS256)
+	_ = public.Set(jwk.KeyIDKey, testKid)
+
+	keyset := jwk.NewSet()
+	_ = keyset.AddKey(public)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != keysRoute || r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(keyset)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func signStreamKey(t *testing.T, key jwk.Key, userID, tokenUse string, expiresAt time.Time) string {
+	token, err := jwt.NewBuilder().Subject("admin").Expiration(expiresAt).Build()
+	assert.Nil(t, err)
+	_ = token.Set("user:id", userID)
+	_ = token.Set("token:use", tokenUse)
+
+	headers := jws.NewHeaders()
+	_ = headers.Set(jws.KeyIDKey, testKid)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.RS256, key, jws.WithProtectedHeaders(headers)))
+	assert.Nil(t, err)
+	return string(signed)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	key, err := jwk.FromRaw(raw)
+	assert.Nil(t, err)
+
+	otherRaw, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	otherKey, err := jwk.FromRaw(otherRaw)
+	assert.Nil(t, err)
+
+	identityURL := identityServer(t, key).URL
+	shared := &Verifier{client: http.DefaultClient, identityURL: identityURL}
+	dedicated := &Verifier{client: http.DefaultClient, identityURL: identityURL, dedicated: true, broadcasterID: testBroadcasterID}
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		verifier  *Verifier
+		streamKey string
+		stream    string
+		err       error
+	}{
+		{"valid", dedicated, "Bearer " + signStreamKey(t, key, testBroadcasterID, streamKeyTokenUse, expiresAt), "admin", nil},
+		{"missing", dedicated, "", "admin", EmptyStreamKeyError},
+		{"malformed", dedicated, "Bearer not-a-token", "admin", InvalidStreamKeyError},
+		{"foreign signature", dedicated, "Bearer " + signStreamKey(t, otherKey, testBroadcasterID, streamKeyTokenUse, expiresAt), "admin", InvalidStreamKeyError},
+		{"access token", dedicated, "Bearer " + signStreamKey(t, key, testBroadcasterID, "access_token", expiresAt), "admin", InvalidStreamKeyError},
+		{"expired", dedicated, "Bearer " + signStreamKey(t, key, testBroadcasterID, streamKeyTokenUse, time.Now().Add(-time.Hour)), "admin", InvalidStreamKeyError},
+		{"another stream", shared, "Bearer " + signStreamKey(t, key, testBroadcasterID, streamKeyTokenUse, expiresAt), "another", ForeignStreamKeyError},
+		{"another broadcaster of dedicated ingest", dedicated, "Bearer " + signStreamKey(t, key, "another", streamKeyTokenUse, expiresAt), "admin", ForeignBroadcasterKeyError},
+		{"any broadcaster of shared ingest", shared, "Bearer " + signStreamKey(t, key, "another", streamKeyTokenUse, expiresAt), "admin", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broadcaster, err := test.verifier.Verify(context.Background(), test.streamKey, test.stream)
+			if test.err == nil {
+				assert.Nil(t, err)
+				assert.Equal(t, test.stream, broadcaster.Username)
+				return
+			}
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
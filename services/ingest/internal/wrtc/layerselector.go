@@ -0,0 +1,397 @@
+package wrtc
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// Viewer may switch layer manually by message over data channel with this label
+	LayerDataChannelLabel = "layers"
+	// Requested layer which turn on switching by bandwidth estimation
+	LayerAuto = "auto"
+
+	// Part of the estimated bandwidth which may be taken by video
+	bitrateHeadroom = 0.85
+	// Switch down is not done more often than this
+	switchDownInterval = 2 * time.Second
+	// Estimation must allow higher layer for this duration before switch up
+	switchUpHold = 5 * time.Second
+	// Receiver estimated bitrate is preferred over loss reports until it's stale
+	estimateTimeout = 5 * time.Second
+
+	// Fraction lost of receiver reports in 1/256 units
+	lossHigh = 26
+	lossLow  = 5
+)
+
+type rtpWriter interface {
+	WriteRTP(*rtp.Packet) error
+}
+
+// Message of the layer data channel. Sent on open and after each switch
+type LayerState struct {
+	Layers []string `json:"layers"`
+	Layer  string   `json:"layer"`
+	Auto   bool     `json:"auto"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Message from the viewer. Layer is RID or auto
+type LayerRequest struct {
+	Layer string `json:"layer"`
+}
+
+// Forward one simulcast layer into the viewer track. Switch is done on keyframe of the target layer,
+// sequence numbers and timestamps are rewritten so the viewer see continuous stream
+type LayerSelector struct {
+	Track *webrtc.TrackLocalStaticRTP
+
+	set       *LayerSet
+	writer    rtpWriter
+	mimeType  string
+	clockRate uint32
+	channel   *webrtc.DataChannel
+
+	current *Layer
+	target  *Layer
+	auto    bool
+
+	estimate    uint64
+	estimatedAt time.Time
+	upSince     time.Time
+	switchedAt  time.Time
+
+	started       bool
+	seqOffset     uint16
+	tsOffset      uint32
+	lastSeq       uint16
+	lastTimestamp uint32
+	lastWrite     time.Time
+
+	mx sync.Mutex
+}
+
+func newLayerSelector(set *LayerSet, writer rtpWriter, codec webrtc.RTPCodecCapability) *LayerSelector {
+	selector := &LayerSelector{
+		set:       set,
+		writer:    writer,
+		mimeType:  codec.MimeType,
+		clockRate: codec.ClockRate,
+		auto:      true,
+	}
+
+	// Start from the lowest layer. Estimation switch it up when viewer has enough bandwidth
+	if layers := set.Layers(); len(layers) > 0 {
+		selector.setTarget(layers[len(layers)-1])
+	}
+
+	set.subscribe(selector)
+	return selector
+}
+
+// Viewer track has codec of the layers. Selector starts in auto mode
+func NewLayerSelector(set *LayerSet, streamID string) (*LayerSelector, error) {
+	layers := set.Layers()
+	if len(layers) == 0 {
+		return nil, NoLayersError
+	}
+
+	codec := layers[0].Track.Codec()
+	track, err := webrtc.NewTrackLocalStaticRTP(codec, "video", streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := newLayerSelector(set, track, codec)
+	selector.Track = track
+	return selector, nil
+}
+
+func (s *LayerSelector) setTarget(layer *Layer) {
+	if layer == s.current {
+		s.target = nil
+		return
+	}
+	s.target = layer
+	layer.RequestKeyframe()
+}
+
+func (s *LayerSelector) onLayer(layer *Layer) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.current == nil && s.target == nil {
+		s.setTarget(layer)
+	}
+}
+
+func (s *LayerSelector) forward(layer *Layer, pkt *rtp.Packet) {
+	s.mx.Lock()
+
+	switched := false
+	if layer == s.target && IsKeyframe(s.mimeType, pkt.Payload) {
+		s.switchTo(layer, pkt)
+		switched = true
+	}
+
+	if layer != s.current {
+		s.mx.Unlock()
+		return
+	}
+
+	out := *pkt
+	out.Header.SequenceNumber = pkt.SequenceNumber + s.seqOffset
+	out.Header.Timestamp = pkt.Timestamp + s.tsOffset
+
+	if !s.started || int16(out.Header.SequenceNumber-s.lastSeq) > 0 {
+		s.lastSeq = out.Header.SequenceNumber
+		s.lastTimestamp = out.Header.Timestamp
+	}
+	s.started = true
+	s.lastWrite = time.Now()
+
+	_ = s.writer.WriteRTP(&out)
+	s.mx.Unlock()
+
+	if switched {
+		s.notify("")
+	}
+}
+
+// Continue sequence and timestamp of the previous layer. Timestamp advance by the time passed since last packet
+func (s *LayerSelector) switchTo(layer *Layer, pkt *rtp.Packet) {
+	if s.started {
+		delta := uint32(time.Since(s.lastWrite).Seconds() * float64(s.clockRate))
+		if delta == 0 {
+			delta = 1
+		}
+		s.seqOffset = s.lastSeq + 1 - pkt.SequenceNumber
+		s.tsOffset = s.lastTimestamp + delta - pkt.Timestamp
+	}
+
+	s.current = layer
+	s.target = nil
+	s.switchedAt = time.Now()
+}
+
+// Pick the highest layer fitting into estimation. Switch down is done at once, switch up only when estimation is stable
+func (s *LayerSelector) adapt() {
+	if !s.auto {
+		return
+	}
+
+	var candidates []*Layer
+	for _, layer := range s.set.Layers() {
+		if layer.Bitrate() > 0 {
+			candidates = append(candidates, layer)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	choice := candidates[len(candidates)-1]
+	for _, layer := range candidates {
+		if float64(layer.Bitrate()) <= float64(s.estimate)*bitrateHeadroom {
+			choice = layer
+			break
+		}
+	}
+
+	reference := s.target
+	if reference == nil {
+		reference = s.current
+	}
+	if choice == reference {
+		s.upSince = time.Time{}
+		return
+	}
+
+	now := time.Now()
+	if reference != nil && choice.Bitrate() > reference.Bitrate() {
+		if s.upSince.IsZero() {
+			s.upSince = now
+		}
+		if now.Sub(s.upSince) < switchUpHold {
+			return
+		}
+	} else if now.Sub(s.switchedAt) < switchDownInterval {
+		return
+	}
+
+	s.upSince = time.Time{}
+	s.setTarget(choice)
+}
+
+// Handle receiver estimated maximum bitrate of the viewer
+func (s *LayerSelector) OnEstimate(bitrate uint64) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.estimate = bitrate
+	s.estimatedAt = time.Now()
+	s.adapt()
+}
+
+// Handle fraction lost of the viewer receiver report. It's used only when viewer doesn't send bitrate estimation
+func (s *LayerSelector) OnLoss(fractionLost uint8) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if time.Since(s.estimatedAt) < estimateTimeout || s.current == nil {
+		return
+	}
+
+	bitrate := s.current.Bitrate()
+	switch {
+	case fractionLost > lossHigh:
+		s.estimate = bitrate * 3 / 4
+	case fractionLost < lossLow:
+		s.estimate = bitrate * 2
+	default:
+		return
+	}
+	s.adapt()
+}
+
+// Select layer by RID or turn on auto mode
+func (s *LayerSelector) Select(rid string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if rid == LayerAuto {
+		s.auto = true
+		s.adapt()
+		return nil
+	}
+
+	layer, err := s.set.Get(rid)
+	if err != nil {
+		return err
+	}
+
+	s.auto = false
+	s.upSince = time.Time{}
+	s.setTarget(layer)
+	return nil
+}
+
+func (s *LayerSelector) requestKeyframe() {
+	s.mx.Lock()
+	current := s.current
+	s.mx.Unlock()
+
+	if current != nil {
+		current.RequestKeyframe()
+	}
+}
+
+// Loss of the sender with the ssrc. Receiver report of the viewer holds blocks of the audio too
+func senderFractionLost(packet *rtcp.ReceiverReport, ssrc webrtc.SSRC) (uint8, bool) {
+	for _, report := range packet.Reports {
+		if report.SSRC == uint32(ssrc) {
+			return report.FractionLost, true
+		}
+	}
+	return 0, false
+}
+
+// Read rtcp of the viewer. Keyframe requests are passed to the publisher of the current layer
+func (s *LayerSelector) ReadRTCP(sender *webrtc.RTPSender) {
+	var ssrc webrtc.SSRC
+	if encodings := sender.GetParameters().Encodings; len(encodings) > 0 {
+		ssrc = encodings[0].SSRC
+	}
+
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+
+		for _, packet := range packets {
+			switch packet := packet.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				s.requestKeyframe()
+			case *rtcp.ReceiverEstimatedMaximumBitrate:
+				s.OnEstimate(uint64(packet.Bitrate))
+			case *rtcp.ReceiverReport:
+				if fractionLost, ok := senderFractionLost(packet, ssrc); ok {
+					s.OnLoss(fractionLost)
+				}
+			}
+		}
+	}
+}
+
+func (s *LayerSelector) state() LayerState {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	state := LayerState{Layers: s.set.RIDs(), Auto: s.auto}
+	if s.current != nil {
+		state.Layer = s.current.RID
+	}
+	return state
+}
+
+func (s *LayerSelector) notify(errMessage string) {
+	s.mx.Lock()
+	channel := s.channel
+	s.mx.Unlock()
+
+	if channel == nil || channel.ReadyState() != webrtc.DataChannelStateOpen {
+		return
+	}
+
+	state := s.state()
+	state.Error = errMessage
+
+	message, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	_ = channel.SendText(string(message))
+}
+
+// Accept layer requests of the viewer. Other data channels are ignored
+func (s *LayerSelector) HandleDataChannel(channel *webrtc.DataChannel) {
+	if channel.Label() != LayerDataChannelLabel {
+		return
+	}
+
+	s.mx.Lock()
+	s.channel = channel
+	s.mx.Unlock()
+
+	channel.OnOpen(func() {
+		s.notify("")
+	})
+
+	channel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		var request LayerRequest
+		if err := json.Unmarshal(msg.Data, &request); err != nil {
+			s.notify(err.Error())
+			return
+		}
+
+		if err := s.Select(request.Layer); err != nil {
+			log.Printf("[LayerSelector] unable select %s layer. Err: %s", request.Layer, err)
+			s.notify(err.Error())
+			return
+		}
+		s.notify("")
+	})
+}
+
+// Stop forwarding into the viewer track
+func (s *LayerSelector) Close() {
+	s.set.unsubscribe(s)
+}
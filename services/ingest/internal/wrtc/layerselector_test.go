@@ -0,0 +1,136 @@
+package wrtc
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type rtpRecorder struct {
+	packets []rtp.Packet
+	mx      sync.Mutex
+}
+
+func (r *rtpRecorder) WriteRTP(p *rtp.Packet) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.packets = append(r.packets, *p)
+	return nil
+}
+
+var h264Capability = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000}
+
+func addLayer(t *testing.T, set *LayerSet, rid string, keyframes *int) *Layer {
+	track, err := webrtc.NewTrackLocalStaticRTP(h264Capability, "video", "test")
+	assert.Nil(t, err)
+	return set.Add(rid, track, func() { *keyframes++ })
+}
+
+func writeLayer(t *testing.T, layer *Layer, seq uint16, timestamp uint32, payload []byte) {
+	raw, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: seq, Timestamp: timestamp},
+		Payload: payload,
+	}).Marshal()
+	assert.Nil(t, err)
+
+	_, err = layer.Write(raw)
+	assert.Nil(t, err)
+}
+
+var (
+	idr   = []byte{0x65, 0x88}
+	slice = []byte{0x41, 0x9a}
+)
+
+func TestSimulcastRIDs(t *testing.T) {
+	offer := "v=0\r\nm=audio 9 UDP/TLS/RTP/SAVPF 111\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\na=rid:f send\r\na=simulcast:send f;~h;q,x\r\n"
+
+	assert.Equal(t, []string{"f", "h", "q"}, SimulcastRIDs(offer))
+	assert.Empty(t, SimulcastRIDs("v=0\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\n"))
+}
+
+func TestIsKeyframe(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsKeyframe(webrtc.MimeTypeH264, idr))
+	assert.False(IsKeyframe(webrtc.MimeTypeH264, slice))
+	// STAP-A with SPS and PPS
+	assert.True(IsKeyframe(webrtc.MimeTypeH264, []byte{0x18, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x68, 0xce}))
+	// FU-A start of IDR and its continuation
+	assert.True(IsKeyframe(webrtc.MimeTypeH264, []byte{0x7c, 0x85, 0x00}))
+	assert.False(IsKeyframe(webrtc.MimeTypeH264, []byte{0x7c, 0x05, 0x00}))
+
+	// VP8 start of partition with picture id, then key frame and inter frame
+	assert.True(IsKeyframe(webrtc.MimeTypeVP8, []byte{0x90, 0x80, 0x81, 0x02, 0x10}))
+	assert.False(IsKeyframe(webrtc.MimeTypeVP8, []byte{0x90, 0x80, 0x81, 0x02, 0x11}))
+	assert.False(IsKeyframe(webrtc.MimeTypeVP8, []byte{0x00, 0x10}))
+}
+
+func TestLayerSelector_SwitchOnKeyframe(t *testing.T) {
+	assert := assert.New(t)
+
+	set := NewLayerSet([]string{"h", "l"})
+	var highKeyframes, lowKeyframes int
+	high := addLayer(t, set, "h", &highKeyframes)
+	low := addLayer(t, set, "l", &lowKeyframes)
+
+	recorder := &rtpRecorder{}
+	selector := newLayerSelector(set, recorder, h264Capability)
+	defer selector.Close()
+
+	// Viewer starts from the lowest layer and waits its keyframe
+	assert.Equal(1, lowKeyframes)
+	writeLayer(t, low, 99, 1000, slice)
+	writeLayer(t, high, 4999, 70000, idr)
+	assert.Empty(recorder.packets)
+
+	writeLayer(t, low, 100, 1000, idr)
+	writeLayer(t, low, 101, 4000, slice)
+
+	assert.Nil(selector.Select("h"))
+	assert.Equal(1, highKeyframes)
+	assert.Equal(LayerNotFoundError, selector.Select("m"))
+
+	// Current layer keeps going until the target keyframe
+	writeLayer(t, high, 5000, 73000, slice)
+	writeLayer(t, low, 102, 7000, slice)
+	writeLayer(t, high, 5001, 76000, idr)
+	writeLayer(t, low, 103, 10000, slice)
+	writeLayer(t, high, 5002, 79000, slice)
+
+	var sequences []uint16
+	for _, packet := range recorder.packets {
+		sequences = append(sequences, packet.SequenceNumber)
+	}
+	assert.Equal([]uint16{100, 101, 102, 103, 104}, sequences)
+
+	switched := recorder.packets[3]
+	assert.Equal(idr, switched.Payload)
+	assert.Greater(switched.Timestamp, uint32(7000))
+	assert.Equal(uint32(3000), recorder.packets[4].Timestamp-switched.Timestamp)
+
+	state := selector.state()
+	assert.Equal("h", state.Layer)
+	assert.False(state.Auto)
+}
+
+func TestSenderFractionLost(t *testing.T) {
+	assert := assert.New(t)
+
+	report := &rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{
+		{SSRC: 1111, FractionLost: 200},
+		{SSRC: 2222, FractionLost: 3},
+	}}
+
+	fractionLost, ok := senderFractionLost(report, 2222)
+	assert.True(ok)
+	assert.Equal(uint8(3), fractionLost)
+
+	// Report of the audio only
+	_, ok = senderFractionLost(&rtcp.ReceiverReport{Reports: report.Reports[:1]}, 2222)
+	assert.False(ok)
+}
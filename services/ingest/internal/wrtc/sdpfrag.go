@@ -0,0 +1,124 @@
+package wrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// Content type of the WHIP and WHEP trickle ICE requests
+	TrickleICEContentType = "application/trickle-ice-sdpfrag"
+
+	attributeICEUfrag        = "a=ice-ufrag:"
+	attributeICEPwd          = "a=ice-pwd:"
+	attributeCandidate       = "a=candidate:"
+	attributeMid             = "a=mid:"
+	attributeEndOfCandidates = "a=end-of-candidates"
+)
+
+// SDP fragment of trickle ICE. Look at RFC 8840
+type SDPFragment struct {
+	ICEUfrag   string
+	ICEPwd     string
+	Mid        string
+	MediaLine  string
+	Candidates []string
+	Completed  bool
+}
+
+func splitSDPLines(sdp string) []string {
+	return strings.Split(strings.ReplaceAll(sdp, "\r\n", "\n"), "\n")
+}
+
+func ParseSDPFragment(fragment string) *SDPFragment {
+	frag := &SDPFragment{}
+
+	for _, line := range splitSDPLines(fragment) {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, attributeICEUfrag):
+			frag.ICEUfrag = strings.TrimPrefix(line, attributeICEUfrag)
+		case strings.HasPrefix(line, attributeICEPwd):
+			frag.ICEPwd = strings.TrimPrefix(line, attributeICEPwd)
+		case strings.HasPrefix(line, attributeMid) && frag.Mid == "":
+			frag.Mid = strings.TrimPrefix(line, attributeMid)
+		case strings.HasPrefix(line, "m=") && frag.MediaLine == "":
+			frag.MediaLine = line
+		case strings.HasPrefix(line, attributeCandidate):
+			frag.Candidates = append(frag.Candidates, strings.TrimPrefix(line, "a="))
+		case line == attributeEndOfCandidates:
+			frag.Completed = true
+		}
+	}
+
+	return frag
+}
+
+// Take ICE credentials and first media section of the session description. Candidates are not included
+func SDPFragmentFromDescription(sdp string) *SDPFragment {
+	frag := ParseSDPFragment(sdp)
+	frag.Candidates = nil
+	frag.Completed = false
+	return frag
+}
+
+func (f *SDPFragment) String() string {
+	var sb strings.Builder
+
+	if f.ICEUfrag != "" {
+		fmt.Fprintf(&sb, "%s%s\r\n", attributeICEUfrag, f.ICEUfrag)
+	}
+	if f.ICEPwd != "" {
+		fmt.Fprintf(&sb, "%s%s\r\n", attributeICEPwd, f.ICEPwd)
+	}
+	if f.MediaLine != "" {
+		fmt.Fprintf(&sb, "%s\r\n", f.MediaLine)
+	}
+	if f.Mid != "" {
+		fmt.Fprintf(&sb, "%s%s\r\n", attributeMid, f.Mid)
+	}
+	for _, candidate := range f.Candidates {
+		fmt.Fprintf(&sb, "a=%s\r\n", candidate)
+	}
+	if f.Completed {
+		fmt.Fprintf(&sb, "%s\r\n", attributeEndOfCandidates)
+	}
+
+	return sb.String()
+}
+
+func (f *SDPFragment) CandidateInits() []webrtc.ICECandidateInit {
+	var mid *string
+	if f.Mid != "" {
+		mid = &f.Mid
+	}
+
+	inits := make([]webrtc.ICECandidateInit, len(f.Candidates))
+	for i, candidate := range f.Candidates {
+		inits[i] = webrtc.ICECandidateInit{Candidate: candidate, SDPMid: mid}
+	}
+	return inits
+}
+
+// Replace ICE credentials of the session description and drop its candidates. Used to restart ICE by the new remote credentials
+func ReplaceICECredentials(sdp, ufrag, pwd string) string {
+	lines := splitSDPLines(sdp)
+	result := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, attributeICEUfrag):
+			line = attributeICEUfrag + ufrag
+		case strings.HasPrefix(line, attributeICEPwd):
+			line = attributeICEPwd + pwd
+		case strings.HasPrefix(line, attributeCandidate), strings.HasPrefix(line, attributeEndOfCandidates):
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\r\n")
+}
@@ -0,0 +1,101 @@
+package wrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSDPFragment(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		expected SDPFragment
+	}{
+		{
+			name: "trickle candidates",
+			fragment: "a=ice-ufrag:EsAw\r\na=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1\r\nm=audio 9 RTP/AVP 0\r\na=mid:0\r\n" +
+				"a=candidate:1 1 UDP 2130706431 198.51.100.1 39132 typ host\r\na=candidate:2 1 UDP 1694498815 192.0.2.1 39132 typ srflx\r\n",
+			expected: SDPFragment{
+				ICEUfrag:  "EsAw",
+				ICEPwd:    "P2uYro0UCOQ4zxjKXaWCBui1",
+				Mid:       "0",
+				MediaLine: "m=audio 9 RTP/AVP 0",
+				Candidates: []string{
+					"candidate:1 1 UDP 2130706431 198.51.100.1 39132 typ host",
+					"candidate:2 1 UDP 1694498815 192.0.2.1 39132 typ srflx",
+				},
+			},
+		},
+		{
+			name:     "end of candidates with lf lines",
+			fragment: "a=ice-ufrag:EsAw\na=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1\na=end-of-candidates\n",
+			expected: SDPFragment{ICEUfrag: "EsAw", ICEPwd: "P2uYro0UCOQ4zxjKXaWCBui1", Completed: true},
+		},
+		{
+			name:     "first media section only",
+			fragment: "m=audio 9 RTP/AVP 0\r\na=mid:0\r\nm=video 9 RTP/AVP 96\r\na=mid:1\r\n",
+			expected: SDPFragment{Mid: "0", MediaLine: "m=audio 9 RTP/AVP 0"},
+		},
+		{
+			name:     "empty",
+			fragment: "",
+			expected: SDPFragment{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, &test.expected, ParseSDPFragment(test.fragment))
+		})
+	}
+}
+
+func TestSDPFragment_String(t *testing.T) {
+	fragment := "a=ice-ufrag:EsAw\r\na=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1\r\nm=audio 9 RTP/AVP 0\r\na=mid:0\r\n" +
+		"a=candidate:1 1 UDP 2130706431 198.51.100.1 39132 typ host\r\na=end-of-candidates\r\n"
+
+	assert.Equal(t, fragment, ParseSDPFragment(fragment).String())
+}
+
+func TestSDPFragment_CandidateInits(t *testing.T) {
+	mid := "0"
+	frag := &SDPFragment{Mid: mid, Candidates: []string{"candidate:1 1 UDP 2130706431 198.51.100.1 39132 typ host"}}
+
+	inits := frag.CandidateInits()
+	assert.Len(t, inits, 1)
+	assert.Equal(t, "candidate:1 1 UDP 2130706431 198.51.100.1 39132 typ host", inits[0].Candidate)
+	assert.Equal(t, &mid, inits[0].SDPMid)
+
+	assert.Nil(t, (&SDPFragment{Candidates: []string{"candidate:1"}}).CandidateInits()[0].SDPMid)
+}
+
+func TestReplaceICECredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		sdp      string
+		expected string
+	}{
+		{
+			name:     "credentials of each media section",
+			sdp:      "v=0\r\nm=audio 9 RTP/AVP 0\r\na=ice-ufrag:old\r\na=ice-pwd:oldpwd\r\nm=video 9 RTP/AVP 96\r\na=ice-ufrag:old\r\na=ice-pwd:oldpwd\r\n",
+			expected: "v=0\r\nm=audio 9 RTP/AVP 0\r\na=ice-ufrag:new\r\na=ice-pwd:newpwd\r\nm=video 9 RTP/AVP 96\r\na=ice-ufrag:new\r\na=ice-pwd:newpwd\r\n",
+		},
+		{
+			name:     "candidates are dropped",
+			sdp:      "v=0\r\na=ice-ufrag:old\r\na=ice-pwd:oldpwd\r\na=candidate:1 1 UDP 2130706431 198.51.100.1 39132 typ host\r\na=end-of-candidates\r\n",
+			expected: "v=0\r\na=ice-ufrag:new\r\na=ice-pwd:newpwd\r\n",
+		},
+		{
+			name:     "session without credentials",
+			sdp:      "v=0\r\nm=audio 9 RTP/AVP 0\r\n",
+			expected: "v=0\r\nm=audio 9 RTP/AVP 0\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ReplaceICECredentials(test.sdp, "new", "newpwd"))
+		})
+	}
+}
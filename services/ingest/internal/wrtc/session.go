@@ -0,0 +1,257 @@
+package wrtc
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pion/webrtc/v3"
+)
+
+// Answer is not delayed longer than this by candidates gathering. Late candidates are sent on trickle
+const gatheringTimeout = 500 * time.Millisecond
+
+var (
+	SessionNotFoundError = errors.New("webrtc session not found")
+	ETagMismatchError    = errors.New("webrtc session etag mismatch")
+	ForeignSessionError  = errors.New("webrtc session of another publisher")
+)
+
+// WHIP and WHEP session resource. Session owns the peer connection and close it once
+type Session struct {
+	ID   string
+	Peer *webrtc.PeerConnection
+	// Stream key of the session resource URL
+	Stream string
+	// Subject of the bearer which created the session. Only the same bearer may update or delete it
+	Publisher string
+
+	etag            string
+	localCandidates []string
+	sentCandidates  int
+	gathered        bool
+	closed          bool
+	onClose         []func()
+
+	mx   sync.Mutex
+	once sync.Once
+}
+
+func NewSession(peer *webrtc.PeerConnection) *Session {
+	session := &Session{
+		ID:   uuid.NewString(),
+		Peer: peer,
+		etag: uuid.NewString(),
+	}
+
+	peer.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		session.mx.Lock()
+		defer session.mx.Unlock()
+
+		if candidate == nil {
+			session.gathered = true
+			return
+		}
+		session.localCandidates = append(session.localCandidates, candidate.ToJSON().Candidate)
+	})
+
+	return session
+}
+
+func (s *Session) ETag() string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.etag
+}
+
+// Register callback called after peer connection is closed. Called immediately when session already closed
+func (s *Session) OnClose(f func()) {
+	s.mx.Lock()
+	if s.closed {
+		s.mx.Unlock()
+		f()
+		return
+	}
+	s.onClose = append(s.onClose, f)
+	s.mx.Unlock()
+}
+
+func (s *Session) waitGathering() {
+	select {
+	case <-webrtc.GatheringCompletePromise(s.Peer):
+	case <-time.After(gatheringTimeout):
+	}
+}
+
+func (s *Session) answer(offer string) error {
+	if err := s.Peer.SetRemoteDescription(webrtc.SessionDescription{
+		SDP:  offer,
+		Type: webrtc.SDPTypeOffer,
+	}); err != nil {
+		return err
+	}
+
+	answer, err := s.Peer.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+
+	return s.Peer.SetLocalDescription(answer)
+}
+
+// Answer the offer. Candidates gathered in time are included into answer
+func (s *Session) Answer(offer string) (string, error) {
+	if err := s.answer(offer); err != nil {
+		return "", err
+	}
+
+	s.waitGathering()
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.sentCandidates = len(s.localCandidates)
+	return s.Peer.LocalDescription().SDP, nil
+}
+
+// Return local candidates which are not sent yet. Empty when there is nothing to send
+func (s *Session) pendingFragment(withCredentials bool) string {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	pending := s.localCandidates[s.sentCandidates:]
+	if len(pending) == 0 && !withCredentials {
+		return ""
+	}
+	s.sentCandidates = len(s.localCandidates)
+
+	frag := SDPFragmentFromDescription(s.Peer.LocalDescription().SDP)
+	frag.Candidates = append([]string(nil), pending...)
+	frag.Completed = s.gathered
+	return frag.String()
+}
+
+func (s *Session) restartICE(remote *SDPFragment) error {
+	current := s.Peer.RemoteDescription()
+	if current == nil {
+		return errors.New("remote description is not set")
+	}
+
+	s.mx.Lock()
+	s.localCandidates, s.sentCandidates, s.gathered = nil, 0, false
+	s.etag = uuid.NewString()
+	s.mx.Unlock()
+
+	if err := s.answer(ReplaceICECredentials(current.SDP, remote.ICEUfrag, remote.ICEPwd)); err != nil {
+		return err
+	}
+
+	s.waitGathering()
+	return nil
+}
+
+// Handle trickle ICE or ICE restart fragment. Return fragment with local candidates or credentials. Empty result means nothing to answer
+func (s *Session) Trickle(fragment string, ifMatch string) (string, error) {
+	ifMatch = strings.Trim(ifMatch, `"`)
+	if ifMatch != "" && ifMatch != "*" && ifMatch != s.ETag() {
+		return "", ETagMismatchError
+	}
+
+	remote := ParseSDPFragment(fragment)
+
+	restart := false
+	if remote.ICEUfrag != "" && remote.ICEPwd != "" {
+		if current := s.Peer.RemoteDescription(); current != nil {
+			restart = SDPFragmentFromDescription(current.SDP).ICEUfrag != remote.ICEUfrag
+		}
+	}
+
+	if restart {
+		if err := s.restartICE(remote); err != nil {
+			return "", err
+		}
+	}
+
+	for _, candidate := range remote.CandidateInits() {
+		if err := s.Peer.AddICECandidate(candidate); err != nil {
+			return "", err
+		}
+	}
+
+	return s.pendingFragment(restart), nil
+}
+
+func (s *Session) Close() {
+	s.once.Do(func() {
+		_ = s.Peer.Close()
+
+		s.mx.Lock()
+		s.closed = true
+		callbacks := s.onClose
+		s.mx.Unlock()
+
+		for _, f := range callbacks {
+			f()
+		}
+	})
+}
+
+// Sessions of the WHIP or WHEP resource addressed by session id
+type SessionRegistry struct {
+	sessions map[string]*Session
+	closed   bool
+	mx       sync.RWMutex
+}
+
+// Add session to registry. Session is removed when closed. Added after CloseAll session is closed immediately
+func (r *SessionRegistry) Add(session *Session) {
+	r.mx.Lock()
+	if r.closed {
+		r.mx.Unlock()
+		session.Close()
+		return
+	}
+	r.sessions[session.ID] = session
+	r.mx.Unlock()
+
+	session.OnClose(func() {
+		r.mx.Lock()
+		delete(r.sessions, session.ID)
+		r.mx.Unlock()
+	})
+}
+
+func (r *SessionRegistry) Get(id string) (*Session, error) {
+	r.mx.RLock()
+	defer r.mx.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, SessionNotFoundError
+	}
+	return session, nil
+}
+
+// Close all sessions. Sessions are removed from registry by close callback
+func (r *SessionRegistry) CloseAll() {
+	r.mx.Lock()
+	r.closed = true
+	sessions := make([]*Session, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		sessions = append(sessions, session)
+	}
+	r.mx.Unlock()
+
+	for _, session := range sessions {
+		session.Close()
+	}
+}
+
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{
+		sessions: make(map[string]*Session),
+	}
+}
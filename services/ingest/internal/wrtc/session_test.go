@@ -0,0 +1,173 @@
+package wrtc
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPeer(t *testing.T) *webrtc.PeerConnection {
+	peer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = peer.Close() })
+	return peer
+}
+
+// Offer of the remote peer with gathered candidates
+func remoteOffer(t *testing.T, peer *webrtc.PeerConnection, options *webrtc.OfferOptions) string {
+	offer, err := peer.CreateOffer(options)
+	assert.Nil(t, err)
+
+	gathered := webrtc.GatheringCompletePromise(peer)
+	assert.Nil(t, peer.SetLocalDescription(offer))
+	<-gathered
+
+	return peer.LocalDescription().SDP
+}
+
+func newAnsweredSession(t *testing.T) (*Session, *webrtc.PeerConnection) {
+	remote := newTestPeer(t)
+	_, err := remote.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio)
+	assert.Nil(t, err)
+
+	session := NewSession(newTestPeer(t))
+	answer, err := session.Answer(remoteOffer(t, remote, nil))
+	assert.Nil(t, err)
+	assert.Nil(t, remote.SetRemoteDescription(webrtc.SessionDescription{SDP: answer, Type: webrtc.SDPTypeAnswer}))
+
+	return session, remote
+}
+
+func TestSession_Trickle(t *testing.T) {
+	tests := []struct {
+		name string
+		// Fragment of the remote peer and If-Match of the request
+		request func(t *testing.T, session *Session, remote *webrtc.PeerConnection) (string, string)
+		err     error
+		restart bool
+	}{
+		{
+			name: "etag mismatch",
+			request: func(t *testing.T, session *Session, remote *webrtc.PeerConnection) (string, string) {
+				return "a=end-of-candidates\r\n", `"stale"`
+			},
+			err: ETagMismatchError,
+		},
+		{
+			name: "candidates of the current credentials",
+			request: func(t *testing.T, session *Session, remote *webrtc.PeerConnection) (string, string) {
+				frag := ParseSDPFragment(remote.LocalDescription().SDP)
+				frag.Completed = true
+				return frag.String(), `"` + session.ETag() + `"`
+			},
+		},
+		{
+			name: "ice restart by new credentials",
+			request: func(t *testing.T, session *Session, remote *webrtc.PeerConnection) (string, string) {
+				offer := remoteOffer(t, remote, &webrtc.OfferOptions{ICERestart: true})
+				return SDPFragmentFromDescription(offer).String(), "*"
+			},
+			restart: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			session, remote := newAnsweredSession(t)
+			defer session.Close()
+
+			etag := session.ETag()
+			local := SDPFragmentFromDescription(session.Peer.LocalDescription().SDP)
+			previous := SDPFragmentFromDescription(session.Peer.RemoteDescription().SDP)
+
+			fragment, ifMatch := test.request(t, session, remote)
+			result, err := session.Trickle(fragment, ifMatch)
+			assert.ErrorIs(err, test.err)
+
+			remoteFrag := ParseSDPFragment(fragment)
+			current := SDPFragmentFromDescription(session.Peer.RemoteDescription().SDP)
+			if !test.restart {
+				assert.Equal(etag, session.ETag())
+				assert.Equal(previous.ICEUfrag, current.ICEUfrag)
+				return
+			}
+
+			// Remote description takes new credentials and answer carries local credentials of the restart
+			assert.NotEqual(etag, session.ETag())
+			assert.Equal(remoteFrag.ICEUfrag, current.ICEUfrag)
+			assert.Equal(remoteFrag.ICEPwd, current.ICEPwd)
+
+			answer := ParseSDPFragment(result)
+			assert.NotEmpty(answer.ICEUfrag)
+			assert.NotEqual(local.ICEUfrag, answer.ICEUfrag)
+			assert.Equal(SDPFragmentFromDescription(session.Peer.LocalDescription().SDP).ICEUfrag, answer.ICEUfrag)
+		})
+	}
+}
+
+func TestSessionRegistry(t *testing.T) {
+	tests := []struct {
+		name string
+		// Act on the registry with the added session and return the session which is looked up
+		act    func(registry *SessionRegistry, session *Session) *Session
+		found  bool
+		closed bool
+	}{
+		{
+			name:  "added session",
+			act:   func(registry *SessionRegistry, session *Session) *Session { return session },
+			found: true,
+		},
+		{
+			name: "closed session is removed",
+			act: func(registry *SessionRegistry, session *Session) *Session {
+				session.Close()
+				return session
+			},
+			closed: true,
+		},
+		{
+			name: "close all",
+			act: func(registry *SessionRegistry, session *Session) *Session {
+				registry.CloseAll()
+				return session
+			},
+			closed: true,
+		},
+		{
+			name: "added after close all",
+			act: func(registry *SessionRegistry, session *Session) *Session {
+				registry.CloseAll()
+				late := NewSession(newTestPeer(t))
+				registry.Add(late)
+				return late
+			},
+			closed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			registry := NewSessionRegistry()
+			session := NewSession(newTestPeer(t))
+			registry.Add(session)
+			defer session.Close()
+
+			target := test.act(registry, session)
+
+			found, err := registry.Get(target.ID)
+			if test.found {
+				assert.Nil(err)
+				assert.Same(target, found)
+			} else {
+				assert.ErrorIs(err, SessionNotFoundError)
+			}
+			assert.Equal(test.closed, target.Peer.ConnectionState() == webrtc.PeerConnectionStateClosed)
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package wrtc
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	attributeSimulcast = "a=simulcast:"
+
+	// Publisher is asked for keyframe not often than this
+	keyframeRequestInterval = 500 * time.Millisecond
+	bitrateWindow           = time.Second
+)
+
+var (
+	LayerNotFoundError = errors.New("simulcast layer not found")
+	NoLayersError      = errors.New("simulcast stream has no layers yet")
+)
+
+// Return RIDs of the simulcast send streams declared by the session description. First RID is the primary layer
+func SimulcastRIDs(sdp string) []string {
+	var rids []string
+
+	for _, line := range splitSDPLines(sdp) {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, attributeSimulcast) {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, attributeSimulcast))
+		for i := 0; i+1 < len(fields); i += 2 {
+			if fields[i] != "send" {
+				continue
+			}
+			for _, alternatives := range strings.Split(fields[i+1], ";") {
+				rid, _, _ := strings.Cut(alternatives, ",")
+				if rid = strings.TrimPrefix(rid, "~"); rid != "" {
+					rids = append(rids, rid)
+				}
+			}
+		}
+	}
+
+	return rids
+}
+
+// Check whether rtp payload starts or contains a keyframe. Viewers may be switched only on it
+func IsKeyframe(mimeType string, payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return isH264Keyframe(payload)
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return isVP8Keyframe(payload)
+	}
+	return false
+}
+
+const (
+	naluTypeIDR   = 5
+	naluTypeSPS   = 7
+	naluTypeSTAPA = 24
+	naluTypeFUA   = 28
+)
+
+func isH264Keyframe(payload []byte) bool {
+	switch naluType := payload[0] & 0x1F; naluType {
+	case naluTypeIDR, naluTypeSPS:
+		return true
+	case naluTypeSTAPA:
+		for offset := 1; offset+2 < len(payload); {
+			size := int(payload[offset])<<8 | int(payload[offset+1])
+			offset += 2
+			if size == 0 || offset+size > len(payload) {
+				return false
+			}
+			if t := payload[offset] & 0x1F; t == naluTypeIDR || t == naluTypeSPS {
+				return true
+			}
+			offset += size
+		}
+	case naluTypeFUA:
+		return len(payload) > 1 && payload[1]&0x80 != 0 && payload[1]&0x1F == naluTypeIDR
+	}
+	return false
+}
+
+// Look at RFC 7741. Keyframe is the start of the first partition with inverse key frame flag unset
+func isVP8Keyframe(payload []byte) bool {
+	start := payload[0]&0x10 != 0
+	partition := payload[0] & 0x07
+	if !start || partition != 0 {
+		return false
+	}
+
+	offset := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return false
+		}
+		extensions := payload[1]
+		offset++
+		if extensions&0x80 != 0 {
+			if len(payload) <= offset {
+				return false
+			}
+			if payload[offset]&0x80 != 0 {
+				offset++
+			}
+			offset++
+		}
+		if extensions&0x40 != 0 {
+			offset++
+		}
+		if extensions&0x30 != 0 {
+			offset++
+		}
+	}
+
+	return len(payload) > offset && payload[offset]&0x01 == 0
+}
+
+// Video layer of the simulcast publish. Layer keeps own local track which may be bound to viewers directly.
+// Viewers with layer switching get packets through the layer set
+type Layer struct {
+	RID   string
+	Track *webrtc.TrackLocalStaticRTP
+
+	set      *LayerSet
+	keyframe func()
+
+	lastKeyframeRequest time.Time
+	lastPacket          time.Time
+	windowStart         time.Time
+	windowBytes         int
+	bitrate             uint64
+
+	mx sync.Mutex
+}
+
+// Write rtp packet of the layer. Errors of the viewers are not returned so next writers of the pipeline still get the packet
+func (l *Layer) Write(p []byte) (int, error) {
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(p); err != nil {
+		return 0, err
+	}
+
+	// Extension ids are negotiated by publisher. Viewers have own ids
+	pkt.Header.Extension = false
+	pkt.Header.Extensions = nil
+
+	l.measure(len(p))
+
+	_ = l.Track.WriteRTP(&pkt)
+	l.set.forward(l, &pkt)
+
+	return len(p), nil
+}
+
+func (l *Layer) measure(size int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := time.Now()
+	if l.windowStart.IsZero() {
+		l.windowStart = now
+	}
+
+	l.lastPacket = now
+	l.windowBytes += size
+	if elapsed := now.Sub(l.windowStart); elapsed >= bitrateWindow {
+		l.bitrate = uint64(float64(l.windowBytes*8) / elapsed.Seconds())
+		l.windowBytes = 0
+		l.windowStart = now
+	}
+}
+
+// Bits per second measured on the last window. Zero until first window is passed or when publisher paused the layer
+func (l *Layer) Bitrate() uint64 {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if time.Since(l.lastPacket) > 2*bitrateWindow {
+		return 0
+	}
+	return l.bitrate
+}
+
+func (l *Layer) RequestKeyframe() {
+	l.mx.Lock()
+	if time.Since(l.lastKeyframeRequest) < keyframeRequestInterval || l.keyframe == nil {
+		l.mx.Unlock()
+		return
+	}
+	l.lastKeyframeRequest = time.Now()
+	l.mx.Unlock()
+
+	l.keyframe()
+}
+
+// Simulcast layers of the stream video in order declared by publisher
+type LayerSet struct {
+	rids      []string
+	layers    map[string]*Layer
+	selectors map[*LayerSelector]struct{}
+
+	mx sync.RWMutex
+}
+
+// Stream is simulcast when publisher declared the layers
+func (s *LayerSet) Simulcast() bool {
+	return len(s.rids) > 0
+}
+
+// Add received layer. Keyframe func asks publisher for keyframe of the layer
+func (s *LayerSet) Add(rid string, track *webrtc.TrackLocalStaticRTP, keyframe func()) *Layer {
+	layer := &Layer{
+		RID:      rid,
+		Track:    track,
+		set:      s,
+		keyframe: keyframe,
+	}
+
+	s.mx.Lock()
+	s.layers[rid] = layer
+	selectors := s.selectorList()
+	s.mx.Unlock()
+
+	for _, selector := range selectors {
+		selector.onLayer(layer)
+	}
+
+	return layer
+}
+
+func (s *LayerSet) Get(rid string) (*Layer, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	layer, ok := s.layers[rid]
+	if !ok {
+		return nil, LayerNotFoundError
+	}
+	return layer, nil
+}
+
+// Primary layer is first declared by publisher. It feeds media processors
+func (s *LayerSet) IsPrimary(rid string) bool {
+	return len(s.rids) > 0 && s.rids[0] == rid
+}
+
+// Received layers from the highest bitrate to the lowest. Not measured layers keep declared order
+func (s *LayerSet) Layers() []*Layer {
+	s.mx.RLock()
+	layers := make([]*Layer, 0, len(s.layers))
+	for _, rid := range s.rids {
+		if layer, ok := s.layers[rid]; ok {
+			layers = append(layers, layer)
+		}
+	}
+	s.mx.RUnlock()
+
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].Bitrate() > layers[j].Bitrate()
+	})
+
+	return layers
+}
+
+func (s *LayerSet) RIDs() []string {
+	return append([]string(nil), s.rids...)
+}
+
+func (s *LayerSet) selectorList() []*LayerSelector {
+	selectors := make([]*LayerSelector, 0, len(s.selectors))
+	for selector := range s.selectors {
+		selectors = append(selectors, selector)
+	}
+	return selectors
+}
+
+func (s *LayerSet) subscribe(selector *LayerSelector) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.selectors[selector] = struct{}{}
+}
+
+func (s *LayerSet) unsubscribe(selector *LayerSelector) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.selectors, selector)
+}
+
+func (s *LayerSet) forward(layer *Layer, pkt *rtp.Packet) {
+	s.mx.RLock()
+	selectors := s.selectorList()
+	s.mx.RUnlock()
+
+	for _, selector := range selectors {
+		selector.forward(layer, pkt)
+	}
+}
+
+// Empty rids means stream is not simulcast
+func NewLayerSet(rids []string) *LayerSet {
+	return &LayerSet{
+		rids:      rids,
+		layers:    make(map[string]*Layer),
+		selectors: make(map[*LayerSelector]struct{}),
+	}
+}
@@ -0,0 +1,232 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	amf0Number      = 0x00
+	amf0Boolean     = 0x01
+	amf0String      = 0x02
+	amf0Object      = 0x03
+	amf0Null        = 0x05
+	amf0Undefined   = 0x06
+	amf0ECMAArray   = 0x08
+	amf0ObjectEnd   = 0x09
+	amf0StrictArray = 0x0A
+	amf0Date        = 0x0B
+	amf0LongString  = 0x0C
+)
+
+var (
+	UnsupportedAMF0TypeError = errors.New("unsupported amf0 type")
+)
+
+// Marker of the amf0 undefined value. nil is encoded as amf0 null
+type Undefined struct{}
+
+func readAMF0String(r *bytes.Reader, long bool) (string, error) {
+	var size uint32
+	if long {
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return "", err
+		}
+	} else {
+		var short uint16
+		if err := binary.Read(r, binary.BigEndian, &short); err != nil {
+			return "", err
+		}
+		size = uint32(short)
+	}
+
+	if int64(size) > int64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readAMF0Properties(r *bytes.Reader) (map[string]any, error) {
+	object := make(map[string]any)
+	for {
+		key, err := readAMF0String(r, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if key == "" {
+			marker, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if marker == amf0ObjectEnd {
+				return object, nil
+			}
+			if err := r.UnreadByte(); err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := readAMF0Value(r)
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+	}
+}
+
+func readAMF0Value(r *bytes.Reader) (any, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch marker {
+	case amf0Number:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	case amf0Boolean:
+		value, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		return value != 0, nil
+	case amf0String:
+		return readAMF0String(r, false)
+	case amf0LongString:
+		return readAMF0String(r, true)
+	case amf0Object:
+		return readAMF0Properties(r)
+	case amf0ECMAArray:
+		// Associative count is only a hint. Array always terminated by object end
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		return readAMF0Properties(r)
+	case amf0StrictArray:
+		var count uint32
+		if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+			return nil, err
+		}
+		array := make([]any, 0)
+		for i := uint32(0); i < count; i++ {
+			value, err := readAMF0Value(r)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case amf0Date:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		// Skip time zone
+		if _, err := r.Seek(2, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	case amf0Null:
+		return nil, nil
+	case amf0Undefined:
+		return Undefined{}, nil
+	default:
+		return nil, fmt.Errorf("%w: 0x%x", UnsupportedAMF0TypeError, marker)
+	}
+}
+
+// Decode all amf0 values of the command message
+func DecodeAMF0(payload []byte) ([]any, error) {
+	r := bytes.NewReader(payload)
+
+	var values []any
+	for r.Len() > 0 {
+		value, err := readAMF0Value(r)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func writeAMF0String(buf *bytes.Buffer, value string) {
+	if len(value) > math.MaxUint16 {
+		buf.WriteByte(amf0LongString)
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(value)))
+	} else {
+		buf.WriteByte(amf0String)
+		_ = binary.Write(buf, binary.BigEndian, uint16(len(value)))
+	}
+	buf.WriteString(value)
+}
+
+func writeAMF0Value(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(amf0Null)
+	case Undefined:
+		buf.WriteByte(amf0Undefined)
+	case bool:
+		buf.WriteByte(amf0Boolean)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case int:
+		return writeAMF0Value(buf, float64(v))
+	case uint32:
+		return writeAMF0Value(buf, float64(v))
+	case float64:
+		buf.WriteByte(amf0Number)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeAMF0String(buf, v)
+	case map[string]any:
+		buf.WriteByte(amf0Object)
+
+		// Keep output stable. Map iteration order is random
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			_ = binary.Write(buf, binary.BigEndian, uint16(len(key)))
+			buf.WriteString(key)
+			if err := writeAMF0Value(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.Write([]byte{0, 0, amf0ObjectEnd})
+	default:
+		return fmt.Errorf("%w: %T", UnsupportedAMF0TypeError, value)
+	}
+	return nil
+}
+
+func EncodeAMF0(values ...any) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, value := range values {
+		if err := writeAMF0Value(&buf, value); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
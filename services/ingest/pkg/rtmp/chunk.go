@@ -0,0 +1,316 @@
+package rtmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	defaultChunkSize  = 128
+	maxChunkSize      = 0xFFFFFF
+	maxMessageLength  = 8 * 1024 * 1024
+	extendedTimestamp = 0xFFFFFF
+)
+
+const (
+	MessageTypeSetChunkSize     = 1
+	MessageTypeAbort            = 2
+	MessageTypeAcknowledgement  = 3
+	MessageTypeUserControl      = 4
+	MessageTypeWindowAckSize    = 5
+	MessageTypeSetPeerBandwidth = 6
+	MessageTypeAudio            = 8
+	MessageTypeVideo            = 9
+	MessageTypeDataAMF3         = 15
+	MessageTypeCommandAMF3      = 17
+	MessageTypeDataAMF0         = 18
+	MessageTypeCommandAMF0      = 20
+)
+
+var (
+	InvalidChunkSizeError     = errors.New("invalid rtmp chunk size")
+	InvalidMessageLengthError = errors.New("invalid rtmp message length")
+	UnknownChunkStreamError   = errors.New("chunk stream continues unknown message")
+)
+
+type Message struct {
+	Type      uint8
+	StreamID  uint32
+	Timestamp uint32
+	Payload   []byte
+}
+
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    uint32
+	typeID    uint8
+	streamID  uint32
+	extended  bool
+	started   bool
+
+	payload []byte
+}
+
+// Read rtmp chunks and assemble messages
+type chunkReader struct {
+	reader    *bufio.Reader
+	chunkSize uint32
+	streams   map[uint32]*chunkStream
+	bytesRead uint64
+	header    [11]byte
+}
+
+func newChunkReader(r io.Reader) *chunkReader {
+	return &chunkReader{
+		reader:    bufio.NewReader(r),
+		chunkSize: defaultChunkSize,
+		streams:   make(map[uint32]*chunkStream),
+	}
+}
+
+func (r *chunkReader) readFull(p []byte) error {
+	n, err := io.ReadFull(r.reader, p)
+	r.bytesRead += uint64(n)
+	return err
+}
+
+func (r *chunkReader) readByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.bytesRead++
+	}
+	return b, err
+}
+
+func uint24(p []byte) uint32 {
+	return uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+}
+
+func (r *chunkReader) SetChunkSize(size uint32) error {
+	if size == 0 || size > maxChunkSize {
+		return InvalidChunkSizeError
+	}
+	r.chunkSize = size
+	return nil
+}
+
+func (r *chunkReader) Abort(csid uint32) {
+	if stream, ok := r.streams[csid]; ok {
+		stream.payload = nil
+	}
+}
+
+func (r *chunkReader) BytesRead() uint64 {
+	return r.bytesRead
+}
+
+// Read chunks until one of chunk streams has complete message
+func (r *chunkReader) ReadMessage() (*Message, error) {
+	for {
+		first, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		format := first >> 6
+		csid := uint32(first & 0x3F)
+
+		switch csid {
+		case 0:
+			b, err := r.readByte()
+			if err != nil {
+				return nil, err
+			}
+			csid = uint32(b) + 64
+		case 1:
+			if err := r.readFull(r.header[:2]); err != nil {
+				return nil, err
+			}
+			csid = uint32(r.header[1])<<8 + uint32(r.header[0]) + 64
+		}
+
+		stream, ok := r.streams[csid]
+		if !ok {
+			if format != 0 {
+				return nil, UnknownChunkStreamError
+			}
+			stream = &chunkStream{}
+			r.streams[csid] = stream
+		}
+
+		var timestamp uint32
+		switch format {
+		case 0:
+			if err := r.readFull(r.header[:11]); err != nil {
+				return nil, err
+			}
+			timestamp = uint24(r.header[0:3])
+			stream.length = uint24(r.header[3:6])
+			stream.typeID = r.header[6]
+			stream.streamID = binary.LittleEndian.Uint32(r.header[7:11])
+		case 1:
+			if err := r.readFull(r.header[:7]); err != nil {
+				return nil, err
+			}
+			timestamp = uint24(r.header[0:3])
+			stream.length = uint24(r.header[3:6])
+			stream.typeID = r.header[6]
+		case 2:
+			if err := r.readFull(r.header[:3]); err != nil {
+				return nil, err
+			}
+			timestamp = uint24(r.header[0:3])
+		case 3:
+			if !stream.started {
+				return nil, UnknownChunkStreamError
+			}
+		}
+
+		if format != 3 {
+			stream.extended = timestamp == extendedTimestamp
+		}
+
+		if stream.extended {
+			if err := r.readFull(r.header[:4]); err != nil {
+				return nil, err
+			}
+			timestamp = binary.BigEndian.Uint32(r.header[:4])
+		}
+
+		newMessage := len(stream.payload) == 0
+
+		switch format {
+		case 0:
+			stream.timestamp = timestamp
+			stream.delta = 0
+		case 1, 2:
+			stream.delta = timestamp
+			stream.timestamp += timestamp
+		case 3:
+			// Continuation of the previous message or new message with the same header
+			if newMessage {
+				stream.timestamp += stream.delta
+			}
+		}
+		stream.started = true
+
+		if stream.length > maxMessageLength {
+			return nil, InvalidMessageLengthError
+		}
+
+		if newMessage {
+			stream.payload = make([]byte, 0, stream.length)
+		}
+
+		size := stream.length - uint32(len(stream.payload))
+		if size > r.chunkSize {
+			size = r.chunkSize
+		}
+
+		offset := len(stream.payload)
+		stream.payload = stream.payload[:offset+int(size)]
+		if err := r.readFull(stream.payload[offset:]); err != nil {
+			return nil, err
+		}
+
+		if uint32(len(stream.payload)) < stream.length {
+			continue
+		}
+
+		message := &Message{
+			Type:      stream.typeID,
+			StreamID:  stream.streamID,
+			Timestamp: stream.timestamp,
+			Payload:   stream.payload,
+		}
+		stream.payload = nil
+
+		return message, nil
+	}
+}
+
+// Split messages into chunks. Each message start with type 0 header and continue by type 3
+type chunkWriter struct {
+	writer    *bufio.Writer
+	chunkSize uint32
+
+	mx sync.Mutex
+}
+
+func newChunkWriter(w io.Writer) *chunkWriter {
+	return &chunkWriter{
+		writer:    bufio.NewWriter(w),
+		chunkSize: defaultChunkSize,
+	}
+}
+
+func (w *chunkWriter) writeBasicHeader(format uint8, csid uint32) {
+	switch {
+	case csid < 64:
+		w.writer.WriteByte(format<<6 | uint8(csid))
+	case csid < 320:
+		w.writer.WriteByte(format << 6)
+		w.writer.WriteByte(uint8(csid - 64))
+	default:
+		w.writer.WriteByte(format<<6 | 1)
+		w.writer.WriteByte(uint8((csid - 64) & 0xFF))
+		w.writer.WriteByte(uint8((csid - 64) >> 8))
+	}
+}
+
+func (w *chunkWriter) WriteMessage(csid uint32, message *Message) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	timestamp := message.Timestamp
+	if timestamp >= extendedTimestamp {
+		timestamp = extendedTimestamp
+	}
+
+	var header [11]byte
+	header[0], header[1], header[2] = byte(timestamp>>16), byte(timestamp>>8), byte(timestamp)
+	length := uint32(len(message.Payload))
+	header[3], header[4], header[5] = byte(length>>16), byte(length>>8), byte(length)
+	header[6] = message.Type
+	binary.LittleEndian.PutUint32(header[7:], message.StreamID)
+
+	var extended [4]byte
+	binary.BigEndian.PutUint32(extended[:], message.Timestamp)
+
+	payload := message.Payload
+	format := uint8(0)
+	for {
+		w.writeBasicHeader(format, csid)
+		if format == 0 {
+			w.writer.Write(header[:])
+		}
+		if timestamp == extendedTimestamp {
+			w.writer.Write(extended[:])
+		}
+
+		size := uint32(len(payload))
+		if size > w.chunkSize {
+			size = w.chunkSize
+		}
+		w.writer.Write(payload[:size])
+		payload = payload[size:]
+
+		if len(payload) == 0 {
+			break
+		}
+		format = 3
+	}
+
+	return w.writer.Flush()
+}
+
+func (w *chunkWriter) SetChunkSize(size uint32) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.chunkSize = size
+}
@@ -0,0 +1,355 @@
+package rtmp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	handshakeSize = 1536
+	rtmpVersion   = 3
+
+	serverChunkSize     = 4096
+	serverWindowAckSize = 2_500_000
+
+	// Chunk stream ids used by server messages
+	csidProtocolControl = 2
+	csidCommand         = 3
+
+	publishStreamID = 1
+
+	// Connection is closed when the peer sends nothing for this long
+	peerIdleTimeout = 10 * time.Second
+)
+
+var (
+	UnsupportedVersionError = errors.New("unsupported rtmp version")
+	PublishRejectedError    = errors.New("rtmp publish rejected")
+	// Publish with wrong credentials. Client gets NetStream.Publish.Denied status
+	PublishDeniedError = errors.New("rtmp publish denied")
+	// Connection publishes single stream. Its next publish is rejected and the first one goes on
+	AlreadyPublishingError = errors.New("rtmp connection is already publishing")
+)
+
+// Receive media of the published stream. Payload is FLV tag body without tag header
+type Publisher interface {
+	WriteAudio(timestamp uint32, payload []byte) error
+	WriteVideo(timestamp uint32, payload []byte) error
+	Close()
+}
+
+// Called when client start publishing. Returning error reject the publish
+type PublishFunc func(app, stream string) (Publisher, error)
+
+func handshake(conn net.Conn) error {
+	c0c1 := make([]byte, 1+handshakeSize)
+	if _, err := io.ReadFull(conn, c0c1); err != nil {
+		return err
+	}
+	if c0c1[0] != rtmpVersion {
+		return fmt.Errorf("%w: %d", UnsupportedVersionError, c0c1[0])
+	}
+
+	s0s1s2 := make([]byte, 1+handshakeSize*2)
+	s0s1s2[0] = rtmpVersion
+
+	s1 := s0s1s2[1 : 1+handshakeSize]
+	binary.BigEndian.PutUint32(s1[0:4], uint32(time.Now().Unix()))
+	if _, err := rand.Read(s1[8:]); err != nil {
+		return err
+	}
+
+	// Simple handshake. S2 echoes C1
+	copy(s0s1s2[1+handshakeSize:], c0c1[1:])
+
+	if _, err := conn.Write(s0s1s2); err != nil {
+		return err
+	}
+
+	c2 := make([]byte, handshakeSize)
+	_, err := io.ReadFull(conn, c2)
+	return err
+}
+
+type session struct {
+	conn        net.Conn
+	reader      *chunkReader
+	writer      *chunkWriter
+	publish     PublishFunc
+	idleTimeout time.Duration
+
+	app       string
+	publisher Publisher
+
+	ackWindow uint32
+	ackSent   uint64
+}
+
+func (s *session) writeMessage(csid uint32, typeID uint8, streamID uint32, payload []byte) error {
+	return s.writer.WriteMessage(csid, &Message{
+		Type:     typeID,
+		StreamID: streamID,
+		Payload:  payload,
+	})
+}
+
+func (s *session) writeUint32(typeID uint8, value uint32) error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, value)
+	return s.writeMessage(csidProtocolControl, typeID, 0, payload)
+}
+
+func (s *session) writeCommand(streamID uint32, values ...any) error {
+	payload, err := EncodeAMF0(values...)
+	if err != nil {
+		return err
+	}
+	return s.writeMessage(csidCommand, MessageTypeCommandAMF0, streamID, payload)
+}
+
+func (s *session) writeStreamBegin(streamID uint32) error {
+	payload := make([]byte, 6)
+	binary.BigEndian.PutUint32(payload[2:], streamID)
+	return s.writeMessage(csidProtocolControl, MessageTypeUserControl, 0, payload)
+}
+
+func (s *session) acknowledge() error {
+	if s.ackWindow == 0 {
+		return nil
+	}
+
+	read := s.reader.BytesRead()
+	if read-s.ackSent < uint64(s.ackWindow) {
+		return nil
+	}
+	s.ackSent = read
+
+	return s.writeUint32(MessageTypeAcknowledgement, uint32(read))
+}
+
+func (s *session) onConnect(transactionID float64, values []any) error {
+	if len(values) > 0 {
+		if object, ok := values[0].(map[string]any); ok {
+			s.app, _ = object["app"].(string)
+		}
+	}
+
+	if err := s.writeUint32(MessageTypeWindowAckSize, serverWindowAckSize); err != nil {
+		return err
+	}
+
+	peerBandwidth := make([]byte, 5)
+	binary.BigEndian.PutUint32(peerBandwidth, serverWindowAckSize)
+	// Dynamic limit type
+	peerBandwidth[4] = 2
+	if err := s.writeMessage(csidProtocolControl, MessageTypeSetPeerBandwidth, 0, peerBandwidth); err != nil {
+		return err
+	}
+
+	if err := s.writeUint32(MessageTypeSetChunkSize, serverChunkSize); err != nil {
+		return err
+	}
+	s.writer.SetChunkSize(serverChunkSize)
+
+	return s.writeCommand(0, "_result", transactionID,
+		map[string]any{
+			"fmsVer":       "FMS/3,0,1,123",
+			"capabilities": 31,
+		},
+		map[string]any{
+			"level":          "status",
+			"code":           "NetConnection.Connect.Success",
+			"description":    "Connection succeeded.",
+			"objectEncoding": 0,
+		},
+	)
+}
+
+func (s *session) onPublish(transactionID float64, values []any) error {
+	var stream string
+	// Values after command object. First is null
+	if len(values) > 1 {
+		stream, _ = values[1].(string)
+	}
+
+	if s.publisher != nil {
+		return s.writeCommand(publishStreamID, "onStatus", 0, nil, map[string]any{
+			"level":       "error",
+			"code":        "NetStream.Publish.BadName",
+			"description": AlreadyPublishingError.Error(),
+		})
+	}
+
+	publisher, err := s.publish(s.app, stream)
+	if err != nil {
+		code := "NetStream.Publish.BadName"
+		if errors.Is(err, PublishDeniedError) {
+			code = "NetStream.Publish.Denied"
+		}
+
+		_ = s.writeCommand(publishStreamID, "onStatus", 0, nil, map[string]any{
+			"level":       "error",
+			"code":        code,
+			"description": err.Error(),
+		})
+		return errors.Join(PublishRejectedError, err)
+	}
+	s.publisher = publisher
+
+	if err := s.writeStreamBegin(publishStreamID); err != nil {
+		return err
+	}
+
+	return s.writeCommand(publishStreamID, "onStatus", 0, nil, map[string]any{
+		"level":       "status",
+		"code":        "NetStream.Publish.Start",
+		"description": fmt.Sprintf("%s is now published.", stream),
+	})
+}
+
+func (s *session) onCommand(payload []byte) error {
+	values, err := DecodeAMF0(payload)
+	if err != nil {
+		return err
+	}
+	if len(values) < 2 {
+		return nil
+	}
+
+	name, _ := values[0].(string)
+	transactionID, _ := values[1].(float64)
+	args := values[2:]
+
+	switch name {
+	case "connect":
+		return s.onConnect(transactionID, args)
+	case "releaseStream", "FCPublish":
+		return s.writeCommand(0, "_result", transactionID, nil, Undefined{})
+	case "createStream":
+		return s.writeCommand(0, "_result", transactionID, nil, publishStreamID)
+	case "publish":
+		return s.onPublish(transactionID, args)
+	case "FCUnpublish", "deleteStream", "closeStream":
+		return io.EOF
+	}
+
+	return nil
+}
+
+func (s *session) onMessage(message *Message) error {
+	switch message.Type {
+	case MessageTypeSetChunkSize:
+		if len(message.Payload) < 4 {
+			return InvalidChunkSizeError
+		}
+		return s.reader.SetChunkSize(binary.BigEndian.Uint32(message.Payload) & 0x7FFFFFFF)
+	case MessageTypeAbort:
+		if len(message.Payload) >= 4 {
+			s.reader.Abort(binary.BigEndian.Uint32(message.Payload))
+		}
+	case MessageTypeWindowAckSize:
+		if len(message.Payload) >= 4 {
+			s.ackWindow = binary.BigEndian.Uint32(message.Payload)
+		}
+	case MessageTypeCommandAMF0:
+		return s.onCommand(message.Payload)
+	case MessageTypeCommandAMF3:
+		// AMF3 command starts with format selector and encoded as amf0
+		if len(message.Payload) > 0 {
+			return s.onCommand(message.Payload[1:])
+		}
+	case MessageTypeAudio:
+		if s.publisher != nil && len(message.Payload) > 0 {
+			return s.publisher.WriteAudio(message.Timestamp, message.Payload)
+		}
+	case MessageTypeVideo:
+		if s.publisher != nil && len(message.Payload) > 0 {
+			return s.publisher.WriteVideo(message.Timestamp, message.Payload)
+		}
+	}
+
+	return nil
+}
+
+func (s *session) serve() error {
+	for {
+		if err := s.conn.SetReadDeadline(time.Now().Add(s.idleTimeout)); err != nil {
+			return err
+		}
+
+		message, err := s.reader.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if err := s.onMessage(message); err != nil {
+			return err
+		}
+
+		if err := s.acknowledge(); err != nil {
+			return err
+		}
+	}
+}
+
+type Server struct {
+	publish     PublishFunc
+	idleTimeout time.Duration
+}
+
+// Serve single client connection until it's stop publishing or idle
+func (srv *Server) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(srv.idleTimeout)); err != nil {
+		return err
+	}
+	if err := handshake(conn); err != nil {
+		return fmt.Errorf("rtmp handshake failed. Err: %w", err)
+	}
+
+	s := &session{
+		conn:        conn,
+		reader:      newChunkReader(conn),
+		writer:      newChunkWriter(conn),
+		publish:     srv.publish,
+		idleTimeout: srv.idleTimeout,
+	}
+
+	defer func() {
+		if s.publisher != nil {
+			s.publisher.Close()
+		}
+	}()
+
+	if err := s.serve(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func (srv *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			if err := srv.ServeConn(conn); err != nil {
+				log.Printf("[RTMP] %s connection closed. Err: %s", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func NewServer(publish PublishFunc) *Server {
+	return &Server{publish: publish, idleTimeout: peerIdleTimeout}
+}
@@ -0,0 +1,233 @@
+package rtmp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordPublisher struct {
+	audio  [][]byte
+	video  [][]byte
+	closed chan struct{}
+}
+
+func (p *recordPublisher) WriteAudio(timestamp uint32, payload []byte) error {
+	p.audio = append(p.audio, payload)
+	return nil
+}
+
+func (p *recordPublisher) WriteVideo(timestamp uint32, payload []byte) error {
+	p.video = append(p.video, payload)
+	return nil
+}
+
+func (p *recordPublisher) Close() {
+	close(p.closed)
+}
+
+func clientHandshake(t *testing.T, conn net.Conn) {
+	c0c1 := make([]byte, 1+handshakeSize)
+	c0c1[0] = rtmpVersion
+	_, err := conn.Write(c0c1)
+	assert.Nil(t, err)
+
+	s0s1s2 := make([]byte, 1+handshakeSize*2)
+	_, err = io.ReadFull(conn, s0s1s2)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(rtmpVersion), s0s1s2[0])
+
+	_, err = conn.Write(s0s1s2[1 : 1+handshakeSize])
+	assert.Nil(t, err)
+}
+
+// Read server messages until command with the name
+func readCommand(t *testing.T, reader *chunkReader, name string) []any {
+	for {
+		message, err := reader.ReadMessage()
+		if !assert.Nil(t, err) {
+			return nil
+		}
+
+		if message.Type == MessageTypeSetChunkSize {
+			_ = reader.SetChunkSize(uint32(message.Payload[0])<<24 | uint32(message.Payload[1])<<16 | uint32(message.Payload[2])<<8 | uint32(message.Payload[3]))
+			continue
+		}
+
+		if message.Type != MessageTypeCommandAMF0 {
+			continue
+		}
+
+		values, err := DecodeAMF0(message.Payload)
+		assert.Nil(t, err)
+		if values[0] == name {
+			return values
+		}
+	}
+}
+
+func TestServeConn_Publishing(t *testing.T) {
+	assert := assert.New(t)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	publisher := &recordPublisher{closed: make(chan struct{})}
+	var app, stream string
+
+	srv := NewServer(func(a, s string) (Publisher, error) {
+		app, stream = a, s
+		return publisher, nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.ServeConn(server)
+	}()
+
+	clientHandshake(t, client)
+
+	writer := newChunkWriter(client)
+	reader := newChunkReader(client)
+
+	command := func(streamID uint32, values ...any) {
+		payload, err := EncodeAMF0(values...)
+		assert.Nil(err)
+		assert.Nil(writer.WriteMessage(csidCommand, &Message{Type: MessageTypeCommandAMF0, StreamID: streamID, Payload: payload}))
+	}
+
+	command(0, "connect", 1, map[string]any{"app": "live"})
+	result := readCommand(t, reader, "_result")
+	assert.Equal(float64(1), result[1])
+
+	command(0, "createStream", 2, nil)
+	result = readCommand(t, reader, "_result")
+	assert.Equal(float64(publishStreamID), result[3])
+
+	command(publishStreamID, "publish", 3, nil, "admin", "live")
+	status := readCommand(t, reader, "onStatus")
+	assert.Equal("NetStream.Publish.Start", status[3].(map[string]any)["code"])
+
+	assert.Equal("live", app)
+	assert.Equal("admin", stream)
+
+	// Payload larger than default chunk size must be split and assembled back
+	video := bytes.Repeat([]byte{0x17}, defaultChunkSize*3+10)
+	assert.Nil(writer.WriteMessage(6, &Message{Type: MessageTypeVideo, StreamID: publishStreamID, Timestamp: 40, Payload: video}))
+	assert.Nil(writer.WriteMessage(4, &Message{Type: MessageTypeAudio, StreamID: publishStreamID, Timestamp: 21, Payload: []byte{0xAF, 0x01, 0x21}}))
+
+	command(publishStreamID, "deleteStream", 4, nil, publishStreamID)
+
+	assert.Nil(<-done)
+	<-publisher.closed
+
+	assert.Equal([][]byte{video}, publisher.video)
+	assert.Equal([][]byte{{0xAF, 0x01, 0x21}}, publisher.audio)
+}
+
+func TestServeConn_PublishRejected(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	srv := NewServer(func(a, s string) (Publisher, error) {
+		return nil, errors.New("invalid stream key")
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.ServeConn(server)
+	}()
+
+	clientHandshake(t, client)
+
+	writer := newChunkWriter(client)
+	reader := newChunkReader(client)
+
+	payload, _ := EncodeAMF0("publish", 1, nil, "admin", "live")
+	go writer.WriteMessage(csidCommand, &Message{Type: MessageTypeCommandAMF0, StreamID: publishStreamID, Payload: payload})
+
+	status := readCommand(t, reader, "onStatus")
+	assert.Equal(t, "NetStream.Publish.BadName", status[3].(map[string]any)["code"])
+	assert.ErrorIs(t, <-done, PublishRejectedError)
+}
+
+func TestServeConn_SecondPublishRejected(t *testing.T) {
+	assert := assert.New(t)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	publisher := &recordPublisher{closed: make(chan struct{})}
+	publishes := 0
+	srv := NewServer(func(a, s string) (Publisher, error) {
+		publishes++
+		return publisher, nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- srv.ServeConn(server)
+	}()
+
+	clientHandshake(t, client)
+
+	writer := newChunkWriter(client)
+	reader := newChunkReader(client)
+
+	command := func(values ...any) {
+		payload, err := EncodeAMF0(values...)
+		assert.Nil(err)
+		assert.Nil(writer.WriteMessage(csidCommand, &Message{Type: MessageTypeCommandAMF0, StreamID: publishStreamID, Payload: payload}))
+	}
+
+	command("publish", 1, nil, "admin", "live")
+	status := readCommand(t, reader, "onStatus")
+	assert.Equal("NetStream.Publish.Start", status[3].(map[string]any)["code"])
+
+	command("publish", 2, nil, "other", "live")
+	status = readCommand(t, reader, "onStatus")
+	assert.Equal("NetStream.Publish.BadName", status[3].(map[string]any)["code"])
+	assert.Equal(1, publishes)
+
+	// First publish goes on
+	assert.Nil(writer.WriteMessage(6, &Message{Type: MessageTypeVideo, StreamID: publishStreamID, Payload: []byte{0x17}}))
+	command("deleteStream", 3, nil, publishStreamID)
+
+	assert.Nil(<-done)
+	<-publisher.closed
+	assert.Equal([][]byte{{0x17}}, publisher.video)
+}
+
+func TestServeConn_IdleTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	publisher := &recordPublisher{closed: make(chan struct{})}
+	srv := NewServer(func(a, s string) (Publisher, error) {
+		return publisher, nil
+	})
+	srv.idleTimeout = 100 * time.Millisecond
+
+	done := make(chan error)
+	go func() {
+		done <- srv.ServeConn(server)
+	}()
+
+	clientHandshake(t, client)
+
+	writer := newChunkWriter(client)
+	reader := newChunkReader(client)
+
+	payload, _ := EncodeAMF0("publish", 1, nil, "admin", "live")
+	assert.Nil(t, writer.WriteMessage(csidCommand, &Message{Type: MessageTypeCommandAMF0, StreamID: publishStreamID, Payload: payload}))
+	readCommand(t, reader, "onStatus")
+
+	assert.ErrorIs(t, <-done, os.ErrDeadlineExceeded)
+	<-publisher.closed
+}
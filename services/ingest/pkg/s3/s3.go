@@ -0,0 +1,269 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm   = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+	amzDayFormat    = "20060102"
+
+	// Objects bigger than the part are uploaded by multipart upload. Single PUT is limited by 5 GiB
+	defaultPartSize = 64 * 1024 * 1024
+	// Upload has at most 10000 parts, the part grows for bigger objects
+	maxParts = 10000
+	// Each request must finish in this time, stalled storage doesn't hold the upload forever
+	requestTimeout = 5 * time.Minute
+	// Abort of the failed upload has own time, the upload context may be already done
+	abortTimeout = 30 * time.Second
+)
+
+var (
+	InvalidEndpointError = errors.New("invalid s3 endpoint")
+	PutObjectError       = errors.New("unable put s3 object")
+	MultipartUploadError = errors.New("unable upload s3 object by parts")
+)
+
+// Client of S3-compatible storage like MinIO. Objects are addressed in path style, so the bucket doesn't need dns
+type Client struct {
+	Endpoint  *url.URL
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	HTTP *http.Client
+	now  func() time.Time
+	// Least part of the multipart upload
+	partSize int64
+}
+
+func NewClient(endpoint, region, bucket, accessKey, secretKey string) (*Client, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") {
+		return nil, fmt.Errorf("%w %q", InvalidEndpointError, endpoint)
+	}
+
+	return &Client{
+		Endpoint:  endpointURL,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		HTTP:      &http.Client{Timeout: requestTimeout},
+		now:       time.Now,
+		partSize:  defaultPartSize,
+	}, nil
+}
+
+func (c *Client) objectURL(key string, query url.Values) string {
+	objectURL := *c.Endpoint
+	objectURL.Path = strings.TrimSuffix(objectURL.Path, "/") + "/" + c.Bucket + "/" + strings.TrimPrefix(key, "/")
+	objectURL.RawQuery = query.Encode()
+	return objectURL.String()
+}
+
+// Signed request of the object. Response with other status is returned as error
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, header http.Header, failure error) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	c.sign(req, unsignedPayload)
+
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		defer res.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("%w %s. Status: %d. Body: %s", failure, key, res.StatusCode, message)
+	}
+	return res, nil
+}
+
+// Upload the object by single request. Payload is not signed, so the body is streamed without hashing it first
+func (c *Client) PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	res, err := c.do(ctx, http.MethodPut, key, nil, body, size, http.Header{"Content-Type": {contentType}}, PutObjectError)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// Part of the object of the size. Part is at least 64 MiB and the object fits into 10000 parts
+func (c *Client) objectPartSize(size int64) int64 {
+	part := c.partSize
+	if least := (size + maxParts - 1) / maxParts; least > part {
+		part = least
+	}
+	return part
+}
+
+// Upload the object of any size. Object bigger than the part goes by multipart upload, which is aborted on failure
+func (c *Client) UploadObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	part := c.objectPartSize(size)
+	if size <= part {
+		return c.PutObject(ctx, key, body, size, contentType)
+	}
+
+	res, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0, http.Header{"Content-Type": {contentType}}, MultipartUploadError)
+	if err != nil {
+		return err
+	}
+	var upload initiateMultipartUploadResult
+	err = xml.NewDecoder(res.Body).Decode(&upload)
+	_ = res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("%w %s. Err: %s", MultipartUploadError, key, err)
+	}
+
+	if err := c.uploadParts(ctx, key, upload.UploadID, body, size, part); err != nil {
+		abortCtx, cancel := context.WithTimeout(context.Background(), abortTimeout)
+		defer cancel()
+
+		if res, abortErr := c.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {upload.UploadID}}, nil, 0, nil, MultipartUploadError); abortErr == nil {
+			_ = res.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *Client) uploadParts(ctx context.Context, key, uploadID string, body io.Reader, size, part int64) error {
+	var complete completeMultipartUpload
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+part, number+1 {
+		length := part
+		if size-offset < length {
+			length = size - offset
+		}
+
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		res, err := c.do(ctx, http.MethodPut, key, query, io.LimitReader(body, length), length, nil, MultipartUploadError)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: number, ETag: res.Header.Get("ETag")})
+	}
+
+	payload, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, bytes.NewReader(payload), int64(len(payload)), nil, MultipartUploadError)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Completion may fail after 200 status, the error is in the body then
+	var result struct {
+		XMLName xml.Name
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return fmt.Errorf("%w %s. Err: %s", MultipartUploadError, key, result.Message)
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func signingKey(secretKey, day, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// Sign the request by AWS signature version 4 in the Authorization header
+func (c *Client) sign(req *http.Request, payloadHash string) {
+	now := c.now().UTC()
+	day := now.Format(amzDayFormat)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(fmt.Sprintf("%s:%s\n", name, strings.TrimSpace(req.Header.Get(name))))
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", day, c.Region)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		now.Format(amzDateFormat),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(c.SecretKey, day, c.Region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, c.AccessKey, scope, signedHeaders, signature))
+
+	// Host is sent from the url, the header is only signed
+	req.Header.Del("Host")
+}
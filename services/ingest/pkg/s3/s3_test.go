@@ -0,0 +1,131 @@
+package s3
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Example of the AWS signature version 4 documentation
+func TestSigningKey(t *testing.T) {
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestClient_PutObject(t *testing.T) {
+	var path, authorization, date, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		path, body = r.URL.Path, string(data)
+		authorization, date = r.Header.Get("Authorization"), r.Header.Get("X-Amz-Date")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "us-east-1", "recordings", "minio", "minio-secret")
+	assert.Nil(t, err)
+	client.now = func() time.Time { return time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC) }
+
+	err = client.PutObject(context.Background(), "admin/broadcast.mkv", strings.NewReader("media"), 5, "video/x-matroska")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/recordings/admin/broadcast.mkv", path)
+	assert.Equal(t, "media", body)
+	assert.Equal(t, "20230901T120000Z", date)
+	assert.True(t, strings.HasPrefix(authorization,
+		"AWS4-HMAC-SHA256 Credential=minio/20230901/us-east-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="))
+
+	_, err = NewClient("minio:9000", "us-east-1", "recordings", "minio", "minio-secret")
+	assert.ErrorIs(t, err, InvalidEndpointError)
+}
+
+func TestClient_UploadObject(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests []string
+	parts := map[string]string{}
+	var completion string
+	var mx sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+
+		data, _ := io.ReadAll(r.Body)
+		query := r.URL.Query()
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.RawQuery))
+
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut:
+			parts[query.Get("partNumber")] = string(data)
+			w.Header().Set("ETag", fmt.Sprintf(`"etag-%s"`, query.Get("partNumber")))
+		case r.Method == http.MethodPost:
+			completion = string(data)
+			fmt.Fprint(w, `<CompleteMultipartUploadResult><Key>admin/broadcast.mkv</Key></CompleteMultipartUploadResult>`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "us-east-1", "recordings", "minio", "minio-secret")
+	assert.Nil(err)
+	client.partSize = 4
+
+	// Object bigger than the part goes by parts, the last one is shorter
+	err = client.UploadObject(context.Background(), "admin/broadcast.mkv", strings.NewReader("media-file"), 10, "video/x-matroska")
+	assert.Nil(err)
+
+	assert.Equal([]string{
+		"POST uploads=",
+		"PUT partNumber=1&uploadId=upload-1",
+		"PUT partNumber=2&uploadId=upload-1",
+		"PUT partNumber=3&uploadId=upload-1",
+		"POST uploadId=upload-1",
+	}, requests)
+	assert.Equal(map[string]string{"1": "medi", "2": "a-fi", "3": "le"}, parts)
+	assert.Equal(`<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>"etag-1"</ETag></Part>`+
+		`<Part><PartNumber>2</PartNumber><ETag>"etag-2"</ETag></Part><Part><PartNumber>3</PartNumber><ETag>"etag-3"</ETag></Part></CompleteMultipartUpload>`,
+		strings.ReplaceAll(completion, "&#34;", `"`))
+
+	// Object which fits the part goes by single request
+	requests = nil
+	err = client.UploadObject(context.Background(), "admin/short.mkv", strings.NewReader("mkv"), 3, "video/x-matroska")
+	assert.Nil(err)
+	assert.Equal([]string{"PUT "}, requests)
+}
+
+func TestClient_UploadObject_Abort(t *testing.T) {
+	assert := assert.New(t)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.RawQuery))
+
+		switch r.Method {
+		case http.MethodPost:
+			fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case http.MethodPut:
+			w.WriteHeader(http.StatusInternalServerError)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "us-east-1", "recordings", "minio", "minio-secret")
+	assert.Nil(err)
+	client.partSize = 4
+
+	err = client.UploadObject(context.Background(), "admin/broadcast.mkv", strings.NewReader("media-file"), 10, "video/x-matroska")
+	assert.ErrorIs(err, MultipartUploadError)
+	assert.Equal([]string{"POST uploads=", "PUT partNumber=1&uploadId=upload-1", "DELETE uploadId=upload-1"}, requests)
+}
@@ -5,19 +5,25 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pion/ice/v2"
 	"github.com/pion/interceptor"
 	"github.com/pion/interceptor/pkg/intervalpli"
+	"github.com/pion/interceptor/pkg/nack"
 	"github.com/pion/webrtc/v3"
 	"github.com/romashorodok/stream-platform/pkg/envutils"
 	"github.com/romashorodok/stream-platform/pkg/httputils"
 	"github.com/romashorodok/stream-platform/pkg/netutils"
 	"github.com/romashorodok/stream-platform/pkg/variables"
+	"github.com/romashorodok/stream-platform/services/ingest/internal/media/rtp"
 	"go.uber.org/fx"
 )
 
+// Publisher keyframe of the received video. Interceptor requests it for each video track, simulcast layers included
+const keyframeRequestInterval = 2 * time.Second
+
 type IngestWebrtcConfig struct {
 	NATPublicIP string
 	UDPPort     uint16
@@ -64,6 +70,47 @@ func NewIngestHttpConfig() (*IngestHttpConfig, error) {
 	}, nil
 }
 
+type IngestRtmpConfig struct {
+	Host string
+	Port string
+}
+
+func (s IngestRtmpConfig) GetAddr() string {
+	return net.JoinHostPort(s.Host, s.Port)
+}
+
+func NewIngestRtmpConfig() (*IngestRtmpConfig, error) {
+	return &IngestRtmpConfig{
+		Host: envutils.Env(variables.INGEST_RTMP_HOST, variables.INGEST_RTMP_HOST_DEFAULT),
+		Port: envutils.Env(variables.INGEST_RTMP_PORT, variables.INGEST_RTMP_PORT_DEFAULT),
+	}, nil
+}
+
+type IngestSrtConfig struct {
+	Host    string
+	Port    string
+	Latency time.Duration
+}
+
+func (s IngestSrtConfig) GetAddr() string {
+	return net.JoinHostPort(s.Host, s.Port)
+}
+
+func NewIngestSrtConfig() (*IngestSrtConfig, error) {
+	latencyRaw := envutils.Env(variables.INGEST_SRT_LATENCY, variables.INGEST_SRT_LATENCY_DEFAULT)
+	latency, err := time.ParseDuration(latencyRaw)
+	if err != nil {
+		log.Printf("[ERROR] wrong srt latency %s. Fallback to %s", latencyRaw, variables.INGEST_SRT_LATENCY_DEFAULT)
+		latency, _ = time.ParseDuration(variables.INGEST_SRT_LATENCY_DEFAULT)
+	}
+
+	return &IngestSrtConfig{
+		Host:    envutils.Env(variables.INGEST_SRT_HOST, variables.INGEST_SRT_HOST_DEFAULT),
+		Port:    envutils.Env(variables.INGEST_SRT_PORT, variables.INGEST_SRT_PORT_DEFAULT),
+		Latency: latency,
+	}, nil
+}
+
 func populateMediaEngine(m *webrtc.MediaEngine) error {
 	for _, codec := range []webrtc.RTPCodecParameters{
 		{
@@ -187,13 +234,28 @@ func NewIngestWebrtcAPI(params IngestWebrtcAPIParams) *webrtc.API {
 
 	interceptorRegistry := &interceptor.Registry{}
 
-	intervalPliFactory, err := intervalpli.NewReceiverInterceptor()
+	// Browser sends keyframe only on loss or request. HLS passthrough cuts segments on them and new viewers wait them
+	intervalPliFactory, err := intervalpli.NewReceiverInterceptor(intervalpli.GeneratorInterval(keyframeRequestInterval))
 	if err != nil {
 		panic(err)
 	}
 	interceptorRegistry.Add(intervalPliFactory)
 
-	if err := webrtc.RegisterDefaultInterceptors(mediaEngine, interceptorRegistry); err != nil {
+	// Jitter buffer of the publisher track requests lost packets and takes their rtx, so the default nack generator isn't used.
+	// Responder retransmits to the viewers
+	nackResponderFactory, err := nack.NewResponderInterceptor()
+	if err != nil {
+		panic(err)
+	}
+	mediaEngine.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack"}, webrtc.RTPCodecTypeVideo)
+	mediaEngine.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack", Parameter: "pli"}, webrtc.RTPCodecTypeVideo)
+	interceptorRegistry.Add(nackResponderFactory)
+	interceptorRegistry.Add(rtp.NewRepairInterceptorFactory())
+
+	if err := webrtc.ConfigureRTCPReports(interceptorRegistry); err != nil {
+		log.Fatal(err)
+	}
+	if err := webrtc.ConfigureTWCCSender(mediaEngine, interceptorRegistry); err != nil {
 		log.Fatal(err)
 	}
 
@@ -249,6 +311,8 @@ var ServerModule = fx.Module("server",
 	fx.Provide(
 		NewIngestWebrtcConfig,
 		NewIngestHttpConfig,
+		NewIngestRtmpConfig,
+		NewIngestSrtConfig,
 
 		fx.Annotate(
 			NewRouter,
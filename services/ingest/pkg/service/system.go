@@ -9,9 +9,12 @@ import (
 )
 
 type IngestSystemConfig struct {
-	FailFast      bool
+	FailFast bool
+	// Dedicated ingest is deployed for the single broadcaster of BroadcasterID
+	Dedicated     bool
 	BroadcasterID string
 	Username      string
+	IdentityURL   string
 }
 
 func NewIngestSystemConfig() *IngestSystemConfig {
@@ -22,9 +25,18 @@ func NewIngestSystemConfig() *IngestSystemConfig {
 		failFast, _ = envutils.ParseBool(variables.INGEST_FAIL_FAST_DEFAULT)
 	}
 
+	dedicatedRaw := envutils.Env(variables.INGEST_DEDICATED, variables.INGEST_DEDICATED_DEFAULT)
+	dedicated, err := envutils.ParseBool(dedicatedRaw)
+	if err != nil {
+		log.Printf("[ERROR] wrong dedicated %s. Fallback to %s", dedicatedRaw, variables.INGEST_DEDICATED_DEFAULT)
+		dedicated, _ = envutils.ParseBool(variables.INGEST_DEDICATED_DEFAULT)
+	}
+
 	return &IngestSystemConfig{
+		Dedicated:     *dedicated,
 		BroadcasterID: envutils.Env(variables.INGEST_BROADCASTER_ID, variables.INGEST_BROADCASTER_ID_DEFAULT),
 		Username:      envutils.Env(variables.INGEST_USERNAME, variables.INGEST_USERNAME_DEFAULT),
+		IdentityURL:   envutils.Env(variables.INGEST_IDENTITY_URL, variables.INGEST_IDENTITY_URL_DEFAULT),
 		FailFast:      *failFast,
 	}
 }
@@ -0,0 +1,367 @@
+package srt
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// Receiver buffer in packets. Announced to sender as available buffer and flow window
+	receiveBufferSize = 8192
+
+	ackInterval       = 10 * time.Millisecond
+	minNakInterval    = 20 * time.Millisecond
+	keepaliveInterval = time.Second
+	peerIdleTimeout   = 5 * time.Second
+
+	initialRTT = 100 * time.Millisecond
+)
+
+type bufferedPacket struct {
+	payload []byte
+	arrival time.Time
+}
+
+// Receiving side of srt live connection. Packets are written to publisher in sequence order as soon as there is no gap.
+// Lost packets are requested by NAK and skipped when they are not retransmitted in latency
+type conn struct {
+	server       *Server
+	addr         net.Addr
+	socketID     uint32
+	peerSocketID uint32
+	streamID     string
+	latency      time.Duration
+	start        time.Time
+
+	writer io.WriteCloser
+
+	// Sent again when caller retransmit conclusion
+	conclusion []byte
+
+	packets chan *packet
+	done    chan struct{}
+	once    sync.Once
+
+	next     uint32
+	highest  uint32
+	buffer   map[uint32]*bufferedPacket
+	lost     map[uint32]time.Time
+	lastSent time.Time
+	lastRecv time.Time
+
+	ackNumber   uint32
+	ackedSeq    uint32
+	ackSentTime map[uint32]time.Time
+	rtt         time.Duration
+	rttVar      time.Duration
+
+	receivedPackets uint32
+	receivedBytes   uint32
+	lastAckTime     time.Time
+}
+
+func newConn(server *Server, addr net.Addr, socketID uint32, hs *handshake, streamID string, latency time.Duration, writer io.WriteCloser) *conn {
+	now := time.Now()
+
+	return &conn{
+		server:       server,
+		addr:         addr,
+		socketID:     socketID,
+		peerSocketID: hs.socketID,
+		streamID:     streamID,
+		latency:      latency,
+		start:        now,
+		writer:       writer,
+		packets:      make(chan *packet, receiveBufferSize),
+		done:         make(chan struct{}),
+		next:         hs.initialSeq,
+		highest:      seqPrev(hs.initialSeq),
+		ackedSeq:     hs.initialSeq,
+		buffer:       make(map[uint32]*bufferedPacket),
+		lost:         make(map[uint32]time.Time),
+		ackSentTime:  make(map[uint32]time.Time),
+		rtt:          initialRTT,
+		rttVar:       initialRTT / 2,
+		lastSent:     now,
+		lastRecv:     now,
+		lastAckTime:  now,
+	}
+}
+
+func (c *conn) timestamp() uint32 {
+	return uint32(time.Since(c.start).Microseconds())
+}
+
+func (c *conn) sendControl(controlType uint16, typeInfo uint32, payload []byte) {
+	p := &packet{
+		control:      true,
+		controlType:  controlType,
+		typeInfo:     typeInfo,
+		timestamp:    c.timestamp(),
+		destSocketID: c.peerSocketID,
+		payload:      payload,
+	}
+
+	if _, err := c.server.conn.WriteTo(p.marshal(), c.addr); err != nil {
+		log.Printf("[SRT] %s failed send control packet. Err: %s", c.addr, err)
+	}
+	c.lastSent = time.Now()
+}
+
+// Encode lost sequence numbers. Range is first number with the highest bit and last number
+func (c *conn) sendNak(ranges [][2]uint32) {
+	if len(ranges) == 0 {
+		return
+	}
+
+	var payload []byte
+	for _, r := range ranges {
+		if r[0] == r[1] {
+			payload = binary.BigEndian.AppendUint32(payload, r[0])
+			continue
+		}
+		payload = binary.BigEndian.AppendUint32(payload, r[0]|controlFlag)
+		payload = binary.BigEndian.AppendUint32(payload, r[1])
+	}
+
+	c.sendControl(ControlTypeNak, 0, payload)
+}
+
+func (c *conn) sendAck(now time.Time) {
+	c.ackNumber++
+	c.ackedSeq = c.next
+	c.ackSentTime[c.ackNumber] = now
+
+	elapsed := now.Sub(c.lastAckTime).Seconds()
+	var packetRate, byteRate uint32
+	if elapsed > 0 {
+		packetRate = uint32(float64(c.receivedPackets) / elapsed)
+		byteRate = uint32(float64(c.receivedBytes) / elapsed)
+	}
+	c.receivedPackets, c.receivedBytes = 0, 0
+	c.lastAckTime = now
+
+	available := receiveBufferSize - len(c.buffer)
+	if available < 2 {
+		available = 2
+	}
+
+	payload := make([]byte, 28)
+	binary.BigEndian.PutUint32(payload[0:4], c.next)
+	binary.BigEndian.PutUint32(payload[4:8], uint32(c.rtt.Microseconds()))
+	binary.BigEndian.PutUint32(payload[8:12], uint32(c.rttVar.Microseconds()))
+	binary.BigEndian.PutUint32(payload[12:16], uint32(available))
+	binary.BigEndian.PutUint32(payload[16:20], packetRate)
+	binary.BigEndian.PutUint32(payload[20:24], packetRate)
+	binary.BigEndian.PutUint32(payload[24:28], byteRate)
+
+	c.sendControl(ControlTypeAck, c.ackNumber, payload)
+
+	// Sender may never confirm some acks
+	for number, sent := range c.ackSentTime {
+		if now.Sub(sent) > peerIdleTimeout {
+			delete(c.ackSentTime, number)
+		}
+	}
+}
+
+func (c *conn) onAckAck(number uint32, now time.Time) {
+	sent, ok := c.ackSentTime[number]
+	if !ok {
+		return
+	}
+	delete(c.ackSentTime, number)
+
+	sample := now.Sub(sent)
+	deviation := c.rtt - sample
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	c.rttVar = (c.rttVar*3 + deviation) / 4
+	c.rtt = (c.rtt*7 + sample) / 8
+}
+
+func (c *conn) deliver() error {
+	for {
+		buffered, ok := c.buffer[c.next]
+		if !ok {
+			return nil
+		}
+		delete(c.buffer, c.next)
+		c.next = seqNext(c.next)
+
+		if _, err := c.writer.Write(buffered.payload); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *conn) onData(p *packet, now time.Time) error {
+	if p.encrypted() {
+		return nil
+	}
+
+	if seqDiff(p.seq, c.next) < 0 {
+		return nil
+	}
+	if _, ok := c.buffer[p.seq]; ok {
+		return nil
+	}
+
+	// Too far ahead. Sender dropped the packets and there is no way to recover them
+	if seqDiff(p.seq, c.next) >= receiveBufferSize {
+		log.Printf("[SRT] %s resync receiver from %d to %d", c.streamID, c.next, p.seq)
+		c.buffer = make(map[uint32]*bufferedPacket)
+		c.lost = make(map[uint32]time.Time)
+		c.next = p.seq
+		c.highest = seqPrev(p.seq)
+	}
+
+	c.receivedPackets++
+	c.receivedBytes += uint32(len(p.payload))
+
+	delete(c.lost, p.seq)
+	c.buffer[p.seq] = &bufferedPacket{
+		payload: append([]byte(nil), p.payload...),
+		arrival: now,
+	}
+
+	if seqDiff(p.seq, c.highest) > 1 {
+		first, last := seqNext(c.highest), seqPrev(p.seq)
+		for seq := first; seqDiff(seq, last) <= 0; seq = seqNext(seq) {
+			c.lost[seq] = now
+		}
+		c.sendNak([][2]uint32{{first, last}})
+	}
+	if seqDiff(p.seq, c.highest) > 0 {
+		c.highest = p.seq
+	}
+
+	return c.deliver()
+}
+
+// Drop lost packets which are not recovered in latency and request again others
+func (c *conn) onTick(now time.Time) error {
+	if len(c.buffer) > 0 {
+		if _, ok := c.buffer[c.next]; !ok {
+			for seq := c.next; seqDiff(seq, c.highest) <= 0; seq = seqNext(seq) {
+				buffered, ok := c.buffer[seq]
+				if !ok {
+					continue
+				}
+
+				if now.Sub(buffered.arrival) < c.latency {
+					break
+				}
+
+				for skipped := c.next; skipped != seq; skipped = seqNext(skipped) {
+					delete(c.lost, skipped)
+				}
+				c.next = seq
+
+				if err := c.deliver(); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	nakInterval := 2 * c.rtt
+	if nakInterval < minNakInterval {
+		nakInterval = minNakInterval
+	}
+
+	var ranges [][2]uint32
+	if len(c.lost) > 0 {
+		for seq := c.next; seqDiff(seq, c.highest) < 0; seq = seqNext(seq) {
+			requested, ok := c.lost[seq]
+			if !ok || now.Sub(requested) < nakInterval {
+				continue
+			}
+			c.lost[seq] = now
+
+			if n := len(ranges); n > 0 && seqNext(ranges[n-1][1]) == seq {
+				ranges[n-1][1] = seq
+			} else {
+				ranges = append(ranges, [2]uint32{seq, seq})
+			}
+		}
+	}
+	c.sendNak(ranges)
+
+	if c.next != c.ackedSeq {
+		c.sendAck(now)
+	}
+
+	if now.Sub(c.lastSent) > keepaliveInterval {
+		c.sendControl(ControlTypeKeepalive, 0, nil)
+	}
+
+	if now.Sub(c.lastRecv) > peerIdleTimeout {
+		return io.EOF
+	}
+
+	return nil
+}
+
+func (c *conn) onPacket(p *packet, now time.Time) error {
+	c.lastRecv = now
+
+	if !p.control {
+		return c.onData(p, now)
+	}
+
+	switch p.controlType {
+	case ControlTypeHandshake:
+		// Caller did not receive conclusion response
+		if _, err := c.server.conn.WriteTo(c.conclusion, c.addr); err != nil {
+			return err
+		}
+	case ControlTypeAckAck:
+		c.onAckAck(p.typeInfo, now)
+	case ControlTypeShutdown:
+		return io.EOF
+	}
+
+	return nil
+}
+
+func (c *conn) push(p *packet) {
+	select {
+	case c.packets <- p:
+	case <-c.done:
+	default:
+		// Receiver is too slow. Treat as network loss, the packet will be requested again
+	}
+}
+
+func (c *conn) serve() error {
+	ticker := time.NewTicker(ackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case p := <-c.packets:
+			if err := c.onPacket(p, time.Now()); err != nil {
+				return err
+			}
+		case now := <-ticker.C:
+			if err := c.onTick(now); err != nil {
+				return err
+			}
+		case <-c.done:
+			return nil
+		}
+	}
+}
+
+func (c *conn) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
@@ -0,0 +1,197 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	handshakeSize = 48
+
+	handshakeVersion4 = 4
+	handshakeVersion5 = 5
+
+	// Listener put it into extension field of induction response to mark it support HSv5
+	handshakeMagic = 0x4A17
+
+	handshakeTypeInduction  = 0x00000001
+	handshakeTypeConclusion = 0xFFFFFFFF
+
+	extensionFlagHSREQ  = 0x1
+	extensionFlagKMREQ  = 0x2
+	extensionFlagCONFIG = 0x4
+
+	extensionTypeHSREQ    = 1
+	extensionTypeHSRSP    = 2
+	extensionTypeKMREQ    = 3
+	extensionTypeStreamID = 5
+
+	// Version put into handshake response. Equal to libsrt 1.5.0
+	srtVersion = 0x00010500
+
+	srtFlagTSBPDSND    = 0x01
+	srtFlagTSBPDRCV    = 0x02
+	srtFlagTLPKTDROP   = 0x08
+	srtFlagPERIODICNAK = 0x10
+	srtFlagREXMITFLG   = 0x20
+
+	maxStreamIDSize = 512
+)
+
+// Handshake rejection reasons sent in handshake type field
+const (
+	RejectionPeer         = 1002
+	RejectionRogue        = 1004
+	RejectionVersion      = 1008
+	RejectionUnsecure     = 1011
+	RejectionBadRequest   = 2400
+	RejectionUnauthorized = 2401
+	RejectionForbidden    = 2403
+	RejectionNotFound     = 2404
+	RejectionConflict     = 2409
+)
+
+var (
+	InvalidHandshakeError = errors.New("invalid srt handshake")
+)
+
+type handshakeExtension struct {
+	kind    uint16
+	content []byte
+}
+
+type handshake struct {
+	version       uint32
+	encryption    uint16
+	extensionFlag uint16
+	initialSeq    uint32
+	mtu           uint32
+	flowWindow    uint32
+	handshakeType uint32
+	socketID      uint32
+	cookie        uint32
+	peerIP        [16]byte
+
+	extensions []handshakeExtension
+}
+
+func parseHandshake(b []byte) (*handshake, error) {
+	if len(b) < handshakeSize {
+		return nil, InvalidHandshakeError
+	}
+
+	hs := &handshake{
+		version:       binary.BigEndian.Uint32(b[0:4]),
+		encryption:    binary.BigEndian.Uint16(b[4:6]),
+		extensionFlag: binary.BigEndian.Uint16(b[6:8]),
+		initialSeq:    binary.BigEndian.Uint32(b[8:12]) & maxSeq,
+		mtu:           binary.BigEndian.Uint32(b[12:16]),
+		flowWindow:    binary.BigEndian.Uint32(b[16:20]),
+		handshakeType: binary.BigEndian.Uint32(b[20:24]),
+		socketID:      binary.BigEndian.Uint32(b[24:28]),
+		cookie:        binary.BigEndian.Uint32(b[28:32]),
+	}
+	copy(hs.peerIP[:], b[32:48])
+
+	for offset := handshakeSize; offset+4 <= len(b); {
+		kind := binary.BigEndian.Uint16(b[offset:])
+		size := int(binary.BigEndian.Uint16(b[offset+2:])) * 4
+		offset += 4
+
+		if offset+size > len(b) {
+			return nil, InvalidHandshakeError
+		}
+
+		hs.extensions = append(hs.extensions, handshakeExtension{kind: kind, content: b[offset : offset+size]})
+		offset += size
+	}
+
+	return hs, nil
+}
+
+func (hs *handshake) marshal() []byte {
+	b := make([]byte, handshakeSize)
+
+	binary.BigEndian.PutUint32(b[0:4], hs.version)
+	binary.BigEndian.PutUint16(b[4:6], hs.encryption)
+	binary.BigEndian.PutUint16(b[6:8], hs.extensionFlag)
+	binary.BigEndian.PutUint32(b[8:12], hs.initialSeq)
+	binary.BigEndian.PutUint32(b[12:16], hs.mtu)
+	binary.BigEndian.PutUint32(b[16:20], hs.flowWindow)
+	binary.BigEndian.PutUint32(b[20:24], hs.handshakeType)
+	binary.BigEndian.PutUint32(b[24:28], hs.socketID)
+	binary.BigEndian.PutUint32(b[28:32], hs.cookie)
+	copy(b[32:48], hs.peerIP[:])
+
+	for _, extension := range hs.extensions {
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint16(header[0:2], extension.kind)
+		binary.BigEndian.PutUint16(header[2:4], uint16(len(extension.content)/4))
+		b = append(b, header...)
+		b = append(b, extension.content...)
+	}
+
+	return b
+}
+
+func (hs *handshake) extension(kind uint16) ([]byte, bool) {
+	for _, extension := range hs.extensions {
+		if extension.kind == kind {
+			return extension.content, true
+		}
+	}
+	return nil, false
+}
+
+// Stream id is sent as 32 bit words in little endian byte order and padded by zeros
+func decodeStreamID(content []byte) string {
+	var sb strings.Builder
+	for offset := 0; offset+4 <= len(content); offset += 4 {
+		word := content[offset : offset+4]
+		sb.Write([]byte{word[3], word[2], word[1], word[0]})
+	}
+	return strings.TrimRight(sb.String(), "\x00")
+}
+
+func encodeStreamID(streamID string) []byte {
+	padded := make([]byte, (len(streamID)+3)/4*4)
+	copy(padded, streamID)
+
+	content := make([]byte, len(padded))
+	for offset := 0; offset < len(padded); offset += 4 {
+		word := padded[offset : offset+4]
+		copy(content[offset:offset+4], []byte{word[3], word[2], word[1], word[0]})
+	}
+	return content
+}
+
+// SRT options of the handshake request and response. Latency is in milliseconds
+type srtOptions struct {
+	version         uint32
+	flags           uint32
+	receiverLatency uint16
+	senderLatency   uint16
+}
+
+func parseSrtOptions(content []byte) (*srtOptions, error) {
+	if len(content) < 12 {
+		return nil, InvalidHandshakeError
+	}
+
+	latency := binary.BigEndian.Uint32(content[8:12])
+	return &srtOptions{
+		version:         binary.BigEndian.Uint32(content[0:4]),
+		flags:           binary.BigEndian.Uint32(content[4:8]),
+		receiverLatency: uint16(latency >> 16),
+		senderLatency:   uint16(latency),
+	}, nil
+}
+
+func (o *srtOptions) marshal() []byte {
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint32(b[0:4], o.version)
+	binary.BigEndian.PutUint32(b[4:8], o.flags)
+	binary.BigEndian.PutUint32(b[8:12], uint32(o.receiverLatency)<<16|uint32(o.senderLatency))
+	return b
+}
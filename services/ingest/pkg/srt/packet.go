@@ -0,0 +1,106 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	headerSize  = 16
+	controlFlag = 0x80000000
+
+	maxSeq = 0x7FFFFFFF
+)
+
+const (
+	ControlTypeHandshake = 0x0000
+	ControlTypeKeepalive = 0x0001
+	ControlTypeAck       = 0x0002
+	ControlTypeNak       = 0x0003
+	ControlTypeShutdown  = 0x0005
+	ControlTypeAckAck    = 0x0006
+)
+
+var (
+	InvalidPacketError = errors.New("invalid srt packet")
+)
+
+// Both data and control packets. Data packet use seq and messageFlags, control packet use controlType, subtype and typeInfo
+type packet struct {
+	control bool
+
+	seq          uint32
+	messageFlags uint32
+
+	controlType uint16
+	subtype     uint16
+	typeInfo    uint32
+
+	timestamp    uint32
+	destSocketID uint32
+	payload      []byte
+}
+
+func parsePacket(b []byte) (*packet, error) {
+	if len(b) < headerSize {
+		return nil, InvalidPacketError
+	}
+
+	first := binary.BigEndian.Uint32(b[0:4])
+	p := &packet{
+		control:      first&controlFlag != 0,
+		timestamp:    binary.BigEndian.Uint32(b[8:12]),
+		destSocketID: binary.BigEndian.Uint32(b[12:16]),
+		payload:      b[headerSize:],
+	}
+
+	if p.control {
+		p.controlType = uint16(first>>16) & 0x7FFF
+		p.subtype = uint16(first)
+		p.typeInfo = binary.BigEndian.Uint32(b[4:8])
+	} else {
+		p.seq = first & maxSeq
+		p.messageFlags = binary.BigEndian.Uint32(b[4:8])
+	}
+
+	return p, nil
+}
+
+func (p *packet) marshal() []byte {
+	b := make([]byte, headerSize+len(p.payload))
+
+	if p.control {
+		binary.BigEndian.PutUint32(b[0:4], controlFlag|uint32(p.controlType)<<16|uint32(p.subtype))
+		binary.BigEndian.PutUint32(b[4:8], p.typeInfo)
+	} else {
+		binary.BigEndian.PutUint32(b[0:4], p.seq&maxSeq)
+		binary.BigEndian.PutUint32(b[4:8], p.messageFlags)
+	}
+	binary.BigEndian.PutUint32(b[8:12], p.timestamp)
+	binary.BigEndian.PutUint32(b[12:16], p.destSocketID)
+	copy(b[headerSize:], p.payload)
+
+	return b
+}
+
+// Encryption key flags of the data packet
+func (p *packet) encrypted() bool {
+	return (p.messageFlags>>27)&0x3 != 0
+}
+
+func seqNext(seq uint32) uint32 {
+	return (seq + 1) & maxSeq
+}
+
+func seqPrev(seq uint32) uint32 {
+	return (seq - 1) & maxSeq
+}
+
+// Distance between sequence numbers in 31 bit circular space. Positive when a is after b
+func seqDiff(a, b uint32) int32 {
+	diff := (a - b) & maxSeq
+	if diff > maxSeq/2 {
+		return int32(diff) - maxSeq - 1
+	}
+	return int32(diff)
+}
@@ -0,0 +1,338 @@
+package srt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	maxPacketSize = 1500
+
+	defaultMTU = 1500
+
+	cookieLifetime = time.Minute
+)
+
+var (
+	PublishRejectedError = errors.New("srt publish rejected")
+)
+
+// Rejection reason sent to the caller. Any other error of the PublishFunc reject with RejectionBadRequest
+type RejectError struct {
+	Reason uint32
+	Err    error
+}
+
+func (e *RejectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
+
+// Called on connection by caller in publish mode. Writer receives payload of data packets. For live mode it is mpeg-ts
+type PublishFunc func(streamID string) (io.WriteCloser, error)
+
+// SRT listener in live mode which accept only publishers. Encryption is not supported
+type Server struct {
+	publish PublishFunc
+	latency time.Duration
+
+	conn   net.PacketConn
+	secret [32]byte
+
+	conns map[uint32]*conn
+	// Callers which publish is being accepted. Retransmitted conclusion of them is ignored
+	accepting map[string]struct{}
+	mx        sync.Mutex
+}
+
+func (srv *Server) cookie(addr net.Addr, at time.Time) uint32 {
+	hash := sha256.New()
+	hash.Write(srv.secret[:])
+	hash.Write([]byte(addr.String()))
+	_ = binary.Write(hash, binary.BigEndian, at.Unix()/int64(cookieLifetime.Seconds()))
+	return binary.BigEndian.Uint32(hash.Sum(nil))
+}
+
+func (srv *Server) validCookie(addr net.Addr, cookie uint32) bool {
+	now := time.Now()
+	return cookie == srv.cookie(addr, now) || cookie == srv.cookie(addr, now.Add(-cookieLifetime))
+}
+
+func (srv *Server) writeHandshake(addr net.Addr, destSocketID uint32, hs *handshake) []byte {
+	p := &packet{
+		control:      true,
+		controlType:  ControlTypeHandshake,
+		destSocketID: destSocketID,
+		payload:      hs.marshal(),
+	}
+
+	raw := p.marshal()
+	if _, err := srv.conn.WriteTo(raw, addr); err != nil {
+		log.Printf("[SRT] %s failed send handshake. Err: %s", addr, err)
+	}
+	return raw
+}
+
+func (srv *Server) reject(addr net.Addr, request *handshake, reason uint32) {
+	log.Printf("[SRT] %s handshake rejected with reason %d", addr, reason)
+
+	srv.writeHandshake(addr, request.socketID, &handshake{
+		version:       handshakeVersion5,
+		initialSeq:    request.initialSeq,
+		mtu:           request.mtu,
+		flowWindow:    request.flowWindow,
+		handshakeType: reason,
+		cookie:        request.cookie,
+		peerIP:        request.peerIP,
+	})
+}
+
+func (srv *Server) onInduction(addr net.Addr, request *handshake) {
+	if request.version != handshakeVersion4 && request.version != handshakeVersion5 {
+		srv.reject(addr, request, RejectionVersion)
+		return
+	}
+
+	srv.writeHandshake(addr, request.socketID, &handshake{
+		version:       handshakeVersion5,
+		extensionFlag: handshakeMagic,
+		initialSeq:    request.initialSeq,
+		mtu:           request.mtu,
+		flowWindow:    request.flowWindow,
+		handshakeType: handshakeTypeInduction,
+		socketID:      request.socketID,
+		cookie:        srv.cookie(addr, time.Now()),
+		peerIP:        request.peerIP,
+	})
+}
+
+func (srv *Server) findByPeer(addr net.Addr, peerSocketID uint32) *conn {
+	srv.mx.Lock()
+	defer srv.mx.Unlock()
+
+	for _, c := range srv.conns {
+		if c.peerSocketID == peerSocketID && c.addr.String() == addr.String() {
+			return c
+		}
+	}
+	return nil
+}
+
+func (srv *Server) onConclusion(addr net.Addr, request *handshake) {
+	if c := srv.findByPeer(addr, request.socketID); c != nil {
+		c.push(&packet{control: true, controlType: ControlTypeHandshake})
+		return
+	}
+
+	if !srv.validCookie(addr, request.cookie) {
+		srv.reject(addr, request, RejectionRogue)
+		return
+	}
+
+	if request.version != handshakeVersion5 {
+		srv.reject(addr, request, RejectionVersion)
+		return
+	}
+
+	if _, ok := request.extension(extensionTypeKMREQ); ok || request.encryption != 0 {
+		srv.reject(addr, request, RejectionUnsecure)
+		return
+	}
+
+	content, ok := request.extension(extensionTypeHSREQ)
+	if !ok {
+		srv.reject(addr, request, RejectionVersion)
+		return
+	}
+	options, err := parseSrtOptions(content)
+	if err != nil {
+		srv.reject(addr, request, RejectionPeer)
+		return
+	}
+
+	var streamID string
+	if content, ok := request.extension(extensionTypeStreamID); ok {
+		if len(content) > maxStreamIDSize {
+			srv.reject(addr, request, RejectionBadRequest)
+			return
+		}
+		streamID = decodeStreamID(content)
+	}
+
+	// Both sides use the greatest latency
+	latency := srv.latency
+	if peerLatency := time.Duration(options.senderLatency) * time.Millisecond; peerLatency > latency {
+		latency = peerLatency
+	}
+
+	caller := fmt.Sprintf("%s/%d", addr, request.socketID)
+
+	srv.mx.Lock()
+	if _, ok := srv.accepting[caller]; ok {
+		srv.mx.Unlock()
+		return
+	}
+	srv.accepting[caller] = struct{}{}
+	srv.mx.Unlock()
+
+	// Publish may verify the caller remotely, packets of other connections are served meanwhile
+	go func() {
+		srv.accept(addr, request, streamID, latency)
+
+		srv.mx.Lock()
+		delete(srv.accepting, caller)
+		srv.mx.Unlock()
+	}()
+}
+
+func (srv *Server) accept(addr net.Addr, request *handshake, streamID string, latency time.Duration) {
+	writer, err := srv.publish(streamID)
+	if err != nil {
+		reason := uint32(RejectionBadRequest)
+		var rejectErr *RejectError
+		if errors.As(err, &rejectErr) {
+			reason = rejectErr.Reason
+		}
+		log.Printf("[SRT] %s reject %s stream. Err: %s", addr, streamID, errors.Join(PublishRejectedError, err))
+		srv.reject(addr, request, reason)
+		return
+	}
+
+	var socketID uint32
+	for socketID == 0 {
+		var b [4]byte
+		_, _ = rand.Read(b[:])
+		socketID = binary.BigEndian.Uint32(b[:]) & maxSeq
+	}
+
+	c := newConn(srv, addr, socketID, request, streamID, latency, writer)
+
+	mtu := request.mtu
+	if mtu == 0 || mtu > defaultMTU {
+		mtu = defaultMTU
+	}
+
+	response := &srtOptions{
+		version:         srtVersion,
+		flags:           srtFlagTSBPDSND | srtFlagTSBPDRCV | srtFlagTLPKTDROP | srtFlagPERIODICNAK | srtFlagREXMITFLG,
+		receiverLatency: uint16(latency.Milliseconds()),
+		senderLatency:   uint16(latency.Milliseconds()),
+	}
+
+	c.conclusion = srv.writeHandshake(addr, request.socketID, &handshake{
+		version:       handshakeVersion5,
+		extensionFlag: extensionFlagHSREQ,
+		initialSeq:    request.initialSeq,
+		mtu:           mtu,
+		flowWindow:    receiveBufferSize,
+		handshakeType: handshakeTypeConclusion,
+		socketID:      socketID,
+		cookie:        request.cookie,
+		peerIP:        request.peerIP,
+		extensions: []handshakeExtension{
+			{kind: extensionTypeHSRSP, content: response.marshal()},
+		},
+	})
+
+	srv.mx.Lock()
+	srv.conns[socketID] = c
+	srv.mx.Unlock()
+
+	log.Printf("[SRT] %s publish %s stream with %s latency", addr, streamID, latency)
+
+	go func() {
+		err := c.serve()
+		if !errors.Is(err, io.EOF) {
+			c.sendControl(ControlTypeShutdown, 0, nil)
+		}
+
+		srv.mx.Lock()
+		delete(srv.conns, socketID)
+		srv.mx.Unlock()
+
+		c.Close()
+		_ = c.writer.Close()
+
+		log.Printf("[SRT] %s stop publishing %s stream", addr, streamID)
+	}()
+}
+
+func (srv *Server) onHandshake(addr net.Addr, p *packet) {
+	request, err := parseHandshake(p.payload)
+	if err != nil {
+		return
+	}
+
+	switch request.handshakeType {
+	case handshakeTypeInduction:
+		srv.onInduction(addr, request)
+	case handshakeTypeConclusion:
+		srv.onConclusion(addr, request)
+	}
+}
+
+func (srv *Server) closeAll() {
+	srv.mx.Lock()
+	defer srv.mx.Unlock()
+
+	for _, c := range srv.conns {
+		c.Close()
+	}
+}
+
+// Read packets from the socket and dispatch them to connections until socket closed
+func (srv *Server) Serve(pc net.PacketConn) error {
+	srv.conn = pc
+	defer srv.closeAll()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		p, err := parsePacket(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			continue
+		}
+
+		if p.destSocketID == 0 {
+			if p.control && p.controlType == ControlTypeHandshake {
+				srv.onHandshake(addr, p)
+			}
+			continue
+		}
+
+		srv.mx.Lock()
+		c, ok := srv.conns[p.destSocketID]
+		srv.mx.Unlock()
+
+		if ok {
+			c.push(p)
+		}
+	}
+}
+
+// Latency is minimal receiver latency. Caller may request greater one
+func NewServer(publish PublishFunc, latency time.Duration) *Server {
+	srv := &Server{
+		publish:   publish,
+		latency:   latency,
+		conns:     make(map[uint32]*conn),
+		accepting: make(map[string]struct{}),
+	}
+	_, _ = rand.Read(srv.secret[:])
+	return srv
+}
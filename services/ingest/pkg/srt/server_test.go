@@ -0,0 +1,171 @@
+package srt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordWriter struct {
+	mx     sync.Mutex
+	data   bytes.Buffer
+	closed chan struct{}
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.data.Write(p)
+}
+
+func (w *recordWriter) Close() error {
+	close(w.closed)
+	return nil
+}
+
+func (w *recordWriter) String() string {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.data.String()
+}
+
+type caller struct {
+	t        *testing.T
+	conn     net.Conn
+	socketID uint32
+	peerID   uint32
+}
+
+func (c *caller) write(p *packet) {
+	_, err := c.conn.Write(p.marshal())
+	assert.Nil(c.t, err)
+}
+
+func (c *caller) read() *packet {
+	buf := make([]byte, maxPacketSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := c.conn.Read(buf)
+	if !assert.Nil(c.t, err) {
+		c.t.FailNow()
+	}
+
+	p, err := parsePacket(buf[:n])
+	assert.Nil(c.t, err)
+	return p
+}
+
+// Read until control packet of the type
+func (c *caller) readControl(controlType uint16) *packet {
+	for {
+		if p := c.read(); p.control && p.controlType == controlType {
+			return p
+		}
+	}
+}
+
+func (c *caller) handshake(streamID string) *handshake {
+	const initialSeq = 100
+
+	c.write(&packet{control: true, controlType: ControlTypeHandshake, payload: (&handshake{
+		version:       handshakeVersion4,
+		extensionFlag: 2,
+		initialSeq:    initialSeq,
+		mtu:           1500,
+		flowWindow:    8192,
+		handshakeType: handshakeTypeInduction,
+		socketID:      c.socketID,
+	}).marshal()})
+
+	induction, err := parseHandshake(c.readControl(ControlTypeHandshake).payload)
+	assert.Nil(c.t, err)
+	assert.Equal(c.t, uint16(handshakeMagic), induction.extensionFlag)
+
+	options := &srtOptions{version: srtVersion, flags: srtFlagTSBPDSND, senderLatency: 50}
+	c.write(&packet{control: true, controlType: ControlTypeHandshake, payload: (&handshake{
+		version:       handshakeVersion5,
+		extensionFlag: extensionFlagHSREQ | extensionFlagCONFIG,
+		initialSeq:    initialSeq,
+		mtu:           1500,
+		flowWindow:    8192,
+		handshakeType: handshakeTypeConclusion,
+		socketID:      c.socketID,
+		cookie:        induction.cookie,
+		extensions: []handshakeExtension{
+			{kind: extensionTypeHSREQ, content: options.marshal()},
+			{kind: extensionTypeStreamID, content: encodeStreamID(streamID)},
+		},
+	}).marshal()})
+
+	conclusion, err := parseHandshake(c.readControl(ControlTypeHandshake).payload)
+	assert.Nil(c.t, err)
+	c.peerID = conclusion.socketID
+	return conclusion
+}
+
+func listen(t *testing.T, publish PublishFunc) *caller {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { pc.Close() })
+
+	srv := NewServer(publish, 20*time.Millisecond)
+	go srv.Serve(pc)
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &caller{t: t, conn: conn, socketID: 42}
+}
+
+func TestServer_PublishWithRetransmission(t *testing.T) {
+	assert := assert.New(t)
+
+	writer := &recordWriter{closed: make(chan struct{})}
+	var streamID string
+
+	caller := listen(t, func(id string) (io.WriteCloser, error) {
+		streamID = id
+		return writer, nil
+	})
+
+	conclusion := caller.handshake("#!::r=admin,m=publish")
+	assert.Equal(uint32(handshakeTypeConclusion), conclusion.handshakeType)
+	assert.Equal("#!::r=admin,m=publish", streamID)
+
+	data := func(seq uint32, payload string) {
+		caller.write(&packet{seq: seq, messageFlags: 0xC0000000, destSocketID: caller.peerID, payload: []byte(payload)})
+	}
+
+	data(100, "a")
+	// Packet 101 is lost
+	data(102, "c")
+
+	nak := caller.readControl(ControlTypeNak)
+	assert.Equal(uint32(101), binary.BigEndian.Uint32(nak.payload))
+
+	data(101, "b")
+
+	ack := caller.readControl(ControlTypeAck)
+	caller.write(&packet{control: true, controlType: ControlTypeAckAck, typeInfo: ack.typeInfo, destSocketID: caller.peerID})
+
+	assert.Eventually(func() bool { return writer.String() == "abc" }, time.Second, 10*time.Millisecond)
+
+	caller.write(&packet{control: true, controlType: ControlTypeShutdown, destSocketID: caller.peerID})
+	<-writer.closed
+}
+
+func TestServer_PublishRejected(t *testing.T) {
+	caller := listen(t, func(id string) (io.WriteCloser, error) {
+		return nil, &RejectError{Reason: RejectionUnauthorized, Err: errors.New("invalid stream key")}
+	})
+
+	conclusion := caller.handshake("admin")
+	assert.Equal(t, uint32(RejectionUnauthorized), conclusion.handshakeType)
+}
@@ -14,6 +14,7 @@ require (
 	github.com/nats-io/nats.go v1.28.0
 	github.com/oapi-codegen/runtime v1.0.0
 	github.com/romashorodok/stream-platform v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.4
 	go.uber.org/fx v1.20.0
 	google.golang.org/grpc v1.57.0
 	google.golang.org/protobuf v1.31.0
@@ -53,7 +54,6 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/segmentio/asm v1.2.0 // indirect
-	github.com/stretchr/testify v1.8.4 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/dig v1.17.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
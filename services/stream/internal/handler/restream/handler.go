@@ -0,0 +1,136 @@
+package restream
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/romashorodok/stream-platform/pkg/auth"
+	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/pkg/openapi3utils"
+	"github.com/romashorodok/stream-platform/services/stream/internal/restreamsvc"
+	"go.uber.org/fx"
+)
+
+//go:generate go run github.com/deepmap/oapi-codegen/cmd/oapi-codegen@latest --config=handler.cfg.yaml ../../../../../gen/openapiv3/streaming/v1alpha/restream.openapi.yaml
+
+type handler struct {
+	Unimplemented
+
+	handlerSpecValidator openapi3utils.HandlerSpecValidator
+	restream             *restreamsvc.RestreamService
+}
+
+var _ ServerInterface = (*handler)(nil)
+var _ httputils.HttpHandler = (*handler)(nil)
+
+func restreamTarget(target restreamsvc.RestreamTarget) RestreamTarget {
+	id := target.ID.String()
+	return RestreamTarget{Id: &id, Url: &target.URL}
+}
+
+func (h *handler) RestreamServiceRestreamTargetList(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.WithTokenPayload(r.Context())
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusPreconditionFailed, "Not found user token payload.", err.Error())
+		return
+	}
+
+	targets, err := h.restream.GetRestreamTargets(r.Context(), token.UserID)
+	if err != nil {
+		unableRestreamTargetErrorHandler(w, err)
+		return
+	}
+
+	result := make([]RestreamTarget, 0, len(targets))
+	for _, target := range targets {
+		result = append(result, restreamTarget(target))
+	}
+
+	_ = json.NewEncoder(w).Encode(RestreamTargetListResponse{Targets: &result})
+}
+
+func (h *handler) RestreamServiceCreateRestreamTarget(w http.ResponseWriter, r *http.Request) {
+	var request CreateRestreamTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httputils.WriteErrorResponse(w, http.StatusPreconditionFailed, "Unable deserialize request body.", err.Error())
+		return
+	}
+
+	token, err := auth.WithTokenPayload(r.Context())
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusPreconditionFailed, "Not found user token payload.", err.Error())
+		return
+	}
+
+	target, err := h.restream.CreateRestreamTarget(r.Context(), token.UserID, request.Url, request.Key)
+	if err != nil {
+		unableRestreamTargetErrorHandler(w, err)
+		return
+	}
+
+	result := restreamTarget(*target)
+	_ = json.NewEncoder(w).Encode(CreateRestreamTargetResponse{Target: &result})
+}
+
+func (h *handler) RestreamServiceDeleteRestreamTarget(w http.ResponseWriter, r *http.Request, id string) {
+	targetID, err := uuid.Parse(id)
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid restream target id.", err.Error())
+		return
+	}
+
+	token, err := auth.WithTokenPayload(r.Context())
+	if err != nil {
+		httputils.WriteErrorResponse(w, http.StatusPreconditionFailed, "Not found user token payload.", err.Error())
+		return
+	}
+
+	if err := h.restream.DeleteRestreamTarget(r.Context(), token.UserID, targetID); err != nil {
+		unableRestreamTargetErrorHandler(w, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(DeleteRestreamTargetResponse{})
+}
+
+func (h *handler) GetOption() httputils.HttpHandlerOption {
+	return func(hand http.Handler) {
+		switch hand.(type) {
+		case *chi.Mux:
+			mux := hand.(*chi.Mux)
+
+			spec, err := GetSwagger()
+			if err != nil {
+				log.Panicf("unable get openapi spec for restream.handler.Err: %s", err)
+			}
+			spec.Servers = nil
+
+			HandlerWithOptions(h, ChiServerOptions{
+				BaseRouter: mux,
+				Middlewares: []MiddlewareFunc{
+					h.handlerSpecValidator(spec),
+					httputils.JsonMiddleware(),
+				},
+			})
+		default:
+			panic("unsupported restream handler")
+		}
+	}
+}
+
+type RestreamServiceHandlerParams struct {
+	fx.In
+
+	HandlerSpecValidator openapi3utils.HandlerSpecValidator
+	Restream             *restreamsvc.RestreamService
+}
+
+func NewRestreamServiceHandler(params RestreamServiceHandlerParams) *handler {
+	return &handler{
+		handlerSpecValidator: params.HandlerSpecValidator,
+		restream:             params.Restream,
+	}
+}
@@ -0,0 +1,19 @@
+package restream
+
+import (
+	"net/http"
+
+	"github.com/romashorodok/stream-platform/pkg/httputils"
+	"github.com/romashorodok/stream-platform/services/stream/internal/restreamsvc"
+)
+
+func unableRestreamTargetErrorHandler(w http.ResponseWriter, err error) {
+	switch err {
+	case restreamsvc.InvalidRestreamTargetUrlError:
+		httputils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+	case restreamsvc.NotFoundRestreamTargetError:
+		httputils.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+	default:
+		httputils.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -66,6 +66,59 @@ func notifyPeerWhenStreamDestroyedNotification(peer *wspeer.WebsocketPeer, conn
 	<-peer.Done()
 }
 
+func notifyPeerWhenStreamStatusNotification(peer *wspeer.WebsocketPeer, conn *nats.Conn, auth *auth.TokenPayload) {
+	log.Printf("[%s] Subscribe to stream status.", auth.Sub)
+
+	subscription, err := conn.Subscribe(subject.NewStreamStatusNotification(auth.UserID.String()), func(msg *nats.Msg) {
+		var status streamingpb.StreamStatus
+
+		if err := subject.DeserializeProtobufMsg(&status, msg); err != nil {
+			log.Printf("[%s] Unable deserialize protobuf message. Err: %s", auth.Sub, err)
+			return
+		}
+
+		if err := peer.WriteProtobuf(&status); err != nil {
+			log.Printf("[%s] Unable send stream status notification protobuf message to peer. Err: %s", auth.Sub, err)
+			return
+		}
+
+		log.Printf("[%s] Peer notified for %s", auth.Sub, subject.NewStreamStatusNotification(auth.UserID.String()))
+	})
+	defer subscription.Drain()
+
+	if err != nil {
+		log.Printf("[%s] Unable start subscription when stream status changed. Err: %s", auth.Sub, err)
+	}
+
+	<-peer.Done()
+}
+
+// Ingest publishes health of the stream processors, so the dashboard shows degraded egress
+func notifyPeerWhenIngestProcessorStatus(peer *wspeer.WebsocketPeer, conn *nats.Conn, auth *auth.TokenPayload) {
+	log.Printf("[%s] Subscribe to ingest processor status.", auth.Sub)
+
+	subscription, err := conn.Subscribe(subject.NewIngestProcessorStatus(auth.UserID.String()), func(msg *nats.Msg) {
+		var status subject.IngestProcessorStatus
+
+		if err := subject.DeserializeProtobufMsg(&status, msg); err != nil {
+			log.Printf("[%s] Unable deserialize protobuf message. Err: %s", auth.Sub, err)
+			return
+		}
+
+		if err := peer.WriteProtobuf(&status); err != nil {
+			log.Printf("[%s] Unable send processor status protobuf message to peer. Err: %s", auth.Sub, err)
+			return
+		}
+	})
+	defer subscription.Drain()
+
+	if err != nil {
+		log.Printf("[%s] Unable start subscription when processor status changed. Err: %s", auth.Sub, err)
+	}
+
+	<-peer.Done()
+}
+
 func (s *StreamingService) StreamingServiceStreamChannel(w http.ResponseWriter, r *http.Request) {
 	plainToken, err := r.Cookie(tokenutils.REFRESH_TOKEN_COOKIE_NAME)
 	if err != nil {
@@ -94,6 +147,8 @@ func (s *StreamingService) StreamingServiceStreamChannel(w http.ResponseWriter,
 
 	go notifyPeerWhenIngestDeployed(peer, s.nats, payload)
 	go notifyPeerWhenStreamDestroyedNotification(peer, s.nats, payload)
+	go notifyPeerWhenStreamStatusNotification(peer, s.nats, payload)
+	go notifyPeerWhenIngestProcessorStatus(peer, s.nats, payload)
 
 	if err != nil {
 		httputils.WriteErrorResponse(w, http.StatusInternalServerError, "Unable upgrade http request", err.Error())
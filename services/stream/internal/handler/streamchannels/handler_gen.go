@@ -26,8 +26,11 @@ type GetStreamChannelResponse struct {
 // StreamChannel defines model for StreamChannel.
 type StreamChannel struct {
 	Egresses *[]StreamEgress `json:"egresses,omitempty"`
-	Title    *string         `json:"title,omitempty"`
-	Username *string         `json:"username,omitempty"`
+
+	// Thumbnail Live preview image of the channel.
+	Thumbnail *string `json:"thumbnail,omitempty"`
+	Title     *string `json:"title,omitempty"`
+	Username  *string `json:"username,omitempty"`
 }
 
 // StreamChannelListResponse defines model for StreamChannelListResponse.
@@ -240,14 +243,14 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/7SUT4/TMBDFvwp6cAxNYG++IYTQCiQQe0R7MOk08Sqxzcyk0irKd0d20u32n5YKcaob",
-	"T9689xs7I+rQx+DJq8CMkLql3ublZ9I7ZbL9x9Z6T90Pkhi8UNqLHCKxOsqV9VyQlm+YNjB4Xe5ly0Wz",
-	"PFDDNBXQx0gwCL8eqFZMBQ5LThpRwyQyr51SL3/X8lN+DfuGltk+5v9Ouxxo2RBl55u0Mwixt/25zReN",
-	"f3WiL9K6NsQTt+MUl+0suU8czOUjNoF7qzAgP/R4knFeqSE+p5weOb8JWWVmdxhd7oi3rqZXH77fosCW",
-	"WFzwMKhW1epdshYieRsdDG5W1eoGBaLVNvsqJUu9fU6oIU0/yb5VF/zt+lLLkxGgAC9TyFLvqyrzD17J",
-	"Z1UbY+fqrFs+SPK5uwFXzeRg3pnRmqRmF3XO/u3LzNI2AvPzvHvcp5JjAuW4O4fTdTCOL2/GzLYnJU4m",
-	"RrjkLKFHgfmg7898Avd7cExrGOWBimdUji/D/X+kfPET9A+QpwJCvN1hGLiDQasaxZRlF2rbtUEUKdei",
-	"Ne74nNec7qc/AQAA//+3mDsoRgUAAA==",
+	"H4sIAAAAAAACA7WUTU/kMAyG/0qV5chOZ+E2t9UKIQTSIjiiOYTiaY2aDxy3CFX97zhph6HzIRit9tQ0",
+	"cV+/fuymU4Uz3lmwHNSiU6GowOi0vAS+ZwJt/lTaWqjvIEhggHjmyXkgRkiRxRAQlycEK7VQP/KNbD5q",
+	"5hM11fenit+8yCn3+AwFK9mYhuwkgpIghGGNDCZ8L+VF+kxtEmoi/Zbeq8Y8Wo0p1xOEgtAzOisxN9hC",
+	"5glahNcMjS4hc6uMK8jGcmfqQy8woS2TIHKdCO2cNAHIarPv8EsSNxj4S/zHUvloxDaWw3ZGkDsOhvBO",
+	"rRwZzfIh2MZs8KBlKIH2KccttCuXVAZ209LDPVCLBWS/b69EsQUKQ3/ms/nsV7QmRqz2KFvnsnUuQV5z",
+	"lXzlIUn9/EyoBI6PaF/HVl89HUq50wKRprELSepsPk/8ndRnk6r2vsYi6ebPIfpc/1JH9WTS78RoOpt/",
+	"rweWuhQfD/vdq2UM2SaQd+s57I+DsX0bJMwkOiwdERMyetFZRC9Hw6BvZj6Ce2mQQPSZGjj9RGX7Z1j+",
+	"R8oH77R/gCwxUma7xtCQXCaqYvZhkee1K3RdOZmdWNeo1a357Nfsl/07eF9ndZcFAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -7,6 +7,7 @@ import (
 	ingestioncontrollerpb "github.com/romashorodok/stream-platform/gen/golang/ingestion_controller_operator/v1alpha"
 	subjectpb "github.com/romashorodok/stream-platform/gen/golang/subject/v1alpha"
 	"github.com/romashorodok/stream-platform/pkg/subject"
+	"github.com/romashorodok/stream-platform/pkg/variables"
 	"github.com/romashorodok/stream-platform/services/stream/pkg/service"
 	"google.golang.org/grpc"
 )
@@ -16,6 +17,12 @@ type IngestController interface {
 	StopServer(ctx context.Context, in *ingestioncontrollerpb.StopServerRequest, opts ...grpc.CallOption) (*ingestioncontrollerpb.StopServerResponse, error)
 }
 
+// Egress served by the media processor of the ingest. Processors without own route are not egresses
+var processorEgresses = map[string]subjectpb.IngestEgressType{
+	variables.INGEST_MEDIA_PROCESSOR_HLS:  subjectpb.IngestEgressType_STREAM_TYPE_HLS,
+	variables.INGEST_MEDIA_PROCESSOR_DASH: subjectpb.IngestEgressType_STREAM_TYPE_DASH,
+}
+
 type StandaloneIngestControllerStub struct {
 	ingestioncontrollerpb.UnimplementedIngestControllerServiceServer
 
@@ -25,6 +32,16 @@ type StandaloneIngestControllerStub struct {
 }
 
 func (ctrl *StandaloneIngestControllerStub) StartServer(ctx context.Context, in *ingestioncontrollerpb.StartServerRequest, _ ...grpc.CallOption) (*ingestioncontrollerpb.StartServerResponse, error) {
+	// WHEP is served by each ingest, other egresses only by the running media processors
+	egresses := []*subjectpb.IngestEgress{
+		{Type: subjectpb.IngestEgressType_STREAM_TYPE_WEBRTC},
+	}
+	for _, processor := range ctrl.config.IngestStandalone.IngestProcessors {
+		if egress, ok := processorEgresses[processor]; ok {
+			egresses = append(egresses, &subjectpb.IngestEgress{Type: egress})
+		}
+	}
+
 	_ = subject.PublishProtobuf(
 		ctrl.conn,
 		/* broadcasterID should be obtained from config of the ingest server that has been deployed specifically for each broadcaster. */
@@ -32,10 +49,7 @@ func (ctrl *StandaloneIngestControllerStub) StartServer(ctx context.Context, in
 		&subject.IngestDeployed{
 			Deployed: true,
 			Meta:     &subjectpb.BroadcasterMeta{BroadcasterId: in.Meta.BroadcasterId, Username: in.Meta.Username},
-			Egresses: []*subjectpb.IngestEgress{
-				{Type: subjectpb.IngestEgressType_STREAM_TYPE_WEBRTC},
-				{Type: subjectpb.IngestEgressType_STREAM_TYPE_HLS},
-			},
+			Egresses: egresses,
 		})
 
 	// NOTE: Operator must return namespace and unique deployment name for each ingest
@@ -0,0 +1,91 @@
+package restreamsvc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/romashorodok/stream-platform/services/stream/internal/storage/postgress/repository"
+	"go.uber.org/fx"
+)
+
+var (
+	InvalidRestreamTargetUrlError   = errors.New("Restream target url must be rtmp or rtmps.")
+	UnableGetRestreamTargetsError   = errors.New("Unable get restream targets.")
+	UnableCreateRestreamTargetError = errors.New("Unable create restream target.")
+	UnableDeleteRestreamTargetError = errors.New("Unable delete restream target.")
+	NotFoundRestreamTargetError     = errors.New("Not found restream target.")
+)
+
+// Stream key is not part of the target. It's only passed to the ingest
+type RestreamTarget struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+}
+
+type RestreamService struct {
+	restreamTargetRepository *repository.RestreamTargetRepository
+}
+
+func validateURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" || (target.Scheme != "rtmp" && target.Scheme != "rtmps") {
+		return InvalidRestreamTargetUrlError
+	}
+	return nil
+}
+
+func (s *RestreamService) GetRestreamTargets(ctx context.Context, broadcasterID uuid.UUID) ([]RestreamTarget, error) {
+	models, err := s.restreamTargetRepository.GetRestreamTargetsByBroadcasterId(ctx, broadcasterID)
+	if err != nil {
+		log.Printf("[%s]: Unable get restream targets. Err: %s", broadcasterID, err)
+		return nil, UnableGetRestreamTargetsError
+	}
+
+	targets := make([]RestreamTarget, 0, len(models))
+	for _, model := range models {
+		targets = append(targets, RestreamTarget{ID: model.ID, URL: model.URL})
+	}
+	return targets, nil
+}
+
+func (s *RestreamService) CreateRestreamTarget(ctx context.Context, broadcasterID uuid.UUID, url, key string) (*RestreamTarget, error) {
+	if err := validateURL(url); err != nil {
+		return nil, err
+	}
+
+	model, err := s.restreamTargetRepository.InsertRestreamTarget(ctx, broadcasterID, url, key)
+	if err != nil {
+		log.Printf("[%s]: Unable insert restream target. Err: %s", broadcasterID, err)
+		return nil, UnableCreateRestreamTargetError
+	}
+
+	return &RestreamTarget{ID: model.ID, URL: model.URL}, nil
+}
+
+func (s *RestreamService) DeleteRestreamTarget(ctx context.Context, broadcasterID uuid.UUID, id uuid.UUID) error {
+	err := s.restreamTargetRepository.DeleteRestreamTarget(ctx, broadcasterID, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return NotFoundRestreamTargetError
+	case err != nil:
+		log.Printf("[%s]: Unable delete restream target. Err: %s", broadcasterID, err)
+		return UnableDeleteRestreamTargetError
+	}
+	return nil
+}
+
+type RestreamServiceParams struct {
+	fx.In
+
+	RestreamTargetRepository *repository.RestreamTargetRepository
+}
+
+func NewRestreamService(params RestreamServiceParams) *RestreamService {
+	return &RestreamService{
+		restreamTargetRepository: params.RestreamTargetRepository,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	. "github.com/go-jet/jet/v2/postgres"
 	"github.com/go-jet/jet/v2/qrm"
@@ -117,6 +118,48 @@ func (r *ActiveStreamRepository) UpdateDeployedStatusByBroadcasterId(q qrm.Query
 	}, err
 }
 
+type UpdateRunningStatusByBroadcasterIdResult struct {
+	ID            uuid.UUID
+	BroadcasterID uuid.UUID
+	Running       bool
+	Deployed      bool
+}
+
+// Stopped stream becomes idle since now. Idle is reset while stream is running
+func (r *ActiveStreamRepository) UpdateRunningStatusByBroadcasterId(q qrm.Queryable, ctx context.Context, broadcasterID uuid.UUID, running bool) (*UpdateRunningStatusByBroadcasterIdResult, error) {
+	model := model.ActiveStreams{Running: running}
+	if !running {
+		idleSince := time.Now()
+		model.IdleSince = &idleSince
+	}
+
+	if q == nil {
+		q = r.db
+	}
+
+	err := ActiveStreams.UPDATE(ActiveStreams.Running, ActiveStreams.IdleSince).
+		MODEL(model).
+		WHERE(ActiveStreams.BroadcasterID.EQ(UUID(broadcasterID))).
+		RETURNING(
+			ActiveStreams.ID,
+			ActiveStreams.BroadcasterID,
+			ActiveStreams.Running,
+			ActiveStreams.Deployed,
+		).
+		QueryContext(ctx, q, &model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdateRunningStatusByBroadcasterIdResult{
+		ID:            model.ID,
+		BroadcasterID: model.BroadcasterID,
+		Running:       model.Running,
+		Deployed:      model.Deployed,
+	}, nil
+}
+
 type GetActiveStreamByBroadcasterIdResponse struct {
 	ID            uuid.UUID
 	BroadcasterID uuid.UUID
@@ -146,6 +189,102 @@ func (r *ActiveStreamRepository) GetActiveStreamByBroadcasterId(broadcasterID uu
 	}, nil
 }
 
+type IdleActiveStream struct {
+	BroadcasterID uuid.UUID
+	Username      string
+	Namespace     string
+	Deployment    string
+	IdleSince     time.Time
+}
+
+// Streams without publisher since the passed time. Streams which stopping was interrupted are included to retry it
+func (r *ActiveStreamRepository) GetIdleActiveStreams(ctx context.Context, idleBefore time.Time) ([]IdleActiveStream, error) {
+	var models []models.ActiveStreams
+
+	err := SELECT(ActiveStreams.AllColumns).FROM(ActiveStreams.Table).
+		WHERE(idleActiveStream(idleBefore)).
+		QueryContext(ctx, r.db, &models)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IdleActiveStream, 0, len(models))
+	for _, model := range models {
+		stream := IdleActiveStream{
+			BroadcasterID: model.BroadcasterID,
+			Username:      model.Username,
+			Namespace:     model.Namespace,
+			Deployment:    model.Deployment,
+		}
+		if model.IdleSince != nil {
+			stream.IdleSince = *model.IdleSince
+		}
+		result = append(result, stream)
+	}
+
+	return result, nil
+}
+
+func idleActiveStream(idleBefore time.Time) BoolExpression {
+	return ActiveStreams.Stopping.IS_TRUE().OR(
+		ActiveStreams.Running.IS_FALSE().
+			AND(ActiveStreams.IdleSince.LT(TimestampzT(idleBefore))),
+	)
+}
+
+// Mark the stream as stopping only if it's still idle. Publisher which connects after the mark doesn't keep the stream
+func (r *ActiveStreamRepository) MarkIdleActiveStreamStoppingByBroadcasterId(q qrm.Executable, ctx context.Context, broadcasterID uuid.UUID, idleBefore time.Time) (bool, error) {
+	if q == nil {
+		q = r.db
+	}
+
+	result, err := ActiveStreams.UPDATE(ActiveStreams.Stopping).
+		SET(Bool(true)).
+		WHERE(
+			ActiveStreams.BroadcasterID.EQ(UUID(broadcasterID)).
+				AND(idleActiveStream(idleBefore)),
+		).
+		ExecContext(ctx, q)
+
+	if err != nil {
+		return false, err
+	}
+
+	marked, err := result.RowsAffected()
+	return marked > 0, err
+}
+
+// Keep the stream when its ingest can't be stopped. It's stopped again when it's still idle
+func (r *ActiveStreamRepository) UnmarkActiveStreamStoppingByBroadcasterId(q qrm.Executable, ctx context.Context, broadcasterID uuid.UUID) error {
+	if q == nil {
+		q = r.db
+	}
+
+	_, err := ActiveStreams.UPDATE(ActiveStreams.Stopping).
+		SET(Bool(false)).
+		WHERE(ActiveStreams.BroadcasterID.EQ(UUID(broadcasterID))).
+		ExecContext(ctx, q)
+
+	return err
+}
+
+// Delete the stream which ingest is stopped
+func (r *ActiveStreamRepository) DeleteStoppingActiveStreamByBroadcasterId(q qrm.Executable, ctx context.Context, broadcasterID uuid.UUID) error {
+	if q == nil {
+		q = r.db
+	}
+
+	_, err := ActiveStreams.DELETE().
+		WHERE(
+			ActiveStreams.BroadcasterID.EQ(UUID(broadcasterID)).
+				AND(ActiveStreams.Stopping.IS_TRUE()),
+		).
+		ExecContext(ctx, q)
+
+	return err
+}
+
 type RunningActiveStreamEgress struct {
 	ID   uuid.UUID `json:"id"`
 	Type string    `json:"type"`
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	. "github.com/go-jet/jet/v2/postgres"
+	"github.com/google/uuid"
+	models "github.com/romashorodok/stream-platform/services/stream/internal/storage/schema/postgres/public/model"
+	. "github.com/romashorodok/stream-platform/services/stream/internal/storage/schema/postgres/public/table"
+	"go.uber.org/fx"
+)
+
+type RestreamTargetRepository struct {
+	db *sql.DB
+}
+
+func (r *RestreamTargetRepository) InsertRestreamTarget(ctx context.Context, broadcasterID uuid.UUID, url, streamKey string) (*models.RestreamTargets, error) {
+	var model models.RestreamTargets
+
+	err := RestreamTargets.
+		INSERT(
+			RestreamTargets.BroadcasterID,
+			RestreamTargets.URL,
+			RestreamTargets.StreamKey,
+		).
+		VALUES(
+			broadcasterID,
+			url,
+			streamKey,
+		).
+		RETURNING(RestreamTargets.AllColumns).
+		QueryContext(ctx, r.db, &model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+func (r *RestreamTargetRepository) GetRestreamTargetsByBroadcasterId(ctx context.Context, broadcasterID uuid.UUID) ([]models.RestreamTargets, error) {
+	var models []models.RestreamTargets
+
+	err := SELECT(RestreamTargets.AllColumns).FROM(RestreamTargets.Table).
+		WHERE(RestreamTargets.BroadcasterID.EQ(UUID(broadcasterID))).
+		ORDER_BY(RestreamTargets.CreatedAt.ASC()).
+		QueryContext(ctx, r.db, &models)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return models, nil
+}
+
+// Returns sql.ErrNoRows when broadcaster has no such target
+func (r *RestreamTargetRepository) DeleteRestreamTarget(ctx context.Context, broadcasterID uuid.UUID, id uuid.UUID) error {
+	result, err := RestreamTargets.DELETE().
+		WHERE(
+			RestreamTargets.ID.EQ(UUID(id)).
+				AND(RestreamTargets.BroadcasterID.EQ(UUID(broadcasterID))),
+		).
+		ExecContext(ctx, r.db)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+type RestreamTargetRepositoryParams struct {
+	fx.In
+
+	DB *sql.DB
+}
+
+func NewRestreamTargetRepository(params RestreamTargetRepositoryParams) *RestreamTargetRepository {
+	return &RestreamTargetRepository{db: params.DB}
+}
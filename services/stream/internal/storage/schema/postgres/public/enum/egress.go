@@ -12,7 +12,9 @@ import "github.com/go-jet/jet/v2/postgres"
 var Egress = &struct {
 	StreamTypeHls    postgres.StringExpression
 	StreamTypeWebrtc postgres.StringExpression
+	StreamTypeDash   postgres.StringExpression
 }{
 	StreamTypeHls:    postgres.NewEnumValue("STREAM_TYPE_HLS"),
 	StreamTypeWebrtc: postgres.NewEnumValue("STREAM_TYPE_WEBRTC"),
+	StreamTypeDash:   postgres.NewEnumValue("STREAM_TYPE_DASH"),
 }
@@ -21,4 +21,6 @@ type ActiveStreams struct {
 	Namespace     string
 	Deployment    string
 	StartAt       time.Time
+	IdleSince     *time.Time
+	Stopping      bool
 }
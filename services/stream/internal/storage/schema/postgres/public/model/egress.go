@@ -14,6 +14,7 @@ type Egress string
 const (
 	Egress_StreamTypeHls    Egress = "STREAM_TYPE_HLS"
 	Egress_StreamTypeWebrtc Egress = "STREAM_TYPE_WEBRTC"
+	Egress_StreamTypeDash   Egress = "STREAM_TYPE_DASH"
 )
 
 func (e *Egress) Scan(value interface{}) error {
@@ -32,6 +33,8 @@ func (e *Egress) Scan(value interface{}) error {
 		*e = Egress_StreamTypeHls
 	case "STREAM_TYPE_WEBRTC":
 		*e = Egress_StreamTypeWebrtc
+	case "STREAM_TYPE_DASH":
+		*e = Egress_StreamTypeDash
 	default:
 		return errors.New("jet: Invalid scan value '" + enumValue + "' for Egress enum")
 	}
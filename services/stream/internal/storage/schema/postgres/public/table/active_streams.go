@@ -25,6 +25,8 @@ type activeStreamsTable struct {
 	Namespace     postgres.ColumnString
 	Deployment    postgres.ColumnString
 	StartAt       postgres.ColumnTimestampz
+	IdleSince     postgres.ColumnTimestampz
+	Stopping      postgres.ColumnBool
 
 	AllColumns     postgres.ColumnList
 	MutableColumns postgres.ColumnList
@@ -73,8 +75,10 @@ func newActiveStreamsTableImpl(schemaName, tableName, alias string) activeStream
 		NamespaceColumn     = postgres.StringColumn("namespace")
 		DeploymentColumn    = postgres.StringColumn("deployment")
 		StartAtColumn       = postgres.TimestampzColumn("start_at")
-		allColumns          = postgres.ColumnList{IDColumn, RunningColumn, DeployedColumn, BroadcasterIDColumn, UsernameColumn, NamespaceColumn, DeploymentColumn, StartAtColumn}
-		mutableColumns      = postgres.ColumnList{RunningColumn, DeployedColumn, BroadcasterIDColumn, UsernameColumn, NamespaceColumn, DeploymentColumn, StartAtColumn}
+		IdleSinceColumn     = postgres.TimestampzColumn("idle_since")
+		StoppingColumn      = postgres.BoolColumn("stopping")
+		allColumns          = postgres.ColumnList{IDColumn, RunningColumn, DeployedColumn, BroadcasterIDColumn, UsernameColumn, NamespaceColumn, DeploymentColumn, StartAtColumn, IdleSinceColumn, StoppingColumn}
+		mutableColumns      = postgres.ColumnList{RunningColumn, DeployedColumn, BroadcasterIDColumn, UsernameColumn, NamespaceColumn, DeploymentColumn, StartAtColumn, IdleSinceColumn, StoppingColumn}
 	)
 
 	return activeStreamsTable{
@@ -89,6 +93,8 @@ func newActiveStreamsTableImpl(schemaName, tableName, alias string) activeStream
 		Namespace:     NamespaceColumn,
 		Deployment:    DeploymentColumn,
 		StartAt:       StartAtColumn,
+		IdleSince:     IdleSinceColumn,
+		Stopping:      StoppingColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
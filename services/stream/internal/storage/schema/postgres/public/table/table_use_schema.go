@@ -12,5 +12,6 @@ package table
 func UseSchema(schema string) {
 	ActiveStreamEgresses = ActiveStreamEgresses.FromSchema(schema)
 	ActiveStreams = ActiveStreams.FromSchema(schema)
+	RestreamTargets = RestreamTargets.FromSchema(schema)
 	SchemaMigrations = SchemaMigrations.FromSchema(schema)
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 	streamingpb "github.com/romashorodok/stream-platform/gen/golang/streaming/v1alpha"
+	"github.com/romashorodok/stream-platform/pkg/variables"
 	"github.com/romashorodok/stream-platform/services/stream/internal/storage/postgress/repository"
 	"github.com/romashorodok/stream-platform/services/stream/pkg/service"
 	"go.uber.org/fx"
@@ -30,8 +31,9 @@ type egressDefault struct {
 }
 
 type activeStreamDefault struct {
-	ID       uuid.UUID `json:"active_stream_id"`
-	Username string    `json:"username"`
+	ID        uuid.UUID `json:"active_stream_id"`
+	Username  string    `json:"username"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
 
 	Egresses []egressDefault `json:"egresses"`
 }
@@ -50,8 +52,9 @@ func (s *StreamChannelsService) GetActiveStreamsList(ctx context.Context) (*getA
 
 	for _, channel := range channels {
 		model := activeStreamDefault{
-			ID:       channel.ID,
-			Username: channel.Username,
+			ID:        channel.ID,
+			Username:  channel.Username,
+			Thumbnail: s.getThumbnailRoute(channel.Username),
 		}
 
 		for _, egress := range channel.Egresses {
@@ -67,6 +70,21 @@ func (s *StreamChannelsService) GetActiveStreamsList(ctx context.Context) (*getA
 	return &result, err
 }
 
+// Live preview of the channel. Empty when the ingest doesn't produce thumbnails
+func (s *StreamChannelsService) getThumbnailRoute(username string) string {
+	if !s.streamSystemConfig.Standalone {
+		return ""
+	}
+
+	for _, processor := range s.streamSystemConfig.IngestStandalone.IngestProcessors {
+		if processor == variables.INGEST_MEDIA_PROCESSOR_THUMBNAIL {
+			return s.streamSystemConfig.IngestStandalone.IngestUri + s.streamSystemConfig.IngestStandalone.IngestThumbnailRoute + "/" + username
+		}
+	}
+
+	return ""
+}
+
 type egressWithRoute struct {
 	repository.RunningActiveStreamEgress `json:"egress"`
 
@@ -83,11 +101,14 @@ func (s *StreamChannelsService) getEgressesRoutes(channel *repository.RunningAct
 
 			var egress streamingpb.StreamEgressType = streamingpb.StreamEgressType(streamingpb.StreamEgressType_value[egress.Type])
 
+			// Standalone ingest host many streams. Each egress route is scoped by broadcaster stream key
 			switch egress {
 			case streamingpb.StreamEgressType_STREAM_TYPE_HLS:
-				model.Route = s.streamSystemConfig.IngestStandalone.IngestUri + s.streamSystemConfig.IngestStandalone.IngestHLSRoute
+				model.Route = s.streamSystemConfig.IngestStandalone.IngestUri + s.streamSystemConfig.IngestStandalone.IngestHLSRoute + "/" + channel.Username
 			case streamingpb.StreamEgressType_STREAM_TYPE_WEBRTC:
-				model.Route = s.streamSystemConfig.IngestStandalone.IngestUri + s.streamSystemConfig.IngestStandalone.IngestWebrtcRoute
+				model.Route = s.streamSystemConfig.IngestStandalone.IngestUri + s.streamSystemConfig.IngestStandalone.IngestWebrtcRoute + "/" + channel.Username
+			case streamingpb.StreamEgressType_STREAM_TYPE_DASH:
+				model.Route = s.streamSystemConfig.IngestStandalone.IngestUri + s.streamSystemConfig.IngestStandalone.IngestDASHRoute + "/" + channel.Username + "/manifest.mpd"
 			}
 
 			result = append(result, model)
@@ -100,8 +121,9 @@ func (s *StreamChannelsService) getEgressesRoutes(channel *repository.RunningAct
 }
 
 type activeStreamWithEgressRoutes struct {
-	ID       uuid.UUID `json:"active_stream_id"`
-	Username string    `json:"username"`
+	ID        uuid.UUID `json:"active_stream_id"`
+	Username  string    `json:"username"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
 
 	Egresses []egressWithRoute `json:"egresses"`
 }
@@ -130,9 +152,10 @@ func (s *StreamChannelsService) GetActiveStream(ctx context.Context, username st
 
 	return &getActiveStream{
 		Channel: activeStreamWithEgressRoutes{
-			ID:       channel.ID,
-			Username: channel.Username,
-			Egresses: egressesWithRoutes,
+			ID:        channel.ID,
+			Username:  channel.Username,
+			Thumbnail: s.getThumbnailRoute(channel.Username),
+			Egresses:  egressesWithRoutes,
 		},
 	}, nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"time"
 
 	ingestioncontrollerpb "github.com/romashorodok/stream-platform/gen/golang/ingestion_controller_operator/v1alpha"
 	"github.com/romashorodok/stream-platform/pkg/auth"
@@ -22,9 +23,29 @@ var (
 )
 
 type StreamService struct {
-	ingestController       ingestioncontrollerpb.IngestControllerServiceClient
-	config                 *service.StreamSystemConfig
-	activeStreamRepository *repository.ActiveStreamRepository
+	ingestController         ingestioncontrollerpb.IngestControllerServiceClient
+	config                   *service.StreamSystemConfig
+	activeStreamRepository   *repository.ActiveStreamRepository
+	restreamTargetRepository *repository.RestreamTargetRepository
+}
+
+// Stream still starts when targets can't be loaded, the broadcast just isn't restreamed
+func (s *StreamService) restreamTargets(ctx context.Context, token *auth.TokenPayload) []*ingestioncontrollerpb.RestreamTarget {
+	models, err := s.restreamTargetRepository.GetRestreamTargetsByBroadcasterId(ctx, token.UserID)
+	if err != nil {
+		log.Printf("[%s]: Unable get restream targets. Err: %s", token.Sub, err)
+		return nil
+	}
+
+	targets := make([]*ingestioncontrollerpb.RestreamTarget, 0, len(models))
+	for _, model := range models {
+		targets = append(targets, &ingestioncontrollerpb.RestreamTarget{
+			Id:  model.ID.String(),
+			Url: model.URL,
+			Key: model.StreamKey,
+		})
+	}
+	return targets
 }
 
 func (s *StreamService) StartIngestServer(ctx context.Context, token *auth.TokenPayload) error {
@@ -37,6 +58,7 @@ func (s *StreamService) StartIngestServer(ctx context.Context, token *auth.Token
 			BroadcasterId: token.UserID.String(),
 			Username:      token.Sub,
 		},
+		RestreamTargets: s.restreamTargets(ctx, token),
 	})
 	if err != nil {
 		log.Printf("[%s]: Unable start ingest server. Err: %s", token.Sub, err)
@@ -98,18 +120,55 @@ func (s *StreamService) StopIngestServer(ctx context.Context, token *auth.TokenP
 	return nil
 }
 
+// Stop ingest which has no publisher since idleBefore. Returns false when the stream is not idle anymore. Stream is marked
+// as stopping before the operator call and deleted after it, so the failed stop is retried
+func (s *StreamService) StopIdleIngestServer(ctx context.Context, stream repository.IdleActiveStream, idleBefore time.Time) (bool, error) {
+	idle, err := s.activeStreamRepository.MarkIdleActiveStreamStoppingByBroadcasterId(nil, ctx, stream.BroadcasterID, idleBefore)
+	if err != nil {
+		log.Printf("[%s]: Unable mark idle stream as stopping. Err: %s", stream.Username, err)
+		return false, UnableDeleteStream
+	}
+	if !idle {
+		return false, nil
+	}
+
+	if _, err := s.ingestController.StopServer(ctx, &ingestioncontrollerpb.StopServerRequest{
+		Namespace:  stream.Namespace,
+		Deployment: stream.Deployment,
+		Meta: &ingestioncontrollerpb.BroadcasterMeta{
+			BroadcasterId: stream.BroadcasterID.String(),
+			Username:      stream.Username,
+		},
+	}); err != nil {
+		log.Printf("[%s]: Unable stop idle stream. Err: %s", stream.Username, err)
+		if err := s.activeStreamRepository.UnmarkActiveStreamStoppingByBroadcasterId(nil, ctx, stream.BroadcasterID); err != nil {
+			log.Printf("[%s]: Unable unmark idle stream. Err: %s", stream.Username, err)
+		}
+		return false, UnableStopStream
+	}
+
+	if err := s.activeStreamRepository.DeleteStoppingActiveStreamByBroadcasterId(nil, ctx, stream.BroadcasterID); err != nil {
+		log.Printf("[%s]: Unable delete idle stream. Err: %s", stream.Username, err)
+		return true, UnableDeleteStream
+	}
+
+	return true, nil
+}
+
 type StreamServiceParams struct {
 	fx.In
 
-	IngestController       ingestioncontrollerpb.IngestControllerServiceClient
-	Config                 *service.StreamSystemConfig
-	ActiveStreamRepository *repository.ActiveStreamRepository
+	IngestController         ingestioncontrollerpb.IngestControllerServiceClient
+	Config                   *service.StreamSystemConfig
+	ActiveStreamRepository   *repository.ActiveStreamRepository
+	RestreamTargetRepository *repository.RestreamTargetRepository
 }
 
 func NewStreamService(params StreamServiceParams) *StreamService {
 	return &StreamService{
-		ingestController:       params.IngestController,
-		config:                 params.Config,
-		activeStreamRepository: params.ActiveStreamRepository,
+		ingestController:         params.IngestController,
+		config:                   params.Config,
+		activeStreamRepository:   params.ActiveStreamRepository,
+		restreamTargetRepository: params.RestreamTargetRepository,
 	}
 }
@@ -0,0 +1,107 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/romashorodok/stream-platform/pkg/subject"
+	"github.com/romashorodok/stream-platform/services/stream/internal/storage/postgress/repository"
+	"github.com/romashorodok/stream-platform/services/stream/internal/streamsvc"
+	"github.com/romashorodok/stream-platform/services/stream/pkg/service"
+	"go.uber.org/fx"
+)
+
+const reapInterval = 30 * time.Second
+
+type idleStreamRepository interface {
+	GetIdleActiveStreams(ctx context.Context, idleBefore time.Time) ([]repository.IdleActiveStream, error)
+}
+
+type idleIngestStopper interface {
+	StopIdleIngestServer(ctx context.Context, stream repository.IdleActiveStream, idleBefore time.Time) (bool, error)
+}
+
+type notificationPublisher interface {
+	Publish(subj string, data []byte) error
+}
+
+// Stop deployed ingests which have no publisher for the idle timeout. Idle time starts on deploy and on publisher disconnect
+type ingestIdleReaper struct {
+	conn                   notificationPublisher
+	activeStreamRepository idleStreamRepository
+	streamService          idleIngestStopper
+	idleTimeout            time.Duration
+	now                    func() time.Time
+}
+
+func (work *ingestIdleReaper) reap() {
+	ctx, cancel := context.WithTimeout(context.Background(), reapInterval)
+	defer cancel()
+
+	idleBefore := work.now().Add(-work.idleTimeout)
+
+	streams, err := work.activeStreamRepository.GetIdleActiveStreams(ctx, idleBefore)
+	if err != nil {
+		log.Printf("[Ingest Idle Reaper]: Unable get idle streams. Err: %s", err)
+		return
+	}
+
+	for _, stream := range streams {
+		stopped, err := work.streamService.StopIdleIngestServer(ctx, stream, idleBefore)
+		if err != nil {
+			log.Printf("[Ingest Idle Reaper]: Unable stop %s. Err: %s", stream.Username, err)
+		}
+		if !stopped {
+			continue
+		}
+
+		log.Printf("[Ingest Idle Reaper]: %s has no publisher since %s. Stopped", stream.Username, stream.IdleSince.Format(time.RFC3339))
+
+		if err := work.conn.Publish(subject.NewStreamDestroyedNotification(stream.BroadcasterID.String()), nil); err != nil {
+			log.Printf("[Ingest Idle Reaper]: Unable send notification for %s", stream.BroadcasterID)
+		}
+	}
+}
+
+func (work *ingestIdleReaper) Start() {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		work.reap()
+	}
+}
+
+type StartIngestIdleReaperParams struct {
+	fx.In
+
+	Conn                   *nats.Conn
+	Config                 *service.StreamSystemConfig
+	ActiveStreamRepository *repository.ActiveStreamRepository
+	StreamService          *streamsvc.StreamService
+}
+
+func StartIngestIdleReaper(params StartIngestIdleReaperParams) {
+	// Standalone ingest is shared and never stopped, its streams are not running per broadcaster
+	if params.Config.Standalone {
+		log.Println("[Ingest Idle Reaper]: Ingest is standalone. Reaper is disabled")
+		return
+	}
+
+	if params.Config.IngestIdleTimeout == 0 {
+		log.Println("[Ingest Idle Reaper]: Idle timeout is zero. Reaper is disabled")
+		return
+	}
+
+	worker := ingestIdleReaper{
+		conn:                   params.Conn,
+		activeStreamRepository: params.ActiveStreamRepository,
+		streamService:          params.StreamService,
+		idleTimeout:            params.Config.IngestIdleTimeout,
+		now:                    time.Now,
+	}
+
+	go worker.Start()
+}
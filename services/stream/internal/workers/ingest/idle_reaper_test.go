@@ -0,0 +1,105 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romashorodok/stream-platform/pkg/subject"
+	"github.com/romashorodok/stream-platform/services/stream/internal/storage/postgress/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+type testIdleStreams struct {
+	streams    []repository.IdleActiveStream
+	err        error
+	idleBefore time.Time
+}
+
+func (r *testIdleStreams) GetIdleActiveStreams(ctx context.Context, idleBefore time.Time) ([]repository.IdleActiveStream, error) {
+	r.idleBefore = idleBefore
+	return r.streams, r.err
+}
+
+// Stops streams of the usernames. Other streams are already running again or stopped by the operator failure
+type testIdleStopper struct {
+	stopped map[string]bool
+	err     error
+}
+
+func (s *testIdleStopper) StopIdleIngestServer(ctx context.Context, stream repository.IdleActiveStream, idleBefore time.Time) (bool, error) {
+	return s.stopped[stream.Username], s.err
+}
+
+type testPublisher struct {
+	subjects []string
+}
+
+func (p *testPublisher) Publish(subj string, data []byte) error {
+	p.subjects = append(p.subjects, subj)
+	return nil
+}
+
+func TestIngestIdleReaper_Reap(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	idle := repository.IdleActiveStream{BroadcasterID: uuid.New(), Username: "idle", IdleSince: now.Add(-time.Hour)}
+	published := repository.IdleActiveStream{BroadcasterID: uuid.New(), Username: "published", IdleSince: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name      string
+		streams   *testIdleStreams
+		stopper   *testIdleStopper
+		destroyed []repository.IdleActiveStream
+	}{
+		{
+			name:      "stopped idle stream is destroyed",
+			streams:   &testIdleStreams{streams: []repository.IdleActiveStream{idle}},
+			stopper:   &testIdleStopper{stopped: map[string]bool{"idle": true}},
+			destroyed: []repository.IdleActiveStream{idle},
+		},
+		{
+			name:    "stream with new publisher is kept",
+			streams: &testIdleStreams{streams: []repository.IdleActiveStream{idle, published}},
+			stopper: &testIdleStopper{stopped: map[string]bool{"idle": true}},
+			// Publisher reconnected after the query, stop is rejected by the idle time
+			destroyed: []repository.IdleActiveStream{idle},
+		},
+		{
+			name:    "operator failure keeps the stream",
+			streams: &testIdleStreams{streams: []repository.IdleActiveStream{idle}},
+			stopper: &testIdleStopper{err: errors.New("operator is unavailable")},
+		},
+		{
+			name:    "repository failure",
+			streams: &testIdleStreams{err: errors.New("connection refused")},
+			stopper: &testIdleStopper{stopped: map[string]bool{"idle": true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			publisher := &testPublisher{}
+			worker := ingestIdleReaper{
+				conn:                   publisher,
+				activeStreamRepository: test.streams,
+				streamService:          test.stopper,
+				idleTimeout:            10 * time.Minute,
+				now:                    func() time.Time { return now },
+			}
+
+			worker.reap()
+
+			assert.Equal(now.Add(-10*time.Minute), test.streams.idleBefore)
+
+			var subjects []string
+			for _, stream := range test.destroyed {
+				subjects = append(subjects, subject.NewStreamDestroyedNotification(stream.BroadcasterID.String()))
+			}
+			assert.Equal(subjects, publisher.subjects)
+		})
+	}
+}
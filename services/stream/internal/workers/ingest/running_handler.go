@@ -0,0 +1,199 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	streamingpb "github.com/romashorodok/stream-platform/gen/golang/streaming/v1alpha"
+	subjectpb "github.com/romashorodok/stream-platform/gen/golang/subject/v1alpha"
+	"github.com/romashorodok/stream-platform/pkg/subject"
+	"github.com/romashorodok/stream-platform/services/stream/internal/storage/postgress/repository"
+	"go.uber.org/fx"
+)
+
+const (
+	// Running ingest which misses this count of heartbeats is considered stopped
+	missedHeartbeats = 3
+	sweepInterval    = 5 * time.Second
+)
+
+type ingestHeartbeat struct {
+	seenAt   time.Time
+	interval time.Duration
+	running  bool
+}
+
+func (h ingestHeartbeat) expired(now time.Time) bool {
+	return now.Sub(h.seenAt) > h.interval*missedHeartbeats
+}
+
+type ingestRunningWorker struct {
+	conn                   *nats.Conn
+	activeStreamRepository *repository.ActiveStreamRepository
+
+	heartbeats map[uuid.UUID]ingestHeartbeat
+	mx         sync.Mutex
+}
+
+// Update running column and notify dashboard peers of the broadcaster
+func (work *ingestRunningWorker) setRunning(broadcasterID uuid.UUID, running bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	activeStream, err := work.activeStreamRepository.UpdateRunningStatusByBroadcasterId(nil, ctx, broadcasterID, running)
+	if err != nil {
+		log.Printf("[Ingest Running Worker]: For %s failed to update running status. Err: %s", broadcasterID, err)
+		return
+	}
+
+	status := &streamingpb.StreamStatus{Running: activeStream.Running, Deployed: activeStream.Deployed}
+	if err := subject.PublishProtobuf(work.conn, subject.NewStreamStatusNotification(broadcasterID.String()), status); err != nil {
+		log.Printf("[Ingest Running Worker]: Unable send status notification for %s. Err: %s", broadcasterID, err)
+	}
+}
+
+// Remember the state reported by ingest. Returns true when it differs from the previous one
+func (work *ingestRunningWorker) track(broadcasterID uuid.UUID, running bool, interval time.Duration) bool {
+	work.mx.Lock()
+	defer work.mx.Unlock()
+
+	previous, ok := work.heartbeats[broadcasterID]
+	if interval == 0 {
+		interval = previous.interval
+	}
+
+	work.heartbeats[broadcasterID] = ingestHeartbeat{seenAt: time.Now(), interval: interval, running: running}
+
+	return !ok || previous.running != running
+}
+
+func (work *ingestRunningWorker) broadcasterID(meta *subjectpb.BroadcasterMeta) (uuid.UUID, bool) {
+	if meta == nil {
+		log.Println("[Ingest Running Worker]: Message without broadcaster meta")
+		return uuid.Nil, false
+	}
+
+	broadcasterID, err := uuid.Parse(meta.BroadcasterId)
+	if err != nil {
+		log.Printf("[Ingest Running Worker]: Unable parse broadcaster UUID %s. Err: %s", meta.BroadcasterId, err)
+		return uuid.Nil, false
+	}
+
+	return broadcasterID, true
+}
+
+func (work *ingestRunningWorker) onRunning(msg *nats.Msg) {
+	var req subject.IngestRunning
+	if err := subject.DeserializeProtobufMsg(&req, msg); err != nil {
+		log.Printf("[Ingest Running Worker]: For %s failed to deserialize msg. Err: %s", msg.Subject, err)
+		return
+	}
+
+	broadcasterID, ok := work.broadcasterID(req.Meta)
+	if !ok {
+		return
+	}
+
+	work.track(broadcasterID, true, 0)
+	work.setRunning(broadcasterID, true)
+}
+
+func (work *ingestRunningWorker) onStopped(msg *nats.Msg) {
+	var req subject.IngestStopped
+	if err := subject.DeserializeProtobufMsg(&req, msg); err != nil {
+		log.Printf("[Ingest Running Worker]: For %s failed to deserialize msg. Err: %s", msg.Subject, err)
+		return
+	}
+
+	broadcasterID, ok := work.broadcasterID(req.Meta)
+	if !ok {
+		return
+	}
+
+	work.track(broadcasterID, false, 0)
+	work.setRunning(broadcasterID, false)
+}
+
+// Heartbeat recovers the state when running or stopped event is missed
+func (work *ingestRunningWorker) onHeartbeat(msg *nats.Msg) {
+	var req subject.IngestHeartbeat
+	if err := subject.DeserializeProtobufMsg(&req, msg); err != nil {
+		log.Printf("[Ingest Running Worker]: For %s failed to deserialize msg. Err: %s", msg.Subject, err)
+		return
+	}
+
+	broadcasterID, ok := work.broadcasterID(req.Meta)
+	if !ok {
+		return
+	}
+
+	if work.track(broadcasterID, req.Running, time.Duration(req.IntervalSeconds)*time.Second) {
+		work.setRunning(broadcasterID, req.Running)
+	}
+}
+
+// Ingest which is gone without stopped event is marked as stopped
+func (work *ingestRunningWorker) sweep() {
+	now := time.Now()
+
+	var stopped []uuid.UUID
+	work.mx.Lock()
+	for broadcasterID, heartbeat := range work.heartbeats {
+		if heartbeat.interval == 0 || !heartbeat.expired(now) {
+			continue
+		}
+		if heartbeat.running {
+			stopped = append(stopped, broadcasterID)
+		}
+		delete(work.heartbeats, broadcasterID)
+	}
+	work.mx.Unlock()
+
+	for _, broadcasterID := range stopped {
+		log.Printf("[Ingest Running Worker]: %s missed heartbeats. Mark as stopped", broadcasterID)
+		work.setRunning(broadcasterID, false)
+	}
+}
+
+func (work *ingestRunningWorker) Start() {
+	defer work.conn.Drain()
+
+	subscriptions := map[string]nats.MsgHandler{
+		subject.IngestAnyUserRunning:   work.onRunning,
+		subject.IngestAnyUserStopped:   work.onStopped,
+		subject.IngestAnyUserHeartbeat: work.onHeartbeat,
+	}
+	for subj, handler := range subscriptions {
+		if _, err := work.conn.Subscribe(subj, handler); err != nil {
+			log.Printf("[Ingest Running Worker]: Unable subscribe %s. Err: %s", subj, err)
+		}
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		work.sweep()
+	}
+}
+
+type StartIngestRunningWorkerParams struct {
+	fx.In
+
+	Conn                   *nats.Conn
+	ActiveStreamRepository *repository.ActiveStreamRepository
+}
+
+func StartIngestRunningWorker(params StartIngestRunningWorkerParams) {
+	worker := ingestRunningWorker{
+		conn:                   params.Conn,
+		activeStreamRepository: params.ActiveStreamRepository,
+		heartbeats:             make(map[uuid.UUID]ingestHeartbeat),
+	}
+
+	go worker.Start()
+}
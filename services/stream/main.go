@@ -15,9 +15,11 @@ import (
 	"github.com/romashorodok/stream-platform/pkg/httputils"
 	"github.com/romashorodok/stream-platform/pkg/subject"
 	"github.com/romashorodok/stream-platform/pkg/variables"
+	"github.com/romashorodok/stream-platform/services/stream/internal/handler/restream"
 	"github.com/romashorodok/stream-platform/services/stream/internal/handler/stream"
 	"github.com/romashorodok/stream-platform/services/stream/internal/handler/streamchannels"
 	"github.com/romashorodok/stream-platform/services/stream/internal/ingestcontroller"
+	"github.com/romashorodok/stream-platform/services/stream/internal/restreamsvc"
 	"github.com/romashorodok/stream-platform/services/stream/internal/storage/postgress/repository"
 	"github.com/romashorodok/stream-platform/services/stream/internal/streamchannelssvc"
 	"github.com/romashorodok/stream-platform/services/stream/internal/streamsvc"
@@ -212,6 +214,7 @@ func main() {
 
 		fx.Provide(httputils.AsHttpHandler(stream.NewStreaminServiceHandler)),
 		fx.Provide(httputils.AsHttpHandler(streamchannels.NewStreamChannelsServiceHandler)),
+		fx.Provide(httputils.AsHttpHandler(restream.NewRestreamServiceHandler)),
 
 		fx.Invoke(service.StartStreamHttp),
 
@@ -230,6 +233,8 @@ func main() {
 
 			repository.NewActiveStreamRepository,
 			repository.NewStreamEgressRepository,
+			repository.NewRestreamTargetRepository,
+			restreamsvc.NewRestreamService,
 			streamsvc.NewStreamStatus,
 			streamsvc.NewStreamService,
 
@@ -237,5 +242,7 @@ func main() {
 		),
 		fx.Invoke(ingestworker.StartIngestStatusWorker),
 		fx.Invoke(ingestworker.StartIngestDestroyedWorker),
+		fx.Invoke(ingestworker.StartIngestRunningWorker),
+		fx.Invoke(ingestworker.StartIngestIdleReaper),
 	).Run()
 }
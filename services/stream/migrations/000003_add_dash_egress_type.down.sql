@@ -0,0 +1,10 @@
+
+DELETE FROM active_stream_egresses WHERE type = 'STREAM_TYPE_DASH';
+
+ALTER TYPE EGRESS RENAME TO EGRESS_OLD;
+
+CREATE TYPE EGRESS AS ENUM ('STREAM_TYPE_HLS', 'STREAM_TYPE_WEBRTC');
+
+ALTER TABLE active_stream_egresses ALTER COLUMN type TYPE EGRESS USING type::text::EGRESS;
+
+DROP TYPE EGRESS_OLD;
@@ -0,0 +1,4 @@
+
+-- Protobuf generated type. Values can be found in ingest.pb.go at `IngestEgressType_value'
+
+ALTER TYPE EGRESS ADD VALUE IF NOT EXISTS 'STREAM_TYPE_DASH';
@@ -0,0 +1,2 @@
+
+DROP TABLE IF EXISTS restream_targets CASCADE;
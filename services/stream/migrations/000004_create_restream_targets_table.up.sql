@@ -0,0 +1,14 @@
+
+CREATE TABLE restream_targets (
+    id UUID NOT NULL DEFAULT uuid_generate_v4(),
+
+    broadcaster_id UUID NOT NULL,
+
+    url VARCHAR(2048) NOT NULL,
+    stream_key VARCHAR(512) NOT NULL,
+    created_at TIMESTAMPTZ(6) NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX restream_targets_broadcaster_id_idx ON restream_targets (broadcaster_id);
@@ -0,0 +1 @@
+ALTER TABLE active_streams DROP COLUMN IF EXISTS idle_since;
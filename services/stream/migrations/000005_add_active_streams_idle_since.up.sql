@@ -0,0 +1,2 @@
+-- Null while the publisher is live
+ALTER TABLE active_streams ADD COLUMN IF NOT EXISTS idle_since TIMESTAMPTZ(6) DEFAULT NOW();
@@ -0,0 +1,2 @@
+-- Idle stream which ingest is stopping. It's deleted once the operator stops the ingest
+ALTER TABLE active_streams ADD COLUMN IF NOT EXISTS stopping BOOLEAN NOT NULL DEFAULT FALSE;
@@ -2,6 +2,8 @@ package service
 
 import (
 	"log"
+	"strings"
+	"time"
 
 	"github.com/romashorodok/stream-platform/pkg/envutils"
 	"github.com/romashorodok/stream-platform/pkg/variables"
@@ -11,6 +13,8 @@ import (
 type StreamSystemConfig struct {
 	Standalone       bool
 	IngestStandalone *IngestStandaloneConfig
+	// Deployed ingest without publisher for this duration is stopped. Zero disables it
+	IngestIdleTimeout time.Duration
 }
 
 type IngestStandaloneConfig struct {
@@ -20,6 +24,11 @@ type IngestStandaloneConfig struct {
 	IngestTemplate    string
 	IngestWebrtcRoute string
 	IngestHLSRoute    string
+	IngestDASHRoute   string
+	// Preview of the channel. Served when ingest runs the thumbnail processor
+	IngestThumbnailRoute string
+	// Must be the same as INGEST_MEDIA_PROCESSORS of the standalone ingest
+	IngestProcessors []string
 }
 
 func NewStreamSystemConfig() *StreamSystemConfig {
@@ -30,16 +39,34 @@ func NewStreamSystemConfig() *StreamSystemConfig {
 		standalone, _ = envutils.ParseBool(variables.STREAM_STANDALONE_DEFAULT)
 	}
 
+	var ingestProcessors []string
+	for _, processor := range strings.Split(envutils.Env(variables.STREAM_STANDALONE_INGEST_PROCESSORS, variables.STREAM_STANDALONE_INGEST_PROCESSORS_DEFAULT), ",") {
+		if processor = strings.TrimSpace(processor); processor != "" {
+			ingestProcessors = append(ingestProcessors, processor)
+		}
+	}
+
+	idleTimeoutRaw := envutils.Env(variables.STREAM_INGEST_IDLE_TIMEOUT, variables.STREAM_INGEST_IDLE_TIMEOUT_DEFAULT)
+	idleTimeout, err := time.ParseDuration(idleTimeoutRaw)
+	if err != nil || idleTimeout < 0 {
+		log.Printf("[ERROR] wrong ingest idle timeout %s. Fallback to %s", idleTimeoutRaw, variables.STREAM_INGEST_IDLE_TIMEOUT_DEFAULT)
+		idleTimeout, _ = time.ParseDuration(variables.STREAM_INGEST_IDLE_TIMEOUT_DEFAULT)
+	}
+
 	return &StreamSystemConfig{
-		Standalone: *standalone,
+		Standalone:        *standalone,
+		IngestIdleTimeout: idleTimeout,
 
 		IngestStandalone: &IngestStandaloneConfig{
-			Deployment:        envutils.Env(variables.STREAM_STANDALONE_INGEST_DEPLOYMENT, variables.STREAM_STANDALONE_INGEST_DEPLOYMENT_DEFAULT),
-			Namespace:         envutils.Env(variables.STREAM_STANDALONE_INGEST_NAMESPACE, variables.STREAM_STANDALONE_INGEST_NAMESPACE_DEFAULT),
-			IngestUri:         envutils.Env(variables.STREAM_STANDALONE_INGEST_URI, variables.STREAM_STANDALONE_INGEST_URI_DEFAULT),
-			IngestTemplate:    envutils.Env(variables.STREAM_INGEST_TEMPLATE, variables.STREAM_INGEST_TEMPLATE_DEFAULT),
-			IngestWebrtcRoute: envutils.Env(variables.STREAM_STANDALONE_INGEST_EGRESS_WEBRTC, variables.STREAM_STANDALONE_INGEST_EGRESS_WEBRTC_DEFAULT),
-			IngestHLSRoute:    envutils.Env(variables.STREAM_STANDALONE_INGEST_EGRESS_HLS, variables.STREAM_STANDALONE_INGEST_EGRESS_HLS_DEFAULT),
+			Deployment:           envutils.Env(variables.STREAM_STANDALONE_INGEST_DEPLOYMENT, variables.STREAM_STANDALONE_INGEST_DEPLOYMENT_DEFAULT),
+			Namespace:            envutils.Env(variables.STREAM_STANDALONE_INGEST_NAMESPACE, variables.STREAM_STANDALONE_INGEST_NAMESPACE_DEFAULT),
+			IngestUri:            envutils.Env(variables.STREAM_STANDALONE_INGEST_URI, variables.STREAM_STANDALONE_INGEST_URI_DEFAULT),
+			IngestTemplate:       envutils.Env(variables.STREAM_INGEST_TEMPLATE, variables.STREAM_INGEST_TEMPLATE_DEFAULT),
+			IngestWebrtcRoute:    envutils.Env(variables.STREAM_STANDALONE_INGEST_EGRESS_WEBRTC, variables.STREAM_STANDALONE_INGEST_EGRESS_WEBRTC_DEFAULT),
+			IngestHLSRoute:       envutils.Env(variables.STREAM_STANDALONE_INGEST_EGRESS_HLS, variables.STREAM_STANDALONE_INGEST_EGRESS_HLS_DEFAULT),
+			IngestDASHRoute:      envutils.Env(variables.STREAM_STANDALONE_INGEST_EGRESS_DASH, variables.STREAM_STANDALONE_INGEST_EGRESS_DASH_DEFAULT),
+			IngestThumbnailRoute: envutils.Env(variables.STREAM_STANDALONE_INGEST_EGRESS_THUMBNAIL, variables.STREAM_STANDALONE_INGEST_EGRESS_THUMBNAIL_DEFAULT),
+			IngestProcessors:     ingestProcessors,
 		},
 	}
 }